@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/importer"
+	"github.com/dsaleh/david-dotfiles/tui"
+)
+
+// runAdd implements the add subcommand:
+//
+//	add [catalog]    search GitHub for a tool and append its entry to the catalog
+//
+// The asset_pattern is detected from the chosen repo's latest release, as for
+// import; binaries are picked on first install.
+func runAdd(ctx context.Context, args []string) int {
+	if len(args) > 1 {
+		fmt.Fprintln(os.Stderr, "usage: installer add [catalog]")
+		return 2
+	}
+	catalogPath := "catalog.toml"
+	if len(args) == 1 {
+		catalogPath = args[0]
+	}
+	programs, err := catalog.Load(catalogPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading catalog: %v\n", err)
+		return 1
+	}
+
+	final, err := tea.NewProgram(tui.NewAdd(ctx, programs), tea.WithAltScreen()).Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
+		return 1
+	}
+	entry, ok := final.(tui.AddModel).Entry()
+	if !ok {
+		return 0
+	}
+
+	if err := appendEntries(catalogPath, []importer.Entry{entry}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", catalogPath, err)
+		return 1
+	}
+	fmt.Printf("Added %s (%s) to %s\n", entry.Name, entry.Repo, catalogPath)
+	return 0
+}
+
+// appendEntries appends entries to the catalog at path as program tables.
+func appendEntries(path string, entries []importer.Entry) error {
+	if err := catalog.Editable(path); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(f)
+	importer.WriteTOML(f, entries)
+	return f.Close()
+}
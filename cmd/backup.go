@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/backup"
+	"github.com/dsaleh/david-dotfiles/internal/config"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/trash"
+)
+
+// runBackup implements the backup subcommand:
+//
+//	backup [-o file] [--no-programs] [catalog...]
+//	        write the state file, config and catalogs, plus every installed
+//	        program's install dir and bin entries, to one tar.gz archive
+//
+// Catalogs default to those an install would load. `restore --from` puts
+// the archive back.
+func runBackup(args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	out := fs.String("o", "", "archive to write (default dotfiles-backup-<host>-<date>.tar.gz)")
+	noPrograms := fs.Bool("no-programs", false, "leave out install dirs and bin entries; a restore then reinstalls them on the next run")
+	fs.Parse(args)
+
+	cfg, err := config.Load(config.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 1
+	}
+	catalogs := fs.Args()
+	if len(catalogs) == 0 {
+		catalogs = cfg.Catalogs
+	}
+	if len(catalogs) == 0 {
+		if _, err := os.Stat("catalog.toml"); err == nil {
+			catalogs = []string{"catalog.toml"}
+		}
+	}
+	st, err := state.Load(state.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading state: %v\n", err)
+		return 1
+	}
+
+	path := *out
+	if path == "" {
+		hostname, _ := os.Hostname()
+		path = fmt.Sprintf("dotfiles-backup-%s-%s.tar.gz", hostname, time.Now().Format("20060102"))
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	m, err := backup.Create(f, st, backup.Options{
+		Home:     os.Getenv("HOME"),
+		State:    state.Path(),
+		Config:   config.Path(),
+		Catalogs: catalogs,
+		Programs: !*noPrograms,
+	})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		fmt.Fprintf(os.Stderr, "Error writing backup: %v\n", err)
+		return 1
+	}
+	for _, it := range m.Items {
+		fmt.Printf("%-8s %s\n", it.Kind, it.Path)
+	}
+	fmt.Printf("Wrote %d item(s) to %s.\n", len(m.Items), path)
+	return 0
+}
+
+// restoreBackup implements `restore --from <archive>`, moving whatever the
+// archive's items replace into a trash entry of their own.
+func restoreBackup(path string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+	// Besides $HOME, items may only replace the files an install would use.
+	allowed := []string{state.Path(), config.Path()}
+	catalogs := []string{"catalog.toml"}
+	if cfg, err := config.Load(config.Path()); err == nil {
+		catalogs = append(catalogs, cfg.Catalogs...)
+	}
+	for _, c := range catalogs {
+		if abs, err := filepath.Abs(c); err == nil {
+			allowed = append(allowed, abs)
+		}
+	}
+	bin := trash.Open(trash.Path(), "backup", "restore", nil)
+	m, restored, err := backup.Restore(f, os.Getenv("HOME"), allowed, bin)
+	for _, p := range restored {
+		fmt.Printf("restored %s\n", p)
+	}
+	if bin.ID != "" {
+		fmt.Printf("What it replaced is in the trash as %s.\n", bin.ID)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error restoring %s: %v\n", path, err)
+		return 1
+	}
+	fmt.Printf("Restored the backup of %s from %s.\n", m.Host, m.Created.Local().Format("2006-01-02 15:04"))
+	return 0
+}
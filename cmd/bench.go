@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/config"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/transport"
+)
+
+// benchStep is one timed step of a bench run.
+type benchStep struct {
+	Name     string         `json:"name"`
+	Duration time.Duration  `json:"duration_ns"`
+	Requests map[string]int `json:"requests"` // host → HTTP requests made during the step
+	Failed   int            `json:"failed,omitempty"`
+}
+
+func (s benchStep) total() int {
+	n := 0
+	for _, c := range s.Requests {
+		n += c
+	}
+	return n
+}
+
+// runBench implements the bench subcommand:
+//
+//	bench [--json] [catalog...]
+//	        time loading the catalogs and resolving every program's release,
+//	        once with nothing known and once more with the latest releases
+//	        the first pass found, counting the HTTP requests of each step
+//
+// Nothing is downloaded or installed. The numbers show whether a change to
+// resolution or its caching saves time and API requests, and --json makes
+// them easy to compare between builds.
+func runBench(ctx context.Context, t *transport.Transport, args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	jsonOut := fs.Bool("json", false, "print the steps as JSON")
+	fs.Parse(args)
+
+	cfg, err := config.Load(config.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 1
+	}
+	st, err := state.Load(state.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading state: %v\n", err)
+		return 1
+	}
+
+	var programs []catalog.Program
+	load := measure(t, "catalog load", func() int {
+		programs, err = loadCatalogs(fs.Args(), cfg)
+		return 0
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading catalog: %v\n", err)
+		return 1
+	}
+	authorize(t, programs)
+
+	opts := runOptions(cfg, true)
+	opts.State = st
+	resolver := installer.NewResolver(opts)
+	resolve := func() int {
+		failed := 0
+		for _, res := range resolver.Resolve(ctx, programs) {
+			if res.Err != nil {
+				failed++
+			}
+		}
+		return failed
+	}
+	steps := []benchStep{
+		load,
+		measure(t, "resolve (cold)", resolve),
+		measure(t, "resolve (warm)", resolve),
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(struct {
+			Programs int         `json:"programs"`
+			Steps    []benchStep `json:"steps"`
+		}{len(programs), steps})
+		return 0
+	}
+	fmt.Printf("%d programs\n", len(programs))
+	for _, s := range steps {
+		line := fmt.Sprintf("%-16s %10s  %3d requests", s.Name, s.Duration.Round(time.Microsecond), s.total())
+		if s.total() > 0 {
+			var hosts []string
+			for _, host := range slices.Sorted(maps.Keys(s.Requests)) {
+				hosts = append(hosts, fmt.Sprintf("%s %d", host, s.Requests[host]))
+			}
+			line += " (" + strings.Join(hosts, ", ") + ")"
+		}
+		if s.Failed > 0 {
+			line += fmt.Sprintf("  %d failed", s.Failed)
+		}
+		fmt.Println(line)
+	}
+	return 0
+}
+
+// measure runs f, which returns how many programs failed, and times it and
+// counts the requests t sent meanwhile.
+func measure(t *transport.Transport, name string, f func() int) benchStep {
+	before := t.Requests()
+	start := time.Now()
+	failed := f()
+	s := benchStep{Name: name, Duration: time.Since(start), Requests: t.Requests(), Failed: failed}
+	for host, n := range before {
+		if s.Requests[host] -= n; s.Requests[host] == 0 {
+			delete(s.Requests, host)
+		}
+	}
+	return s
+}
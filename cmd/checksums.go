@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dsaleh/david-dotfiles/internal/sumdb"
+)
+
+// runChecksums implements the checksums subcommand:
+//
+//	checksums                  list the recorded asset hashes
+//	checksums export [file]    write them out (stdout by default)
+//	checksums import <file>... add the hashes another machine exported
+//
+// Imported hashes that disagree with recorded ones are reported and not
+// added; the command then exits non-zero.
+func runChecksums(args []string) int {
+	db, err := sumdb.Open(sumdb.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading checksum database: %v\n", err)
+		return 1
+	}
+	if len(args) == 0 {
+		entries := db.Entries()
+		if len(entries) == 0 {
+			fmt.Println("No checksums recorded yet.")
+		}
+		for _, e := range entries {
+			fmt.Printf("%s  %-20s %-12s %-16s %s\n", e.SHA256, e.Program, e.Version, e.Host, e.URL)
+		}
+		return 0
+	}
+
+	switch args[0] {
+	case "export":
+		if len(args) > 2 {
+			break
+		}
+		w := io.Writer(os.Stdout)
+		if len(args) == 2 {
+			f, err := os.Create(args[1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := db.Export(w); err != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting checksums: %v\n", err)
+			return 1
+		}
+		return 0
+	case "import":
+		if len(args) < 2 {
+			break
+		}
+		code := 0
+		for _, path := range args[1:] {
+			if !importChecksums(db, path) {
+				code = 1
+			}
+		}
+		return code
+	}
+	fmt.Fprintln(os.Stderr, "usage: installer checksums [export [file] | import <file>...]")
+	return 2
+}
+
+// importChecksums merges the exported database at path into db, reporting
+// what was added and any conflicts. It returns false on conflicts or errors.
+func importChecksums(db *sumdb.DB, path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return false
+	}
+	defer f.Close()
+	added, conflicts, err := db.Import(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error importing %s: %v\n", path, err)
+		return false
+	}
+	fmt.Printf("%s: %d new checksum(s)\n", path, added)
+	for _, c := range conflicts {
+		fmt.Fprintf(os.Stderr, "Conflict: %s\n  recorded %s by %s on %s\n  imported %s by %s on %s\n",
+			c.Recorded.URL,
+			c.Recorded.SHA256, c.Recorded.Host, c.Recorded.Time.Format("2006-01-02"),
+			c.Imported.SHA256, c.Imported.Host, c.Imported.Time.Format("2006-01-02"))
+	}
+	return len(conflicts) == 0
+}
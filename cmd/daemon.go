@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/check"
+	"github.com/dsaleh/david-dotfiles/internal/config"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/transport"
+)
+
+// runDaemon implements the daemon subcommand:
+//
+//	daemon [--interval 6h] [--listen 127.0.0.1:9101] [catalog...]
+//
+// It checks the installed programs for updates every interval until
+// interrupted, saving each result to check.Path() for `status`, and serves
+// the results as Prometheus metrics on /metrics at the listen address.
+// Catalogs are looked up as for an install, without the sync checkout, and
+// GitHub Enterprise programs use the config's host and token.
+func runDaemon(ctx context.Context, t *transport.Transport, args []string) int {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	interval := fs.Duration("interval", 6*time.Hour, "time between update checks")
+	listen := fs.String("listen", "127.0.0.1:9101", "address of the metrics endpoint; empty to disable it")
+	fs.Parse(args)
+
+	cfg, err := config.Load(config.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 1
+	}
+	programs, err := loadCatalogs(fs.Args(), cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading catalog: %v\n", err)
+		return 1
+	}
+	authenticated := authorize(t, programs)
+
+	var metrics check.Metrics
+	if *listen != "" {
+		ln, err := net.Listen("tcp", *listen)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", &metrics)
+		srv := &http.Server{Handler: mux}
+		go srv.Serve(ln)
+		defer srv.Close()
+		fmt.Fprintf(os.Stderr, "Serving metrics on http://%s/metrics\n", ln.Addr())
+	}
+
+	for {
+		// Reload state each time: installs happen between checks.
+		st, err := state.Load(state.Path())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading state: %v\n", err)
+		} else {
+			opts := runOptions(cfg, true)
+			opts.State, opts.Authenticated = st, authenticated
+			res := check.Run(ctx, programs, opts)
+			if ctx.Err() != nil {
+				return 0
+			}
+			metrics.Record(res)
+			if err := check.Save(check.Path(), res); err != nil {
+				fmt.Fprintf(os.Stderr, "Error saving check result: %v\n", err)
+			}
+			fmt.Fprintf(os.Stderr, "%s: %d outdated, %d failed\n", res.Time.Format(time.RFC3339), len(res.Outdated), len(res.Failed))
+			if len(res.Released) > 0 {
+				fmt.Fprintf(os.Stderr, "%s: hold_until met for %s\n", res.Time.Format(time.RFC3339), strings.Join(res.Released, ", "))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-time.After(*interval):
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/config"
+	"github.com/dsaleh/david-dotfiles/internal/dotfile"
+	"github.com/dsaleh/david-dotfiles/internal/trash"
+	"github.com/dsaleh/david-dotfiles/tui"
+)
+
+// runDotfiles implements the dotfiles subcommand:
+//
+//	dotfiles [--accept | --keep] [catalog...]
+//	        copy each [dotfiles.<name>] source of the catalogs to its target;
+//	        for targets changed locally, show the diff and ask whether to
+//	        accept the catalog's version, keep the local one or merge them
+//
+// --accept takes the catalog's version of every changed file and --keep
+// leaves them all, printing their diffs instead. Replaced targets go to the
+// trash unless trash_days is negative.
+func runDotfiles(args []string) int {
+	fs := flag.NewFlagSet("dotfiles", flag.ExitOnError)
+	acceptAll := fs.Bool("accept", false, "overwrite every locally changed target with the catalog's version")
+	keepAll := fs.Bool("keep", false, "leave every locally changed target as it is and print its diff")
+	fs.Parse(args)
+	if *acceptAll && *keepAll {
+		fmt.Fprintln(os.Stderr, "Error: --accept and --keep cannot be combined")
+		return 2
+	}
+
+	cfg, err := config.Load(config.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 1
+	}
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = cfg.Catalogs
+	}
+	if len(paths) == 0 {
+		paths = []string{"catalog.toml"}
+	}
+	var files []dotfile.File
+	for _, path := range paths {
+		dotfiles, err := catalog.LoadDotfiles(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading catalog: %s: %v\n", path, err)
+			return 1
+		}
+		for _, d := range dotfiles {
+			f, err := dotfile.Check(d)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: [dotfiles.%s]: %v\n", d.Name, err)
+				return 1
+			}
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		fmt.Println("The catalog lists no dotfiles.")
+		return 0
+	}
+
+	var bin *trash.Batch
+	if cfg.TrashDays > 0 {
+		bin = trash.Open(trash.Path(), "dotfiles", "dotfiles", nil)
+	}
+	code := 0
+	var modified []dotfile.File
+	for _, f := range files {
+		switch f.Status {
+		case dotfile.Missing:
+			if err := dotfile.Accept(f, nil); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", f.Target, err)
+				code = 1
+				continue
+			}
+			fmt.Printf("created    %s\n", f.Target)
+		case dotfile.Modified:
+			modified = append(modified, f)
+		}
+	}
+
+	var results []tui.DotfileResult
+	switch {
+	case len(modified) == 0:
+	case *acceptAll:
+		for _, f := range modified {
+			results = append(results, tui.DotfileResult{File: f, Choice: tui.DotfileAccepted, Err: dotfile.Accept(f, bin)})
+		}
+	case *keepAll:
+		for _, f := range modified {
+			fmt.Printf("--- %s\n+++ %s\n", f.Target, f.Source)
+			for _, h := range dotfile.Hunks(dotfile.Diff(f.Local, f.Incoming), 3) {
+				fmt.Println(h.Header())
+				for _, l := range h.Lines {
+					fmt.Println(string(l.Op) + strings.TrimSuffix(l.Text, "\n"))
+				}
+			}
+			results = append(results, tui.DotfileResult{File: f, Choice: tui.DotfileKept})
+		}
+	default:
+		final, err := tea.NewProgram(tui.NewDotfiles(modified, bin), tea.WithAltScreen()).Run()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
+			return 1
+		}
+		results = final.(tui.DotfilesModel).Results()
+	}
+
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", r.File.Target, r.Err)
+			code = 1
+			continue
+		}
+		fmt.Printf("%-10s %s\n", r.Choice, r.File.Target)
+		if r.Choice == tui.DotfileConflicted {
+			code = 1
+		}
+	}
+	if bin != nil && bin.ID != "" {
+		fmt.Printf("The replaced files are in the trash as %s (`installer restore dotfiles`).\n", bin.ID)
+	}
+	return code
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/export"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+)
+
+// runExport implements the export subcommand, writing to stdout:
+//
+//	export dockerfile [--installed] [catalog]    RUN instructions reproducing the installs
+//	export script [--installed] [catalog]        the same as a POSIX sh script
+//	export brewfile [--installed] [catalog]      a Brewfile
+//	export nix [--installed] [catalog]           a nix buildEnv expression
+//	export catalog                               the built-in catalog, to start a catalog.toml from
+//
+// dockerfile and script resolve versions as an install would (catalog
+// version, state pin, latest release), so the output pins exact URLs.
+// --installed limits the export to programs recorded in the state file.
+func runExport(ctx context.Context, args []string) int {
+	if len(args) == 1 && args[0] == "catalog" {
+		os.Stdout.Write(catalog.DefaultTOML)
+		return 0
+	}
+
+	resolved := map[string]func(io.Writer, []installer.Resolution) error{
+		"dockerfile": export.Dockerfile,
+		"script":     export.Script,
+	}
+	unresolved := map[string]func(io.Writer, []catalog.Program) error{
+		"brewfile": export.Brewfile,
+		"nix":      export.Nix,
+	}
+	if len(args) < 1 || (resolved[args[0]] == nil && unresolved[args[0]] == nil) {
+		fmt.Fprintln(os.Stderr, "usage: installer export dockerfile|script|brewfile|nix [--installed] [catalog]\n       installer export catalog")
+		return 2
+	}
+	format := args[0]
+
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	installed := fs.Bool("installed", false, "only export programs recorded in the state file")
+	fs.Parse(args[1:])
+	catalogPath := "catalog.toml"
+	if fs.NArg() > 0 {
+		catalogPath = fs.Arg(0)
+	}
+
+	programs, err := catalog.Load(catalogPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading catalog: %v\n", err)
+		return 1
+	}
+	st, err := state.Load(state.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading state: %v\n", err)
+		return 1
+	}
+	if *installed {
+		var kept []catalog.Program
+		for _, p := range programs {
+			if _, ok := st.Get(p.Name); ok {
+				kept = append(kept, p)
+			}
+		}
+		programs = kept
+	}
+
+	if write := unresolved[format]; write != nil {
+		if err := write(os.Stdout, programs); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", format, err)
+			return 1
+		}
+		return 0
+	}
+
+	res := installer.Resolve(ctx, programs, installer.Options{State: st})
+	if err := resolved[format](os.Stdout, res); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", format, err)
+		return 1
+	}
+	for _, r := range res {
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %s: %v\n", r.Program.Name, r.Err)
+		}
+	}
+	return 0
+}
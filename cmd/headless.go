@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	gh "github.com/dsaleh/david-dotfiles/internal/github"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/report"
+)
+
+// Exit codes of a headless run, so wrapper scripts can tell the kind of
+// failure apart. Other errors, such as an unreadable state file, exit 1, and
+// invalid flags exit 2 like the flag package does.
+const (
+	exitOK        = 0
+	exitFailed    = 2 // some programs failed
+	exitCatalog   = 3 // the catalog could not be loaded or is invalid
+	exitPreflight = 4 // packages or other requirements of the programs are missing; nothing was installed
+	exitNetwork   = 5 // every failure was a network error or a GitHub rate limit
+)
+
+// headlessBinTimeout is how long a program without a guessable bin list may
+// wait for binaries in a headless run before it fails.
+const headlessBinTimeout = 30 * time.Second
+
+// runHeadless installs programs without the TUI, writing one JSON object per
+// progress event to w, if non-nil, followed by a final report with per-state
+// durations.
+// Programs that would need the interactive bin picker are linked as picked
+// for an earlier release with the same layout, else from
+// installer.SuggestBins; when nothing can be guessed they fail once
+// headlessBinTimeout expires. If log is non-nil a plain transcript of the
+// run is written to it as well: what --all and --only print instead of JSON.
+// It returns the process exit code (exitOK, exitFailed or exitNetwork) and
+// the report.
+func runHeadless(ctx context.Context, programs []catalog.Program, opts installer.Options, w, log io.Writer) (int, report.Report) {
+	names := make([]string, len(programs))
+	byName := make(map[string]catalog.Program, len(programs))
+	for i, p := range programs {
+		names[i] = p.Name
+		byName[p.Name] = p
+	}
+	opts.BinTimeout = headlessBinTimeout
+	rec := report.NewRecorder(names)
+	var enc *json.Encoder
+	if w != nil {
+		enc = json.NewEncoder(w)
+	}
+	var tr *transcript
+	if log != nil {
+		tr = &transcript{w: log, last: map[string]installer.State{}}
+		tr.start(len(programs))
+	}
+
+	failed, network := false, true
+	for msg := range installer.Run(ctx, programs, opts) {
+		if msg.State == installer.StateAwaitingBinSelection {
+			// Nothing is sent without a suggestion: the program then fails
+			// with a descriptive error when BinTimeout expires.
+			bins := msg.Preselect
+			if bins == nil {
+				bins = installer.SuggestBins(byName[msg.Program], msg.InstallDir, msg.Version)
+			}
+			if bins != nil {
+				msg.BinCh <- bins
+			}
+		}
+		if msg.State == installer.StateError {
+			failed = true
+			network = network && networkError(msg.Err)
+		}
+		ev := rec.Record(msg)
+		if enc != nil {
+			enc.Encode(ev)
+		}
+		if tr != nil {
+			tr.record(msg)
+		}
+	}
+	rep := rec.Report()
+	if enc != nil {
+		enc.Encode(rep)
+	}
+	if tr != nil {
+		tr.finish(rep)
+	}
+
+	switch {
+	case failed && network:
+		return exitNetwork, rep
+	case failed:
+		return exitFailed, rep
+	}
+	return exitOK, rep
+}
+
+// selectOnly returns the programs of the comma-separated list names, given
+// by name or by a tool they provide, in catalog order. Names the catalog does
+// not know are an error.
+func selectOnly(programs []catalog.Program, names string) ([]catalog.Program, error) {
+	want := map[string]bool{}
+	for _, n := range strings.Split(names, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			want[n] = false
+		}
+	}
+	if len(want) == 0 {
+		return nil, errors.New("no programs given")
+	}
+	var out []catalog.Program
+	for _, p := range programs {
+		picked := false
+		for _, t := range p.Tools() {
+			if _, ok := want[t]; ok {
+				want[t], picked = true, true
+			}
+		}
+		if picked {
+			out = append(out, p)
+		}
+	}
+	var unknown []string
+	for n, found := range want {
+		if !found {
+			unknown = append(unknown, n)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("not in the catalog: %s", strings.Join(unknown, ", "))
+	}
+	return out, nil
+}
+
+// networkError reports whether err means GitHub or the download host could
+// not be reached, or refused the request because of the rate limit.
+func networkError(err error) bool {
+	var uerr *url.Error
+	return errors.Is(err, gh.ErrRateLimited) || errors.As(err, &uerr)
+}
+
+// transcript is the plain log of a headless run written for --log-file: a
+// timestamped line per state a program enters, e.g.
+//
+//	2026-10-16T10:00:01Z fzf            downloading 0.60.0
+//
+// and a summary line at the end. Download progress within a state is left
+// out; the JSON events carry it.
+type transcript struct {
+	w       io.Writer
+	last    map[string]installer.State
+	started time.Time
+}
+
+func (t *transcript) line(at time.Time, format string, args ...any) {
+	fmt.Fprintf(t.w, "%s %s\n", at.UTC().Format(time.RFC3339), fmt.Sprintf(format, args...))
+}
+
+func (t *transcript) start(n int) {
+	t.started = time.Now()
+	t.line(t.started, "run started: %d program(s)", n)
+}
+
+func (t *transcript) record(msg installer.ProgressMsg) {
+	if last, ok := t.last[msg.Program]; ok && last == msg.State && msg.State != installer.StateFetchingVersion {
+		return
+	}
+	t.last[msg.Program] = msg.State
+	text := msg.State.String()
+	switch {
+	case msg.State == installer.StateSkipped && msg.Skip != installer.SkipNone:
+		text += " (" + msg.Skip.String() + ")"
+	case msg.State == installer.StateError && msg.Err != nil:
+		text += ": " + msg.Err.Error()
+	case msg.AssetPattern != "":
+		text += ": using asset_pattern " + msg.AssetPattern
+	case msg.Version != "":
+		text += " " + msg.Version
+	}
+	t.line(msg.Time, "%-14s %s", msg.Program, text)
+}
+
+func (t *transcript) finish(rep report.Report) {
+	counts := map[string]int{}
+	for _, p := range rep.Programs {
+		counts[p.State]++
+	}
+	t.line(time.Now(), "run finished in %s: %d done, %d skipped, %d failed, %d removed",
+		time.Since(t.started).Round(time.Second), counts["done"], counts["skipped"], counts["error"], counts["removed"])
+}
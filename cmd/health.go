@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/config"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// runHealth implements the health subcommand:
+//
+//	health [catalog...]    run the health_cmd of every installed program that has one
+//
+// Results are printed as a table; the exit code is 1 if any check failed.
+func runHealth(ctx context.Context, args []string) int {
+	cfg, err := config.Load(config.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 1
+	}
+	programs, err := loadCatalogs(args, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading catalog: %v\n", err)
+		return 1
+	}
+	st, err := state.Load(state.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading state: %v\n", err)
+		return 1
+	}
+	programs = applyTrust(programs, "", cfg, true, os.Stderr)
+
+	code, checked := 0, 0
+	for _, p := range programs {
+		ps, ok := st.Get(p.Name)
+		if !ok || p.HealthCmd == "" {
+			continue
+		}
+		checked++
+		h := installer.CheckHealth(ctx, p, system.BinPath())
+		result := "ok"
+		if h.Err != nil {
+			result, code = "FAIL  "+h.Reason(), 1
+		}
+		fmt.Printf("%-20s %-12s %-30s %6s  %s\n", p.Name, ps.Version, p.HealthCmd, h.Duration.Round(10*time.Millisecond), result)
+		if h.Hint != "" {
+			fmt.Printf("%-20s hint: %s\n", "", h.Hint)
+		}
+	}
+	if checked == 0 {
+		fmt.Println("No installed program has a health_cmd.")
+	}
+	return code
+}
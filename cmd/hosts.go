@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/url"
+	"slices"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/config"
+	"github.com/dsaleh/david-dotfiles/internal/transport"
+)
+
+// applyHost points programs whose catalog entry sets neither host nor
+// api_base at the config's GitHub Enterprise Server, if it names one.
+func applyHost(programs []catalog.Program, cfg config.Config) []catalog.Program {
+	if cfg.Host == "" && cfg.APIBase == "" {
+		return programs
+	}
+	for i, p := range programs {
+		if p.Host == "" && p.APIBase == "" {
+			programs[i].Host, programs[i].APIBase = cfg.Host, cfg.APIBase
+		}
+	}
+	return programs
+}
+
+// authorize sends the enterprise token from the environment to the GitHub
+// Enterprise Server hosts (and API hosts) of programs, and returns the hosts
+// that now get a token, for installer.Options.Authenticated.
+func authorize(t *transport.Transport, programs []catalog.Program) []string {
+	var hosts []string
+	if transport.TokenFromEnv() != "" {
+		hosts = append(hosts, catalog.DefaultHost)
+	}
+	token := transport.EnterpriseTokenFromEnv()
+	if token == "" {
+		return hosts
+	}
+	for _, p := range programs {
+		host := p.GitHubHost()
+		if host == catalog.DefaultHost {
+			continue
+		}
+		t.SetToken(host, token)
+		if u, err := url.Parse(p.GitHubAPI()); err == nil && u.Host != host {
+			t.SetToken(u.Host, token)
+		}
+		if !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/dsaleh/david-dotfiles/internal/importer"
+)
+
+// runImport implements the import subcommand, printing catalog entries to
+// stdout so they can be reviewed and appended to catalog.toml:
+//
+//	import brewfile <Brewfile>    one entry per GitHub-hosted formula
+//	import repos <file>           one entry per owner/name (or GitHub URL) line
+//
+// Entries that cannot be resolved are reported on stderr and left out.
+func runImport(ctx context.Context, args []string) int {
+	if len(args) != 2 || (args[0] != "brewfile" && args[0] != "repos") {
+		fmt.Fprintln(os.Stderr, "usage: installer import brewfile|repos <file>")
+		return 2
+	}
+	f, err := os.Open(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+
+	im := importer.New()
+	var entries []importer.Entry
+	failed := 0
+	warn := func(name string, err error) {
+		fmt.Fprintf(os.Stderr, "warning: %s: %v\n", name, err)
+		failed++
+	}
+
+	if args[0] == "brewfile" {
+		formulae, err := importer.ParseBrewfile(f)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", args[1], err)
+			return 1
+		}
+		for _, formula := range formulae {
+			repo, err := im.BrewRepo(ctx, formula)
+			if err != nil {
+				warn(formula, err)
+				continue
+			}
+			e, err := im.Entry(ctx, formula, repo)
+			if err != nil {
+				warn(formula, err)
+				continue
+			}
+			e.Brew = formula
+			entries = append(entries, e)
+		}
+	} else {
+		repos, err := importer.ParseRepos(f)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", args[1], err)
+			return 1
+		}
+		for _, repo := range repos {
+			e, err := im.Entry(ctx, "", repo)
+			if err != nil {
+				warn(repo, err)
+				continue
+			}
+			entries = append(entries, e)
+		}
+	}
+
+	importer.WriteTOML(os.Stdout, entries)
+	fmt.Fprintf(os.Stderr, "%d imported, %d skipped\n", len(entries), failed)
+	return 0
+}
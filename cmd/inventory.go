@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dsaleh/david-dotfiles/internal/config"
+	"github.com/dsaleh/david-dotfiles/internal/inventory"
+	"github.com/dsaleh/david-dotfiles/internal/sign"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+	"github.com/dsaleh/david-dotfiles/tui"
+)
+
+// runInventory implements the inventory subcommand:
+//
+//	inventory                  this machine plus every --target host
+//	inventory <dir|git-url>    every exported state file in dir / the repo
+//	inventory export [--sign] <dir>   write this machine's state to <dir>/<hostname>.json
+//	inventory keygen           create the key --sign uses
+//	inventory verify <file>    check an exported state file against trusted_keys
+//
+// With trusted_keys in the config, every state file read from a dir or repo
+// must be signed by one of them.
+func runInventory(ctx context.Context, args []string) int {
+	hostname, _ := os.Hostname()
+	cfg, err := config.Load(config.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 1
+	}
+
+	if len(args) == 1 && args[0] == "keygen" {
+		pub, err := sign.GenerateKey(sign.KeyPath())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %s. Trust it elsewhere with this line in config.toml:\n", sign.KeyPath())
+		fmt.Printf("trusted_keys = [%q]\n", pub)
+		return 0
+	}
+
+	if len(args) > 0 && args[0] == "verify" {
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "usage: installer inventory verify <file>")
+			return 2
+		}
+		if len(cfg.TrustedKeys) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: no trusted_keys in the config file")
+			return 1
+		}
+		if err := sign.Verify(cfg.TrustedKeys, args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "%s: signature OK\n", args[1])
+		return 0
+	}
+
+	if len(args) > 0 && args[0] == "export" {
+		fs := flag.NewFlagSet("inventory export", flag.ExitOnError)
+		signed := fs.Bool("sign", false, "sign the file with the key from inventory keygen")
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "usage: installer inventory export [--sign] <dir>")
+			return 2
+		}
+		st, err := state.Load(state.Path())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading state: %v\n", err)
+			return 1
+		}
+		path, err := inventory.Export(st, fs.Arg(0), hostname)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting state: %v\n", err)
+			return 1
+		}
+		if *signed {
+			if err := sign.Sign(sign.KeyPath(), path); err != nil {
+				fmt.Fprintf(os.Stderr, "Error signing %s: %v\n", path, err)
+				return 1
+			}
+		}
+		fmt.Println(path)
+		return 0
+	}
+
+	var machines []inventory.Machine
+	if len(args) > 0 {
+		dir, err := inventory.Fetch(ctx, args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error fetching inventory: %v\n", err)
+			return 1
+		}
+		if len(cfg.TrustedKeys) > 0 {
+			machines, err = inventory.LoadVerified(dir, cfg.TrustedKeys)
+		} else {
+			machines, err = inventory.LoadDir(dir)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading inventory: %v\n", err)
+			return 1
+		}
+	} else {
+		st, err := state.Load(state.Path())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading state: %v\n", err)
+			return 1
+		}
+		machines = append(machines, inventory.Machine{Name: hostname, State: st})
+		// Remote targets are optional; a missing targets dir just means none.
+		if targets, err := inventory.LoadDir(filepath.Join(system.DataPath(), "targets")); err == nil {
+			machines = append(machines, targets...)
+		}
+	}
+
+	if _, err := tea.NewProgram(tui.NewInventory(machines), tea.WithAltScreen()).Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
+		return 1
+	}
+	return 0
+}
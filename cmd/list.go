@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dsaleh/david-dotfiles/internal/config"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+)
+
+// runList implements the list subcommand:
+//
+//	list [--verify] [catalog...]    print every tool the catalogs provide and its installed version
+//
+// A program with a provides list gets one line per tool, so suites show each
+// of their tools. --verify adds a line per linked binary saying whether it
+// still matches the hash recorded by lock_links, and exits 1 if any does not.
+func runList(args []string) int {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	verify := fs.Bool("verify", false, "hash each linked binary and compare it with the one installed")
+	fs.Parse(args)
+	args = fs.Args()
+
+	cfg, err := config.Load(config.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 1
+	}
+	programs, err := loadCatalogs(args, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading catalog: %v\n", err)
+		return 1
+	}
+	st, err := state.Load(state.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading state: %v\n", err)
+		return 1
+	}
+
+	code := 0
+	for _, p := range programs {
+		version := "-"
+		ps, installed := st.Get(p.Name)
+		if installed {
+			version = ps.Version
+		}
+		for _, t := range p.Tools() {
+			from := p.Repo
+			if t != p.Name {
+				from = "provided by " + p.Name
+			}
+			fmt.Printf("%-20s %-12s %s\n", t, version, from)
+		}
+		if !*verify || !installed {
+			continue
+		}
+		for _, c := range installer.VerifyBins(st, p.Name) {
+			fmt.Printf("  %-18s %s\n", c.Bin, c.Status)
+			switch c.Status {
+			case installer.BinModified, installer.BinRelinked, installer.BinMissing:
+				code = 1
+			}
+		}
+	}
+	return code
+}
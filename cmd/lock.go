@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/config"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// lockPath returns the lock file of the catalogs in use: beside catalogPath
+// when a single catalog file is, else beside the config file.
+func lockPath(catalogPath string) string {
+	if catalogPath != "" {
+		return catalog.LockPath(catalogPath)
+	}
+	return filepath.Join(filepath.Dir(config.Path()), catalog.LockName)
+}
+
+// writeLock writes the lock file at path: the release each installed
+// program of the catalog is installed from, with its checksum. Nothing is
+// written when none is installed.
+func writeLock(path string, programs []catalog.Program, st *state.State) {
+	lock := catalog.Lock{Programs: map[string]catalog.Locked{}}
+	for _, p := range programs {
+		ps, ok := st.Get(p.Name)
+		if !ok || ps.Asset == "" {
+			continue
+		}
+		e := catalog.Locked{Tag: ps.Tag, URL: ps.Asset}
+		if !p.Rolling {
+			e.SHA256, _ = st.Checksum(ps.Asset)
+		}
+		lock.Programs[p.Name] = e
+	}
+	if len(lock.Programs) == 0 {
+		return
+	}
+	if err := lock.Save(path, system.FilePerm()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: writing %s: %v\n", path, err)
+	}
+}
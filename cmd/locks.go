@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+)
+
+// checkLocks warns about managed links that were changed outside the
+// installer since it made them (lock_links in config.toml) and, when prompt
+// is set, offers to point them back at the installed binaries.
+func checkLocks(st *state.State, prompt bool) {
+	tampered := installer.CheckLocks(st)
+	if len(tampered) == 0 {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Managed links changed outside the installer:")
+	for _, t := range tampered {
+		fmt.Fprintf(os.Stderr, "  %s (%s) %s\n", t.Lock.Path, t.Program, t.Reason())
+	}
+	if !prompt {
+		return
+	}
+	fmt.Fprint(os.Stderr, "Repair them? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+	default:
+		return
+	}
+	for _, t := range tampered {
+		if err := installer.RepairLock(t); err != nil {
+			fmt.Fprintf(os.Stderr, "  %s: %v\n", t.Bin, err)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "  %s -> %s\n", t.Lock.Path, t.Lock.Target)
+	}
+}
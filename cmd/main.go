@@ -4,45 +4,506 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/config"
+	"github.com/dsaleh/david-dotfiles/internal/gitsync"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/notify"
+	"github.com/dsaleh/david-dotfiles/internal/remote"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/sumdb"
 	"github.com/dsaleh/david-dotfiles/internal/system"
+	"github.com/dsaleh/david-dotfiles/internal/transport"
+	"github.com/dsaleh/david-dotfiles/internal/trash"
 	"github.com/dsaleh/david-dotfiles/tui"
 )
 
 func main() {
 	verbose := flag.Bool("verbose", false, "print resolved download URLs and version info to stderr")
 	flag.BoolVar(verbose, "v", false, "shorthand for --verbose")
+	targetHost := flag.String("target", "", "install on user@host over ssh instead of this machine")
+	apply := flag.Bool("apply", false, "treat the selection as the complete set: uninstall installed programs that are not selected or no longer in the catalog")
+	systemWide := flag.Bool("system", false, "install into /usr/local/{share,bin} for all users, escalating via sudo where needed")
+	shared := flag.Bool("shared", false, "reuse extracted programs in /usr/local/share across users, linking into your own ~/.local/bin")
+	rootDir := flag.String("root", "", "install under this directory instead of $HOME (e.g. a container rootfs); links are made relative")
+	profileRender := flag.String("profile-render", "", "internal: log the duration and size of every TUI frame to this file")
+	dryRun := flag.Bool("dry-run", false, "print what a run over the whole catalog would install, upgrade and remove (with --apply), path by path, and exit")
+	jsonOut := flag.Bool("json", false, "install every catalog program without the TUI, printing JSON progress events and a final report to stdout")
+	logFile := flag.String("log-file", "", "with --json, also append a plain timestamped transcript of the run to this file")
+	all := flag.Bool("all", false, "install every catalog program without the TUI, printing a plain progress line per state change to stdout")
+	only := flag.String("only", "", "install just these comma-separated programs (e.g. fzf,ripgrep) without the TUI, like --all")
+	locked := flag.Bool("locked", false, "install exactly the releases recorded in catalog.lock instead of the latest ones")
+	traceHTTP := flag.Bool("trace-http", false, "log every HTTP request (status, duration, GitHub rate limit) to the debug log")
 	flag.Parse()
 
-	// Find catalog.toml relative to binary location or working dir.
+	// Termux keeps programs under $PREFIX, whose bin dir is on PATH; every
+	// subcommand has to look there.
+	if system.Termux() {
+		system.SetPrefix(os.Getenv("PREFIX"))
+	}
+
+	// The umask covers every subcommand; one whose config fails to load
+	// reports that itself.
+	if cfg, err := config.Load(config.Path()); err == nil && cfg.Umask != "" {
+		m, _ := system.ParseUmask(cfg.Umask) // validated by config.Load
+		system.SetUmask(m)
+	}
+
+	httpTransport, closeTrace := setupHTTP(*traceHTTP)
+	if closeTrace != nil {
+		defer closeTrace()
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	switch flag.Arg(0) {
+	case "inventory":
+		code := runInventory(ctx, flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	case "sync":
+		code := runSync(ctx, flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	case "export":
+		code := runExport(ctx, flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	case "import":
+		code := runImport(ctx, flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	case "stats":
+		code := runStats(flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	case "search":
+		code := runSearch(ctx, flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	case "suggest":
+		code := runSuggest(ctx, flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	case "add":
+		code := runAdd(ctx, flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	case "relink":
+		code := runRelink(flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	case "daemon":
+		code := runDaemon(ctx, httpTransport, flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	case "status":
+		code := runStatus(flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	case "list":
+		code := runList(flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	case "uninstall":
+		code := runUninstall(ctx, flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	case "backup":
+		code := runBackup(flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	case "restore":
+		code := runRestore(flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	case "checksums":
+		code := runChecksums(flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	case "bench":
+		code := runBench(ctx, httpTransport, flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	case "health":
+		code := runHealth(ctx, flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	case "dotfiles":
+		code := runDotfiles(flag.Args()[1:])
+		cancel()
+		os.Exit(code)
+	}
+
+	cfg, err := config.Load(config.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Catalog lookup: explicit arguments, then the catalogs listed in the
+	// config, then ./catalog.toml, then the catalog in the sync checkout
+	// (pulled first, pushed after the run), then the built-in catalog.
+	// catalogPath is the single catalog in use, "" for the built-in one or
+	// when several are loaded (programs then carry their Source).
+	catalogPaths := flag.Args()
+	if len(catalogPaths) == 0 {
+		catalogPaths = cfg.Catalogs
+	}
 	catalogPath := "catalog.toml"
-	if flag.NArg() > 0 {
-		catalogPath = flag.Arg(0)
+	var syncRepo *gitsync.Repo
+	if len(catalogPaths) > 0 {
+		catalogPath = catalogPaths[0]
+	} else if _, err := os.Stat(catalogPath); os.IsNotExist(err) {
+		if path, repo, ok := syncedCatalog(ctx); ok {
+			catalogPath, syncRepo = path, repo
+		} else {
+			catalogPath = ""
+		}
 	}
 
-	programs, err := catalog.Load(catalogPath)
+	var programs []catalog.Program
+	switch {
+	case len(catalogPaths) > 1:
+		programs, err = catalog.LoadAll(catalogPaths)
+		catalogPath = ""
+	case catalogPath == "":
+		fmt.Fprintln(os.Stderr, "No catalog.toml found; using the built-in catalog (run `installer export catalog > catalog.toml` to customize it).")
+		programs, err = catalog.Default()
+	default:
+		programs, err = catalog.Load(catalogPath)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading catalog: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCatalog)
 	}
 
-	if err := system.EnsureBaseDirs(); err != nil {
+	opts := runOptions(cfg, *targetHost == "" && !*systemWide && !*shared && *rootDir == "")
+	opts.Verbose, opts.Apply, opts.System, opts.Shared = *verbose, *apply, *systemWide, *shared
+	statePath := state.Path()
+	if *logFile != "" && !*jsonOut {
+		fmt.Fprintln(os.Stderr, "Error: --log-file needs --json")
+		os.Exit(2)
+	}
+	if *all && *only != "" {
+		fmt.Fprintln(os.Stderr, "Error: --all and --only cannot be combined")
+		os.Exit(2)
+	}
+	// --only leaves out the rest of the catalog; --apply would remove it.
+	if *only != "" && *apply {
+		fmt.Fprintln(os.Stderr, "Error: --only cannot be combined with --apply")
+		os.Exit(2)
+	}
+	catalogPrograms := programs // for the lock, which covers the whole catalog
+	if *only != "" {
+		if programs, err = selectOnly(programs, *only); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --only: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	// Headless runs never prompt: with --json, --all or --only.
+	interactive := !*jsonOut && !*all && *only == ""
+	if n := countTrue(*targetHost != "", *systemWide, *shared, *rootDir != ""); n > 1 {
+		fmt.Fprintln(os.Stderr, "Error: --target, --system, --shared and --root cannot be combined")
+		os.Exit(2)
+	}
+	if *rootDir != "" {
+		abs, err := filepath.Abs(*rootDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		system.SetRoot(abs)
+		statePath = state.Path()
+	}
+	if *targetHost != "" {
+		if opts.Target, err = remote.Parse(*targetHost); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := opts.Target.EnsureBaseDirs(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating base dirs on %s: %v\n", opts.Target.Host, err)
+			os.Exit(1)
+		}
+		statePath = state.TargetPath(opts.Target.Host)
+	} else if *systemWide {
+		if err := ensureSystemDirs(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error preparing %s: %v\n", system.SystemSharePath, err)
+			os.Exit(1)
+		}
+		statePath = state.SystemPath()
+	} else if *shared {
+		if err := ensureSystemDirs(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error preparing %s: %v\n", system.SystemSharePath, err)
+			os.Exit(1)
+		}
+		if err := system.EnsureBaseDirs(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating base dirs: %v\n", err)
+			os.Exit(1)
+		}
+		statePath = state.SharedPath()
+	} else if err := system.EnsureBaseDirs(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating base dirs: %v\n", err)
 		os.Exit(1)
 	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer cancel()
+	if opts.State, err = state.Load(statePath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading state: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *locked {
+		lock, err := catalog.LoadLock(lockPath(catalogPath))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --locked: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Lock = &lock
+	}
+
+	if *dryRun {
+		if cfg.TrashDays > 0 {
+			opts.Trash = trash.Path()
+		}
+		tui.PrintPlan(os.Stdout, installer.Plan(ctx, programs, opts), opts.TrashDir() != "")
+		cancel()
+		os.Exit(0)
+	}
+
+	if cfg.TrashDays > 0 {
+		opts.Trash = trash.Path()
+		if _, err := trash.Purge(opts.Trash, time.Duration(cfg.TrashDays)*24*time.Hour); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: emptying trash: %v\n", err)
+		}
+	}
+
+	if opts.Checksums, err = sumdb.Open(sumdb.Path()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: checksum database: %v\n", err)
+	}
+
+	if cfg.LockLinks && opts.Target == nil {
+		checkLocks(opts.State, interactive)
+	}
+
+	if system.WSL() && opts.Target == nil {
+		if bin := system.BinPath(); system.OnWindowsDrive(bin) {
+			fmt.Fprintf(os.Stderr, "Warning: %s is on a Windows drive; symlinks and executable bits there are unreliable under WSL. Keep the bin dir on the Linux filesystem.\n", bin)
+		}
+		// Windows companions only go along with installs into this user's home.
+		if !*systemWide && !*shared && *rootDir == "" {
+			opts.WindowsBin = cfg.WindowsBinDir
+		}
+	}
+	programs = applyTrust(programs, catalogPath, cfg, interactive, os.Stderr)
+	programs = applyHost(programs, cfg)
+	opts.Authenticated = authorize(httpTransport, programs)
+
+	if !interactive {
+		if missing := installer.Preflight(programs); len(missing) > 0 {
+			fmt.Fprintf(os.Stderr, "Error: missing requirements: %s\nInstall what is missing and re-run.\n", strings.Join(missing, ", "))
+			os.Exit(exitPreflight)
+		}
+		var log io.Writer // nil without --log-file
+		if *logFile != "" {
+			f, err := os.OpenFile(*logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, system.FilePerm())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening log file: %v\n", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			log = f
+		}
+		var events io.Writer = os.Stdout
+		if !*jsonOut {
+			events, log = nil, os.Stdout
+		}
+		code, rep := runHeadless(ctx, programs, opts, events, log)
+		if cfg.Stats {
+			saveStats(rep)
+		}
+		if !*locked {
+			writeLock(lockPath(catalogPath), catalogPrograms, opts.State)
+		}
+		cancel()
+		os.Exit(code)
+	}
 
-	model := tui.New(programs, ctx, *verbose)
-	p := tea.NewProgram(model, tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	tui.SetPalette(cfg.Palette)
+	opts.Triage = cfg.StopOnError == "prompt"
+	model := tui.New(programs, catalogPath, ctx, opts)
+	if resume := offerResume(programs); resume != nil {
+		model = model.WithResume(resume)
+	}
+	if len(catalogPaths) > 1 || catalogPath != "" {
+		model = model.WithReload(catalogReloader(catalogPaths, catalogPath, cfg))
+	}
+	if cfg.ProgressSocket {
+		if srv, err := notify.Listen(notify.Path()); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: progress socket: %v\n", err)
+		} else {
+			defer srv.Close()
+			model = model.WithNotifier(srv)
+		}
+	}
+	if *profileRender != "" {
+		f, err := os.Create(*profileRender)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		model = model.WithRenderProfile(f)
+	}
+	p := tea.NewProgram(model, tea.WithAltScreen(), tea.WithFPS(frameRate(cfg.MaxFPS)))
+	final, err := p.Run()
+	installer.Cleanup()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
 		os.Exit(1)
 	}
+	final.(tui.RootModel).RenderSummary()
+	if rep, ok := final.(tui.RootModel).Report(); ok {
+		if cfg.Stats {
+			saveStats(rep)
+		}
+		if !*locked {
+			writeLock(lockPath(catalogPath), catalogPrograms, opts.State)
+		}
+	}
+	if syncRepo != nil {
+		pushCatalogEdits(ctx, syncRepo)
+	}
+}
+
+// setupHTTP routes every HTTP request through a transport that sets the
+// User-Agent (the config's user_agent, if the config loads), sends the
+// github.com token from the environment to GitHub and, with trace, appends a
+// line per request to the debug log. It returns the transport and a func
+// closing the log, or nil.
+func setupHTTP(trace bool) (*transport.Transport, func()) {
+	t := &transport.Transport{UserAgent: transport.DefaultUserAgent()}
+	for _, host := range transport.GitHubHosts {
+		t.SetToken(host, transport.TokenFromEnv())
+	}
+	if cfg, err := config.Load(config.Path()); err == nil && cfg.UserAgent != "" {
+		t.UserAgent = cfg.UserAgent
+	}
+	var closeLog func()
+	if trace {
+		path := transport.LogPath()
+		os.MkdirAll(filepath.Dir(path), system.DirPerm())
+		// Private: request URLs can be signed download links.
+		if f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: HTTP trace: %v\n", err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Tracing HTTP requests to %s\n", path)
+			t.Trace, closeLog = f, func() { f.Close() }
+		}
+	}
+	transport.Install(t)
+	return t, closeLog
+}
+
+// runOptions returns the installer options cfg sets for a run. local is
+// whether the run installs into this user's home on this machine, where the
+// platform's asset rules (Termux assets, unsupported) apply.
+func runOptions(cfg config.Config, local bool) installer.Options {
+	opts := installer.Options{LinkMode: cfg.LinkMode, LockLinks: cfg.LockLinks, DownloadCmd: cfg.DownloadCmd, Downloads: cfg.MaxDownloads, Extractions: cfg.MaxExtractions}
+	if local {
+		opts.Platform = system.Platform()
+	}
+	return opts
+}
+
+// frameRate returns the TUI's redraw limit: limit if set, else a lower rate
+// in an SSH session than bubbletea's default of 60.
+func frameRate(limit int) int {
+	if limit > 0 {
+		return limit
+	}
+	if os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "" {
+		return 20
+	}
+	return 60
+}
+
+// ensureSystemDirs makes sure the /usr/local roots exist and, if this process
+// cannot write to them, caches sudo credentials now — before the TUI takes
+// over the terminal — so the installer's sudo calls never need to prompt.
+func ensureSystemDirs() error {
+	if system.Writable(system.SystemSharePath) && system.Writable(system.SystemBinPath) {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "%s is not writable; sudo will be used to install and link.\n", system.SystemSharePath)
+	if err := system.SudoValidate(); err != nil {
+		return err
+	}
+	return system.Sudo(context.Background(), "mkdir", "-p", system.SystemSharePath, system.SystemBinPath)
+}
+
+// loadCatalogs loads the catalogs of a subcommand that runs outside the
+// install flow: paths, else the config's catalogs, else ./catalog.toml, else
+// the built-in catalog. The sync checkout is not consulted. Programs get the
+// config's host as in an install; subcommands that query GitHub still have
+// to authorize them.
+func loadCatalogs(paths []string, cfg config.Config) ([]catalog.Program, error) {
+	if len(paths) == 0 {
+		paths = cfg.Catalogs
+	}
+	if len(paths) == 0 {
+		if _, err := os.Stat("catalog.toml"); err == nil {
+			paths = []string{"catalog.toml"}
+		}
+	}
+	var programs []catalog.Program
+	var err error
+	if len(paths) == 0 {
+		programs, err = catalog.Default()
+	} else {
+		programs, err = catalog.LoadAll(paths)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return applyHost(programs, cfg), nil
+}
+
+// catalogReloader returns how the TUI reloads the catalogs it was started
+// with: the same files, with the same trust and host settings. There is no
+// terminal to ask on, so commands that are not approved yet are skipped.
+func catalogReloader(paths []string, catalogPath string, cfg config.Config) func() ([]catalog.Program, error) {
+	return func() ([]catalog.Program, error) {
+		var programs []catalog.Program
+		var err error
+		if len(paths) > 1 {
+			programs, err = catalog.LoadAll(paths)
+		} else {
+			programs, err = catalog.Load(catalogPath)
+		}
+		if err != nil {
+			return nil, err
+		}
+		programs = applyTrust(programs, catalogPath, cfg, false, io.Discard)
+		return applyHost(programs, cfg), nil
+	}
+}
+
+func countTrue(bs ...bool) int {
+	n := 0
+	for _, b := range bs {
+		if b {
+			n++
+		}
+	}
+	return n
 }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+	"github.com/dsaleh/david-dotfiles/tui"
+)
+
+// runRelink implements the relink subcommand:
+//
+//	relink <program>    add, rename or remove the program's links in ~/.local/bin
+func runRelink(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: installer relink <program>")
+		return 2
+	}
+	name := args[0]
+	st, err := state.Load(state.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading state: %v\n", err)
+		return 1
+	}
+	if info, err := os.Stat(filepath.Join(system.SharePath(), st.DirOf(name))); err != nil || !info.IsDir() {
+		fmt.Fprintf(os.Stderr, "Error: %s is not installed in %s\n", name, system.SharePath())
+		return 1
+	}
+
+	if _, err := tea.NewProgram(tui.NewRelink(name, st), tea.WithAltScreen()).Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
+		return 1
+	}
+	return 0
+}
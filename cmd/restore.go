@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/trash"
+)
+
+// runRestore implements the restore subcommand:
+//
+//	restore              list what is in the trash, newest first
+//	restore <id|program> move a trash entry (or a program's newest) back
+//	restore --from <archive>
+//	                     put back everything in an archive written by backup
+//
+// Restoring swaps out whatever replaced the files, such as the newer install
+// dir of an upgrade, into a trash entry of its own, and puts the program's
+// install record back in the state file.
+func runRestore(args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	from := fs.String("from", "", "restore the archive written by `installer backup` instead of a trash entry")
+	fs.Parse(args)
+	if *from != "" {
+		return restoreBackup(*from)
+	}
+	args = fs.Args()
+
+	batches, err := trash.List(trash.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading trash: %v\n", err)
+		return 1
+	}
+	if len(args) == 0 {
+		if len(batches) == 0 {
+			fmt.Println("The trash is empty.")
+		}
+		for _, b := range batches {
+			version := "-"
+			if b.State != nil {
+				version = b.State.Version
+			}
+			fmt.Printf("%-32s %s  %-20s %-10s %-12s %d item(s)\n", b.ID, b.Time.Format("2006-01-02 15:04"), b.Program, b.Reason, version, len(b.Items))
+		}
+		return 0
+	}
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: installer restore [id|program]\n       installer restore --from <archive>")
+		return 2
+	}
+
+	// Batches are newest first, so a program name picks its latest.
+	var batch *trash.Batch
+	for i, b := range batches {
+		if b.ID == args[0] || b.Program == args[0] {
+			batch = &batches[i]
+			break
+		}
+	}
+	if batch == nil {
+		fmt.Fprintf(os.Stderr, "Error: nothing in the trash matches %q; run `installer restore` to list it\n", args[0])
+		return 1
+	}
+
+	st, err := state.Load(state.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading state: %v\n", err)
+		return 1
+	}
+	var current *state.ProgramState
+	if ps, ok := st.Get(batch.Program); ok {
+		current = &ps
+	}
+	restored, displaced, err := trash.Restore(trash.Path(), batch.ID, current)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if restored.State != nil {
+		st.Set(restored.Program, *restored.State)
+	} else {
+		st.Delete(restored.Program)
+	}
+	if err := st.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving state: %v\n", err)
+		return 1
+	}
+	for _, path := range restored.Items {
+		fmt.Printf("restored %s\n", path)
+	}
+	if displaced.ID != "" {
+		fmt.Printf("What it replaced is in the trash as %s.\n", displaced.ID)
+	}
+	return 0
+}
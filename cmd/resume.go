@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+)
+
+// offerResume asks whether to finish a run that was interrupted, returning
+// the catalog programs to install if so. Declining discards the journal and
+// the assets the run had downloaded.
+func offerResume(programs []catalog.Program) []catalog.Program {
+	j, ok := installer.Interrupted()
+	if !ok {
+		return nil
+	}
+	var resume []catalog.Program
+	for _, p := range programs {
+		if slices.Contains(j.Programs, p.Name) {
+			resume = append(resume, p)
+		}
+	}
+	if len(resume) == 0 {
+		installer.DiscardInterrupted()
+		return nil
+	}
+
+	fmt.Printf("The run started %s was interrupted before finishing: %s\n",
+		j.Started.Format("2006-01-02 15:04"), strings.Join(j.Programs, ", "))
+	fmt.Print("Resume it? Already downloaded assets are reused. [Y/n] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "", "y", "yes":
+		return resume
+	}
+	installer.DiscardInterrupted()
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/config"
+	"github.com/dsaleh/david-dotfiles/internal/importer"
+	"github.com/dsaleh/david-dotfiles/internal/index"
+)
+
+// runSearch implements the search subcommand, querying the community index
+// (index_url in the config, index.DefaultURL by default):
+//
+//	search <query>                  list index programs matching query
+//	search --add <name> [catalog]   append the index entry for name to the catalog
+func runSearch(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	add := fs.String("add", "", "append the index entry with this name to the catalog")
+	fs.Parse(args)
+	if (*add == "" && fs.NArg() != 1) || (*add != "" && fs.NArg() > 1) {
+		fmt.Fprintln(os.Stderr, "usage: installer search <query>\n       installer search --add <name> [catalog]")
+		return 2
+	}
+
+	cfg, err := config.Load(config.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 1
+	}
+	entries, err := index.New(cfg.IndexURL).Fetch(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if *add == "" {
+		matches := index.Search(entries, fs.Arg(0))
+		if len(matches) == 0 {
+			fmt.Fprintf(os.Stderr, "No programs in the index match %q\n", fs.Arg(0))
+			return 1
+		}
+		for _, e := range matches {
+			fmt.Printf("%-16s %-32s %s\n", e.Name, e.Repo, e.Description)
+		}
+		fmt.Fprintln(os.Stderr, "\nAdd one with: installer search --add <name>")
+		return 0
+	}
+
+	e, ok := index.Find(entries, *add)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: %s is not in the index — try installer search %s\n", *add, *add)
+		return 1
+	}
+	catalogPath := "catalog.toml"
+	if fs.NArg() == 1 {
+		catalogPath = fs.Arg(0)
+	}
+	programs, err := catalog.Load(catalogPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading catalog: %v\n", err)
+		return 1
+	}
+	for _, p := range programs {
+		if p.Name == e.Name {
+			fmt.Fprintf(os.Stderr, "Error: %s is already in %s\n", e.Name, catalogPath)
+			return 1
+		}
+	}
+	entry := importer.Entry{Name: e.Name, Repo: e.Repo, AssetPattern: e.AssetPattern, Bin: e.Bin}
+	if err := appendEntries(catalogPath, []importer.Entry{entry}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", catalogPath, err)
+		return 1
+	}
+	fmt.Printf("Added %s (%s) to %s\n", e.Name, e.Repo, catalogPath)
+	return 0
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dsaleh/david-dotfiles/internal/report"
+	"github.com/dsaleh/david-dotfiles/internal/stats"
+)
+
+// runStats implements the stats subcommand:
+//
+//	stats    summarize the local run history kept when `stats = true` is set
+//	         in the config: installs and updates per month, bytes downloaded,
+//	         cache hit ratio and the slowest programs
+func runStats(args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: installer stats")
+		return 2
+	}
+	reps, err := stats.Load(stats.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", stats.Path(), err)
+		return 1
+	}
+	if len(reps) == 0 {
+		fmt.Println("No run history yet. Set `stats = true` in the config file to record runs locally.")
+		return 0
+	}
+
+	sum := stats.Summarize(reps)
+	fmt.Printf("%d runs recorded in %s (never uploaded)\n\n", sum.Runs, stats.Path())
+
+	fmt.Printf("  %-8s  %8s  %8s  %8s\n", "month", "installs", "updates", "failures")
+	for _, m := range sum.Months {
+		bar := strings.Repeat("█", min(m.Installs+m.Updates, 40))
+		fmt.Printf("  %-8s  %8d  %8d  %8d  %s\n", m.Month, m.Installs, m.Updates, m.Failures, bar)
+	}
+
+	fmt.Printf("\n  downloaded:  %s\n", formatBytes(sum.Bytes))
+	fmt.Printf("  cache hits:  %.0f%% (%d of %d programs needed no download)\n", sum.HitRatio()*100, sum.Hits, sum.Hits+sum.Fetches)
+
+	if len(sum.Slowest) > 0 {
+		fmt.Println("\n  slowest installs (mean):")
+		for _, s := range sum.Slowest {
+			fmt.Printf("    %-20s %6.1fs  over %d runs\n", s.Program, s.Seconds, s.Runs)
+		}
+	}
+	return 0
+}
+
+// saveStats appends rep to the local history. A failure only warns: the run
+// itself already happened.
+func saveStats(rep report.Report) {
+	if len(rep.Programs) == 0 {
+		return
+	}
+	if err := stats.Append(stats.Path(), rep); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: record stats: %v\n", err)
+	}
+}
+
+// formatBytes renders n with a binary unit, e.g. 1536 → "1.5 KiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
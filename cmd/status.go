@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/check"
+)
+
+// runStatus implements the status subcommand:
+//
+//	status [--short]
+//
+// It prints the last update check saved by `installer daemon` without
+// contacting GitHub. --short prints a one-line summary such as "3⬆ 1✗"
+// (updates available, broken links) for tmux status lines and waybar modules,
+// and nothing at all when there is nothing to report.
+func runStatus(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	short := fs.Bool("short", false, "print a compact one-line summary")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "usage: installer status [--short]")
+		return 2
+	}
+
+	res, err := check.Load(check.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", check.Path(), err)
+		return 1
+	}
+
+	if *short {
+		var parts []string
+		if n := len(res.Outdated); n > 0 {
+			parts = append(parts, fmt.Sprintf("%d⬆", n))
+		}
+		if n := len(res.Broken); n > 0 {
+			parts = append(parts, fmt.Sprintf("%d✗", n))
+		}
+		if len(parts) > 0 {
+			fmt.Println(strings.Join(parts, " "))
+		}
+		return 0
+	}
+
+	if res.Time.IsZero() {
+		fmt.Println("No update check yet. Run `installer daemon` to check periodically.")
+		return 0
+	}
+	fmt.Printf("Last checked %s (%s ago)\n", res.Time.Format("2006-01-02 15:04"), time.Since(res.Time).Round(time.Minute))
+	if len(res.Outdated) == 0 && len(res.Broken) == 0 && len(res.Failed) == 0 {
+		fmt.Println("  everything is up to date")
+	}
+	if len(res.Outdated) > 0 {
+		fmt.Printf("  updates available: %s\n", strings.Join(res.Outdated, ", "))
+	}
+	if len(res.Released) > 0 {
+		fmt.Printf("  holds lifted:      %s (hold_until met; the next run upgrades them)\n", strings.Join(res.Released, ", "))
+	}
+	if len(res.Broken) > 0 {
+		fmt.Printf("  broken links:      %s\n", strings.Join(res.Broken, ", "))
+	}
+	for _, name := range slices.Sorted(maps.Keys(res.Failed)) {
+		fmt.Printf("  %s: check failed: %s\n", name, res.Failed[name])
+	}
+	return 0
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dsaleh/david-dotfiles/internal/importer"
+)
+
+// runSuggest implements the suggest subcommand:
+//
+//	suggest [--name <name>] <owner/repo>   print a catalog entry for repo
+//
+// The latest release is inspected as for import, and its asset for this
+// machine is downloaded to infer the bin list. The TOML goes to stdout, ready
+// to paste or append to a catalog; notes go to stderr.
+func runSuggest(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("suggest", flag.ExitOnError)
+	name := fs.String("name", "", "program name (default: the repo name)")
+	fs.Parse(args)
+	if fs.NArg() != 1 || !strings.Contains(fs.Arg(0), "/") {
+		fmt.Fprintln(os.Stderr, "usage: installer suggest [--name <name>] <owner/repo>")
+		return 2
+	}
+
+	e, err := importer.New().Suggest(ctx, *name, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	importer.WriteTOML(os.Stdout, []importer.Entry{e})
+	if len(e.Bin) == 0 {
+		fmt.Fprintln(os.Stderr, "\nNo binary could be inferred from the release; it will be picked on first install.")
+	}
+	return 0
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dsaleh/david-dotfiles/internal/gitsync"
+	"github.com/dsaleh/david-dotfiles/internal/inventory"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/sumdb"
+)
+
+// runSync implements the sync subcommand:
+//
+//	sync init <url>   clone the catalog repo into the sync checkout
+//	sync [--state] [--checksums]
+//	                  pull, optionally export this machine's state to
+//	                  states/<hostname>.json and merge checksums.jsonl with
+//	                  the local checksum database, then commit and push
+func runSync(ctx context.Context, args []string) int {
+	if len(args) > 0 && args[0] == "init" {
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "usage: installer sync init <git-url>")
+			return 2
+		}
+		repo, err := gitsync.Clone(ctx, args[1], gitsync.Dir())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error cloning sync repo: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Cloned into %s\n", repo.Dir)
+		return 0
+	}
+
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	withState := fs.Bool("state", false, "also export this machine's state to states/<hostname>.json")
+	withSums := fs.Bool("checksums", false, "also merge checksums.jsonl in the repo with the local checksum database, both ways")
+	fs.Parse(args)
+
+	repo, err := gitsync.Open(gitsync.Dir())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := repo.Pull(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error pulling: %v\n", err)
+		return 1
+	}
+
+	hostname, _ := os.Hostname()
+	if *withState {
+		st, err := state.Load(state.Path())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading state: %v\n", err)
+			return 1
+		}
+		if _, err := inventory.Export(st, filepath.Join(repo.Dir, "states"), hostname); err != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting state: %v\n", err)
+			return 1
+		}
+	}
+
+	if *withSums {
+		if err := syncChecksums(filepath.Join(repo.Dir, "checksums.jsonl")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error syncing checksums: %v\n", err)
+			return 1
+		}
+	}
+
+	committed, err := repo.Commit(ctx, "sync from "+hostname)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error committing: %v\n", err)
+		return 1
+	}
+	if committed {
+		if err := repo.Push(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Error pushing: %v\n", err)
+			return 1
+		}
+		fmt.Println("Pushed local changes.")
+	} else {
+		fmt.Println("Up to date.")
+	}
+	return 0
+}
+
+// syncChecksums imports the checksums other machines pushed to path into the
+// local database, then rewrites path with the merged result. Conflicts are
+// reported but do not stop the sync.
+func syncChecksums(path string) error {
+	db, err := sumdb.Open(sumdb.Path())
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		importChecksums(db, path)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := db.Export(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncedCatalog returns the sync checkout's catalog.toml, freshly pulled, when
+// a sync repo has been set up. A failed pull is reported but not fatal.
+func syncedCatalog(ctx context.Context) (string, *gitsync.Repo, bool) {
+	repo, err := gitsync.Open(gitsync.Dir())
+	if err != nil {
+		return "", nil, false
+	}
+	path := filepath.Join(repo.Dir, "catalog.toml")
+	if _, err := os.Stat(path); err != nil {
+		return "", nil, false
+	}
+	if err := repo.Pull(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not pull sync repo: %v\n", err)
+	}
+	return path, repo, true
+}
+
+// pushCatalogEdits commits and pushes any catalog changes made during the run.
+func pushCatalogEdits(ctx context.Context, repo *gitsync.Repo) {
+	hostname, _ := os.Hostname()
+	committed, err := repo.Commit(ctx, "catalog: update from "+hostname)
+	if err == nil && committed {
+		err = repo.Push(ctx)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not push catalog changes: %v\n", err)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/config"
+	"github.com/dsaleh/david-dotfiles/internal/trust"
+)
+
+// applyTrust marks the programs of every catalog that may not run its
+// download_cmd, extract_cmd and health_cmd entries as Untrusted. The
+// built-in catalog is trusted; any other follows its catalog_trust level. At
+// the default level the commands run once approved: when interactive,
+// unapproved commands are listed and an approval is asked for and remembered
+// until they change; otherwise they are skipped with a warning, written to
+// warn.
+func applyTrust(programs []catalog.Program, catalogPath string, cfg config.Config, interactive bool, warn io.Writer) []catalog.Program {
+	levels := map[string]trust.Level{}
+	for path, level := range cfg.CatalogTrust {
+		levels[absPath(path)] = level
+	}
+
+	// Group by catalog, in the order the catalogs first appear.
+	var order []string
+	byCatalog := map[string][]int{}
+	for i, p := range programs {
+		src := p.Source
+		if src == "" {
+			src = catalogPath
+		}
+		if src == "" {
+			continue // built-in
+		}
+		src = absPath(src)
+		if _, ok := byCatalog[src]; !ok {
+			order = append(order, src)
+		}
+		byCatalog[src] = append(byCatalog[src], i)
+	}
+
+	var approvals *trust.Approvals
+	for _, src := range order {
+		var own []catalog.Program
+		for _, i := range byCatalog[src] {
+			own = append(own, programs[i])
+		}
+		cmds := trust.Commands(own)
+		if len(cmds) == 0 {
+			continue
+		}
+		level := levels[src]
+		if level == "" {
+			level = trust.Ask
+		}
+		if level == trust.Ask {
+			if approvals == nil {
+				var err error
+				if approvals, err = trust.Load(trust.Path()); err != nil {
+					fmt.Fprintf(warn, "Warning: catalog approvals: %v\n", err)
+					approvals, _ = trust.Load("")
+				}
+			}
+			fp := trust.Fingerprint(cmds)
+			if approvals.Approved(src, fp) {
+				continue
+			}
+			if interactive && askTrust(src, cmds) {
+				if err := approvals.Approve(src, fp); err != nil {
+					fmt.Fprintf(warn, "Warning: saving approval: %v\n", err)
+				}
+				continue
+			}
+			fmt.Fprintf(warn, "Warning: %s is not approved to run commands; its download_cmd, extract_cmd and health_cmd entries are skipped (set catalog_trust to change this).\n", src)
+		}
+		if level == trust.Trusted {
+			continue
+		}
+		for _, i := range byCatalog[src] {
+			programs[i].Untrusted = true
+		}
+	}
+	return programs
+}
+
+// askTrust lists the commands the catalog at path would run and asks
+// whether to allow them.
+func askTrust(path string, cmds []trust.Command) bool {
+	fmt.Printf("The catalog %s specifies commands to run on this machine:\n", path)
+	for _, c := range cmds {
+		fmt.Printf("  %s %s: %s\n", c.Program, c.Field, c.Cmd)
+	}
+	fmt.Print("Allow them? You will be asked again if they change. [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// absPath returns path made absolute, or path itself if that fails.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
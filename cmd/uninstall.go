@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dsaleh/david-dotfiles/internal/config"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/trash"
+	"github.com/dsaleh/david-dotfiles/tui"
+)
+
+// runUninstall implements the uninstall subcommand:
+//
+//	uninstall [--dry-run] <program>...
+//	        remove the programs' install dirs and the bin entries that still
+//	        point into them, forget them in the state file and list what was
+//	        removed
+//
+// Like an --apply run, it moves what it removes to the trash unless
+// trash_days is -1, and keeps an install dir other programs share.
+func runUninstall(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("uninstall", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "print what would be removed without touching anything")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: installer uninstall [--dry-run] <program>...")
+		return 2
+	}
+
+	cfg, err := config.Load(config.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 1
+	}
+	st, err := state.Load(state.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading state: %v\n", err)
+		return 1
+	}
+	for _, name := range fs.Args() {
+		if _, ok := st.Get(name); !ok {
+			fmt.Fprintf(os.Stderr, "Error: %s is not installed; `installer list` shows what is\n", name)
+			return 1
+		}
+	}
+
+	opts := installer.Options{State: st, Remove: fs.Args()}
+	if cfg.TrashDays > 0 {
+		opts.Trash = trash.Path()
+	}
+	if *dryRun {
+		tui.PrintPlan(os.Stdout, installer.Plan(ctx, nil, opts), opts.TrashDir() != "")
+		return 0
+	}
+
+	code := 0
+	for msg := range installer.Run(ctx, nil, opts) {
+		switch msg.State {
+		case installer.StateRemoved:
+			fmt.Printf("removed %s %s\n", msg.Program, msg.Version)
+			for _, p := range msg.Removed {
+				fmt.Printf("  %s\n", p)
+			}
+		case installer.StateError:
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", msg.Program, msg.Err)
+			code = 1
+		}
+	}
+	if opts.Trash != "" && code == 0 {
+		fmt.Println("The files are in the trash; `installer restore <program>` brings them back.")
+	}
+	return code
+}
@@ -0,0 +1,327 @@
+// Package backup bundles what the installer manages — the state file, config
+// and catalogs, and optionally the installed programs with their bin entries —
+// into one tar.gz archive, and restores it on the same machine or a new one.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+	"github.com/dsaleh/david-dotfiles/internal/trash"
+)
+
+// manifestName is the archive's first entry.
+const manifestName = "manifest.json"
+
+// Manifest describes a backup archive.
+type Manifest struct {
+	Created time.Time `json:"created"`
+	Host    string    `json:"host"`
+
+	// Home is $HOME on the machine that made the backup. Items and symlink
+	// targets under it are restored under the new $HOME.
+	Home string `json:"home"`
+
+	Items []Item `json:"items"`
+}
+
+// Item is one file or tree in the archive, stored under items/<index>.
+type Item struct {
+	Path string `json:"path"`           // absolute path it was read from
+	Kind string `json:"kind"`           // "state", "config", "catalog", "program" or "bin"
+	Name string `json:"name,omitempty"` // the program, for program and bin items
+}
+
+// Options selects what Create puts in the archive.
+type Options struct {
+	Home     string
+	State    string   // state file
+	Config   string   // config file; skipped when missing
+	Catalogs []string // catalog files
+
+	// Programs adds each recorded program's install dir and bin entries,
+	// so a restore does not need to download anything.
+	Programs bool
+}
+
+// Create writes a backup of what opts selects to w, recording st's programs
+// when opts.Programs is set. Missing files are left out.
+func Create(w io.Writer, st *state.State, opts Options) (Manifest, error) {
+	host, _ := os.Hostname()
+	m := Manifest{Created: time.Now().UTC(), Host: host, Home: opts.Home}
+	add := func(path, kind, name string) {
+		if _, err := os.Lstat(path); err == nil {
+			m.Items = append(m.Items, Item{Path: path, Kind: kind, Name: name})
+		}
+	}
+	add(opts.State, "state", "")
+	if opts.Config != "" {
+		add(opts.Config, "config", "")
+	}
+	for _, c := range opts.Catalogs {
+		abs, err := filepath.Abs(c)
+		if err != nil {
+			return Manifest{}, err
+		}
+		add(abs, "catalog", "")
+	}
+	if opts.Programs {
+		dirs := map[string]bool{}
+		for _, name := range st.Names() {
+			ps, _ := st.Get(name)
+			if dir := st.DirOf(name); !dirs[dir] {
+				dirs[dir] = true
+				add(filepath.Join(system.SharePath(), dir), "program", dir)
+			}
+			binDir := ps.BinDir
+			if binDir == "" {
+				binDir = system.BinPath()
+			}
+			for _, b := range ps.Bins {
+				add(filepath.Join(binDir, b), "bin", name)
+			}
+		}
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return Manifest{}, err
+	}
+	hdr := &tar.Header{Name: manifestName, Mode: 0644, Size: int64(len(data)), ModTime: m.Created, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return Manifest{}, err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return Manifest{}, err
+	}
+	for i, it := range m.Items {
+		root := it.Path
+		if it.Kind == "program" {
+			// An install dir is a link to its store entry; archive the tree,
+			// which restores as a plain dir.
+			root += string(filepath.Separator)
+		}
+		if err := addTree(tw, root, "items/"+strconv.Itoa(i)); err != nil {
+			return Manifest{}, fmt.Errorf("back up %s: %w", it.Path, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return Manifest{}, err
+	}
+	return m, gw.Close()
+}
+
+// addTree writes the file, symlink or directory tree at root to tw under the
+// archive name base.
+func addTree(tw *tar.Writer, root, base string) error {
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(base, filepath.ToSlash(rel))
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+}
+
+// Restore unpacks the archive read from r, putting every item back at its
+// path with the backup's home replaced by home. Items may only land under
+// home or at one of the paths in allowed (the state, config and catalog files
+// in use), since the manifest could name any path. Whatever is already at an
+// item's path is moved into bin first, when bin is non-nil, and overwritten
+// otherwise. It returns the manifest and the paths restored.
+func Restore(r io.Reader, home string, allowed []string, bin *trash.Batch) (Manifest, []string, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return Manifest{}, nil, fmt.Errorf("open backup: %w", err)
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != manifestName {
+		return Manifest{}, nil, errors.New("not a backup archive: manifest missing")
+	}
+	var m Manifest
+	if err := json.NewDecoder(tr).Decode(&m); err != nil {
+		return Manifest{}, nil, fmt.Errorf("read manifest: %w", err)
+	}
+
+	var restored []string
+	cleared := map[int]bool{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return m, restored, err
+		}
+		i, rel, ok := itemOf(hdr.Name)
+		if !ok || i >= len(m.Items) {
+			return m, restored, fmt.Errorf("unexpected entry %q in backup", hdr.Name)
+		}
+		root := Rehome(m.Items[i].Path, m.Home, home)
+		if !cleared[i] {
+			if !allowedPath(root, home, allowed) {
+				return m, restored, fmt.Errorf("backup item %s is outside %s", root, home)
+			}
+			cleared[i] = true
+			if err := makeWay(root, bin); err != nil {
+				return m, restored, err
+			}
+			restored = append(restored, root)
+		}
+		dst := root
+		if rel != "" {
+			dst = filepath.Join(root, filepath.FromSlash(rel))
+			if link := restoredLink(root, filepath.Dir(dst)); link != "" {
+				return m, restored, fmt.Errorf("backup entry %q would be written through the symlink %s", hdr.Name, link)
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), system.DirPerm()); err != nil {
+			return m, restored, err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(dst, system.DirPerm())
+		case tar.TypeSymlink:
+			err = os.Symlink(Rehome(hdr.Linkname, m.Home, home), dst)
+		case tar.TypeReg:
+			err = writeFile(dst, tr, system.Perm(os.FileMode(hdr.Mode).Perm()))
+		}
+		if err != nil {
+			return m, restored, err
+		}
+	}
+	return m, restored, nil
+}
+
+// itemOf splits an entry name of the form items/<index>[/<rel>], rejecting
+// names that would escape the item.
+func itemOf(name string) (int, string, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSuffix(name, "/"), "items/")
+	if !ok {
+		return 0, "", false
+	}
+	idx, rel, _ := strings.Cut(rest, "/")
+	i, err := strconv.Atoi(idx)
+	if err != nil || i < 0 {
+		return 0, "", false
+	}
+	if rel != "" && (path.Clean(rel) != rel || rel == ".." || strings.HasPrefix(rel, "../")) {
+		return 0, "", false
+	}
+	return i, rel, true
+}
+
+// allowedPath reports whether an item may be restored at p: strictly under
+// home, or one of the allowed paths.
+func allowedPath(p, home string, allowed []string) bool {
+	if !filepath.IsAbs(p) {
+		return false
+	}
+	p = filepath.Clean(p)
+	for _, a := range allowed {
+		if a != "" && p == filepath.Clean(a) {
+			return true
+		}
+	}
+	rel, err := filepath.Rel(home, p)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// restoredLink returns the first of root and the dirs between it and dir
+// that is a symlink, or "". Everything under root was just restored, so a
+// link there came from the archive and must not be written through.
+func restoredLink(root, dir string) string {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return root
+	}
+	p := root
+	for _, part := range append([]string{""}, strings.Split(rel, string(filepath.Separator))...) {
+		if part != "" && part != "." {
+			p = filepath.Join(p, part)
+		}
+		if info, err := os.Lstat(p); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return p
+		}
+	}
+	return ""
+}
+
+// makeWay makes way for an item at path, trashing what is there when bin is
+// set.
+func makeWay(path string, bin *trash.Batch) error {
+	if bin != nil {
+		return bin.Move(path)
+	}
+	return os.RemoveAll(path)
+}
+
+func writeFile(path string, r io.Reader, mode os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Rehome returns p with the directory from replaced by to, when p is under
+// from; p otherwise.
+func Rehome(p, from, to string) string {
+	if from == "" || to == "" || from == to {
+		return p
+	}
+	if rel, err := filepath.Rel(from, p); err == nil && filepath.IsAbs(p) && rel != ".." && !strings.HasPrefix(rel, "../") {
+		return filepath.Join(to, rel)
+	}
+	return p
+}
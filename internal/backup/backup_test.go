@@ -0,0 +1,166 @@
+package backup_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dsaleh/david-dotfiles/internal/backup"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+func TestCreateAndRestore(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "backup-*")
+	defer os.RemoveAll(dir)
+	old, home := filepath.Join(dir, "old"), filepath.Join(dir, "new")
+	system.SetRoot(old)
+	defer system.SetRoot("")
+
+	install := filepath.Join(system.SharePath(), "fzf")
+	os.MkdirAll(install, 0755)
+	os.WriteFile(filepath.Join(install, "fzf"), []byte("v1"), 0755)
+	os.MkdirAll(system.BinPath(), 0755)
+	os.Symlink(filepath.Join(install, "fzf"), filepath.Join(system.BinPath(), "fzf"))
+	st := state.New(filepath.Join(old, "state.json"))
+	st.Set("fzf", state.ProgramState{Version: "0.60.0", Bins: []string{"fzf"}})
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	m, err := backup.Create(&buf, st, backup.Options{
+		Home:     old,
+		State:    filepath.Join(old, "state.json"),
+		Config:   filepath.Join(old, "missing.toml"),
+		Programs: true,
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if len(m.Items) != 3 {
+		t.Fatalf("items = %+v, want state, program and bin", m.Items)
+	}
+
+	_, restored, err := backup.Restore(&buf, home, nil, nil)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if len(restored) != 3 {
+		t.Errorf("restored = %v", restored)
+	}
+	link := filepath.Join(home, system.BinDir, "fzf")
+	if target, _ := os.Readlink(link); target != filepath.Join(home, system.ShareDir, "fzf", "fzf") {
+		t.Errorf("link points at %q, want it under the new home", target)
+	}
+	if data, _ := os.ReadFile(link); string(data) != "v1" {
+		t.Errorf("restored link reads %q, want v1", data)
+	}
+	if info, err := os.Stat(link); err != nil || info.Mode()&0100 == 0 {
+		t.Errorf("restored binary mode = %v, %v", info, err)
+	}
+	got, err := state.Load(filepath.Join(home, "state.json"))
+	if ps, ok := got.Get("fzf"); err != nil || !ok || ps.Version != "0.60.0" {
+		t.Errorf("restored state = %+v, %v", ps, err)
+	}
+}
+
+func TestRestore_notABackup(t *testing.T) {
+	if _, _, err := backup.Restore(bytes.NewReader([]byte("nope")), "/tmp", nil, nil); err == nil {
+		t.Error("expected error for a file that is not a backup")
+	}
+}
+
+// entry is an archive entry and, for a regular file, its content.
+type entry struct {
+	tar.Header
+	data string
+}
+
+// archive builds a backup of m with entries by hand.
+func archive(t *testing.T, m backup.Manifest, entries ...entry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	data, _ := json.Marshal(m)
+	tw.WriteHeader(&tar.Header{Name: "manifest.json", Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg})
+	tw.Write(data)
+	for _, e := range entries {
+		e.Size = int64(len(e.data))
+		if err := tw.WriteHeader(&e.Header); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(e.data))
+	}
+	tw.Close()
+	gw.Close()
+	return &buf
+}
+
+func TestRestore_outsideHome(t *testing.T) {
+	dir := t.TempDir()
+	home, victim := filepath.Join(dir, "home"), filepath.Join(dir, "etc", "passwd")
+	os.MkdirAll(filepath.Dir(victim), 0755)
+	os.WriteFile(victim, []byte("root"), 0644)
+	m := backup.Manifest{Home: "/home/a", Items: []backup.Item{{Path: victim, Kind: "config"}}}
+	file := entry{tar.Header{Name: "items/0", Mode: 0644, Typeflag: tar.TypeReg}, "evil"}
+
+	if _, _, err := backup.Restore(archive(t, m, file), home, nil, nil); err == nil {
+		t.Error("expected an error for an item outside the home")
+	}
+	if data, _ := os.ReadFile(victim); string(data) != "root" {
+		t.Errorf("%s was overwritten with %q", victim, data)
+	}
+
+	// The files an install uses may be restored wherever they are.
+	if _, _, err := backup.Restore(archive(t, m, file), home, []string{victim}, nil); err != nil {
+		t.Fatalf("Restore of an allowed path: %v", err)
+	}
+	if data, _ := os.ReadFile(victim); string(data) != "evil" {
+		t.Errorf("allowed path reads %q after restore", data)
+	}
+}
+
+func TestRestore_throughSymlink(t *testing.T) {
+	dir := t.TempDir()
+	home, outside := filepath.Join(dir, "home"), filepath.Join(dir, "outside")
+	os.MkdirAll(outside, 0755)
+	m := backup.Manifest{Home: "/home/a", Items: []backup.Item{{Path: "/home/a/.local/share/x", Kind: "program", Name: "x"}}}
+	for _, entries := range [][]entry{
+		{
+			{tar.Header{Name: "items/0", Typeflag: tar.TypeSymlink, Linkname: outside}, ""},
+			{tar.Header{Name: "items/0/evil", Mode: 0644, Typeflag: tar.TypeReg}, "evil"},
+		},
+		{
+			{tar.Header{Name: "items/0/", Mode: 0755, Typeflag: tar.TypeDir}, ""},
+			{tar.Header{Name: "items/0/lib", Typeflag: tar.TypeSymlink, Linkname: outside}, ""},
+			{tar.Header{Name: "items/0/lib/sub/", Mode: 0755, Typeflag: tar.TypeDir}, ""},
+		},
+	} {
+		if _, _, err := backup.Restore(archive(t, m, entries...), home, nil, nil); err == nil {
+			t.Errorf("%s: expected an error for an entry under a restored symlink", entries[len(entries)-1].Name)
+		}
+	}
+	if left, _ := os.ReadDir(outside); len(left) != 0 {
+		t.Errorf("wrote through the symlink: %v", left)
+	}
+}
+
+func TestRehome(t *testing.T) {
+	for _, tc := range []struct{ p, want string }{
+		{"/home/a/.local/bin/fzf", "/home/b/.local/bin/fzf"},
+		{"/home/a", "/home/b"},
+		{"/home/ab/x", "/home/ab/x"},
+		{"/opt/x", "/opt/x"},
+		{"../share/fzf", "../share/fzf"},
+	} {
+		if got := backup.Rehome(tc.p, "/home/a", "/home/b"); got != tc.want {
+			t.Errorf("Rehome(%q) = %q, want %q", tc.p, got, tc.want)
+		}
+	}
+}
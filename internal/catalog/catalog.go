@@ -1,34 +1,250 @@
 package catalog
 
 import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
-// Load parses catalog.toml at path and returns a validated, sorted slice of Programs.
+// DefaultTOML is the built-in catalog, used when no catalog.toml is present so
+// the binary is useful straight after download.
+//
+//go:embed default.toml
+var DefaultTOML []byte
+
+// Load parses the catalog at path and returns a validated, sorted slice of
+// Programs. Files ending in .json are read as JSON and ones ending in .yaml
+// or .yml as YAML, both with the same schema as catalog.toml ({"programs":
+// {"fzf": {"repo": ..., "bin": [...]}}}); anything else is TOML.
 func Load(path string) ([]Program, error) {
-	var raw struct {
-		Programs map[string]Program `toml:"programs"`
+	raw, err := decodeFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return validate(raw.Programs)
+}
+
+// decodeFile reads the catalog at path in the format its extension names.
+func decodeFile(path string) (Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Catalog{}, fmt.Errorf("parse catalog: %w", err)
 	}
-	if _, err := toml.DecodeFile(path, &raw); err != nil {
-		return nil, fmt.Errorf("parse catalog: %w", err)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return decodeJSON(data)
+	case ".yaml", ".yml":
+		return decodeYAML(data)
 	}
+	return decode(data)
+}
+
+// LoadAll loads several catalogs into one program list, ordered by catalog
+// and then by name. Each program's Source is set to the file it came from. A
+// program name defined in more than one catalog is an error, as is a tool
+// provided by programs of different catalogs.
+func LoadAll(paths []string) ([]Program, error) {
+	var all []Program
+	from := map[string]string{}
+	var conflicts []string
+	for _, path := range paths {
+		programs, err := Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, p := range programs {
+			if prev, ok := from[p.Name]; ok {
+				conflicts = append(conflicts, fmt.Sprintf("[%s] is defined in both %s and %s", p.Name, prev, path))
+				continue
+			}
+			from[p.Name] = path
+			p.Source = path
+			all = append(all, p)
+		}
+	}
+	conflicts = append(conflicts, provideConflicts(all)...)
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("catalog conflicts:\n%s", strings.Join(conflicts, "\n"))
+	}
+	return all, nil
+}
+
+// Namespace names the group of programs loaded from path: its file name
+// without the extension, e.g. "work" for ~/catalogs/work.toml.
+func Namespace(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// Default returns the programs of the built-in catalog.
+func Default() ([]Program, error) {
+	return parse(DefaultTOML)
+}
 
+func parse(data []byte) ([]Program, error) {
+	raw, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return validate(raw.Programs)
+}
+
+func decode(data []byte) (Catalog, error) {
+	var raw Catalog
+	if _, err := toml.Decode(string(data), &raw); err != nil {
+		return Catalog{}, fmt.Errorf("parse catalog: %w", err)
+	}
+	return raw, nil
+}
+
+func decodeJSON(data []byte) (Catalog, error) {
+	var raw Catalog
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return Catalog{}, fmt.Errorf("parse catalog: %w", err)
+	}
+	return raw, nil
+}
+
+func decodeYAML(data []byte) (Catalog, error) {
+	var raw Catalog
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&raw); err != nil && err != io.EOF {
+		return Catalog{}, fmt.Errorf("parse catalog: %w", err)
+	}
+	return raw, nil
+}
+
+// provideConflicts reports tools that are provided by more than one program
+// of a catalog, or that name another program.
+func provideConflicts(programs []Program) []string {
+	names := map[string]bool{}
+	providers := map[string][]string{}
+	for _, p := range programs {
+		names[p.Name] = true
+		for _, t := range p.Tools()[1:] {
+			providers[t] = append(providers[t], p.Name)
+		}
+	}
+	var errs []string
+	for t, by := range providers {
+		if len(by) > 1 {
+			sort.Strings(by)
+			errs = append(errs, fmt.Sprintf("%s is provided by more than one program: %s", t, strings.Join(by, ", ")))
+		}
+		if names[t] {
+			for _, name := range by {
+				errs = append(errs, fmt.Sprintf("[%s]: provides %s, which is another program", name, t))
+			}
+		}
+	}
+	sort.Strings(errs)
+	return errs
+}
+
+// CheckDownloadCmd validates a download_cmd template. The command is split on
+// spaces (no shell is involved) and each word has {url} replaced by the asset
+// URL, {out} by the path to write it to, and {dir} and {file} by that path's
+// directory and file name. It must use {url} and say where to write: {out} or
+// {file}. An empty template is valid and means the built-in client.
+func CheckDownloadCmd(tmpl string) error {
+	if tmpl == "" {
+		return nil
+	}
+	if !strings.Contains(tmpl, "{url}") {
+		return fmt.Errorf("download_cmd %q must contain {url}", tmpl)
+	}
+	if !strings.Contains(tmpl, "{out}") && !strings.Contains(tmpl, "{file}") {
+		return fmt.Errorf("download_cmd %q must contain {out} or {file}", tmpl)
+	}
+	return nil
+}
+
+// CheckExtractCmd validates an extract_cmd template. Like download_cmd it is
+// split on spaces and run without a shell, in an empty directory whose
+// contents become the install tree; {archive} is replaced by the path of the
+// downloaded asset (made executable first) and {dir} by that directory. It
+// must use {archive}. An empty template means the built-in extractor.
+func CheckExtractCmd(tmpl string) error {
+	if tmpl != "" && !strings.Contains(tmpl, "{archive}") {
+		return fmt.Errorf("extract_cmd %q must contain {archive}", tmpl)
+	}
+	return nil
+}
+
+// CheckHost validates a host and api_base: the host is a bare host name
+// (with an optional port) and the API root an http(s) URL.
+func CheckHost(host, apiBase string) error {
+	if host != "" {
+		if u, err := url.Parse("https://" + host); err != nil || u.Host != host {
+			return fmt.Errorf("host %q must be a host name such as github.example.com, without a scheme or path", host)
+		}
+	}
+	if apiBase != "" {
+		if u, err := url.Parse(apiBase); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("api_base %q must be an http(s) URL such as https://github.example.com/api/v3", apiBase)
+		}
+	}
+	return nil
+}
+
+// validate names, expands and checks the decoded programs, whatever format
+// they were read from.
+func validate(raw map[string]Program) ([]Program, error) {
 	var errs []string
 	var programs []Program
 
-	for name, p := range raw.Programs {
+	for name, p := range raw {
 		p.Name = name
-		var fieldErrs []string
+		fieldErrs := p.expandEnv()
 		if p.Repo == "" {
 			fieldErrs = append(fieldErrs, "repo is required")
 		}
 		if p.AssetPattern == "" {
 			fieldErrs = append(fieldErrs, "asset_pattern is required")
 		}
+		if p.UseTags && p.AssetURL == "" {
+			fieldErrs = append(fieldErrs, "use_tags needs asset_url, since tags have no release assets")
+		}
+		switch p.LinkMode {
+		case "", "symlink", "relative", "hardlink", "copy":
+		default:
+			fieldErrs = append(fieldErrs, fmt.Sprintf("link_mode %q must be symlink, relative, hardlink or copy", p.LinkMode))
+		}
+		if err := CheckDownloadCmd(p.DownloadCmd); err != nil {
+			fieldErrs = append(fieldErrs, err.Error())
+		}
+		if err := CheckExtractCmd(p.ExtractCmd); err != nil {
+			fieldErrs = append(fieldErrs, err.Error())
+		}
+		if err := CheckHost(p.Host, p.APIBase); err != nil {
+			fieldErrs = append(fieldErrs, err.Error())
+		}
+		if err := CheckHoldUntil(p.HoldUntil); err != nil {
+			fieldErrs = append(fieldErrs, err.Error())
+		}
+		if err := CheckSignature(p.Signature, p.Pubkey); err != nil {
+			fieldErrs = append(fieldErrs, err.Error())
+		}
+		fieldErrs = append(fieldErrs, CheckRequires(p.Requires)...)
+		for _, pl := range p.Unsupported {
+			if !slices.Contains(Platforms, pl) {
+				fieldErrs = append(fieldErrs, fmt.Sprintf("unsupported %q must be one of %v", pl, Platforms))
+			}
+		}
 		// bin is optional — if empty, the user picks binaries interactively at install time
 		if len(fieldErrs) > 0 {
 			errs = append(errs, fmt.Sprintf("[%s]: %s", name, strings.Join(fieldErrs, ", ")))
@@ -36,6 +252,7 @@ func Load(path string) ([]Program, error) {
 		}
 		programs = append(programs, p)
 	}
+	errs = append(errs, provideConflicts(programs)...)
 
 	if len(errs) > 0 {
 		return nil, fmt.Errorf("catalog validation errors:\n%s", strings.Join(errs, "\n"))
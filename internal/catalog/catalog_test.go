@@ -2,6 +2,8 @@ package catalog_test
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/dsaleh/david-dotfiles/internal/catalog"
@@ -52,3 +54,547 @@ bin           = [{src = "foo", dst = "foo"}]
 		t.Fatal("expected validation error for missing repo")
 	}
 }
+
+func TestSetString(t *testing.T) {
+	f, _ := os.CreateTemp("", "catalog-*.toml")
+	f.WriteString(`# my tools
+[programs.fzf]
+repo          = "junegunn/fzf"
+asset_pattern = "fzf-{version}-linux_amd64.tar.gz" # old name
+packages      = []
+
+[programs.kitty]
+repo          = "kovidgoyal/kitty"
+asset_pattern = "kitty-{version}-amd64.txz"
+`)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	if err := catalog.SetString(f.Name(), "kitty", "asset_pattern", "kitty-{version}-x86_64.txz"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := catalog.SetString(f.Name(), "fzf", "version", "v0.60.0"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	programs, err := catalog.Load(f.Name())
+	if err != nil {
+		t.Fatalf("edited catalog does not load: %v", err)
+	}
+	if programs[1].AssetPattern != "kitty-{version}-x86_64.txz" {
+		t.Errorf("asset_pattern not updated: %q", programs[1].AssetPattern)
+	}
+	if programs[0].AssetPattern != "fzf-{version}-linux_amd64.tar.gz" || programs[0].Version != "v0.60.0" {
+		t.Errorf("unexpected fzf entry: %+v", programs[0])
+	}
+	data, _ := os.ReadFile(f.Name())
+	if !strings.HasPrefix(string(data), "# my tools\n") {
+		t.Error("comments should be preserved")
+	}
+
+	if err := catalog.SetString(f.Name(), "missing", "repo", "x/y"); err == nil {
+		t.Error("expected error for unknown program")
+	}
+}
+
+func TestDefault(t *testing.T) {
+	programs, err := catalog.Default()
+	if err != nil {
+		t.Fatalf("built-in catalog does not load: %v", err)
+	}
+	if len(programs) == 0 {
+		t.Fatal("built-in catalog is empty")
+	}
+	for _, p := range programs {
+		if len(p.Bin) == 0 {
+			t.Errorf("%s: built-in entries should list their bins", p.Name)
+		}
+	}
+}
+
+func TestLoadAll(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "catalogs-*")
+	defer os.RemoveAll(dir)
+	personal := filepath.Join(dir, "personal.toml")
+	work := filepath.Join(dir, "work.toml")
+	os.WriteFile(personal, []byte(`
+[programs.zoxide]
+repo          = "ajeetdsouza/zoxide"
+asset_pattern = "zoxide-{version}-x86_64-unknown-linux-musl.tar.gz"
+`), 0644)
+	os.WriteFile(work, []byte(`
+[programs.kubectx]
+repo          = "ahmetb/kubectx"
+asset_pattern = "kubectx_v{version}_linux_x86_64.tar.gz"
+`), 0644)
+
+	programs, err := catalog.LoadAll([]string{personal, work})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(programs) != 2 || programs[0].Name != "zoxide" || programs[1].Name != "kubectx" {
+		t.Fatalf("expected programs in catalog order, got %+v", programs)
+	}
+	if programs[1].Source != work || catalog.Namespace(programs[1].Source) != "work" {
+		t.Errorf("unexpected source %q", programs[1].Source)
+	}
+
+	dup := filepath.Join(dir, "dup.toml")
+	os.WriteFile(dup, []byte(`
+[programs.zoxide]
+repo          = "someone/zoxide"
+asset_pattern = "zoxide.tar.gz"
+`), 0644)
+	_, err = catalog.LoadAll([]string{personal, work, dup})
+	if err == nil || !strings.Contains(err.Error(), "[zoxide] is defined in both") {
+		t.Errorf("expected a conflict error, got %v", err)
+	}
+}
+
+func TestLoad_expandsEnv(t *testing.T) {
+	t.Setenv("TOOLS_MIRROR_OWNER", "mirror")
+	f, _ := os.CreateTemp("", "catalog-*.toml")
+	f.WriteString(`
+[programs.fzf]
+repo          = "${TOOLS_MIRROR_OWNER}/fzf"
+asset_pattern = "fzf-{version}-${TOOLS_ARCH:-linux_amd64}.tar.gz"
+`)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	programs, err := catalog.Load(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if programs[0].Repo != "mirror/fzf" || programs[0].AssetPattern != "fzf-{version}-linux_amd64.tar.gz" {
+		t.Errorf("unexpected expansion: %+v", programs[0])
+	}
+}
+
+func TestLoad_unsetEnv(t *testing.T) {
+	f, _ := os.CreateTemp("", "catalog-*.toml")
+	f.WriteString(`
+[programs.fzf]
+repo          = "${DEFINITELY_UNSET_MIRROR}/fzf"
+asset_pattern = "fzf.tar.gz"
+`)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	_, err := catalog.Load(f.Name())
+	if err == nil || !strings.Contains(err.Error(), "[fzf]: repo: environment variable DEFINITELY_UNSET_MIRROR is not set") {
+		t.Fatalf("expected an unset variable error, got %v", err)
+	}
+}
+
+func TestLoad_json(t *testing.T) {
+	f, _ := os.CreateTemp("", "catalog-*.json")
+	f.WriteString(`{
+  "programs": {
+    "fzf": {
+      "repo": "junegunn/fzf",
+      "asset_pattern": "fzf-{version}-linux_amd64.tar.gz",
+      "bin": [{"src": "fzf", "dst": "fzf"}]
+    },
+    "rg": {"repo": "BurntSushi/ripgrep"}
+  }
+}`)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	// Validation is shared with TOML catalogs.
+	_, err := catalog.Load(f.Name())
+	if err == nil || !strings.Contains(err.Error(), "[rg]: asset_pattern is required") {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+
+	os.WriteFile(f.Name(), []byte(`{"programs": {"fzf": {"repo": "junegunn/fzf", "asset_pattern": "fzf.tar.gz", "bin": [{"src": "fzf", "dst": "fzf"}]}}}`), 0644)
+	programs, err := catalog.Load(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(programs) != 1 || programs[0].Name != "fzf" || programs[0].Bin[0].Src != "fzf" {
+		t.Errorf("unexpected programs: %+v", programs)
+	}
+
+	os.WriteFile(f.Name(), []byte(`{"programs": {"fzf": {"repository": "junegunn/fzf"}}}`), 0644)
+	if _, err := catalog.Load(f.Name()); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+	if err := catalog.Editable(f.Name()); err == nil {
+		t.Error("JSON catalogs should not be editable")
+	}
+
+	// The program name comes from the key, not a field.
+	os.WriteFile(f.Name(), []byte(`{"programs": {"fzf": {"Name": "other", "repo": "junegunn/fzf", "asset_pattern": "fzf.tar.gz"}}}`), 0644)
+	if _, err := catalog.Load(f.Name()); err == nil {
+		t.Error("expected an error for a Name field")
+	}
+}
+
+func TestLoad_yaml(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.yaml")
+	os.WriteFile(path, []byte(`
+programs:
+  fzf:
+    repo: junegunn/fzf
+    asset_pattern: fzf-{version}-linux_amd64.tar.gz
+    bin:
+      - {src: fzf, dst: fzf}
+    requires:
+      commands: {git: "2.30"}
+  rg:
+    repo: BurntSushi/ripgrep
+`), 0644)
+
+	// Validation is shared with TOML catalogs.
+	_, err := catalog.Load(path)
+	if err == nil || !strings.Contains(err.Error(), "[rg]: asset_pattern is required") {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+
+	os.WriteFile(path, []byte("programs:\n  fzf:\n    repo: junegunn/fzf\n    asset_pattern: fzf.tar.gz\n    bin: [{src: fzf, dst: fzf}]\n    requires: {commands: {git: \"2.30\"}}\n"), 0644)
+	programs, err := catalog.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(programs) != 1 || programs[0].Name != "fzf" || programs[0].Bin[0].Dst != "fzf" || programs[0].Requires.Commands["git"] != "2.30" {
+		t.Errorf("unexpected programs: %+v", programs)
+	}
+
+	for _, bad := range []string{
+		"programs:\n  fzf:\n    repository: junegunn/fzf\n",
+		"programs:\n  fzf:\n    Name: other\n    repo: junegunn/fzf\n    asset_pattern: fzf.tar.gz\n",
+	} {
+		os.WriteFile(path, []byte(bad), 0644)
+		if _, err := catalog.Load(path); err == nil {
+			t.Errorf("expected an error for an unknown field in %q", bad)
+		}
+	}
+	if err := catalog.Editable(path); err == nil {
+		t.Error("YAML catalogs should not be editable")
+	}
+}
+
+func TestLoad_useTagsNeedsAssetURL(t *testing.T) {
+	f, _ := os.CreateTemp("", "catalog-*.toml")
+	f.WriteString(`
+[programs.tool]
+repo          = "owner/tool"
+asset_pattern = "tool-{version}.tar.gz"
+use_tags      = true
+`)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	_, err := catalog.Load(f.Name())
+	if err == nil || !strings.Contains(err.Error(), "use_tags needs asset_url") {
+		t.Fatalf("expected a use_tags error, got %v", err)
+	}
+}
+
+func TestCheckDownloadCmd(t *testing.T) {
+	for tmpl, ok := range map[string]bool{
+		"":                                true,
+		"aria2c {url} -d {dir} -o {file}": true,
+		"curl -fsSL -o {out} {url}":       true,
+		"curl -fsSL {url}":                false,
+		"wget -O {out}":                   false,
+	} {
+		if err := catalog.CheckDownloadCmd(tmpl); (err == nil) != ok {
+			t.Errorf("CheckDownloadCmd(%q) = %v, want ok=%v", tmpl, err, ok)
+		}
+	}
+}
+
+func TestCheckExtractCmd(t *testing.T) {
+	if err := catalog.CheckExtractCmd("{archive} --prefix={dir} --skip-license"); err != nil {
+		t.Errorf("valid template rejected: %v", err)
+	}
+	if err := catalog.CheckExtractCmd("./install.sh --prefix={dir}"); err == nil {
+		t.Error("template without {archive} accepted")
+	}
+}
+
+func TestLoad_provides(t *testing.T) {
+	f, _ := os.CreateTemp("", "catalog-*.toml")
+	f.WriteString(`
+[programs.fd]
+repo          = "sharkdp/fd"
+asset_pattern = "fd-v{version}-x86_64-unknown-linux-musl.tar.gz"
+provides      = ["fdfind", "fd"]
+`)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	programs, err := catalog.Load(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(programs[0].Tools(), ","); got != "fd,fdfind" {
+		t.Errorf("Tools() = %s, want fd,fdfind", got)
+	}
+}
+
+func TestLoad_providesConflicts(t *testing.T) {
+	f, _ := os.CreateTemp("", "catalog-*.toml")
+	f.WriteString(`
+[programs.bat]
+repo          = "sharkdp/bat"
+asset_pattern = "bat.tar.gz"
+
+[programs.suite-a]
+repo          = "owner/suite-a"
+asset_pattern = "a.tar.gz"
+provides      = ["bat", "grep-ng"]
+
+[programs.suite-b]
+repo          = "owner/suite-b"
+asset_pattern = "b.tar.gz"
+provides      = ["grep-ng"]
+`)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	_, err := catalog.Load(f.Name())
+	if err == nil {
+		t.Fatal("expected conflict errors")
+	}
+	for _, want := range []string{
+		"[suite-a]: provides bat, which is another program",
+		"grep-ng is provided by more than one program: suite-a, suite-b",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in %v", want, err)
+		}
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	for _, c := range []struct {
+		host, apiBase string
+		ok            bool
+	}{
+		{"", "", true},
+		{"github.example.com", "", true},
+		{"github.example.com:8443", "https://github.example.com:8443/api/v3", true},
+		{"https://github.example.com", "", false},
+		{"github.example.com/org", "", false},
+		{"", "github.example.com/api/v3", false},
+	} {
+		if err := catalog.CheckHost(c.host, c.apiBase); (err == nil) != c.ok {
+			t.Errorf("CheckHost(%q, %q) = %v, want ok=%v", c.host, c.apiBase, err, c.ok)
+		}
+	}
+}
+
+func TestCheckSignature(t *testing.T) {
+	const minisignKey = "RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3"
+	for _, c := range []struct {
+		signature, pubkey string
+		ok                bool
+	}{
+		{"", "", true},
+		{"{asset}.minisig", minisignKey, true},
+		{"{asset}.minisig", "untrusted comment: minisign public key\n" + minisignKey + "\n", true},
+		{"{asset}.asc", "~/.config/installer/keys/just.asc", true},
+		{"https://example.com/{tag}/{asset}.sig", "-----BEGIN PGP PUBLIC KEY BLOCK-----", true},
+		{"{asset}.minisig", "", false},
+		{"", minisignKey, false},
+		{"{asset}.minisig", "-----BEGIN PGP PUBLIC KEY BLOCK-----", false},
+		{"{asset}.sha256", minisignKey, false},
+	} {
+		if err := catalog.CheckSignature(c.signature, c.pubkey); (err == nil) != c.ok {
+			t.Errorf("CheckSignature(%q, %q) = %v, want ok=%v", c.signature, c.pubkey, err, c.ok)
+		}
+	}
+}
+
+func TestHoldReleased(t *testing.T) {
+	for _, c := range []struct {
+		cond, version string
+		want          bool
+	}{
+		{">=1.4.2", "1.4.2", true},
+		{">=1.4.2", "1.4.10", true},
+		{">=1.4.2", "1.4.1", false},
+		{">1.4.2", "1.4.2", false},
+		{">1.4", "1.4.1", true},
+		{"1.4.2", "v2.0", true},
+		{">=1.4.2", "1.5.0-rc1", false},
+		{"", "9.9.9", false},
+	} {
+		p := catalog.Program{HoldUntil: c.cond}
+		if got := p.HoldReleased(c.version); got != c.want {
+			t.Errorf("hold_until %q, version %q: got %v, want %v", c.cond, c.version, got, c.want)
+		}
+	}
+	for _, cond := range []string{">=next", "<2.0", ">=1.4.2-rc1"} {
+		if err := catalog.CheckHoldUntil(cond); err == nil {
+			t.Errorf("CheckHoldUntil(%q): expected an error", cond)
+		}
+	}
+}
+
+func TestLoad_requires(t *testing.T) {
+	f, _ := os.CreateTemp("", "catalog-*.toml")
+	f.WriteString(`
+[programs.neovim]
+repo          = "neovim/neovim"
+asset_pattern = "nvim-linux-x86_64.appimage"
+
+[programs.neovim.requires]
+libraries  = ["libfuse.so.2"]
+pkg_config = ["gtk+-3.0"]
+commands   = { git = "2.30" }
+kernel     = ["fuse"]
+
+[programs.bad]
+repo          = "owner/bad"
+asset_pattern = "bad.tar.gz"
+requires      = { commands = { git = "recent" }, kernel = ["bpf"] }
+`)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	_, err := catalog.Load(f.Name())
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{`requires.commands "git" = "recent"`, `requires.kernel "bpf"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in %v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "[neovim]") {
+		t.Errorf("valid requires rejected: %v", err)
+	}
+}
+
+func TestLoad_unsupported(t *testing.T) {
+	f, _ := os.CreateTemp("", "catalog-*.toml")
+	f.WriteString(`
+[programs.kitty]
+repo          = "kovidgoyal/kitty"
+asset_pattern = "kitty-{version}-x86_64.txz"
+unsupported   = ["termux", "android"]
+`)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	_, err := catalog.Load(f.Name())
+	if err == nil || !strings.Contains(err.Error(), `unsupported "android"`) {
+		t.Fatalf("expected an error for an unknown platform, got %v", err)
+	}
+	if strings.Contains(err.Error(), `"termux" must`) {
+		t.Errorf("termux rejected: %v", err)
+	}
+}
+
+func TestRequires_Merge(t *testing.T) {
+	a := catalog.Requires{Libraries: []string{"libfuse.so.2"}, Commands: map[string]string{"git": "2.30"}}
+	b := catalog.Requires{Libraries: []string{"libfuse.so.2", "libz.so.1"}, Commands: map[string]string{"git": "2.40", "curl": "7.0"}}
+	got := a.Merge(b)
+	if strings.Join(got.Libraries, ",") != "libfuse.so.2,libz.so.1" {
+		t.Errorf("libraries = %v", got.Libraries)
+	}
+	if got.Commands["git"] != "2.40" || got.Commands["curl"] != "7.0" {
+		t.Errorf("commands = %v", got.Commands)
+	}
+	if a.Commands["git"] != "2.30" {
+		t.Error("Merge modified its receiver")
+	}
+}
+
+func TestGitHubHostAndAPI(t *testing.T) {
+	for _, c := range []struct {
+		p         catalog.Program
+		host, api string
+	}{
+		{catalog.Program{}, "github.com", ""},
+		{catalog.Program{Host: "github.example.com"}, "github.example.com", "https://github.example.com/api/v3"},
+		{catalog.Program{APIBase: "https://api.example.com/gh"}, "api.example.com", "https://api.example.com/gh"},
+		{catalog.Program{Host: "github.example.com", APIBase: "https://api.example.com/gh"}, "github.example.com", "https://api.example.com/gh"},
+	} {
+		if host, api := c.p.GitHubHost(), c.p.GitHubAPI(); host != c.host || api != c.api {
+			t.Errorf("%+v: host %q, api %q; want %q, %q", c.p, host, api, c.host, c.api)
+		}
+	}
+}
+
+func TestLoadDotfiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", filepath.Join(dir, "home"))
+	t.Setenv("TMUX_THEME", "dark")
+	path := filepath.Join(dir, "catalog.toml")
+	os.WriteFile(path, []byte(`
+[dotfiles.tmux]
+source = "dotfiles/tmux-${TMUX_THEME}.conf"
+target = "~/.tmux.conf"
+
+[dotfiles.git]
+source = "/etc/skel/.gitconfig"
+target = "${HOME}/.gitconfig"
+`), 0644)
+
+	dotfiles, err := catalog.LoadDotfiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dotfiles) != 2 || dotfiles[0].Name != "git" || dotfiles[1].Name != "tmux" {
+		t.Fatalf("dotfiles = %+v", dotfiles)
+	}
+	if got, want := dotfiles[1].Source, filepath.Join(dir, "dotfiles", "tmux-dark.conf"); got != want {
+		t.Errorf("source = %s, want %s", got, want)
+	}
+	if got, want := dotfiles[1].Target, filepath.Join(dir, "home", ".tmux.conf"); got != want {
+		t.Errorf("target = %s, want %s", got, want)
+	}
+
+	os.WriteFile(path, []byte(`
+[dotfiles.a]
+source = "a"
+target = "relative/a"
+
+[dotfiles.b]
+source = "b"
+target = "/tmp/x"
+
+[dotfiles.c]
+source = "c"
+target = "/tmp/x"
+`), 0644)
+	_, err = catalog.LoadDotfiles(path)
+	if err == nil || !strings.Contains(err.Error(), "[dotfiles.a]: target") || !strings.Contains(err.Error(), "[dotfiles.c]: target /tmp/x is also the target of [dotfiles.b]") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestLock_roundTrip(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "lock-*")
+	defer os.RemoveAll(dir)
+	path := catalog.LockPath(filepath.Join(dir, "catalog.toml"))
+	if filepath.Base(path) != "catalog.lock" {
+		t.Fatalf("LockPath = %s", path)
+	}
+
+	want := catalog.Lock{Programs: map[string]catalog.Locked{
+		"fzf":    {Tag: "v0.60.0", URL: "https://github.com/junegunn/fzf/releases/download/v0.60.0/fzf-0.60.0-linux_amd64.tar.gz", SHA256: "ab12"},
+		"neovim": {Tag: "nightly", URL: "https://github.com/neovim/neovim/releases/download/nightly/nvim-linux-x86_64.tar.gz"},
+	}}
+	if err := want.Save(path, 0644); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := catalog.LoadLock(path)
+	if err != nil {
+		t.Fatalf("LoadLock: %v", err)
+	}
+	if len(got.Programs) != 2 || got.Programs["fzf"] != want.Programs["fzf"] || got.Programs["neovim"] != want.Programs["neovim"] {
+		t.Errorf("LoadLock = %+v, want %+v", got, want)
+	}
+
+	os.WriteFile(path, []byte("[programs.fzf]\ntag = \"v0.60.0\"\n"), 0644)
+	if _, err := catalog.LoadLock(path); err == nil {
+		t.Error("expected an error for an entry without a url")
+	}
+}
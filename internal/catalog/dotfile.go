@@ -0,0 +1,79 @@
+package catalog
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Dotfile is a config file kept next to the catalog and copied into place by
+// `installer dotfiles`, from a [dotfiles.<name>] table.
+type Dotfile struct {
+	Name   string `toml:"-" json:"-" yaml:"-"`                // populated from the table key
+	Source string `toml:"source" json:"source" yaml:"source"` // relative to the catalog file's dir
+	Target string `toml:"target" json:"target" yaml:"target"` // where it goes, e.g. "~/.tmux.conf"
+}
+
+// LoadDotfiles returns the dotfiles of the catalog at path, sorted by name.
+// Sources are made absolute against the catalog's directory, and both fields
+// have ${VAR} and ~ expanded as program fields do; targets must then be
+// absolute.
+func LoadDotfiles(path string) ([]Dotfile, error) {
+	raw, err := decodeFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []string
+	var dotfiles []Dotfile
+	targets := map[string]string{}
+	names := make([]string, 0, len(raw.Dotfiles))
+	for name := range raw.Dotfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		d := raw.Dotfiles[name]
+		d.Name = name
+		var fieldErrs []string
+		for _, f := range []struct {
+			key string
+			s   *string
+		}{{"source", &d.Source}, {"target", &d.Target}} {
+			v, err := ExpandEnv(*f.s)
+			switch {
+			case err != nil:
+				fieldErrs = append(fieldErrs, fmt.Sprintf("%s: %v", f.key, err))
+			case v == "":
+				fieldErrs = append(fieldErrs, f.key+" is required")
+			}
+			*f.s = v
+		}
+		if d.Source != "" && !filepath.IsAbs(d.Source) {
+			d.Source = filepath.Join(dir, d.Source)
+		}
+		if d.Target != "" && !filepath.IsAbs(d.Target) {
+			fieldErrs = append(fieldErrs, fmt.Sprintf("target %q must be an absolute path or start with ~/", d.Target))
+		}
+		if len(fieldErrs) > 0 {
+			errs = append(errs, fmt.Sprintf("[dotfiles.%s]: %s", name, strings.Join(fieldErrs, ", ")))
+			continue
+		}
+		d.Target = filepath.Clean(d.Target)
+		if prev, ok := targets[d.Target]; ok {
+			errs = append(errs, fmt.Sprintf("[dotfiles.%s]: target %s is also the target of [dotfiles.%s]", name, d.Target, prev))
+			continue
+		}
+		targets[d.Target] = name
+		dotfiles = append(dotfiles, d)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("catalog validation errors:\n%s", strings.Join(errs, "\n"))
+	}
+	return dotfiles, nil
+}
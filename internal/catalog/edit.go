@@ -0,0 +1,82 @@
+package catalog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	tableHeader = regexp.MustCompile(`^\s*\[`)
+	keyLine     = regexp.MustCompile(`^(\s*([A-Za-z0-9_-]+)\s*=\s*)(.*)$`)
+)
+
+// Editable reports an error for catalogs the installer cannot write to: only
+// TOML catalogs are edited in place, JSON and YAML ones are usually
+// generated.
+func Editable(path string) error {
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".toml" && ext != "" {
+		return fmt.Errorf("%s is not a TOML catalog; edit its source instead", path)
+	}
+	return nil
+}
+
+// SetString rewrites the string field key of program name in the catalog file
+// at path. Only that line changes, so comments, alignment and ordering
+// elsewhere survive. The field is appended to the program's table if absent.
+func SetString(path, name, key, value string) error {
+	if err := Editable(path); err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(string(data), "\n")
+	headers := []string{"[programs." + name + "]", `[programs."` + name + `"]`}
+
+	start := -1
+	for i, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		if trimmed == headers[0] || trimmed == headers[1] {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return fmt.Errorf("program %q not found in %s", name, path)
+	}
+	end := len(lines)
+	for i := start + 1; i < len(lines); i++ {
+		if tableHeader.MatchString(lines[i]) {
+			end = i
+			break
+		}
+	}
+
+	quoted := strconv.Quote(value)
+	done := false
+	for i := start + 1; i < end; i++ {
+		if m := keyLine.FindStringSubmatch(lines[i]); m != nil && m[2] == key {
+			lines[i] = m[1] + quoted
+			done = true
+			break
+		}
+	}
+	if !done {
+		// Insert after the table's last non-blank line.
+		at := end
+		for at > start+1 && strings.TrimSpace(lines[at-1]) == "" {
+			at--
+		}
+		lines = append(lines[:at], append([]string{key + " = " + quoted}, lines[at:]...)...)
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")), info.Mode().Perm())
+}
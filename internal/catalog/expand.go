@@ -0,0 +1,79 @@
+package catalog
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envRef matches ${VAR} and ${VAR:-default}. Bare $VAR is left alone so
+// shell-like text in asset patterns is not mangled.
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// ExpandEnv replaces ${VAR} and ${VAR:-default} references in s with values
+// from the environment, and a leading ~/ with the home directory. A ${VAR}
+// without a default that is unset or empty is an error naming the variable.
+func ExpandEnv(s string) (string, error) {
+	var missing []string
+	s = envRef.ReplaceAllStringFunc(s, func(ref string) string {
+		m := envRef.FindStringSubmatch(ref)
+		if v := os.Getenv(m[1]); v != "" {
+			return v
+		}
+		if m[2] != "" {
+			return m[3]
+		}
+		missing = append(missing, m[1])
+		return ref
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	if rest, ok := strings.CutPrefix(s, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			s = home + "/" + rest
+		}
+	}
+	return s, nil
+}
+
+// expandEnv applies ExpandEnv to every string field of p, returning one
+// message per field that references an unset variable.
+func (p *Program) expandEnv() []string {
+	var errs []string
+	expand := func(field string, s *string) {
+		v, err := ExpandEnv(*s)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", field, err))
+			return
+		}
+		*s = v
+	}
+	expand("repo", &p.Repo)
+	expand("asset_pattern", &p.AssetPattern)
+	expand("termux_asset_pattern", &p.TermuxAssetPattern)
+	expand("asset_url", &p.AssetURL)
+	expand("version", &p.Version)
+	expand("brew", &p.Brew)
+	expand("nix", &p.Nix)
+	expand("host", &p.Host)
+	expand("api_base", &p.APIBase)
+	expand("download_cmd", &p.DownloadCmd)
+	expand("extract_cmd", &p.ExtractCmd)
+	expand("health_cmd", &p.HealthCmd)
+	expand("signature", &p.Signature)
+	expand("pubkey", &p.Pubkey)
+	expand("hold_until", &p.HoldUntil)
+	for i := range p.Packages {
+		expand("packages", &p.Packages[i])
+	}
+	for i := range p.Provides {
+		expand("provides", &p.Provides[i])
+	}
+	for i := range p.Bin {
+		expand("bin.src", &p.Bin[i].Src)
+		expand("bin.dst", &p.Bin[i].Dst)
+	}
+	return errs
+}
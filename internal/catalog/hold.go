@@ -0,0 +1,67 @@
+package catalog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dsaleh/david-dotfiles/internal/version"
+)
+
+// CheckHoldUntil validates a hold_until condition: a release version with an
+// optional ">=" (the default) or ">" in front, such as ">=1.4.2". An empty
+// condition is valid and means the hold is only lifted by hand.
+func CheckHoldUntil(cond string) error {
+	if cond == "" {
+		return nil
+	}
+	if _, _, ok := parseHoldUntil(cond); !ok {
+		return fmt.Errorf("hold_until %q must be a version such as \">=1.4.2\" or \">1.4\"", cond)
+	}
+	return nil
+}
+
+// HoldReleased reports whether a release of version v lifts p's hold:
+// it satisfies hold_until. Versions that are not plain numeric versions, such
+// as prereleases, never do.
+func (p Program) HoldReleased(v string) bool {
+	want, strict, ok := parseHoldUntil(p.HoldUntil)
+	if !ok {
+		return false
+	}
+	got, ok := version.Parse(v)
+	if !ok {
+		return false
+	}
+	c := version.Compare(got, want)
+	return c > 0 || (c == 0 && !strict)
+}
+
+// CompareVersions compares two versions such as "1.4.2" and "v1.5", treating
+// missing parts as 0: the result is -1, 0 or 1 as a is older than, the same
+// as or newer than b. ok is false unless both are plain numeric versions.
+func CompareVersions(a, b string) (c int, ok bool) {
+	x, ok := version.Parse(a)
+	if !ok {
+		return 0, false
+	}
+	y, ok := version.Parse(b)
+	if !ok {
+		return 0, false
+	}
+	return version.Compare(x, y), true
+}
+
+// parseHoldUntil splits a hold_until condition into its version and whether
+// the comparison is strict (">").
+func parseHoldUntil(cond string) ([]int, bool, bool) {
+	cond = strings.TrimSpace(cond)
+	strict := false
+	switch {
+	case strings.HasPrefix(cond, ">="):
+		cond = cond[2:]
+	case strings.HasPrefix(cond, ">"):
+		cond, strict = cond[1:], true
+	}
+	v, ok := version.Parse(strings.TrimSpace(cond))
+	return v, strict, ok
+}
@@ -0,0 +1,61 @@
+package catalog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// LockName is the file name of the lock kept beside the built-in catalog or
+// several loaded at once; see LockPath.
+const LockName = "catalog.lock"
+
+// Locked is the exact release asset a program was installed from.
+type Locked struct {
+	Tag    string `toml:"tag"`
+	URL    string `toml:"url"`              // download URL of the asset
+	SHA256 string `toml:"sha256,omitempty"` // hex; empty for rolling tags, which change by design
+}
+
+// Lock is a catalog.lock: the release each installed program of a catalog
+// was installed from, so that the same versions can be installed elsewhere.
+type Lock struct {
+	Programs map[string]Locked `toml:"programs"`
+}
+
+// LockPath returns the lock file of the catalog at path: path with its
+// extension replaced by .lock, e.g. catalog.toml → catalog.lock.
+func LockPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".lock"
+}
+
+// LoadLock parses the lock file at path.
+func LoadLock(path string) (Lock, error) {
+	var l Lock
+	if _, err := toml.DecodeFile(path, &l); err != nil {
+		return Lock{}, fmt.Errorf("parse lock: %w", err)
+	}
+	for name, e := range l.Programs {
+		if e.Tag == "" || e.URL == "" {
+			return Lock{}, fmt.Errorf("parse lock: [programs.%s] needs tag and url", name)
+		}
+	}
+	return l, nil
+}
+
+// Save writes l to path, programs sorted by name, creating it with perm
+// (system.FilePerm; this package cannot import system).
+func (l Lock) Save(path string, perm os.FileMode) error {
+	var buf bytes.Buffer
+	buf.WriteString("# Written by the installer after each run; `--locked` installs exactly these releases.\n\n")
+	enc := toml.NewEncoder(&buf)
+	enc.Indent = ""
+	if err := enc.Encode(l); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), perm)
+}
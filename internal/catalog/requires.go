@@ -0,0 +1,81 @@
+package catalog
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/dsaleh/david-dotfiles/internal/version"
+)
+
+// Requires is what a program needs from the system besides commands on PATH,
+// checked before anything is installed:
+//
+//	[programs.neovim.requires]
+//	libraries  = ["libfuse.so.2"]
+//	pkg_config = ["gtk+-3.0"]
+//	commands   = { git = "2.30" }
+//	kernel     = ["fuse", "userns"]
+type Requires struct {
+	Libraries []string          `toml:"libraries" json:"libraries,omitempty" yaml:"libraries"`    // shared libraries by soname, as listed by ldconfig -p
+	PkgConfig []string          `toml:"pkg_config" json:"pkg_config,omitempty" yaml:"pkg_config"` // pkg-config modules
+	Commands  map[string]string `toml:"commands" json:"commands,omitempty" yaml:"commands"`       // command → minimum version reported by its --version
+	Kernel    []string          `toml:"kernel" json:"kernel,omitempty" yaml:"kernel"`             // KernelFeatures the program uses
+}
+
+// KernelFeatures are the kernel features a program can require: "fuse"
+// (/dev/fuse is present) and "userns" (unprivileged user namespaces are
+// enabled).
+var KernelFeatures = []string{"fuse", "userns"}
+
+// Empty reports whether r requires nothing.
+func (r Requires) Empty() bool {
+	return len(r.Libraries) == 0 && len(r.PkgConfig) == 0 && len(r.Commands) == 0 && len(r.Kernel) == 0
+}
+
+// Merge returns the requirements of both r and o, keeping the higher minimum
+// version of a command both require.
+func (r Requires) Merge(o Requires) Requires {
+	add := func(dst []string, src []string) []string {
+		for _, s := range src {
+			if !slices.Contains(dst, s) {
+				dst = append(dst, s)
+			}
+		}
+		return dst
+	}
+	out := Requires{
+		Libraries: add(slices.Clone(r.Libraries), o.Libraries),
+		PkgConfig: add(slices.Clone(r.PkgConfig), o.PkgConfig),
+		Kernel:    add(slices.Clone(r.Kernel), o.Kernel),
+	}
+	for _, m := range []map[string]string{r.Commands, o.Commands} {
+		for cmd, min := range m {
+			if out.Commands == nil {
+				out.Commands = map[string]string{}
+			}
+			if c, ok := CompareVersions(min, out.Commands[cmd]); !ok || c > 0 {
+				out.Commands[cmd] = min
+			}
+		}
+	}
+	return out
+}
+
+// CheckRequires validates r: command versions are plain versions such as
+// "2.30" and kernel features are among KernelFeatures. It returns one
+// message per problem.
+func CheckRequires(r Requires) []string {
+	var errs []string
+	for _, cmd := range slices.Sorted(maps.Keys(r.Commands)) {
+		if _, ok := version.Parse(r.Commands[cmd]); cmd == "" || !ok {
+			errs = append(errs, fmt.Sprintf("requires.commands %q = %q must name a command and a version such as \"2.30\"", cmd, r.Commands[cmd]))
+		}
+	}
+	for _, f := range r.Kernel {
+		if !slices.Contains(KernelFeatures, f) {
+			errs = append(errs, fmt.Sprintf("requires.kernel %q must be one of %v", f, KernelFeatures))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,52 @@
+package catalog
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Signature formats, told apart by the extension of the signature file.
+const (
+	Minisign = "minisign" // .minisig
+	OpenPGP  = "openpgp"  // .asc or .sig, checked with gpg
+)
+
+// SignatureFormat returns the format of the signature file name, or "" for
+// one it does not know.
+func SignatureFormat(name string) string {
+	switch path.Ext(name) {
+	case ".minisig":
+		return Minisign
+	case ".asc", ".sig":
+		return OpenPGP
+	}
+	return ""
+}
+
+// CheckSignature validates a signature and pubkey. The signature is the name
+// of the release asset holding the signature of the downloaded asset, with
+// {asset}, {version} and {tag} filled in, e.g. "{asset}.minisig", or a full
+// URL template. The pubkey is a minisign public key for .minisig files and
+// an ASCII-armored OpenPGP key, or the path of one, for .asc and .sig files.
+func CheckSignature(signature, pubkey string) error {
+	if signature == "" && pubkey == "" {
+		return nil
+	}
+	if signature == "" || pubkey == "" {
+		return fmt.Errorf("signature and pubkey must be set together")
+	}
+	switch SignatureFormat(signature) {
+	case Minisign:
+		lines := strings.Split(strings.TrimSpace(pubkey), "\n")
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[len(lines)-1]))
+		if err != nil || len(b) != 42 || string(b[:2]) != "Ed" {
+			return fmt.Errorf("pubkey must be a minisign public key (RW...) for a .minisig signature")
+		}
+	case OpenPGP:
+	default:
+		return fmt.Errorf("signature %q must end in .minisig, .asc or .sig", signature)
+	}
+	return nil
+}
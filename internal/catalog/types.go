@@ -1,21 +1,144 @@
 package catalog
 
+import (
+	"net/url"
+	"slices"
+)
+
 // Bin represents a single binary to symlink from the extracted archive.
 type Bin struct {
-	Src string `toml:"src"`
-	Dst string `toml:"dst"`
+	Src string `toml:"src" json:"src" yaml:"src"`
+	Dst string `toml:"dst" json:"dst" yaml:"dst"`
 }
 
 // Program is a single installable entry from catalog.toml.
 type Program struct {
-	Name         string   // populated from the TOML table key
-	Repo         string   `toml:"repo"`
-	AssetPattern string   `toml:"asset_pattern"`
-	Packages     []string `toml:"packages"`
-	Bin          []Bin    `toml:"bin"`
+	Name         string   `toml:"-" json:"-" yaml:"-"` // populated from the table key
+	Repo         string   `toml:"repo" json:"repo" yaml:"repo"`
+	AssetPattern string   `toml:"asset_pattern" json:"asset_pattern" yaml:"asset_pattern"`
+	Packages     []string `toml:"packages" json:"packages" yaml:"packages"`
+	Bin          []Bin    `toml:"bin" json:"bin" yaml:"bin"`
+	Version      string   `toml:"version" json:"version" yaml:"version"`       // optional release tag to install instead of the latest
+	Priority     int      `toml:"priority" json:"priority" yaml:"priority"`    // higher values are queued for install first
+	LinkMode     string   `toml:"link_mode" json:"link_mode" yaml:"link_mode"` // symlink, relative, hardlink or copy; overrides the global setting
+	Brew         string   `toml:"brew" json:"brew" yaml:"brew"`                // Homebrew formula for exports; defaults to Name
+	Nix          string   `toml:"nix" json:"nix" yaml:"nix"`                   // nixpkgs attribute for exports; defaults to Name
+	UseTags      bool     `toml:"use_tags" json:"use_tags" yaml:"use_tags"`    // resolve the newest semver tag instead of the latest release
+	AssetURL     string   `toml:"asset_url" json:"asset_url" yaml:"asset_url"` // download URL template; {version}, {tag} and {asset} are filled in
+	Rolling      bool     `toml:"rolling" json:"rolling" yaml:"rolling"`       // the tag is rebuilt in place (e.g. "nightly"); update by date, not version
+	Provides     []string `toml:"provides" json:"provides" yaml:"provides"`    // further tools the entry installs, e.g. the tools of a suite
+
+	// Host is the GitHub Enterprise Server the repo lives on, e.g.
+	// "github.example.com"; empty means github.com. APIBase is its API root
+	// when that is not https://<host>/api/v3. Both override the global
+	// settings; see GitHubHost and GitHubAPI.
+	Host    string `toml:"host" json:"host" yaml:"host"`
+	APIBase string `toml:"api_base" json:"api_base" yaml:"api_base"`
+
+	// DownloadCmd fetches the asset with an external tool instead of the
+	// built-in HTTP client, e.g. "aria2c {url} -d {dir} -o {file}". It
+	// overrides the global setting; see CheckDownloadCmd.
+	DownloadCmd string `toml:"download_cmd" json:"download_cmd" yaml:"download_cmd"`
+
+	// ExtractCmd unpacks the asset with an external command instead of the
+	// built-in extractor, for formats it does not know such as
+	// self-extracting installers. See CheckExtractCmd.
+	ExtractCmd string `toml:"extract_cmd" json:"extract_cmd" yaml:"extract_cmd"`
+
+	// Signature names the release asset holding a signature of the asset,
+	// e.g. "{asset}.minisig", checked with Pubkey before extraction. See
+	// CheckSignature.
+	Signature string `toml:"signature" json:"signature" yaml:"signature"`
+	Pubkey    string `toml:"pubkey" json:"pubkey" yaml:"pubkey"`
+
+	// HealthCmd checks that the installed program works, e.g. "fzf
+	// --version" or "nvim --headless +q". It runs after upgrades and with
+	// `installer health`; a non-zero exit is a failure.
+	HealthCmd string `toml:"health_cmd" json:"health_cmd" yaml:"health_cmd"`
+
+	// Windows marks an entry whose asset is a Windows build, such as
+	// win32yank.exe, used from WSL: its bins are copied into the configured
+	// windows_bin_dir, and it is skipped on other systems.
+	Windows bool `toml:"windows" json:"windows" yaml:"windows"`
+
+	// Unsupported lists the platforms (see Platforms) the program is skipped
+	// on, e.g. ["termux"] for a GUI tool.
+	Unsupported []string `toml:"unsupported" json:"unsupported" yaml:"unsupported"`
+
+	// TermuxAssetPattern replaces AssetPattern on Termux. Without it the
+	// Android or arm64 build next to the asset_pattern match is used when
+	// the release has one.
+	TermuxAssetPattern string `toml:"termux_asset_pattern" json:"termux_asset_pattern" yaml:"termux_asset_pattern"`
+
+	// HoldUntil lifts a hold — a version set here or a pin in state — once
+	// the latest release satisfies it, e.g. ">=1.4.2" for the release that
+	// fixes the bug the program was held back for. See HoldReleased.
+	HoldUntil string `toml:"hold_until" json:"hold_until" yaml:"hold_until"`
+
+	// Requires lists what the program needs from the system beyond the
+	// commands in Packages; see CheckRequires.
+	Requires Requires `toml:"requires" json:"requires" yaml:"requires"`
+
+	// Untrusted is set by callers when the program's catalog is not trusted
+	// to run commands: its download_cmd is ignored and its extract_cmd
+	// fails. See package trust.
+	Untrusted bool `toml:"-" json:"-" yaml:"-"`
+
+	// Source is the catalog file the program came from when several are
+	// loaded with LoadAll; "" otherwise.
+	Source string `toml:"-" json:"-" yaml:"-"`
+}
+
+// Tools returns the names the program is known by: its own, then Provides.
+func (p Program) Tools() []string {
+	tools := []string{p.Name}
+	for _, t := range p.Provides {
+		if !slices.Contains(tools, t) {
+			tools = append(tools, t)
+		}
+	}
+	return tools
+}
+
+// Platforms are the environments a program can be Unsupported on, as
+// reported by system.Platform.
+var Platforms = []string{"termux", "wsl"}
+
+// DefaultHost is the host of repos without a host.
+const DefaultHost = "github.com"
+
+// GitHubHost returns the host p's repo and release assets are on: its host,
+// else that of its api_base, else github.com.
+func (p Program) GitHubHost() string {
+	if p.Host != "" {
+		return p.Host
+	}
+	if u, err := url.Parse(p.APIBase); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return DefaultHost
+}
+
+// RepoURL returns the web page of p's repo, e.g. https://github.com/junegunn/fzf.
+func (p Program) RepoURL() string {
+	return "https://" + p.GitHubHost() + "/" + p.Repo
+}
+
+// GitHubAPI returns the API root for p's repo: its api_base, else
+// https://<host>/api/v3 for a GitHub Enterprise Server host. It is "" for
+// github.com, which github.NewClient takes to mean api.github.com.
+func (p Program) GitHubAPI() string {
+	switch {
+	case p.APIBase != "":
+		return p.APIBase
+	case p.Host != "" && p.Host != DefaultHost:
+		return "https://" + p.Host + "/api/v3"
+	}
+	return ""
 }
 
-// Catalog is the parsed catalog.toml.
+// Catalog is the parsed catalog.toml (or catalog.json or catalog.yaml).
 type Catalog struct {
-	Programs map[string]Program `toml:"programs"`
+	Programs map[string]Program `toml:"programs" json:"programs" yaml:"programs"`
+	Dotfiles map[string]Dotfile `toml:"dotfiles" json:"dotfiles" yaml:"dotfiles"`
 }
@@ -0,0 +1,168 @@
+// Package check runs update checks for the installed programs and keeps the
+// latest result in the data dir, so status lines and the metrics endpoint of
+// `installer daemon` can report it without calling GitHub themselves.
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// Path returns the default location of the latest check result.
+func Path() string {
+	return filepath.Join(system.DataPath(), "check.json")
+}
+
+// Result is the outcome of one update check.
+type Result struct {
+	Time     time.Time         `json:"time"`
+	Outdated []string          `json:"outdated"`           // installed programs with a newer release
+	Released []string          `json:"released,omitempty"` // held programs whose hold_until is now satisfied; also in Outdated
+	Broken   []string          `json:"broken,omitempty"`   // bin entries whose link no longer resolves
+	Failed   map[string]string `json:"failed,omitempty"`   // program → why its release could not be resolved
+}
+
+// Run checks which of the installed programs would be upgraded by a run with
+// opts, and which of their links in the bin dir are broken. Programs that are
+// not installed are ignored.
+func Run(ctx context.Context, programs []catalog.Program, opts installer.Options) Result {
+	res := Result{Time: time.Now(), Outdated: []string{}, Broken: Broken(opts.State, system.BinPath())}
+	byName := make(map[string]catalog.Program, len(programs))
+	for _, p := range programs {
+		byName[p.Name] = p
+	}
+	for _, c := range installer.Plan(ctx, programs, opts) {
+		if c.From == "" {
+			continue
+		}
+		switch c.Kind {
+		case installer.ChangeUpgrade:
+			res.Outdated = append(res.Outdated, c.Program)
+			if held(byName[c.Program], opts.State) {
+				res.Released = append(res.Released, c.Program)
+			}
+		case installer.ChangeUnknown:
+			if res.Failed == nil {
+				res.Failed = map[string]string{}
+			}
+			res.Failed[c.Program] = c.Err.Error()
+		}
+	}
+	sort.Strings(res.Outdated)
+	sort.Strings(res.Released)
+	return res
+}
+
+// held reports whether p is held with a hold_until condition, so an upgrade
+// means the condition is met.
+func held(p catalog.Program, st *state.State) bool {
+	if p.HoldUntil == "" {
+		return false
+	}
+	if p.Version != "" {
+		return true
+	}
+	ps, ok := st.Get(p.Name)
+	return ok && ps.Pinned
+}
+
+// Broken returns the bins recorded in st whose entry in binDir is missing or
+// no longer resolves, e.g. because the install dir was deleted by hand.
+func Broken(st *state.State, binDir string) []string {
+	var out []string
+	for _, name := range st.Names() {
+		ps, _ := st.Get(name)
+		for _, b := range ps.Bins {
+			if _, err := os.Stat(filepath.Join(binDir, b)); err != nil {
+				out = append(out, b)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Save writes r to path, replacing the previous result.
+func Save(path string, r Result) error {
+	if err := os.MkdirAll(filepath.Dir(path), system.DirPerm()); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, system.FilePerm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load reads the result saved at path. A missing file yields a zero Result.
+func Load(path string) (Result, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Result{}, nil
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	var r Result
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Result{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Metrics serves the results recorded so far in the Prometheus text format.
+type Metrics struct {
+	mu       sync.Mutex
+	last     Result
+	failures int
+}
+
+// Record makes r the latest result and adds its failures to the total.
+func (m *Metrics) Record(r Result) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.last = r
+	m.failures += len(r.Failed)
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.Write(w)
+}
+
+// Write writes the metrics to w.
+func (m *Metrics) Write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ts int64
+	if !m.last.Time.IsZero() {
+		ts = m.last.Time.Unix()
+	}
+	fmt.Fprintf(w, "# HELP dotfiles_outdated_programs Installed programs with a newer release at the last check.\n")
+	fmt.Fprintf(w, "# TYPE dotfiles_outdated_programs gauge\n")
+	fmt.Fprintf(w, "dotfiles_outdated_programs %d\n", len(m.last.Outdated))
+	fmt.Fprintf(w, "# HELP dotfiles_last_check_timestamp_seconds Unix time of the last completed check.\n")
+	fmt.Fprintf(w, "# TYPE dotfiles_last_check_timestamp_seconds gauge\n")
+	fmt.Fprintf(w, "dotfiles_last_check_timestamp_seconds %d\n", ts)
+	fmt.Fprintf(w, "# HELP dotfiles_check_failures_total Programs whose release could not be resolved, summed over all checks.\n")
+	fmt.Fprintf(w, "# TYPE dotfiles_check_failures_total counter\n")
+	fmt.Fprintf(w, "dotfiles_check_failures_total %d\n", m.failures)
+}
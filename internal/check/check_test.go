@@ -0,0 +1,75 @@
+package check_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/check"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+)
+
+func TestSaveLoad(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "check-*")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sub", "check.json")
+
+	r, err := check.Load(path)
+	if err != nil || !r.Time.IsZero() {
+		t.Fatalf("missing file: got %+v, %v", r, err)
+	}
+
+	want := check.Result{
+		Time:     time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		Outdated: []string{"bat", "fzf"},
+		Failed:   map[string]string{"rg": "rate limited"},
+	}
+	if err := check.Save(path, want); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	got, err := check.Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !got.Time.Equal(want.Time) || len(got.Outdated) != 2 || got.Failed["rg"] != "rate limited" {
+		t.Errorf("round trip: got %+v", got)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	var m check.Metrics
+	m.Record(check.Result{Time: time.Unix(1772366400, 0), Outdated: []string{"fzf"}, Failed: map[string]string{"rg": "x"}})
+	m.Record(check.Result{Time: time.Unix(1772370000, 0), Outdated: []string{"bat", "fzf"}, Failed: map[string]string{"rg": "x"}})
+
+	var sb strings.Builder
+	m.Write(&sb)
+	for _, line := range []string{
+		"dotfiles_outdated_programs 2\n",
+		"dotfiles_last_check_timestamp_seconds 1772370000\n",
+		"dotfiles_check_failures_total 2\n",
+	} {
+		if !strings.Contains(sb.String(), line) {
+			t.Errorf("missing %q in:\n%s", line, sb.String())
+		}
+	}
+}
+
+func TestBroken(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "check-*")
+	defer os.RemoveAll(dir)
+	target := filepath.Join(dir, "fzf-bin")
+	os.WriteFile(target, nil, 0755)
+	os.Symlink(target, filepath.Join(dir, "fzf"))
+	os.Symlink(filepath.Join(dir, "gone"), filepath.Join(dir, "bat"))
+
+	st := state.New("")
+	st.Set("fzf", state.ProgramState{Bins: []string{"fzf"}})
+	st.Set("bat", state.ProgramState{Bins: []string{"bat", "batgrep"}})
+
+	got := check.Broken(st, dir)
+	if strings.Join(got, ",") != "bat,batgrep" {
+		t.Errorf("Broken = %v, want [bat batgrep]", got)
+	}
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/linker"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+	"github.com/dsaleh/david-dotfiles/internal/trust"
+)
+
+// Config holds the user's global settings. Per-program catalog fields of the
+// same name take precedence.
+type Config struct {
+	LinkMode linker.Mode `toml:"link_mode"` // how binaries are placed in the bin dir
+	IndexURL string      `toml:"index_url"` // community catalog index for search; "" means index.DefaultURL
+	Stats    bool        `toml:"stats"`     // keep a local history of run reports for `installer stats`
+
+	// Catalogs are loaded together, each as its own section of the selector,
+	// when no catalog is given on the command line.
+	Catalogs []string `toml:"catalogs"`
+
+	// TrustedKeys are the minisign public keys (from `installer inventory
+	// keygen` or `minisign -G`) whose signatures are accepted on state files read by inventory. When
+	// set, unsigned files are rejected.
+	TrustedKeys []string `toml:"trusted_keys"`
+
+	// LockLinks records a fingerprint of every symlink the installer makes
+	// and warns at the next run when one was changed by something else.
+	LockLinks bool `toml:"lock_links"`
+
+	// ProgressSocket serves the progress of TUI runs as JSON lines on a Unix
+	// socket (notify.Path) for status bars and other local tools.
+	ProgressSocket bool `toml:"progress_socket"`
+
+	// TrashDays is how long uninstalled programs and install dirs replaced by
+	// upgrades stay in the trash (trash.Path) for `installer restore`. 0
+	// means 14; a negative value deletes them straight away.
+	TrashDays int `toml:"trash_days"`
+
+	// DownloadCmd fetches release assets with an external tool instead of
+	// the built-in HTTP client; see catalog.CheckDownloadCmd. A program's
+	// download_cmd takes precedence.
+	DownloadCmd string `toml:"download_cmd"`
+
+	// CatalogTrust sets the trust level of catalogs by path: "trusted" runs
+	// their download_cmd and extract_cmd entries without asking,
+	// "untrusted" never runs them, and "ask" (the default for any catalog
+	// not listed) needs an approval first. See package trust.
+	CatalogTrust map[string]trust.Level `toml:"catalog_trust"`
+
+	// UserAgent replaces the User-Agent sent with every HTTP request, for
+	// proxies or mirrors that filter on it. Empty means
+	// transport.DefaultUserAgent.
+	UserAgent string `toml:"user_agent"`
+
+	// Host and APIBase point programs whose catalog entry sets neither at a
+	// GitHub Enterprise Server instead of github.com; see catalog.Program.
+	Host    string `toml:"host"`
+	APIBase string `toml:"api_base"`
+
+	// MaxDownloads and MaxExtractions limit how many programs download, and
+	// how many extract, at once. 0 means 3 downloads and one extraction per
+	// CPU, up to 4.
+	MaxDownloads   int `toml:"max_downloads"`
+	MaxExtractions int `toml:"max_extractions"`
+
+	// MaxFPS caps how often the TUI redraws. 0 means 60 locally and 20 in
+	// an SSH session, where every frame crosses the network.
+	MaxFPS int `toml:"max_fps"`
+
+	// Palette picks the TUI's state colours: "default", or "colorblind"
+	// for a scheme that does not lean on telling green from red.
+	Palette string `toml:"palette"`
+
+	// StopOnError is what the TUI does when a program fails: "continue"
+	// (the default) reports it with the rest at the end, "prompt" pauses
+	// the run on the first failure to retry, skip, edit or abort.
+	StopOnError string `toml:"stop_on_error"`
+
+	// WindowsBinDir is where programs marked windows in the catalog copy
+	// their binaries under WSL, e.g. "/mnt/c/Users/me/bin". Without it they
+	// are skipped.
+	WindowsBinDir string `toml:"windows_bin_dir"`
+
+	// Umask, an octal mode such as "077", replaces the process umask and is
+	// cleared from every mode the installer creates files and dirs with;
+	// see system.SetUmask. Empty keeps the umask of the shell.
+	Umask string `toml:"umask"`
+}
+
+// defaultTrashDays is the TrashDays of a config that does not set it.
+const defaultTrashDays = 14
+
+// Path returns the default config file location.
+func Path() string {
+	return filepath.Join(system.ConfigPath(), "config.toml")
+}
+
+// Load reads the config file at path. A missing file yields the defaults.
+func Load(path string) (Config, error) {
+	cfg := Config{LinkMode: linker.Symlink, TrashDays: defaultTrashDays}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		return Config{}, fmt.Errorf("parse config: %w", err)
+	}
+	mode, err := linker.ParseMode(string(cfg.LinkMode))
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.LinkMode = mode
+	if cfg.TrashDays == 0 {
+		cfg.TrashDays = defaultTrashDays
+	}
+	for i, c := range cfg.Catalogs {
+		if cfg.Catalogs[i], err = catalog.ExpandEnv(c); err != nil {
+			return Config{}, fmt.Errorf("%s: catalogs: %w", path, err)
+		}
+	}
+	if cfg.IndexURL, err = catalog.ExpandEnv(cfg.IndexURL); err != nil {
+		return Config{}, fmt.Errorf("%s: index_url: %w", path, err)
+	}
+	if cfg.DownloadCmd, err = catalog.ExpandEnv(cfg.DownloadCmd); err != nil {
+		return Config{}, fmt.Errorf("%s: download_cmd: %w", path, err)
+	}
+	if err := catalog.CheckDownloadCmd(cfg.DownloadCmd); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if cfg.WindowsBinDir, err = catalog.ExpandEnv(cfg.WindowsBinDir); err != nil {
+		return Config{}, fmt.Errorf("%s: windows_bin_dir: %w", path, err)
+	}
+	switch cfg.Palette {
+	case "", "default", "colorblind":
+	default:
+		return Config{}, fmt.Errorf("%s: unknown palette %q (want default or colorblind)", path, cfg.Palette)
+	}
+	switch cfg.StopOnError {
+	case "", "continue", "prompt":
+	default:
+		return Config{}, fmt.Errorf("%s: unknown stop_on_error %q (want continue or prompt)", path, cfg.StopOnError)
+	}
+	if cfg.Umask != "" {
+		if _, err := system.ParseUmask(cfg.Umask); err != nil {
+			return Config{}, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := catalog.CheckHost(cfg.Host, cfg.APIBase); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	trusts := make(map[string]trust.Level, len(cfg.CatalogTrust))
+	for c, level := range cfg.CatalogTrust {
+		if level, err = trust.ParseLevel(string(level)); err != nil {
+			return Config{}, fmt.Errorf("%s: catalog_trust: %w", path, err)
+		}
+		if c, err = catalog.ExpandEnv(c); err != nil {
+			return Config{}, fmt.Errorf("%s: catalog_trust: %w", path, err)
+		}
+		trusts[c] = level
+	}
+	cfg.CatalogTrust = trusts
+	return cfg, nil
+}
@@ -0,0 +1,138 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dsaleh/david-dotfiles/internal/config"
+	"github.com/dsaleh/david-dotfiles/internal/linker"
+	"github.com/dsaleh/david-dotfiles/internal/trust"
+)
+
+func TestLoad_missingFileUsesDefaults(t *testing.T) {
+	cfg, err := config.Load(filepath.Join(os.TempDir(), "does-not-exist", "config.toml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.LinkMode != linker.Symlink {
+		t.Errorf("expected default symlink mode, got %q", cfg.LinkMode)
+	}
+	if cfg.TrashDays != 14 {
+		t.Errorf("expected 14 trash days by default, got %d", cfg.TrashDays)
+	}
+}
+
+func TestLoad_linkMode(t *testing.T) {
+	f, _ := os.CreateTemp("", "config-*.toml")
+	f.WriteString(`link_mode = "copy"` + "\n")
+	f.Close()
+	defer os.Remove(f.Name())
+
+	cfg, err := config.Load(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.LinkMode != linker.Copy {
+		t.Errorf("expected copy, got %q", cfg.LinkMode)
+	}
+}
+
+func TestLoad_invalidLinkMode(t *testing.T) {
+	f, _ := os.CreateTemp("", "config-*.toml")
+	f.WriteString(`link_mode = "junction"` + "\n")
+	f.Close()
+	defer os.Remove(f.Name())
+
+	if _, err := config.Load(f.Name()); err == nil {
+		t.Fatal("expected error for invalid link_mode")
+	}
+}
+
+func TestLoad_catalogTrust(t *testing.T) {
+	f, _ := os.CreateTemp("", "config-*.toml")
+	f.WriteString("[catalog_trust]\n\"/c/personal.toml\" = \"trusted\"\n\"/c/shared.toml\" = \"\"\n")
+	f.Close()
+	defer os.Remove(f.Name())
+
+	cfg, err := config.Load(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.CatalogTrust["/c/personal.toml"] != trust.Trusted || cfg.CatalogTrust["/c/shared.toml"] != trust.Ask {
+		t.Errorf("catalog_trust = %v", cfg.CatalogTrust)
+	}
+
+	os.WriteFile(f.Name(), []byte("[catalog_trust]\n\"/c/x.toml\" = \"maybe\"\n"), 0644)
+	if _, err := config.Load(f.Name()); err == nil {
+		t.Error("expected error for an invalid trust level")
+	}
+}
+
+func TestLoad_host(t *testing.T) {
+	f, _ := os.CreateTemp("", "config-*.toml")
+	f.WriteString("host = \"github.example.com\"\n")
+	f.Close()
+	defer os.Remove(f.Name())
+
+	cfg, err := config.Load(f.Name())
+	if err != nil || cfg.Host != "github.example.com" {
+		t.Fatalf("Load = %+v, %v", cfg, err)
+	}
+
+	os.WriteFile(f.Name(), []byte("host = \"https://github.example.com\"\n"), 0644)
+	if _, err := config.Load(f.Name()); err == nil {
+		t.Error("expected error for a host with a scheme")
+	}
+}
+
+func TestLoad_umask(t *testing.T) {
+	f, _ := os.CreateTemp("", "config-*.toml")
+	f.WriteString("umask = \"077\"\n")
+	f.Close()
+	defer os.Remove(f.Name())
+
+	cfg, err := config.Load(f.Name())
+	if err != nil || cfg.Umask != "077" {
+		t.Fatalf("Load = %+v, %v", cfg, err)
+	}
+
+	os.WriteFile(f.Name(), []byte("umask = \"755x\"\n"), 0644)
+	if _, err := config.Load(f.Name()); err == nil {
+		t.Error("expected error for a malformed umask")
+	}
+}
+
+func TestLoad_palette(t *testing.T) {
+	f, _ := os.CreateTemp("", "config-*.toml")
+	f.WriteString("palette = \"colorblind\"\n")
+	f.Close()
+	defer os.Remove(f.Name())
+
+	cfg, err := config.Load(f.Name())
+	if err != nil || cfg.Palette != "colorblind" {
+		t.Fatalf("Load = %+v, %v", cfg, err)
+	}
+
+	os.WriteFile(f.Name(), []byte("palette = \"neon\"\n"), 0644)
+	if _, err := config.Load(f.Name()); err == nil {
+		t.Error("expected error for an unknown palette")
+	}
+}
+
+func TestLoad_stopOnError(t *testing.T) {
+	f, _ := os.CreateTemp("", "config-*.toml")
+	f.WriteString("stop_on_error = \"prompt\"\n")
+	f.Close()
+	defer os.Remove(f.Name())
+
+	cfg, err := config.Load(f.Name())
+	if err != nil || cfg.StopOnError != "prompt" {
+		t.Fatalf("Load = %+v, %v", cfg, err)
+	}
+
+	os.WriteFile(f.Name(), []byte("stop_on_error = \"panic\"\n"), 0644)
+	if _, err := config.Load(f.Name()); err == nil {
+		t.Error("expected error for an unknown stop_on_error")
+	}
+}
@@ -0,0 +1,225 @@
+package detect
+
+import (
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// osAliases and archAliases are the spellings release assets commonly use for
+// each GOOS / GOARCH.
+var (
+	osAliases = map[string][]string{
+		"linux":   {"linux"},
+		"darwin":  {"darwin", "macos", "apple", "osx"},
+		"windows": {"windows", "win64", "win32"},
+	}
+	archAliases = map[string][]string{
+		"amd64": {"amd64", "x86_64", "x64", "64bit"},
+		"arm64": {"arm64", "aarch64"},
+	}
+	// skipSuffixes marks assets that are never the program itself.
+	skipSuffixes = []string{
+		".sha256", ".sha256sum", ".sha512", ".md5", ".sig", ".asc", ".pem", ".sbom",
+		".deb", ".rpm", ".apk", ".msi", ".exe", ".dmg", ".pkg", ".txt", ".json",
+	}
+	archiveSuffixes = []string{".tar.gz", ".tgz", ".tar.xz", ".txz", ".tar.bz2", ".zip"}
+)
+
+// Asset picks the release asset best suited to this machine from names, the
+// asset list of a release. It reports false when nothing matches the OS and
+// architecture.
+func Asset(names []string) (string, bool) {
+	return AssetFor(names, runtime.GOOS, runtime.GOARCH)
+}
+
+// AssetFor is Asset for an explicit GOOS / GOARCH.
+func AssetFor(names []string, goos, goarch string) (string, bool) {
+	type candidate struct {
+		name  string
+		score int
+	}
+	var candidates []candidate
+	for _, name := range names {
+		lower := strings.ToLower(name)
+		if hasAnySuffix(lower, skipSuffixes) {
+			continue
+		}
+		if !containsAny(lower, osAliases[goos]) {
+			continue
+		}
+		score := 0
+		if !containsAny(lower, archAliases[goarch]) {
+			// macOS universal binaries run on both architectures, but a
+			// native build is smaller.
+			if goos != "darwin" || !strings.Contains(lower, "universal") {
+				continue
+			}
+			score--
+		}
+		if hasAnySuffix(lower, archiveSuffixes) {
+			score += 2 // archives usually carry completions and man pages too
+		}
+		if strings.Contains(lower, "musl") {
+			score++ // statically linked, runs on any distro
+		}
+		candidates = append(candidates, candidate{name, score})
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	return candidates[0].name, true
+}
+
+// Android returns the build of asset for Android on arm64 among names, for
+// Termux: the same name with its architecture mapped to aarch64/arm64 and its
+// OS to android, else to linux for arm64 (a static Linux build runs there
+// too). It reports false when names has neither, or asset is already one.
+func Android(asset string, names []string) (string, bool) {
+	arm := asset
+	for _, pair := range [][2]string{{"x86_64", "aarch64"}, {"amd64", "arm64"}, {"x64", "arm64"}} {
+		arm = strings.ReplaceAll(arm, pair[0], pair[1])
+	}
+	android := arm
+	for _, pair := range [][2]string{{"unknown-linux-musl", "linux-android"}, {"unknown-linux-gnu", "linux-android"}, {"linux", "android"}} {
+		if strings.Contains(android, pair[0]) {
+			android = strings.ReplaceAll(android, pair[0], pair[1])
+			break
+		}
+	}
+	for _, candidate := range []string{android, arm} {
+		if candidate != asset && slices.Contains(names, candidate) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// Pattern turns a concrete asset name into an asset_pattern by replacing the
+// release version with {version}. version is the tag without a leading "v".
+func Pattern(asset, version string) string {
+	if version == "" {
+		return asset
+	}
+	return strings.ReplaceAll(asset, version, "{version}")
+}
+
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnySuffix(s string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Closest returns the name in names most similar to want, ignoring checksums,
+// signatures and distro packages. It is used to suggest a fix when an
+// asset_pattern stops matching because upstream renamed its artifacts.
+// Names built for another OS than want, such as a Windows build next to the
+// Linux one, are never suggested. It reports false when no name is
+// reasonably close.
+func Closest(want string, names []string) (string, bool) {
+	best, bestDist := "", -1
+	wantOS := osOf(want)
+	for _, name := range names {
+		if hasAnySuffix(strings.ToLower(name), skipSuffixes) {
+			continue
+		}
+		if got := osOf(name); wantOS != "" && got != "" && got != wantOS {
+			continue
+		}
+		d := distance(normalize(want), normalize(name))
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	if bestDist < 0 || bestDist > len(want)/2 {
+		return "", false
+	}
+	return best, true
+}
+
+// SamePlatform reports whether the assets named a and b are built for the
+// same OS and architecture, as far as their names tell: they mention the
+// same ones, whatever the spelling.
+func SamePlatform(a, b string) bool {
+	return slices.Equal(platform(a), platform(b))
+}
+
+// platform returns the canonical OS and architecture names name mentions.
+func platform(name string) []string {
+	lower := strings.ToLower(name)
+	var out []string
+	for _, aliases := range []map[string][]string{osAliases, archAliases} {
+		for canonical, spellings := range aliases {
+			if containsAny(lower, spellings) {
+				out = append(out, canonical)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// osOf returns the canonical OS name mentions, or "" if none.
+func osOf(name string) string {
+	lower := strings.ToLower(name)
+	for canonical, spellings := range osAliases {
+		if containsAny(lower, spellings) {
+			return canonical
+		}
+	}
+	return ""
+}
+
+// normalize lowercases name and maps OS and architecture aliases to their Go
+// spelling, so x86_64 and amd64 builds compare as equal.
+func normalize(name string) string {
+	name = strings.ToLower(name)
+	for canonical, aliases := range archAliases {
+		for _, a := range aliases {
+			name = strings.ReplaceAll(name, a, canonical)
+		}
+	}
+	for canonical, aliases := range osAliases {
+		for _, a := range aliases {
+			name = strings.ReplaceAll(name, a, canonical)
+		}
+	}
+	return name
+}
+
+// distance is the Levenshtein edit distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,122 @@
+package detect_test
+
+import (
+	"testing"
+
+	"github.com/dsaleh/david-dotfiles/internal/detect"
+)
+
+var ripgrepAssets = []string{
+	"ripgrep-14.1.1-aarch64-apple-darwin.tar.gz",
+	"ripgrep-14.1.1-aarch64-apple-darwin.tar.gz.sha256",
+	"ripgrep-14.1.1-x86_64-unknown-linux-gnu.tar.gz",
+	"ripgrep-14.1.1-x86_64-unknown-linux-musl.tar.gz",
+	"ripgrep-14.1.1-x86_64-unknown-linux-musl.tar.gz.sha256",
+	"ripgrep_14.1.1-1_amd64.deb",
+	"ripgrep-14.1.1-x86_64-pc-windows-msvc.zip",
+}
+
+func TestAssetFor_linuxPrefersMusl(t *testing.T) {
+	got, ok := detect.AssetFor(ripgrepAssets, "linux", "amd64")
+	if !ok || got != "ripgrep-14.1.1-x86_64-unknown-linux-musl.tar.gz" {
+		t.Errorf("unexpected asset %q (ok=%v)", got, ok)
+	}
+}
+
+func TestAssetFor_darwinArm(t *testing.T) {
+	got, ok := detect.AssetFor(ripgrepAssets, "darwin", "arm64")
+	if !ok || got != "ripgrep-14.1.1-aarch64-apple-darwin.tar.gz" {
+		t.Errorf("unexpected asset %q (ok=%v)", got, ok)
+	}
+}
+
+func TestAssetFor_darwinUniversal(t *testing.T) {
+	assets := []string{"tool-1.0-linux-amd64.tar.gz", "tool-1.0-macos-universal.zip"}
+	got, ok := detect.AssetFor(assets, "darwin", "amd64")
+	if !ok || got != "tool-1.0-macos-universal.zip" {
+		t.Errorf("unexpected asset %q (ok=%v)", got, ok)
+	}
+	got, _ = detect.AssetFor(append(assets, "tool-1.0-macos-arm64.zip"), "darwin", "arm64")
+	if got != "tool-1.0-macos-arm64.zip" {
+		t.Errorf("expected the native build to win, got %q", got)
+	}
+}
+
+func TestAssetFor_noMatch(t *testing.T) {
+	if _, ok := detect.AssetFor([]string{"tool-windows-amd64.zip", "checksums.txt"}, "linux", "amd64"); ok {
+		t.Error("expected no match")
+	}
+}
+
+func TestPattern(t *testing.T) {
+	got := detect.Pattern("ripgrep-14.1.1-x86_64-unknown-linux-musl.tar.gz", "14.1.1")
+	if got != "ripgrep-{version}-x86_64-unknown-linux-musl.tar.gz" {
+		t.Errorf("unexpected pattern %q", got)
+	}
+}
+
+func TestClosest(t *testing.T) {
+	names := []string{
+		"kitty-0.36.0-x86_64.txz",
+		"kitty-0.36.0-x86_64.txz.sig",
+		"kitty-0.36.0-arm64.txz",
+		"kitty-0.36.0.dmg",
+	}
+	got, ok := detect.Closest("kitty-0.36.0-amd64.txz", names)
+	if !ok || got != "kitty-0.36.0-x86_64.txz" {
+		t.Errorf("unexpected match %q (ok=%v)", got, ok)
+	}
+	if _, ok := detect.Closest("something-completely-different.zip", names); ok {
+		t.Error("expected no match for an unrelated name")
+	}
+}
+
+func TestClosest_otherOS(t *testing.T) {
+	names := []string{
+		"tool-1.2.0-x86_64-pc-windows-msvc.zip",
+		"tool-1.2.0-x86_64-unknown-linux-gnu.tar.gz",
+	}
+	got, ok := detect.Closest("tool-1.2.0-x86_64-unknown-linux-musl.tar.gz", names)
+	if !ok || got != "tool-1.2.0-x86_64-unknown-linux-gnu.tar.gz" {
+		t.Errorf("unexpected match %q (ok=%v)", got, ok)
+	}
+	if got, ok := detect.Closest("tool-1.2.0-x86_64-unknown-linux-musl.zip", names[:1]); ok {
+		t.Errorf("suggested the Windows build %q for a Linux asset", got)
+	}
+}
+
+func TestAndroid(t *testing.T) {
+	names := []string{
+		"rg-14.1.0-x86_64-unknown-linux-musl.tar.gz",
+		"rg-14.1.0-aarch64-linux-android.tar.gz",
+		"fzf-0.60.0-linux_amd64.tar.gz",
+		"fzf-0.60.0-linux_arm64.tar.gz",
+		"fzf-0.60.0-android_arm64.tar.gz",
+		"bat-0.25.0-x86_64-unknown-linux-gnu.tar.gz",
+		"bat-0.25.0-aarch64-unknown-linux-gnu.tar.gz",
+	}
+	for asset, want := range map[string]string{
+		"rg-14.1.0-x86_64-unknown-linux-musl.tar.gz": "rg-14.1.0-aarch64-linux-android.tar.gz",
+		"fzf-0.60.0-linux_amd64.tar.gz":              "fzf-0.60.0-android_arm64.tar.gz",
+		"bat-0.25.0-x86_64-unknown-linux-gnu.tar.gz": "bat-0.25.0-aarch64-unknown-linux-gnu.tar.gz",
+		"fzf-0.60.0-android_arm64.tar.gz":            "",
+		"tool-1.0-x86_64-unknown-linux-musl.tar.gz":  "",
+	} {
+		got, ok := detect.Android(asset, names)
+		if got != want || ok != (want != "") {
+			t.Errorf("Android(%q) = %q, %v; want %q", asset, got, ok, want)
+		}
+	}
+}
+
+func TestSamePlatform(t *testing.T) {
+	if !detect.SamePlatform("kitty-0.36.0-amd64.txz", "kitty-0.36.0-x86_64.txz") {
+		t.Error("amd64 and x86_64 should be the same platform")
+	}
+	if detect.SamePlatform("tool-linux-amd64.tar.gz", "tool-linux-arm64.tar.gz") {
+		t.Error("amd64 and arm64 should differ")
+	}
+	if detect.SamePlatform("tool-linux-amd64.tar.gz", "tool.tar.gz") {
+		t.Error("a name without a platform should not match one with")
+	}
+}
@@ -0,0 +1,224 @@
+package dotfile
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Line is one line of a diff. Op is ' ' for a line both sides have, '-' for
+// one only the local file has and '+' for one only the catalog's has. Text
+// keeps its trailing newline, if any.
+type Line struct {
+	Op   byte
+	Text string
+}
+
+// maxEdits caps the edit distance Diff searches: the trace it keeps grows
+// with its square. Files further apart are shown as replaced whole.
+const maxEdits = 2000
+
+// Diff returns a shortest line diff turning local into incoming, using
+// Myers' algorithm. Past maxEdits changes it gives up and returns every local
+// line removed and every incoming one added.
+func Diff(local, incoming []byte) []Line {
+	a, b := splitLines(local), splitLines(incoming)
+	n, m := len(a), len(b)
+	off := n + m
+	v := make([]int, 2*off+2)
+	// trace[d] holds the diagonals -d..d of v before step d, all that
+	// backtrack reads from it.
+	var trace [][]int
+	for d := 0; d <= min(off, maxEdits); d++ {
+		trace = append(trace, slices.Clone(v[off-d:off+d+1]))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+	out := make([]Line, 0, n+m)
+	for _, l := range a {
+		out = append(out, Line{'-', l})
+	}
+	for _, l := range b {
+		out = append(out, Line{'+', l})
+	}
+	return out
+}
+
+// backtrack walks the furthest-reaching paths recorded in trace back from
+// the end of both files, collecting the edits in order.
+func backtrack(trace [][]int, a, b []string) []Line {
+	var out []Line
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		v := func(k int) int { return trace[d][k+d] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v(k-1) < v(k+1)) {
+			prevK = k + 1
+		}
+		prevX := v(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			out = append(out, Line{' ', a[x-1]})
+			x, y = x-1, y-1
+		}
+		if x == prevX {
+			out = append(out, Line{'+', b[y-1]})
+			y--
+		} else {
+			out = append(out, Line{'-', a[x-1]})
+			x--
+		}
+	}
+	for x > 0 {
+		out = append(out, Line{' ', a[x-1]})
+		x--
+	}
+	slices.Reverse(out)
+	return out
+}
+
+// splitLines splits data after each newline; a last line without one is
+// kept, so a missing final newline shows up as a change.
+func splitLines(data []byte) []string {
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Hunk is a run of changes with the unchanged lines around them, as in
+// `diff -u`. Starts are 1-based line numbers.
+type Hunk struct {
+	LocalStart, LocalLines       int
+	IncomingStart, IncomingLines int
+	Lines                        []Line
+}
+
+// Header returns the hunk's @@ line.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.LocalStart, h.LocalLines, h.IncomingStart, h.IncomingLines)
+}
+
+// Hunks groups diff into hunks with up to context unchanged lines before and
+// after each change. Changes closer than twice that share a hunk.
+func Hunks(diff []Line, context int) []Hunk {
+	var hunks []Hunk
+	i := 0
+	for i < len(diff) {
+		if diff[i].Op == ' ' {
+			i++
+			continue
+		}
+		// Extend over changes and the short unchanged runs between them.
+		end := i
+		for j := i; j < len(diff); j++ {
+			if diff[j].Op != ' ' {
+				end = j + 1
+				continue
+			}
+			if j-end >= 2*context {
+				break
+			}
+		}
+		start := max(i-context, 0)
+		end = min(end+context, len(diff))
+
+		var h Hunk
+		h.LocalStart, h.IncomingStart = 1, 1
+		for _, l := range diff[:start] {
+			if l.Op != '+' {
+				h.LocalStart++
+			}
+			if l.Op != '-' {
+				h.IncomingStart++
+			}
+		}
+		h.Lines = diff[start:end]
+		for _, l := range h.Lines {
+			if l.Op != '+' {
+				h.LocalLines++
+			}
+			if l.Op != '-' {
+				h.IncomingLines++
+			}
+		}
+		hunks = append(hunks, h)
+		i = end
+	}
+	return hunks
+}
+
+// Conflict markers written by Merge.
+const (
+	markerLocal    = "<<<<<<< local\n"
+	markerSplit    = "=======\n"
+	markerIncoming = ">>>>>>> catalog\n"
+)
+
+// Merge combines local and incoming into one file: lines both have are kept,
+// and each place they differ becomes a conflict block with the local lines
+// first, in git's marker format, for the user to resolve in an editor.
+func Merge(local, incoming []byte) []byte {
+	var buf bytes.Buffer
+	diff := Diff(local, incoming)
+	for i := 0; i < len(diff); {
+		if diff[i].Op == ' ' {
+			buf.WriteString(diff[i].Text)
+			i++
+			continue
+		}
+		var ours, theirs []string
+		for ; i < len(diff) && diff[i].Op != ' '; i++ {
+			if diff[i].Op == '-' {
+				ours = append(ours, diff[i].Text)
+			} else {
+				theirs = append(theirs, diff[i].Text)
+			}
+		}
+		buf.WriteString(markerLocal)
+		writeLines(&buf, ours)
+		buf.WriteString(markerSplit)
+		writeLines(&buf, theirs)
+		buf.WriteString(markerIncoming)
+	}
+	return buf.Bytes()
+}
+
+// writeLines writes lines, ending the last with a newline so a marker
+// after it starts a line of its own.
+func writeLines(buf *bytes.Buffer, lines []string) {
+	for _, l := range lines {
+		buf.WriteString(l)
+	}
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		buf.WriteByte('\n')
+	}
+}
+
+// HasConflicts reports whether data still has a conflict block from Merge.
+func HasConflicts(data []byte) bool {
+	for _, l := range splitLines(data) {
+		if l == markerLocal || l == markerIncoming {
+			return true
+		}
+	}
+	return false
+}
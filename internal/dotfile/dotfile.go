@@ -0,0 +1,77 @@
+// Package dotfile puts the dotfiles a catalog lists in place. A target that
+// already exists with other contents is not overwritten blindly: Diff and
+// Merge give the user what they need to accept the catalog's version, keep
+// their own or merge the two.
+package dotfile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+	"github.com/dsaleh/david-dotfiles/internal/trash"
+)
+
+// Status is how a dotfile's target compares with its source.
+type Status int
+
+const (
+	Missing  Status = iota // the target does not exist yet
+	Same                   // the target already has the source's contents
+	Modified               // the target exists with other contents
+)
+
+// File is a dotfile with the contents of both sides.
+type File struct {
+	catalog.Dotfile
+	Status   Status
+	Local    []byte // the target's contents; nil when Missing
+	Incoming []byte // the source's contents
+}
+
+// Check reads d's source and target and compares them.
+func Check(d catalog.Dotfile) (File, error) {
+	f := File{Dotfile: d}
+	var err error
+	if f.Incoming, err = os.ReadFile(d.Source); err != nil {
+		return f, err
+	}
+	f.Local, err = os.ReadFile(d.Target)
+	switch {
+	case os.IsNotExist(err):
+		f.Status = Missing
+	case err != nil:
+		return f, err
+	case bytes.Equal(f.Local, f.Incoming):
+		f.Status = Same
+	default:
+		f.Status = Modified
+	}
+	return f, nil
+}
+
+// Accept replaces the target with the source's contents.
+func Accept(f File, bin *trash.Batch) error {
+	return Write(f, f.Incoming, bin)
+}
+
+// Write puts data at f's target with the source's permissions, creating the
+// parent dirs. What is at the target is moved into bin first, when bin is
+// non-nil, so `installer restore` can bring it back.
+func Write(f File, data []byte, bin *trash.Batch) error {
+	mode := system.FilePerm()
+	if info, err := os.Stat(f.Source); err == nil {
+		mode = system.Perm(info.Mode().Perm())
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Target), system.DirPerm()); err != nil {
+		return err
+	}
+	if bin != nil {
+		if err := bin.Move(f.Target); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(f.Target, data, mode)
+}
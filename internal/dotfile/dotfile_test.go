@@ -0,0 +1,154 @@
+package dotfile_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/dotfile"
+	"github.com/dsaleh/david-dotfiles/internal/trash"
+)
+
+// render writes diff in `diff -u` style, one line per entry.
+func render(diff []dotfile.Line) string {
+	var sb strings.Builder
+	for _, l := range diff {
+		sb.WriteByte(l.Op)
+		sb.WriteString(strings.TrimSuffix(l.Text, "\n"))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func TestDiff(t *testing.T) {
+	cases := []struct {
+		local, incoming, want string
+	}{
+		{"", "", ""},
+		{"a\nb\n", "a\nb\n", " a\n b\n"},
+		{"", "a\n", "+a\n"},
+		{"a\nb\nc\n", "a\nc\n", " a\n-b\n c\n"},
+		{"a\nb\nc\n", "a\nB\nc\nd\n", " a\n-b\n+B\n c\n+d\n"},
+		{"a\nb", "a\nb\n", " a\n-b\n+b\n"}, // final newline added
+	}
+	for _, c := range cases {
+		if got := render(dotfile.Diff([]byte(c.local), []byte(c.incoming))); got != c.want {
+			t.Errorf("Diff(%q, %q) =\n%s\nwant\n%s", c.local, c.incoming, got, c.want)
+		}
+	}
+}
+
+func TestDiff_large(t *testing.T) {
+	var local, edited, rewritten strings.Builder
+	for i := range 10000 {
+		fmt.Fprintf(&local, "line %d\n", i)
+		fmt.Fprintf(&rewritten, "other %d\n", i)
+		if i == 5000 {
+			edited.WriteString("changed\n")
+		} else {
+			fmt.Fprintf(&edited, "line %d\n", i)
+		}
+	}
+	count := func(diff []dotfile.Line) map[byte]int {
+		ops := map[byte]int{}
+		for _, l := range diff {
+			ops[l.Op]++
+		}
+		return ops
+	}
+
+	ops := count(dotfile.Diff([]byte(local.String()), []byte(edited.String())))
+	if ops[' '] != 9999 || ops['-'] != 1 || ops['+'] != 1 {
+		t.Errorf("one changed line: got %v", ops)
+	}
+	// Fully rewritten: too far apart to search, so replaced whole.
+	ops = count(dotfile.Diff([]byte(local.String()), []byte(rewritten.String())))
+	if ops[' '] != 0 || ops['-'] != 10000 || ops['+'] != 10000 {
+		t.Errorf("rewritten file: got %v", ops)
+	}
+}
+
+func TestHunks(t *testing.T) {
+	var local, incoming strings.Builder
+	for i := 1; i <= 20; i++ {
+		line := strings.Repeat("x", i) + "\n"
+		local.WriteString(line)
+		switch i {
+		case 3:
+			incoming.WriteString("changed\n")
+		case 17:
+			// dropped
+		default:
+			incoming.WriteString(line)
+		}
+	}
+	hunks := dotfile.Hunks(dotfile.Diff([]byte(local.String()), []byte(incoming.String())), 3)
+	if len(hunks) != 2 {
+		t.Fatalf("got %d hunks, want 2: %+v", len(hunks), hunks)
+	}
+	if got := hunks[0].Header(); got != "@@ -1,6 +1,6 @@" {
+		t.Errorf("first hunk header = %s", got)
+	}
+	if got := hunks[1].Header(); got != "@@ -14,7 +14,6 @@" {
+		t.Errorf("second hunk header = %s", got)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	local := "set -g mouse on\nset -g prefix C-a\nbind r source-file ~/.tmux.conf"
+	incoming := "set -g mouse on\nset -g prefix C-b\n"
+	got := string(dotfile.Merge([]byte(local), []byte(incoming)))
+	want := "set -g mouse on\n" +
+		"<<<<<<< local\nset -g prefix C-a\nbind r source-file ~/.tmux.conf\n" +
+		"=======\nset -g prefix C-b\n" +
+		">>>>>>> catalog\n"
+	if got != want {
+		t.Errorf("Merge =\n%s\nwant\n%s", got, want)
+	}
+	if !dotfile.HasConflicts([]byte(got)) {
+		t.Error("HasConflicts = false on a merge with conflicts")
+	}
+	if dotfile.HasConflicts([]byte(incoming)) {
+		t.Error("HasConflicts = true without markers")
+	}
+}
+
+func TestCheckAndAccept(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "repo", "gitconfig")
+	os.MkdirAll(filepath.Dir(src), 0755)
+	os.WriteFile(src, []byte("[user]\n\tname = me\n"), 0600)
+	d := catalog.Dotfile{Name: "git", Source: src, Target: filepath.Join(dir, "home", ".gitconfig")}
+
+	f, err := dotfile.Check(d)
+	if err != nil || f.Status != dotfile.Missing {
+		t.Fatalf("Check = %v, %v; want Missing", f.Status, err)
+	}
+	if err := dotfile.Accept(f, nil); err != nil {
+		t.Fatal(err)
+	}
+	if f, _ = dotfile.Check(d); f.Status != dotfile.Same {
+		t.Fatalf("after Accept: status %v, want Same", f.Status)
+	}
+	if info, _ := os.Stat(d.Target); info.Mode().Perm() != 0600 {
+		t.Errorf("target mode = %v, want the source's 0600", info.Mode().Perm())
+	}
+
+	os.WriteFile(d.Target, []byte("[user]\n\tname = local\n"), 0600)
+	if f, _ = dotfile.Check(d); f.Status != dotfile.Modified {
+		t.Fatalf("after a local edit: status %v, want Modified", f.Status)
+	}
+	bin := trash.Open(filepath.Join(dir, "trash"), "dotfiles", "dotfiles", nil)
+	if err := dotfile.Accept(f, bin); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(d.Target); string(data) != string(f.Incoming) {
+		t.Errorf("target = %q after Accept", data)
+	}
+	if len(bin.Items) != 1 || bin.Items[0] != d.Target {
+		t.Errorf("trashed %v, want the local target", bin.Items)
+	}
+}
@@ -0,0 +1,99 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+)
+
+// Script writes a POSIX sh script that reproduces the resolved installs:
+// each program is downloaded from its pinned URL, extracted into
+// $HOME/.local/share/<name> and its catalog bins are linked into
+// $HOME/.local/bin. Programs that failed to resolve are reported as comments.
+func Script(w io.Writer, res []installer.Resolution) error {
+	fmt.Fprintln(w, "#!/bin/sh")
+	fmt.Fprintln(w, "# Generated by david-dotfiles from catalog.toml — re-export instead of editing.")
+	fmt.Fprintln(w, "set -eu")
+	fmt.Fprintln(w, `mkdir -p "$HOME/.local/share" "$HOME/.local/bin"`)
+	for _, r := range res {
+		fmt.Fprintln(w)
+		if r.Err != nil {
+			fmt.Fprintf(w, "# %s: skipped, %v\n", r.Program.Name, r.Err)
+			continue
+		}
+		fmt.Fprintf(w, "# %s %s\n", r.Program.Name, r.Version)
+		for _, line := range commands(r) {
+			fmt.Fprintln(w, line)
+		}
+	}
+	return nil
+}
+
+// Dockerfile writes one RUN instruction per resolved program, so each becomes
+// its own cacheable image layer. Programs that failed to resolve are reported
+// as comments.
+func Dockerfile(w io.Writer, res []installer.Resolution) error {
+	fmt.Fprintln(w, "# Generated by david-dotfiles from catalog.toml — re-export instead of editing.")
+	fmt.Fprintln(w, "# Requires curl, tar and (for .zip assets) unzip in the base image.")
+	fmt.Fprintln(w, "# Add the bin dir to PATH for the image user, e.g. ENV PATH=/root/.local/bin:$PATH")
+	fmt.Fprintln(w, `RUN mkdir -p "$HOME/.local/share" "$HOME/.local/bin"`)
+	for _, r := range res {
+		fmt.Fprintln(w)
+		if r.Err != nil {
+			fmt.Fprintf(w, "# %s: skipped, %v\n", r.Program.Name, r.Err)
+			continue
+		}
+		fmt.Fprintf(w, "# %s %s\n", r.Program.Name, r.Version)
+		fmt.Fprintf(w, "RUN set -eu; \\\n    %s\n", strings.Join(commands(r), "; \\\n    "))
+	}
+	return nil
+}
+
+// commands returns the shell commands installing one resolved program,
+// mirroring what the installer does: extract into the share dir, write
+// .version, link the catalog bins.
+func commands(r installer.Resolution) []string {
+	// The asset name comes from the upstream tag, so it is quoted like
+	// everything else that does not come from this file.
+	dir := `"$HOME/.local/share/"` + shellQuote(r.Program.Name)
+	tmp := "/tmp/" + shellQuote(r.AssetName)
+	cmds := []string{
+		"rm -rf " + dir,
+		"mkdir -p " + dir,
+		"curl -fsSL -o " + tmp + " " + shellQuote(r.URL),
+		extractCommand(r.AssetName, tmp, dir),
+		"rm -f " + tmp,
+		"echo " + shellQuote(r.Version) + " > " + dir + "/.version",
+	}
+	if len(r.Program.Bin) == 0 {
+		cmds = append(cmds, ": no bin list in the catalog, nothing linked")
+	}
+	for _, b := range r.Program.Bin {
+		src := strings.ReplaceAll(b.Src, "{version}", r.Version)
+		cmds = append(cmds, "ln -sfn "+dir+"/"+shellQuote(src)+` "$HOME/.local/bin/"`+shellQuote(b.Dst))
+	}
+	return cmds
+}
+
+// extractCommand mirrors extractor.Extract's dispatch on the file extension.
+func extractCommand(name, src, dir string) string {
+	switch {
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		return "tar -xzf " + src + " -C " + dir
+	case strings.HasSuffix(name, ".tar.xz") || strings.HasSuffix(name, ".txz"):
+		return "tar -xJf " + src + " -C " + dir
+	case strings.HasSuffix(name, ".tar.bz2"):
+		return "tar -xjf " + src + " -C " + dir
+	case strings.HasSuffix(name, ".zip"):
+		return "unzip -q " + src + " -d " + dir
+	default:
+		return "cp " + src + " " + dir + "/" + shellQuote(name) + " && chmod +x " + dir + "/" + shellQuote(name)
+	}
+}
+
+// shellQuote wraps s in single quotes for POSIX sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,99 @@
+package export_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/export"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+)
+
+func resolutions() []installer.Resolution {
+	return []installer.Resolution{
+		{
+			Program: catalog.Program{
+				Name: "delta",
+				Bin:  []catalog.Bin{{Src: "delta-{version}-x86_64/delta", Dst: "delta"}},
+			},
+			Tag:       "0.18.2",
+			Version:   "0.18.2",
+			AssetName: "delta-0.18.2-x86_64.tar.gz",
+			URL:       "https://github.com/dandavison/delta/releases/download/0.18.2/delta-0.18.2-x86_64.tar.gz",
+		},
+		{Program: catalog.Program{Name: "broken"}, Err: fmt.Errorf("repo not found")},
+	}
+}
+
+func TestScript(t *testing.T) {
+	var buf bytes.Buffer
+	if err := export.Script(&buf, resolutions()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"curl -fsSL -o /tmp/'delta-0.18.2-x86_64.tar.gz' 'https://github.com/dandavison/delta/releases/download/0.18.2/delta-0.18.2-x86_64.tar.gz'",
+		"tar -xzf /tmp/'delta-0.18.2-x86_64.tar.gz' -C \"$HOME/.local/share/\"'delta'",
+		"ln -sfn \"$HOME/.local/share/\"'delta'/'delta-0.18.2-x86_64/delta' \"$HOME/.local/bin/\"'delta'",
+		"# broken: skipped, repo not found",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("script missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestScript_quotesTagText(t *testing.T) {
+	res := []installer.Resolution{{
+		Program:   catalog.Program{Name: "tool"},
+		Tag:       "v1$(id)`x`\"",
+		Version:   "1$(id)`x`\"",
+		AssetName: "tool-1$(id)`x`\".tar.gz",
+		URL:       "https://example.com/tool.tar.gz",
+	}}
+	var buf bytes.Buffer
+	if err := export.Script(&buf, res); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"-o /tmp/'tool-1$(id)`x`\".tar.gz' ",
+		"tar -xzf /tmp/'tool-1$(id)`x`\".tar.gz' -C \"$HOME/.local/share/\"'tool'",
+		"rm -f /tmp/'tool-1$(id)`x`\".tar.gz'",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("script missing %q\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestDockerfile_oneRunPerProgram(t *testing.T) {
+	var buf bytes.Buffer
+	if err := export.Dockerfile(&buf, resolutions()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// One RUN for the base dirs plus one per resolved program.
+	if n := strings.Count(buf.String(), "\nRUN "); n != 2 {
+		t.Errorf("expected 2 RUN instructions, got %d\n%s", n, buf.String())
+	}
+}
+
+func TestBrewfileAndNix_useOverrides(t *testing.T) {
+	programs := []catalog.Program{
+		{Name: "nvim", Repo: "neovim/neovim", Brew: "neovim", Nix: "neovim"},
+		{Name: "fzf", Repo: "junegunn/fzf"},
+	}
+
+	var brew bytes.Buffer
+	export.Brewfile(&brew, programs)
+	if !strings.Contains(brew.String(), `brew "neovim" # neovim/neovim`) || !strings.Contains(brew.String(), `brew "fzf"`) {
+		t.Errorf("unexpected Brewfile:\n%s", brew.String())
+	}
+
+	var nix bytes.Buffer
+	export.Nix(&nix, programs)
+	if !strings.Contains(nix.String(), "    neovim # neovim/neovim") || !strings.Contains(nix.String(), "    fzf # junegunn/fzf") {
+		t.Errorf("unexpected nix expression:\n%s", nix.String())
+	}
+}
@@ -0,0 +1,45 @@
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+)
+
+// Brewfile writes a Brewfile with one formula per program, for moving a
+// catalog to Homebrew. The formula is the catalog's brew field, else the
+// program name.
+func Brewfile(w io.Writer, programs []catalog.Program) error {
+	fmt.Fprintln(w, "# Generated by david-dotfiles from catalog.toml — re-export instead of editing.")
+	for _, p := range programs {
+		name := p.Brew
+		if name == "" {
+			name = p.Name
+		}
+		fmt.Fprintf(w, "brew %q # %s\n", name, p.Repo)
+	}
+	return nil
+}
+
+// Nix writes a nix expression building an environment with one nixpkgs
+// attribute per program, usable with nix-env -if or as a flake's package.
+// The attribute is the catalog's nix field, else the program name.
+func Nix(w io.Writer, programs []catalog.Program) error {
+	fmt.Fprintln(w, "# Generated by david-dotfiles from catalog.toml — re-export instead of editing.")
+	fmt.Fprintln(w, "{ pkgs ? import <nixpkgs> { } }:")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "pkgs.buildEnv {")
+	fmt.Fprintln(w, `  name = "david-dotfiles";`)
+	fmt.Fprintln(w, "  paths = with pkgs; [")
+	for _, p := range programs {
+		name := p.Nix
+		if name == "" {
+			name = p.Name
+		}
+		fmt.Fprintf(w, "    %s # %s\n", name, p.Repo)
+	}
+	fmt.Fprintln(w, "  ];")
+	fmt.Fprintln(w, "}")
+	return nil
+}
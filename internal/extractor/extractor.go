@@ -3,33 +3,118 @@ package extractor
 import (
 	"archive/tar"
 	"archive/zip"
+	"bytes"
+	"cmp"
 	"compress/bzip2"
 	"compress/gzip"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/dsaleh/david-dotfiles/internal/system"
 	"github.com/ulikunitz/xz"
 )
 
 // Extract dispatches to the correct extraction strategy based on the file extension.
 // For unknown extensions, the file is treated as a raw binary and copied to dst.
+// On macOS the Gatekeeper quarantine attribute is then cleared from dstDir so
+// the extracted binaries run without a prompt.
 func Extract(srcPath, dstDir string) error {
 	name := filepath.Base(srcPath)
+	var err error
 	switch {
 	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
-		return extractTar(srcPath, dstDir, "gz")
+		err = extractTar(srcPath, dstDir, "gz")
 	case strings.HasSuffix(name, ".tar.xz") || strings.HasSuffix(name, ".txz"):
-		return extractTar(srcPath, dstDir, "xz")
+		err = extractTar(srcPath, dstDir, "xz")
 	case strings.HasSuffix(name, ".tar.bz2"):
-		return extractTar(srcPath, dstDir, "bz2")
+		err = extractTar(srcPath, dstDir, "bz2")
 	case strings.HasSuffix(name, ".zip"):
-		return extractZip(srcPath, dstDir)
+		err = extractZip(srcPath, dstDir)
 	default:
-		return copyBinary(srcPath, dstDir)
+		err = copyBinary(srcPath, dstDir)
 	}
+	if err != nil {
+		return err
+	}
+	clearQuarantine(dstDir)
+	return nil
+}
+
+// junk reports whether an archive entry is macOS metadata rather than part of
+// the release: __MACOSX resource-fork folders, AppleDouble "._" files and
+// .DS_Store.
+func junk(name string) bool {
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == "__MACOSX" || part == ".DS_Store" || strings.HasPrefix(part, "._") {
+			return true
+		}
+	}
+	return false
+}
+
+// magic lists the leading bytes expected for each archive suffix.
+var magic = []struct {
+	suffixes []string
+	prefix   []byte
+	format   string
+}{
+	{[]string{".tar.gz", ".tgz"}, []byte{0x1f, 0x8b}, "gzip"},
+	{[]string{".tar.xz", ".txz"}, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, "xz"},
+	{[]string{".tar.bz2"}, []byte("BZh"), "bzip2"},
+	{[]string{".zip"}, []byte("PK\x03\x04"), "zip"},
+}
+
+// Validate sniffs the start of srcPath and rejects content that cannot be what
+// the file name promises: an HTML page (GitHub serves these for rate-limit and
+// auth walls with a 200 status) or an archive with the wrong magic bytes.
+// Unknown extensions are raw binaries and only get the HTML check.
+func Validate(srcPath string) error {
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return fmt.Errorf("downloaded file is empty")
+		}
+		return err
+	}
+	head = head[:n]
+
+	if strings.HasPrefix(http.DetectContentType(head), "text/html") {
+		return fmt.Errorf("got HTML, not an archive — GitHub may be rate limiting or asking for authentication")
+	}
+
+	name := filepath.Base(srcPath)
+	for _, m := range magic {
+		for _, suffix := range m.suffixes {
+			if strings.HasSuffix(name, suffix) && !bytes.HasPrefix(head, m.prefix) {
+				return fmt.Errorf("%s is not a valid %s archive (unexpected leading bytes %q)", name, m.format, head[:min(len(head), 8)])
+			}
+		}
+	}
+	return nil
+}
+
+// safeJoin maps an archive entry name to a path inside dstDir. Names are
+// treated as untrusted: backslashes (written by Windows zip tools) count as
+// separators, and a drive letter, leading slashes or ".." components cannot
+// lead outside dstDir: `C:\..\evil` and "/../evil" both become dstDir/evil.
+func safeJoin(dstDir, name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if len(name) >= 2 && name[1] == ':' && ('a' <= name[0]|0x20 && name[0]|0x20 <= 'z') {
+		name = name[2:]
+	}
+	return filepath.Join(dstDir, filepath.FromSlash(path.Clean("/" + name)[1:]))
 }
 
 func extractTar(srcPath, dstDir, compression string) error {
@@ -67,14 +152,22 @@ func extractTar(srcPath, dstDir, compression string) error {
 		if err != nil {
 			return fmt.Errorf("read tar: %w", err)
 		}
-		// Sanitize path to prevent path traversal
-		target := filepath.Join(dstDir, filepath.Clean("/" + hdr.Name)[1:])
+		if junk(hdr.Name) {
+			continue
+		}
+		target := safeJoin(dstDir, hdr.Name)
+		if target == filepath.Clean(dstDir) {
+			continue
+		}
 		switch hdr.Typeflag {
 		case tar.TypeDir:
-			os.MkdirAll(target, 0755)
+			os.MkdirAll(target, system.DirPerm())
 		case tar.TypeReg:
-			os.MkdirAll(filepath.Dir(target), 0755)
-			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, hdr.FileInfo().Mode())
+			os.MkdirAll(filepath.Dir(target), system.DirPerm())
+			// Replace rather than truncate, so a binary that is running
+			// keeps its old inode instead of failing with "text file busy".
+			os.Remove(target)
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, system.Perm(hdr.FileInfo().Mode()))
 			if err != nil {
 				return err
 			}
@@ -88,6 +181,16 @@ func extractTar(srcPath, dstDir, compression string) error {
 	return nil
 }
 
+// zipBufferSize is the size of the buffer extractZip copies every entry
+// through.
+const zipBufferSize = 256 << 10
+
+// extractZip unpacks the entries in the order their data is stored, so the
+// archive is read front to back as unzip does instead of seeking around it
+// in central directory order. Each entry goes through one buffer shared by
+// the whole archive (io.Copy would allocate one per file), and each dir is
+// created once rather than for every file in it. This keeps toolchain zips
+// of a gigabyte or more, with tens of thousands of files, cheap to extract.
 func extractZip(srcPath, dstDir string) error {
 	r, err := zip.OpenReader(srcPath)
 	if err != nil {
@@ -95,32 +198,68 @@ func extractZip(srcPath, dstDir string) error {
 	}
 	defer r.Close()
 
-	for _, f := range r.File {
-		target := filepath.Join(dstDir, filepath.Clean("/" + f.Name)[1:])
-		if f.FileInfo().IsDir() {
-			os.MkdirAll(target, 0755)
+	// DataOffset reads the entry's local header, so look each one up once.
+	type entry struct {
+		file   *zip.File
+		offset int64
+	}
+	entries := make([]entry, len(r.File))
+	for i, f := range r.File {
+		offset, _ := f.DataOffset()
+		entries[i] = entry{f, offset}
+	}
+	slices.SortStableFunc(entries, func(a, b entry) int {
+		return cmp.Compare(a.offset, b.offset)
+	})
+
+	buf := make([]byte, zipBufferSize)
+	made := map[string]bool{}
+	mkdir := func(dir string) {
+		if !made[dir] {
+			os.MkdirAll(dir, system.DirPerm())
+			made[dir] = true
+		}
+	}
+	for _, e := range entries {
+		f := e.file
+		if junk(f.Name) {
 			continue
 		}
-		os.MkdirAll(filepath.Dir(target), 0755)
-		rc, err := f.Open()
-		if err != nil {
-			return err
+		target := safeJoin(dstDir, f.Name)
+		if target == filepath.Clean(dstDir) {
+			continue
 		}
-		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, f.Mode())
-		if err != nil {
-			rc.Close()
-			return err
+		if f.FileInfo().IsDir() {
+			mkdir(target)
+			continue
 		}
-		_, err = io.Copy(out, rc)
-		out.Close()
-		rc.Close()
-		if err != nil {
+		mkdir(filepath.Dir(target))
+		if err := extractZipFile(f, target, buf); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func extractZipFile(f *zip.File, target string, buf []byte) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	os.Remove(target) // see extractTar
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, system.Perm(f.Mode()))
+	if err != nil {
+		return err
+	}
+	// Hide ReadFrom and WriteTo so that CopyBuffer uses buf.
+	_, err = io.CopyBuffer(struct{ io.Writer }{out}, struct{ io.Reader }{rc}, buf)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
 func copyBinary(srcPath, dstDir string) error {
 	name := filepath.Base(srcPath)
 	dst := filepath.Join(dstDir, name)
@@ -131,7 +270,8 @@ func copyBinary(srcPath, dstDir string) error {
 	}
 	defer in.Close()
 
-	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	os.Remove(dst) // see extractTar
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, system.ExecPerm())
 	if err != nil {
 		return err
 	}
@@ -5,8 +5,10 @@ import (
 	"archive/zip"
 	"bytes"
 	"compress/gzip"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ulikunitz/xz"
@@ -64,6 +66,75 @@ func TestExtract_zip(t *testing.T) {
 	}
 }
 
+func TestExtract_zipSkipsMacMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"tool/bin/tool", "__MACOSX/tool/bin/._tool", "tool/._README", "tool/.DS_Store"} {
+		f, _ := zw.Create(name)
+		f.Write([]byte("data"))
+	}
+	zw.Close()
+
+	src, _ := os.CreateTemp("", "test-*.zip")
+	src.Write(buf.Bytes())
+	src.Close()
+	defer os.Remove(src.Name())
+
+	dst, _ := os.MkdirTemp("", "extract-dst-*")
+	defer os.RemoveAll(dst)
+
+	if err := extractor.Extract(src.Name(), dst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "tool", "bin", "tool")); err != nil {
+		t.Errorf("tool not extracted: %v", err)
+	}
+	for _, junk := range []string{"__MACOSX", "tool/._README", "tool/.DS_Store"} {
+		if _, err := os.Stat(filepath.Join(dst, junk)); err == nil {
+			t.Errorf("%s should have been skipped", junk)
+		}
+	}
+}
+
+func TestExtract_zipUnsafeNames(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	names := map[string]string{
+		`..\..\evil`:        "evil",
+		`C:\tools\tool.exe`: "tools/tool.exe",
+		"d:/x/y":            "x/y",
+		"/etc/passwd":       "etc/passwd",
+		"../../outside":     "outside",
+		`bin\nested\..\rg`:  "bin/rg",
+	}
+	for name := range names {
+		f, _ := zw.Create(name)
+		f.Write([]byte("data"))
+	}
+	zw.Close()
+
+	root, _ := os.MkdirTemp("", "extract-root-*")
+	defer os.RemoveAll(root)
+	src := filepath.Join(root, "test.zip")
+	os.WriteFile(src, buf.Bytes(), 0644)
+	dst := filepath.Join(root, "a", "b", "dst")
+	os.MkdirAll(dst, 0755)
+
+	if err := extractor.Extract(src, dst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, want := range names {
+		if _, err := os.Stat(filepath.Join(dst, filepath.FromSlash(want))); err != nil {
+			t.Errorf("%q: expected %s inside dst: %v", name, want, err)
+		}
+	}
+	for _, escaped := range []string{"evil", "outside", filepath.Join("a", "evil"), filepath.Join("a", "outside")} {
+		if _, err := os.Stat(filepath.Join(root, escaped)); err == nil {
+			t.Errorf("%s was written outside dst", escaped)
+		}
+	}
+}
+
 func TestExtract_txz(t *testing.T) {
 	// Build a .txz (xz-compressed tar) with a single file "mybin"
 	var buf bytes.Buffer
@@ -115,3 +186,83 @@ func TestExtract_rawBinary(t *testing.T) {
 		t.Error("raw binary should be executable")
 	}
 }
+
+func TestValidate_html(t *testing.T) {
+	src, _ := os.CreateTemp("", "test-*.tar.gz")
+	src.Write([]byte("<!DOCTYPE html><html><body>Rate limit exceeded</body></html>"))
+	src.Close()
+	defer os.Remove(src.Name())
+
+	err := extractor.Validate(src.Name())
+	if err == nil || !strings.Contains(err.Error(), "got HTML") {
+		t.Fatalf("expected HTML error, got %v", err)
+	}
+}
+
+func TestValidate_wrongMagic(t *testing.T) {
+	src, _ := os.CreateTemp("", "test-*.zip")
+	src.Write([]byte("definitely not a zip file"))
+	src.Close()
+	defer os.Remove(src.Name())
+
+	if err := extractor.Validate(src.Name()); err == nil {
+		t.Fatal("expected error for zip without PK header")
+	}
+}
+
+func TestValidate_validArchiveAndRawBinary(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("payload"))
+	gz.Close()
+
+	src, _ := os.CreateTemp("", "test-*.tar.gz")
+	src.Write(buf.Bytes())
+	src.Close()
+	defer os.Remove(src.Name())
+	if err := extractor.Validate(src.Name()); err != nil {
+		t.Errorf("valid gzip rejected: %v", err)
+	}
+
+	raw, _ := os.CreateTemp("", "mybinary-linux-amd64")
+	raw.Write([]byte("\x7fELF binary content"))
+	raw.Close()
+	defer os.Remove(raw.Name())
+	if err := extractor.Validate(raw.Name()); err != nil {
+		t.Errorf("raw binary rejected: %v", err)
+	}
+}
+
+func TestExtract_zipManyEntries(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	want := map[string]string{}
+	for i := range 50 {
+		name := fmt.Sprintf("toolchain/lib/%d/file%d", i%5, i)
+		// Larger than the copy buffer, so entries span several reads.
+		want[name] = strings.Repeat(fmt.Sprintf("entry %d\n", i), 40000)
+		f, _ := zw.Create(name)
+		f.Write([]byte(want[name]))
+	}
+	// A dir entry after the files in it.
+	zw.Create("toolchain/lib/0/")
+	zw.Close()
+
+	src, _ := os.CreateTemp("", "test-*.zip")
+	src.Write(buf.Bytes())
+	src.Close()
+	defer os.Remove(src.Name())
+
+	dst, _ := os.MkdirTemp("", "extract-dst-*")
+	defer os.RemoveAll(dst)
+
+	if err := extractor.Extract(src.Name(), dst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(dst, name))
+		if err != nil || string(got) != content {
+			t.Errorf("%s: %d bytes, %v; want %d bytes", name, len(got), err, len(content))
+		}
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !darwin
+
+package extractor
+
+// clearQuarantine is a no-op outside macOS, which has no Gatekeeper.
+func clearQuarantine(string) {}
@@ -2,11 +2,16 @@ package github
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/version"
 )
 
 const defaultBaseURL = "https://api.github.com"
@@ -31,28 +36,289 @@ func NewClient(baseURL string) *Client {
 	}
 }
 
+// WithHTTPClient makes c send its requests with hc, e.g. one that trusts a
+// test server's certificate, and returns c.
+func (c *Client) WithHTTPClient(hc *http.Client) *Client {
+	c.httpClient = hc
+	return c
+}
+
 // Release holds the raw tag and the version with any leading "v" stripped.
 type Release struct {
-	Tag     string // raw tag as returned by GitHub, e.g. "v15.1.0" or "15.1.0"
-	Version string // tag with leading "v" stripped, e.g. "15.1.0"
+	Tag         string    // raw tag as returned by GitHub, e.g. "v15.1.0" or "15.1.0"
+	Version     string    // tag with leading "v" stripped, e.g. "15.1.0"
+	PublishedAt time.Time // zero if GitHub did not report a publish date
+	Updated     time.Time // newest of PublishedAt and the assets' updated_at, for rolling tags
+	Prerelease  bool
+	Assets      []string          // names of the files attached to the release
+	Sizes       map[string]int64  // asset name → size in bytes
+	URLs        map[string]string // asset name → browser_download_url
+	APIURLs     map[string]string // asset name → API url; see DownloadAccept
+	MovedTo     string            // the repo's new owner/name if it was renamed; "" otherwise
+}
+
+// DownloadAccept is the Accept header that makes a GET of an asset's API URL
+// return the file itself. GitHub answers with a redirect to its CDN; unlike
+// browser_download_url, this works for private repos when a token is sent.
+const DownloadAccept = "application/octet-stream"
+
+// apiRelease is the subset of the GitHub release object we decode.
+type apiRelease struct {
+	TagName     string    `json:"tag_name"`
+	PublishedAt time.Time `json:"published_at"`
+	Prerelease  bool      `json:"prerelease"`
+	Draft       bool      `json:"draft"`
+	Assets      []struct {
+		Name      string    `json:"name"`
+		Size      int64     `json:"size"`
+		URL       string    `json:"browser_download_url"`
+		APIURL    string    `json:"url"`
+		UpdatedAt time.Time `json:"updated_at"`
+	} `json:"assets"`
+}
+
+func (r apiRelease) release() Release {
+	rel := Release{
+		Tag:         r.TagName,
+		Version:     strings.TrimPrefix(r.TagName, "v"),
+		PublishedAt: r.PublishedAt,
+		Updated:     r.PublishedAt,
+		Prerelease:  r.Prerelease,
+		Sizes:       make(map[string]int64, len(r.Assets)),
+		URLs:        make(map[string]string, len(r.Assets)),
+		APIURLs:     make(map[string]string, len(r.Assets)),
+	}
+	for _, a := range r.Assets {
+		rel.Assets = append(rel.Assets, a.Name)
+		rel.Sizes[a.Name] = a.Size
+		rel.URLs[a.Name] = a.URL
+		rel.APIURLs[a.Name] = a.APIURL
+		if a.UpdatedAt.After(rel.Updated) {
+			rel.Updated = a.UpdatedAt
+		}
+	}
+	return rel
 }
 
 // LatestRelease returns the latest release tag and version for the given repo (owner/name).
 // Tag is the raw value from the GitHub API; Version has any leading "v" stripped.
 func (c *Client) LatestRelease(ctx context.Context, repo string) (Release, error) {
-	url := fmt.Sprintf("%s/repos/%s/releases/latest", c.baseURL, repo)
+	var raw apiRelease
+	redirected, err := c.fetch(ctx, fmt.Sprintf("%s/repos/%s/releases/latest", c.baseURL, repo), repo, &raw)
+	if err != nil {
+		return Release{}, err
+	}
+	rel := raw.release()
+	if rel.Version == "" {
+		return Release{}, fmt.Errorf("empty tag_name in GitHub response for %q", repo)
+	}
+	if redirected {
+		rel.MovedTo = c.newName(ctx, repo)
+	}
+	return rel, nil
+}
+
+// ReleaseByTag returns the release published under tag. It is used for pinned
+// versions, where the tag is known but the asset list is not.
+func (c *Client) ReleaseByTag(ctx context.Context, repo, tag string) (Release, error) {
+	var raw apiRelease
+	url := fmt.Sprintf("%s/repos/%s/releases/tags/%s", c.baseURL, repo, tag)
+	if err := c.get(ctx, url, repo, &raw); err != nil {
+		return Release{}, err
+	}
+	return raw.release(), nil
+}
+
+// ListReleases returns up to n of the most recent published releases for repo,
+// newest first. Drafts are skipped; prereleases are included and flagged.
+func (c *Client) ListReleases(ctx context.Context, repo string, n int) ([]Release, error) {
+	var raw []apiRelease
+	if err := c.get(ctx, fmt.Sprintf("%s/repos/%s/releases?per_page=%d", c.baseURL, repo, n), repo, &raw); err != nil {
+		return nil, err
+	}
+
+	releases := make([]Release, 0, len(raw))
+	for _, r := range raw {
+		if r.Draft || r.TagName == "" {
+			continue
+		}
+		releases = append(releases, r.release())
+	}
+	return releases, nil
+}
+
+// LatestTag returns the newest version tag of repo, for projects that tag
+// versions but never publish GitHub Releases. Tags that are not versions
+// (e.g. "nightly" or "20240101") and prerelease tags (e.g. "v2.0.0-rc1")
+// are ignored; see version.ParseTag. The returned Release has no assets or
+// publish date.
+func (c *Client) LatestTag(ctx context.Context, repo string) (Release, error) {
+	var best Release
+	var bestParts []int
+	var redirected bool
+	// Tags come in name order, not by version, so read every page.
+	u := fmt.Sprintf("%s/repos/%s/tags?per_page=100", c.baseURL, repo)
+	for page := 1; u != ""; page++ {
+		var raw []struct {
+			Name string `json:"name"`
+		}
+		next, moved, err := c.fetchPage(ctx, u, repo, &raw)
+		if err != nil {
+			return Release{}, err
+		}
+		if page == 1 {
+			redirected = moved
+		}
+		for _, t := range raw {
+			parts, ok := version.ParseTag(t.Name)
+			if !ok {
+				continue
+			}
+			if bestParts == nil || version.Compare(parts, bestParts) > 0 {
+				best = Release{Tag: t.Name, Version: strings.TrimPrefix(t.Name, "v")}
+				bestParts = parts
+			}
+		}
+		u = next
+	}
+	if bestParts == nil {
+		return Release{}, fmt.Errorf("no version tags found for %q", repo)
+	}
+	if redirected {
+		best.MovedTo = c.newName(ctx, repo)
+	}
+	return best, nil
+}
+
+// FindTag returns the tag of repo named tag, or "v"+tag if only that exists,
+// so a version can be given with or without the prefix. It fails if neither
+// exists.
+func (c *Client) FindTag(ctx context.Context, repo, tag string) (string, error) {
+	candidates := []string{tag}
+	if !strings.HasPrefix(tag, "v") {
+		candidates = append(candidates, "v"+tag)
+	}
+	for _, t := range candidates {
+		var ref struct {
+			Ref string `json:"ref"`
+		}
+		err := c.get(ctx, fmt.Sprintf("%s/repos/%s/git/ref/tags/%s", c.baseURL, repo, t), repo, &ref)
+		if err == nil {
+			return t, nil
+		}
+		if !errors.As(err, new(notFoundError)) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("%s has no tag %q", repo, tag)
+}
+
+// Repo is a repository returned by SearchRepos.
+type Repo struct {
+	FullName    string // owner/name
+	Description string
+	Stars       int
+}
+
+// SearchRepos returns up to n repositories matching query, ordered by
+// GitHub's best-match ranking.
+func (c *Client) SearchRepos(ctx context.Context, query string, n int) ([]Repo, error) {
+	var raw struct {
+		Items []struct {
+			FullName    string `json:"full_name"`
+			Description string `json:"description"`
+			Stars       int    `json:"stargazers_count"`
+		} `json:"items"`
+	}
+	u := fmt.Sprintf("%s/search/repositories?q=%s&per_page=%d", c.baseURL, url.QueryEscape(query), n)
+	if err := c.get(ctx, u, query, &raw); err != nil {
+		return nil, err
+	}
+	repos := make([]Repo, 0, len(raw.Items))
+	for _, it := range raw.Items {
+		repos = append(repos, Repo{FullName: it.FullName, Description: it.Description, Stars: it.Stars})
+	}
+	return repos, nil
+}
+
+// Readme returns the repo's README, as found by GitHub on its default
+// branch, in its source markup.
+func (c *Client) Readme(ctx context.Context, repo string) (string, error) {
+	var raw struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}
+	if err := c.get(ctx, fmt.Sprintf("%s/repos/%s/readme", c.baseURL, repo), repo, &raw); err != nil {
+		if errors.As(err, new(notFoundError)) {
+			return "", fmt.Errorf("no README found for %q", repo)
+		}
+		return "", err
+	}
+	if raw.Encoding != "base64" {
+		return raw.Content, nil
+	}
+	// GitHub wraps the base64 content in lines of 60 characters.
+	data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(raw.Content, "\n", ""))
+	if err != nil {
+		return "", fmt.Errorf("decode README of %q: %w", repo, err)
+	}
+	return string(data), nil
+}
+
+// newName returns the current owner/name of a repo that GitHub redirected,
+// or "" if it cannot be determined or is unchanged.
+func (c *Client) newName(ctx context.Context, repo string) string {
+	var raw struct {
+		FullName string `json:"full_name"`
+	}
+	if err := c.get(ctx, fmt.Sprintf("%s/repos/%s", c.baseURL, repo), repo, &raw); err != nil {
+		return ""
+	}
+	if raw.FullName == "" || strings.EqualFold(raw.FullName, repo) {
+		return ""
+	}
+	return raw.FullName
+}
+
+// ErrRateLimited is wrapped by the errors of requests GitHub refused with
+// 403 or 429, which usually means the rate limit is used up.
+var ErrRateLimited = errors.New("GitHub API rate limited")
+
+// notFoundError is a 404 from the API: for most requests, a wrong repo.
+type notFoundError struct{ repo string }
+
+func (e notFoundError) Error() string {
+	return fmt.Sprintf("repo %q not found on GitHub — check the repo field in catalog.toml", e.repo)
+}
+
+// get performs a GitHub API request and decodes the JSON body into v,
+// translating the common failure statuses into actionable errors.
+func (c *Client) get(ctx context.Context, url, repo string, v any) error {
+	_, err := c.fetch(ctx, url, repo, v)
+	return err
+}
+
+// fetch is get, also reporting whether the request was redirected, which the
+// API does for renamed repos.
+func (c *Client) fetch(ctx context.Context, url, repo string, v any) (bool, error) {
+	_, redirected, err := c.fetchPage(ctx, url, repo, v)
+	return redirected, err
+}
+
+// fetchPage is fetch, also returning the URL of the next page of a paginated
+// list from the Link header, or "" on the last page.
+func (c *Client) fetchPage(ctx context.Context, url, repo string, v any) (string, bool, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return Release{}, fmt.Errorf("build request: %w", err)
+		return "", false, fmt.Errorf("build request: %w", err)
 	}
 	req.Header.Set("Accept", "application/vnd.github+json")
 
-	// Use GITHUB_TOKEN if available.
-	// (No requirement to set it, but respects it if present.)
+	// The token, if any, is added per host by transport.Transport.
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return Release{}, fmt.Errorf("github request: %w", err)
+		return "", false, fmt.Errorf("github request: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -60,24 +326,32 @@ func (c *Client) LatestRelease(ctx context.Context, repo string) (Release, error
 	case http.StatusOK:
 		// handled below
 	case http.StatusNotFound:
-		return Release{}, fmt.Errorf("repo %q not found on GitHub — check the repo field in catalog.toml", repo)
+		return "", false, notFoundError{repo}
 	case http.StatusForbidden, http.StatusTooManyRequests:
-		return Release{}, fmt.Errorf("GitHub API rate limited for %q — set GITHUB_TOKEN env var to increase limit", repo)
+		return "", false, fmt.Errorf("%w for %q — set GITHUB_TOKEN env var to increase limit", ErrRateLimited, repo)
 	default:
-		return Release{}, fmt.Errorf("unexpected GitHub API status %d for %q", resp.StatusCode, repo)
+		return "", false, fmt.Errorf("unexpected GitHub API status %d for %q", resp.StatusCode, repo)
 	}
 
-	var apiRelease struct {
-		TagName string `json:"tag_name"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&apiRelease); err != nil {
-		return Release{}, fmt.Errorf("decode GitHub response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", false, fmt.Errorf("decode GitHub response: %w", err)
 	}
+	return nextLink(resp.Header.Get("Link")), resp.Request.URL.String() != url, nil
+}
 
-	tag := apiRelease.TagName
-	version := strings.TrimPrefix(tag, "v")
-	if version == "" {
-		return Release{}, fmt.Errorf("empty tag_name in GitHub response for %q", repo)
+// nextLink returns the rel="next" URL of a Link header such as
+// `<https://api.github.com/...&page=2>; rel="next", <...>; rel="last"`.
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if !ok {
+			continue
+		}
+		for _, p := range strings.Split(params, ";") {
+			if strings.TrimSpace(p) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
 	}
-	return Release{Tag: tag, Version: version}, nil
+	return ""
 }
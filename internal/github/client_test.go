@@ -2,9 +2,12 @@ package github_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	gh "github.com/dsaleh/david-dotfiles/internal/github"
 )
@@ -50,6 +53,33 @@ func TestLatestRelease_bareTag(t *testing.T) {
 	}
 }
 
+// GitHub answers requests for a renamed repo with a redirect to its id.
+func TestLatestRelease_renamed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/old/tool/releases/latest":
+			http.Redirect(w, r, "/repositories/42/releases/latest", http.StatusMovedPermanently)
+		case "/repos/old/tool":
+			http.Redirect(w, r, "/repositories/42", http.StatusMovedPermanently)
+		case "/repositories/42/releases/latest":
+			w.Write([]byte(`{"tag_name": "v1.0.0"}`))
+		case "/repositories/42":
+			w.Write([]byte(`{"full_name": "new/tool"}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	rel, err := gh.NewClient(srv.URL).LatestRelease(context.Background(), "old/tool")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rel.Version != "1.0.0" || rel.MovedTo != "new/tool" {
+		t.Errorf("got version %q moved to %q, want 1.0.0 / new/tool", rel.Version, rel.MovedTo)
+	}
+}
+
 func TestLatestRelease_notFound(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
@@ -71,7 +101,217 @@ func TestLatestRelease_rateLimited(t *testing.T) {
 
 	client := gh.NewClient(srv.URL)
 	_, err := client.LatestRelease(context.Background(), "owner/repo")
-	if err == nil {
-		t.Fatal("expected error for 403")
+	if !errors.Is(err, gh.ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited for 403, got %v", err)
+	}
+}
+
+func TestListReleases(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("per_page"); got != "5" {
+			t.Errorf("expected per_page=5, got %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[
+			{"tag_name": "v2.0.0-rc1", "prerelease": true, "published_at": "2024-06-01T00:00:00Z"},
+			{"tag_name": "v1.9.0", "draft": true},
+			{"tag_name": "v1.8.0", "published_at": "2024-05-01T00:00:00Z"}
+		]`))
+	}))
+	defer srv.Close()
+
+	client := gh.NewClient(srv.URL)
+	rels, err := client.ListReleases(context.Background(), "owner/repo", 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rels) != 2 {
+		t.Fatalf("expected 2 releases (draft skipped), got %d", len(rels))
+	}
+	if !rels[0].Prerelease || rels[0].Version != "2.0.0-rc1" {
+		t.Errorf("unexpected first release: %+v", rels[0])
+	}
+	if rels[1].Tag != "v1.8.0" || rels[1].PublishedAt.IsZero() {
+		t.Errorf("unexpected second release: %+v", rels[1])
+	}
+}
+
+func TestReleaseByTag_assets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/releases/tags/v1.0.0" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"tag_name": "v1.0.0", "assets": [{"name": "tool-linux-amd64.tar.gz", "size": 2048, "browser_download_url": "https://example.com/tool-linux-amd64.tar.gz", "url": "https://api.example.com/repos/owner/tool/releases/assets/7"}, {"name": "tool-darwin-arm64.tar.gz"}]}`))
+	}))
+	defer srv.Close()
+
+	rel, err := gh.NewClient(srv.URL).ReleaseByTag(context.Background(), "owner/repo", "v1.0.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rel.Assets) != 2 || rel.Assets[0] != "tool-linux-amd64.tar.gz" {
+		t.Errorf("unexpected assets %v", rel.Assets)
+	}
+	if rel.Sizes["tool-linux-amd64.tar.gz"] != 2048 {
+		t.Errorf("unexpected sizes %v", rel.Sizes)
+	}
+	if rel.URLs["tool-linux-amd64.tar.gz"] != "https://example.com/tool-linux-amd64.tar.gz" {
+		t.Errorf("unexpected URLs %v", rel.URLs)
+	}
+	if rel.APIURLs["tool-linux-amd64.tar.gz"] != "https://api.example.com/repos/owner/tool/releases/assets/7" {
+		t.Errorf("unexpected API URLs %v", rel.APIURLs)
+	}
+}
+
+// A nightly tag is rebuilt in place: its assets are newer than the release.
+func TestReleaseByTag_updated(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag_name": "nightly", "published_at": "2026-01-01T00:00:00Z", "assets": [
+			{"name": "a.tar.gz", "updated_at": "2026-03-02T00:00:00Z"},
+			{"name": "b.tar.gz", "updated_at": "2026-03-01T00:00:00Z"}]}`))
+	}))
+	defer srv.Close()
+
+	rel, err := gh.NewClient(srv.URL).ReleaseByTag(context.Background(), "owner/repo", "nightly")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC); !rel.Updated.Equal(want) {
+		t.Errorf("Updated = %v, want %v", rel.Updated, want)
+	}
+}
+
+func TestLatestTag(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/tags" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"name":"nightly"},{"name":"v1.9.0"},{"name":"v1.10.0"},{"name":"v2.0.0-rc1"},{"name":"v1.2"}]`))
+	}))
+	defer srv.Close()
+
+	rel, err := gh.NewClient(srv.URL).LatestTag(context.Background(), "owner/repo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rel.Tag != "v1.10.0" || rel.Version != "1.10.0" {
+		t.Errorf("got tag %q version %q, want v1.10.0 / 1.10.0", rel.Tag, rel.Version)
+	}
+}
+
+func TestLatestTag_paginated(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repositories/1/tags?per_page=100&page=2>; rel="next", <%[1]s/repositories/1/tags?per_page=100&page=2>; rel="last"`, srv.URL))
+			w.Write([]byte(`[{"name":"v1.9.0"},{"name":"v1.10.0"}]`))
+		case "2":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repositories/1/tags?per_page=100&page=1>; rel="prev"`, srv.URL))
+			w.Write([]byte(`[{"name":"v10.0.0"},{"name":"v1.0.0"}]`))
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	rel, err := gh.NewClient(srv.URL).LatestTag(context.Background(), "owner/repo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rel.Tag != "v10.0.0" {
+		t.Errorf("got tag %q, want v10.0.0 from the second page", rel.Tag)
+	}
+}
+
+func TestLatestTag_dateTags(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"name":"20240101"},{"name":"2023"},{"name":"v1.9.0"},{"name":"1.2.3.4"},{"name":"v1.8"}]`))
+	}))
+	defer srv.Close()
+
+	rel, err := gh.NewClient(srv.URL).LatestTag(context.Background(), "owner/repo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rel.Tag != "v1.9.0" {
+		t.Errorf("got tag %q, want v1.9.0 over the date and build tags", rel.Tag)
+	}
+}
+
+func TestLatestTag_none(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"name":"nightly"}]`))
+	}))
+	defer srv.Close()
+
+	if _, err := gh.NewClient(srv.URL).LatestTag(context.Background(), "owner/repo"); err == nil {
+		t.Error("expected an error when no tag is a version")
+	}
+}
+
+func TestSearchRepos(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search/repositories" || r.URL.Query().Get("q") != "fuzzy finder" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"items": [{"full_name": "junegunn/fzf", "description": "A command-line fuzzy finder", "stargazers_count": 70000}]}`))
+	}))
+	defer srv.Close()
+
+	repos, err := gh.NewClient(srv.URL).SearchRepos(context.Background(), "fuzzy finder", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repos) != 1 || repos[0].FullName != "junegunn/fzf" || repos[0].Stars != 70000 {
+		t.Errorf("unexpected repos %+v", repos)
+	}
+}
+
+func TestFindTag(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/git/ref/tags/v1.2.0" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"ref": "refs/tags/v1.2.0"}`))
+	}))
+	defer srv.Close()
+	client := gh.NewClient(srv.URL)
+
+	for _, in := range []string{"v1.2.0", "1.2.0"} {
+		if tag, err := client.FindTag(context.Background(), "owner/repo", in); err != nil || tag != "v1.2.0" {
+			t.Errorf("FindTag(%q) = %q, %v", in, tag, err)
+		}
+	}
+	if _, err := client.FindTag(context.Background(), "owner/repo", "1.3.0"); err == nil {
+		t.Error("expected an error for a missing tag")
+	}
+}
+
+func TestReadme(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/readme" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// "# tool\n\nInstall it." in base64, wrapped the way GitHub does.
+		w.Write([]byte(`{"encoding": "base64", "content": "IyB0b29sCgpJbnN0\nYWxsIGl0Lg==\n"}`))
+	}))
+	defer srv.Close()
+	client := gh.NewClient(srv.URL)
+
+	readme, err := client.Readme(context.Background(), "owner/repo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if readme != "# tool\n\nInstall it." {
+		t.Errorf("unexpected README %q", readme)
+	}
+	if _, err := client.Readme(context.Background(), "owner/other"); err == nil {
+		t.Error("expected an error for a repo without a README")
 	}
 }
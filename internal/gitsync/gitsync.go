@@ -0,0 +1,85 @@
+package gitsync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// Dir returns the default checkout used by the sync subcommand.
+func Dir() string {
+	return filepath.Join(system.DataPath(), "sync")
+}
+
+// Repo is a git working copy driven through the git CLI.
+type Repo struct {
+	Dir string
+}
+
+// Open returns the repo at dir, or an error if dir is not a git checkout.
+func Open(dir string) (*Repo, error) {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return nil, fmt.Errorf("%s is not a git checkout — run `installer sync init <url>` first", dir)
+	}
+	return &Repo{Dir: dir}, nil
+}
+
+// Clone clones url into dir, creating parent directories as needed.
+func Clone(ctx context.Context, url, dir string) (*Repo, error) {
+	if err := os.MkdirAll(filepath.Dir(dir), system.DirPerm()); err != nil {
+		return nil, err
+	}
+	if _, err := run(ctx, "", "clone", "--quiet", url, dir); err != nil {
+		return nil, err
+	}
+	return &Repo{Dir: dir}, nil
+}
+
+// Pull fetches and rebases local commits onto the upstream branch.
+func (r *Repo) Pull(ctx context.Context) error {
+	_, err := run(ctx, r.Dir, "pull", "--rebase", "--quiet")
+	return err
+}
+
+// Dirty reports whether the working tree has uncommitted changes.
+func (r *Repo) Dirty(ctx context.Context) (bool, error) {
+	out, err := run(ctx, r.Dir, "status", "--porcelain")
+	return out != "", err
+}
+
+// Commit stages every change and commits it with msg. It returns false
+// without committing when there is nothing to commit.
+func (r *Repo) Commit(ctx context.Context, msg string) (bool, error) {
+	dirty, err := r.Dirty(ctx)
+	if err != nil || !dirty {
+		return false, err
+	}
+	if _, err := run(ctx, r.Dir, "add", "-A"); err != nil {
+		return false, err
+	}
+	if _, err := run(ctx, r.Dir, "commit", "--quiet", "-m", msg); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Push pushes the current branch to its upstream.
+func (r *Repo) Push(ctx context.Context) error {
+	_, err := run(ctx, r.Dir, "push", "--quiet")
+	return err
+}
+
+func run(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,89 @@
+package gitsync_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/dsaleh/david-dotfiles/internal/gitsync"
+)
+
+func setupRemote(t *testing.T) (string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	dir, _ := os.MkdirTemp("", "gitsync-*")
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	bare := filepath.Join(dir, "remote.git")
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", "-b", "main", bare).CombinedOutput(); err != nil {
+		t.Fatalf("init bare repo: %v: %s", err, out)
+	}
+	return dir, bare
+}
+
+func TestCommitPushPull(t *testing.T) {
+	dir, bare := setupRemote(t)
+	ctx := context.Background()
+
+	a, err := gitsync.Clone(ctx, bare, filepath.Join(dir, "a"))
+	if err != nil {
+		t.Fatalf("clone a: %v", err)
+	}
+	os.WriteFile(filepath.Join(a.Dir, "catalog.toml"), []byte("[programs]\n"), 0644)
+	committed, err := a.Commit(ctx, "add catalog")
+	if err != nil || !committed {
+		t.Fatalf("commit: committed=%v err=%v", committed, err)
+	}
+	if err := a.Push(ctx); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	b, err := gitsync.Clone(ctx, bare, filepath.Join(dir, "b"))
+	if err != nil {
+		t.Fatalf("clone b: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(b.Dir, "catalog.toml")); err != nil {
+		t.Fatalf("catalog not propagated: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(a.Dir, "catalog.toml"), []byte("[programs.fzf]\n"), 0644)
+	a.Commit(ctx, "edit catalog")
+	a.Push(ctx)
+	if err := b.Pull(ctx); err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(b.Dir, "catalog.toml"))
+	if string(data) != "[programs.fzf]\n" {
+		t.Errorf("edit not propagated, got %q", data)
+	}
+}
+
+func TestCommit_nothingToCommit(t *testing.T) {
+	dir, bare := setupRemote(t)
+	ctx := context.Background()
+
+	r, err := gitsync.Clone(ctx, bare, filepath.Join(dir, "a"))
+	if err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	committed, err := r.Commit(ctx, "noop")
+	if err != nil || committed {
+		t.Errorf("expected no commit, got committed=%v err=%v", committed, err)
+	}
+}
+
+func TestOpen_notACheckout(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "gitsync-*")
+	defer os.RemoveAll(dir)
+	if _, err := gitsync.Open(dir); err == nil {
+		t.Fatal("expected error for a non-git directory")
+	}
+}
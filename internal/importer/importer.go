@@ -0,0 +1,273 @@
+package importer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/detect"
+	"github.com/dsaleh/david-dotfiles/internal/extractor"
+	gh "github.com/dsaleh/david-dotfiles/internal/github"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// Entry is a catalog entry produced by an import.
+type Entry struct {
+	Name         string
+	Repo         string
+	AssetPattern string
+	Brew         string        // formula the entry came from, if any
+	Bin          []catalog.Bin // known binaries; empty means pick on first install
+}
+
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlKey quotes name unless it is a valid bare TOML key.
+func tomlKey(name string) string {
+	if bareKey.MatchString(name) {
+		return name
+	}
+	return fmt.Sprintf("%q", name)
+}
+
+var brewLine = regexp.MustCompile(`^\s*brew\s+["']([^"']+)["']`)
+
+// ParseBrewfile returns the formula names of the `brew "..."` lines in r.
+// Taps, casks and other entries are ignored; tapped formulae keep only their
+// short name.
+func ParseBrewfile(r io.Reader) ([]string, error) {
+	var names []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if m := brewLine.FindStringSubmatch(sc.Text()); m != nil {
+			name := m[1]
+			if i := strings.LastIndex(name, "/"); i >= 0 {
+				name = name[i+1:]
+			}
+			names = append(names, name)
+		}
+	}
+	return names, sc.Err()
+}
+
+// ParseRepos returns the owner/name repos listed in r, one per line, either
+// bare or as github.com URLs. Blank lines and # comments are skipped.
+func ParseRepos(r io.Reader) ([]string, error) {
+	var repos []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" {
+			continue
+		}
+		repo, ok := repoFromURL(line)
+		if !ok {
+			return nil, fmt.Errorf("invalid repo %q: expected owner/name or a github.com URL", line)
+		}
+		repos = append(repos, repo)
+	}
+	return repos, sc.Err()
+}
+
+// repoFromURL extracts owner/name from "owner/name" or a github.com URL.
+func repoFromURL(s string) (string, bool) {
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "/"), ".git")
+	for _, prefix := range []string{"https://github.com/", "http://github.com/", "github.com/"} {
+		s = strings.TrimPrefix(s, prefix)
+	}
+	parts := strings.Split(s, "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" || strings.Contains(parts[0], ".") {
+		return "", false
+	}
+	return parts[0] + "/" + parts[1], true
+}
+
+// Importer resolves package names and repos into catalog entries.
+type Importer struct {
+	GitHub  *gh.Client
+	BrewAPI string // Homebrew formula API base URL; defaults to formulae.brew.sh
+	HTTP    *http.Client
+
+	// Downloads is the base URL release assets are fetched from by Suggest;
+	// defaults to https://github.com.
+	Downloads string
+}
+
+// New returns an Importer using the public GitHub and Homebrew APIs.
+func New() *Importer {
+	return &Importer{
+		GitHub:  gh.NewClient(""),
+		BrewAPI: "https://formulae.brew.sh/api/formula",
+		HTTP:    &http.Client{Timeout: 30 * time.Second},
+
+		Downloads: "https://github.com",
+	}
+}
+
+// BrewRepo looks up the GitHub repo a Homebrew formula is built from, using
+// its homepage or source URL.
+func (im *Importer) BrewRepo(ctx context.Context, formula string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, im.BrewAPI+"/"+formula+".json", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := im.HTTP.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("homebrew request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("formula %q not found on Homebrew", formula)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected Homebrew API status %d for %q", resp.StatusCode, formula)
+	}
+	var info struct {
+		Homepage string `json:"homepage"`
+		URLs     struct {
+			Stable struct {
+				URL string `json:"url"`
+			} `json:"stable"`
+		} `json:"urls"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", fmt.Errorf("decode Homebrew response: %w", err)
+	}
+	for _, u := range []string{info.Homepage, info.URLs.Stable.URL} {
+		if strings.Contains(u, "github.com/") {
+			if repo, ok := repoFromURL(u[strings.Index(u, "github.com/"):]); ok {
+				return repo, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("formula %q is not hosted on GitHub", formula)
+}
+
+// Entry builds a catalog entry for repo by picking the latest release asset
+// that matches this machine and turning it into an asset_pattern.
+func (im *Importer) Entry(ctx context.Context, name, repo string) (Entry, error) {
+	e, _, _, err := im.entry(ctx, name, repo)
+	return e, err
+}
+
+func (im *Importer) entry(ctx context.Context, name, repo string) (Entry, gh.Release, string, error) {
+	rel, err := im.GitHub.LatestRelease(ctx, repo)
+	if err != nil {
+		return Entry{}, gh.Release{}, "", err
+	}
+	asset, ok := detect.Asset(rel.Assets)
+	if !ok {
+		return Entry{}, rel, "", fmt.Errorf("no release asset of %s %s matches this OS and architecture", repo, rel.Tag)
+	}
+	if name == "" {
+		name = repo[strings.Index(repo, "/")+1:]
+	}
+	return Entry{Name: name, Repo: repo, AssetPattern: detect.Pattern(asset, rel.Version)}, rel, asset, nil
+}
+
+// Suggest is Entry plus a bin list inferred from the release asset itself: it
+// is downloaded and extracted into a temp dir, and installer.SuggestBins picks
+// the binaries. Their src paths are relative to the archive root with the
+// version replaced by {version}, like the asset_pattern. Bin is left empty
+// when no binary can be inferred.
+func (im *Importer) Suggest(ctx context.Context, name, repo string) (Entry, error) {
+	e, rel, asset, err := im.entry(ctx, name, repo)
+	if err != nil {
+		return Entry{}, err
+	}
+	dir, err := os.MkdirTemp("", "installer-suggest-*")
+	if err != nil {
+		return Entry{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, asset)
+	url := fmt.Sprintf("%s/%s/releases/download/%s/%s", im.Downloads, repo, rel.Tag, asset)
+	if err := im.download(ctx, url, archive); err != nil {
+		return Entry{}, fmt.Errorf("download %s: %w", asset, err)
+	}
+	tree := filepath.Join(dir, "tree")
+	if err := os.Mkdir(tree, system.DirPerm()); err != nil {
+		return Entry{}, err
+	}
+	if err := extractor.Extract(archive, tree); err != nil {
+		return Entry{}, fmt.Errorf("extract %s: %w", asset, err)
+	}
+
+	p := catalog.Program{Name: e.Name, Repo: repo}
+	for _, b := range installer.SuggestBins(p, tree, rel.Version) {
+		src, err := filepath.Rel(tree, b.Src)
+		if err != nil {
+			continue
+		}
+		e.Bin = append(e.Bin, catalog.Bin{Src: detect.Pattern(filepath.ToSlash(src), rel.Version), Dst: b.Dst})
+	}
+	return e, nil
+}
+
+func (im *Importer) download(ctx context.Context, url, dst string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	// Assets can be large; rely on ctx rather than the API client timeout.
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d for %s", resp.StatusCode, url)
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return extractor.Validate(dst)
+}
+
+// WriteTOML writes entries as catalog.toml program tables, in the layout used
+// by the bundled catalog. Entries without a bin list have their binaries
+// picked interactively on first install.
+func WriteTOML(w io.Writer, entries []Entry) error {
+	for i, e := range entries {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "[programs.%s]\n", tomlKey(e.Name))
+		fmt.Fprintf(w, "repo          = %q\n", e.Repo)
+		fmt.Fprintf(w, "asset_pattern = %q\n", e.AssetPattern)
+		fmt.Fprintln(w, "packages      = []")
+		if e.Brew != "" && e.Brew != e.Name {
+			fmt.Fprintf(w, "brew          = %q\n", e.Brew)
+		}
+		if len(e.Bin) > 0 {
+			bins := make([]string, len(e.Bin))
+			for i, b := range e.Bin {
+				bins[i] = fmt.Sprintf("{src = %q, dst = %q}", b.Src, b.Dst)
+			}
+			fmt.Fprintf(w, "bin           = [%s]\n", strings.Join(bins, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package importer_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	gh "github.com/dsaleh/david-dotfiles/internal/github"
+	"github.com/dsaleh/david-dotfiles/internal/importer"
+)
+
+func TestParseBrewfile(t *testing.T) {
+	names, err := importer.ParseBrewfile(strings.NewReader(`
+tap "homebrew/bundle"
+brew "fzf"
+brew 'ripgrep', args: ["HEAD"]
+brew "neovim/neovim/neovim"
+cask "kitty"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(names) != "[fzf ripgrep neovim]" {
+		t.Errorf("unexpected names %v", names)
+	}
+}
+
+func TestParseRepos(t *testing.T) {
+	repos, err := importer.ParseRepos(strings.NewReader(`
+# my tools
+junegunn/fzf
+https://github.com/BurntSushi/ripgrep.git
+github.com/sharkdp/bat/releases  # trailing path is ignored
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(repos) != "[junegunn/fzf BurntSushi/ripgrep sharkdp/bat]" {
+		t.Errorf("unexpected repos %v", repos)
+	}
+
+	if _, err := importer.ParseRepos(strings.NewReader("not-a-repo\n")); err == nil {
+		t.Error("expected error for invalid line")
+	}
+}
+
+func TestBrewRepo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fd.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"homepage": "https://github.com/sharkdp/fd", "urls": {"stable": {"url": "https://github.com/sharkdp/fd/archive/v10.0.0.tar.gz"}}}`))
+	}))
+	defer srv.Close()
+
+	im := importer.New()
+	im.BrewAPI = srv.URL
+	repo, err := im.BrewRepo(context.Background(), "fd")
+	if err != nil || repo != "sharkdp/fd" {
+		t.Errorf("got %q, %v", repo, err)
+	}
+	if _, err := im.BrewRepo(context.Background(), "missing"); err == nil {
+		t.Error("expected error for unknown formula")
+	}
+}
+
+func TestEntryAndWriteTOML_roundTrip(t *testing.T) {
+	arch := map[string]string{"amd64": "x86_64", "arm64": "aarch64"}[runtime.GOARCH]
+	asset := fmt.Sprintf("tool-1.2.3-%s-%s.tar.gz", runtime.GOOS, arch)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"tag_name": "v1.2.3", "assets": [{"name": %q}, {"name": "checksums.txt"}]}`, asset)
+	}))
+	defer srv.Close()
+
+	im := importer.New()
+	im.GitHub = gh.NewClient(srv.URL)
+	e, err := im.Entry(context.Background(), "", "owner/tool")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e.Name != "tool" || !strings.HasPrefix(e.AssetPattern, "tool-{version}-") {
+		t.Errorf("unexpected entry %+v", e)
+	}
+
+	var buf bytes.Buffer
+	importer.WriteTOML(&buf, []importer.Entry{e})
+	f, _ := os.CreateTemp("", "catalog-*.toml")
+	f.Write(buf.Bytes())
+	f.Close()
+	defer os.Remove(f.Name())
+
+	programs, err := catalog.Load(f.Name())
+	if err != nil {
+		t.Fatalf("generated catalog does not load: %v\n%s", err, buf.String())
+	}
+	if len(programs) != 1 || programs[0].Repo != "owner/tool" {
+		t.Errorf("unexpected programs %+v", programs)
+	}
+}
+
+func TestSuggest_infersBins(t *testing.T) {
+	arch := map[string]string{"amd64": "x86_64", "arm64": "aarch64"}[runtime.GOARCH]
+	asset := fmt.Sprintf("tool-1.2.3-%s-%s.tar.gz", runtime.GOOS, arch)
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	for name, mode := range map[string]int64{"tool-1.2.3/bin/tool": 0755, "tool-1.2.3/README.md": 0644} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: mode, Size: 4})
+		tw.Write([]byte("data"))
+	}
+	tw.Close()
+	gz.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/tool/releases/latest":
+			fmt.Fprintf(w, `{"tag_name": "v1.2.3", "assets": [{"name": %q}]}`, asset)
+		case "/owner/tool/releases/download/v1.2.3/" + asset:
+			w.Write(archive.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	im := importer.New()
+	im.GitHub = gh.NewClient(srv.URL)
+	im.Downloads = srv.URL
+	e, err := im.Suggest(context.Background(), "", "owner/tool")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(e.Bin) != 1 || e.Bin[0] != (catalog.Bin{Src: "tool-{version}/bin/tool", Dst: "tool"}) {
+		t.Errorf("unexpected bins %+v", e.Bin)
+	}
+}
+
+func TestWriteTOML_bins(t *testing.T) {
+	var buf bytes.Buffer
+	importer.WriteTOML(&buf, []importer.Entry{{
+		Name: "rg", Repo: "BurntSushi/ripgrep", AssetPattern: "ripgrep-{version}.tar.gz",
+		Bin: []catalog.Bin{{Src: "ripgrep-{version}/rg", Dst: "rg"}},
+	}})
+	f, _ := os.CreateTemp("", "catalog-*.toml")
+	f.Write(buf.Bytes())
+	f.Close()
+	defer os.Remove(f.Name())
+
+	programs, err := catalog.Load(f.Name())
+	if err != nil {
+		t.Fatalf("generated catalog does not load: %v\n%s", err, buf.String())
+	}
+	if len(programs[0].Bin) != 1 || programs[0].Bin[0].Src != "ripgrep-{version}/rg" {
+		t.Errorf("bins not round-tripped: %+v", programs[0].Bin)
+	}
+}
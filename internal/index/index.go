@@ -0,0 +1,113 @@
+// Package index reads the community catalog index: a JSON list of known
+// programs with vetted asset patterns and bin lists, served over HTTPS.
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+)
+
+// DefaultURL is the index published alongside this repository.
+const DefaultURL = "https://raw.githubusercontent.com/Dasagho/david-dotfiles/main/index.json"
+
+// Entry is one program of the index.
+type Entry struct {
+	Name         string        `json:"name"`
+	Repo         string        `json:"repo"`
+	Description  string        `json:"description"`
+	AssetPattern string        `json:"asset_pattern"`
+	Bin          []catalog.Bin `json:"bin"`
+}
+
+// Client fetches the index.
+type Client struct {
+	URL  string
+	HTTP *http.Client
+}
+
+// New returns a Client for the index at url, or DefaultURL if url is "".
+func New(url string) *Client {
+	if url == "" {
+		url = DefaultURL
+	}
+	return &Client{URL: url, HTTP: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Fetch downloads and decodes the index. Entries missing a name, repo or
+// asset_pattern are dropped.
+func (c *Client) Fetch(ctx context.Context) ([]Entry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch index: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch index %s: status %d", c.URL, resp.StatusCode)
+	}
+	var raw struct {
+		Programs []Entry `json:"programs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("decode index: %w", err)
+	}
+	entries := raw.Programs[:0]
+	for _, e := range raw.Programs {
+		if e.Name != "" && e.Repo != "" && e.AssetPattern != "" {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// Search returns the entries whose name, repo or description contains query,
+// ignoring case. Exact name matches come first, then name matches, then the
+// rest, each group sorted by name.
+func Search(entries []Entry, query string) []Entry {
+	q := strings.ToLower(strings.TrimSpace(query))
+	rank := func(e Entry) int {
+		name := strings.ToLower(e.Name)
+		switch {
+		case name == q:
+			return 0
+		case strings.Contains(name, q):
+			return 1
+		case strings.Contains(strings.ToLower(e.Repo), q), strings.Contains(strings.ToLower(e.Description), q):
+			return 2
+		}
+		return -1
+	}
+	var out []Entry
+	for _, e := range entries {
+		if rank(e) >= 0 {
+			out = append(out, e)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if ri, rj := rank(out[i]), rank(out[j]); ri != rj {
+			return ri < rj
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// Find returns the entry named name.
+func Find(entries []Entry, name string) (Entry, bool) {
+	for _, e := range entries {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
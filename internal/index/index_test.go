@@ -0,0 +1,60 @@
+package index_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dsaleh/david-dotfiles/internal/index"
+)
+
+const sample = `{"programs": [
+	{"name": "ripgrep", "repo": "BurntSushi/ripgrep", "description": "recursive grep", "asset_pattern": "ripgrep-{version}-x86_64-unknown-linux-musl.tar.gz", "bin": [{"src": "ripgrep-{version}-x86_64-unknown-linux-musl/rg", "dst": "rg"}]},
+	{"name": "ripgrep-all", "repo": "phiresky/ripgrep-all", "description": "ripgrep, but also search in PDFs", "asset_pattern": "ripgrep_all-v{version}-x86_64-unknown-linux-musl.tar.gz"},
+	{"name": "fd", "repo": "sharkdp/fd", "description": "a simple alternative to find", "asset_pattern": "fd-v{version}-x86_64-unknown-linux-musl.tar.gz"},
+	{"name": "broken", "repo": "x/broken"}
+]}`
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(sample))
+	}))
+	defer srv.Close()
+
+	entries, err := index.New(srv.URL).Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries (incomplete one dropped), got %d", len(entries))
+	}
+	if len(entries[0].Bin) != 1 || entries[0].Bin[0].Dst != "rg" {
+		t.Errorf("bin not decoded: %+v", entries[0].Bin)
+	}
+}
+
+func TestFetch_notFound(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	if _, err := index.New(srv.URL).Fetch(context.Background()); err == nil {
+		t.Fatal("expected error for 404")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	entries := []index.Entry{
+		{Name: "ripgrep-all", Repo: "phiresky/ripgrep-all"},
+		{Name: "fd", Repo: "sharkdp/fd", Description: "a simple alternative to find"},
+		{Name: "ripgrep", Repo: "BurntSushi/ripgrep"},
+	}
+
+	got := index.Search(entries, "RipGrep")
+	if len(got) != 2 || got[0].Name != "ripgrep" || got[1].Name != "ripgrep-all" {
+		t.Errorf("unexpected ranking %+v", got)
+	}
+	if got := index.Search(entries, "find"); len(got) != 1 || got[0].Name != "fd" {
+		t.Errorf("description search: %+v", got)
+	}
+}
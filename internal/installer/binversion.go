@@ -0,0 +1,39 @@
+package installer
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// versionTimeout bounds a `--version` call, for binaries that ignore the flag
+// and start up instead.
+const versionTimeout = 3 * time.Second
+
+// versionRe matches the first version-looking token of --version output,
+// e.g. "0.10.1" in "NVIM v0.10.1".
+var versionRe = regexp.MustCompile(`\d+\.\d+(\.\d+)?([-+][0-9A-Za-z.-]+)?`)
+
+// binVersion runs the program's main linked binary with --version and returns
+// the version it reports, or "" if it has no links on this machine or prints
+// nothing recognisable. The binary named after the program is preferred.
+func (r *runner) binVersion(ctx context.Context, name string) string {
+	binDir := r.dest.localBin()
+	ps, _ := r.state.Get(name)
+	if binDir == "" || len(ps.Bins) == 0 {
+		return ""
+	}
+	bin := ps.Bins[0]
+	if slices.Contains(ps.Bins, name) {
+		bin = name
+	}
+	ctx, cancel := context.WithTimeout(ctx, versionTimeout)
+	defer cancel()
+	out, _ := exec.CommandContext(ctx, filepath.Join(binDir, bin), "--version").CombinedOutput()
+	first, _, _ := strings.Cut(string(out), "\n")
+	return versionRe.FindString(first)
+}
@@ -0,0 +1,120 @@
+package installer
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	gh "github.com/dsaleh/david-dotfiles/internal/github"
+)
+
+// placement is where a program's files were put by this run.
+type placement struct {
+	dir   string // local path of the install dir
+	owner string // program the install dir is named after: the program itself unless its asset is shared
+	asset string // download URL of the asset extracted there
+}
+
+// assetClaims lets the programs of one run that resolve to the same asset (a
+// suite shipping several tools in one archive) download and extract it once:
+// the first to get there installs it, the others wait and link from its dir.
+type assetClaims struct {
+	mu     sync.Mutex
+	claims map[string]*assetClaim
+}
+
+type assetClaim struct {
+	owner string
+	done  chan struct{} // closed by finish
+	once  sync.Once
+	ok    bool // the owner's install dir is in place; set before done is closed
+}
+
+// claim registers name as installing url. It returns the claim and whether
+// name got it; if not, the returned claim is the owner's.
+func (a *assetClaims) claim(url, name string) (*assetClaim, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if c, ok := a.claims[url]; ok {
+		return c, false
+	}
+	if a.claims == nil {
+		a.claims = map[string]*assetClaim{}
+	}
+	c := &assetClaim{owner: name, done: make(chan struct{})}
+	a.claims[url] = c
+	return c, true
+}
+
+// finish releases the programs waiting on c. Only the first call counts.
+func (c *assetClaim) finish(ok bool) {
+	c.once.Do(func() {
+		c.ok = ok
+		close(c.done)
+	})
+}
+
+// latestReleases memoizes the latest release of each repo for one run, so
+// programs that share a repo (the tools of a multi-tool repo, or a program
+// resolved again after a failed attempt) cost one API request. A lookup in
+// flight is waited on rather than repeated; failed lookups are not kept, so
+// a later attempt asks again.
+type latestReleases struct {
+	mu      sync.Mutex
+	lookups map[string]*latestLookup
+}
+
+type latestLookup struct {
+	done chan struct{} // closed once rel and err are set
+	rel  gh.Release
+	err  error
+}
+
+// get returns the release cached under key, calling fetch if there is none.
+func (l *latestReleases) get(ctx context.Context, key string, fetch func() (gh.Release, error)) (gh.Release, error) {
+	key = strings.ToLower(key) // GitHub repo names are case-insensitive
+	l.mu.Lock()
+	if lk, ok := l.lookups[key]; ok {
+		l.mu.Unlock()
+		select {
+		case <-lk.done:
+			return lk.rel, lk.err
+		case <-ctx.Done():
+			return gh.Release{}, ctx.Err()
+		}
+	}
+	if l.lookups == nil {
+		l.lookups = map[string]*latestLookup{}
+	}
+	lk := &latestLookup{done: make(chan struct{})}
+	l.lookups[key] = lk
+	l.mu.Unlock()
+
+	lk.rel, lk.err = fetch()
+	if lk.err != nil {
+		l.mu.Lock()
+		delete(l.lookups, key)
+		l.mu.Unlock()
+	}
+	close(lk.done)
+	return lk.rel, lk.err
+}
+
+// sharer returns the install dir name of another installed program whose
+// files came from url at version, so p can link from it instead of
+// installing the same asset again. Destinations without a local view of the
+// install dir never share.
+func (r *runner) sharer(ctx context.Context, p catalog.Program, url, version string) (string, bool) {
+	for _, name := range r.state.Names() {
+		ps, _ := r.state.Get(name)
+		dir := r.state.DirOf(name)
+		if name == p.Name || dir == p.Name || ps.Asset != url {
+			continue
+		}
+		if r.dest.liveDir(dir) != "" && r.dest.installedVersion(ctx, dir) == version {
+			return dir, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,363 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dsaleh/david-dotfiles/internal/linker"
+	"github.com/dsaleh/david-dotfiles/internal/remote"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+	"github.com/dsaleh/david-dotfiles/internal/trash"
+)
+
+// destination is where programs end up: this machine or a remote target.
+// Extraction always happens in a local directory (so the bin picker can browse
+// it); commit then makes that directory live at the destination.
+type destination interface {
+	// installedVersion returns the version recorded for name, or "".
+	installedVersion(ctx context.Context, name string) string
+	// stored returns the local path of the tree extracted from the asset
+	// key names (see storeKey) when the destination still has it, so it
+	// need not be extracted again, or "".
+	stored(key string) string
+	// prepare returns the local directory to extract into and a cleanup func.
+	prepare(name string) (dir string, cleanup func(), err error)
+	// commit publishes dir, extracted from the asset key names or returned
+	// by stored, as the program's install dir. A previous install in the way
+	// is kept in old, if non-nil. It returns the local dir the bins are
+	// linked from.
+	commit(ctx context.Context, name, key, dir string, old *trash.Batch) (string, error)
+	// link creates the bin entry dst for src, an absolute path under dir,
+	// using mode. owned reports that dst was placed by an earlier install.
+	link(ctx context.Context, name, dir, src, dst string, mode linker.Mode, owned bool) error
+	// remove deletes the install dir and the named bin entries (placed with
+	// mode) that still belong to it, returning the paths removed. keep leaves
+	// the install dir for other programs that share it. With a non-nil tr
+	// they are moved there instead of deleted.
+	remove(ctx context.Context, name string, bins []string, mode linker.Mode, keep bool, tr *trash.Batch) ([]string, error)
+	// localBin returns the bin dir when this process can inspect and change
+	// its links directly (to lock them or run the linked binaries), or "".
+	localBin() string
+	// liveDir returns the local path of name's install dir, for checking
+	// whether its binaries are running, or "" if it is on another host.
+	liveDir(name string) string
+}
+
+// newDestination picks where a Run with opts installs to. The staging dirs of
+// non-local destinations are created under tmpDir.
+func newDestination(opts Options, tmpDir string) destination {
+	switch {
+	case opts.Target != nil:
+		return remoteDest{target: opts.Target, tmpDir: tmpDir}
+	case opts.Shared:
+		return sharedDest{artifacts: systemDest(tmpDir), share: system.SystemSharePath, bin: system.BinPath()}
+	case opts.System:
+		return systemDest(tmpDir)
+	}
+	return localDest{share: system.SharePath(), bin: system.BinPath()}
+}
+
+// systemDest installs into the /usr/local roots, directly when they are
+// writable and through sudo otherwise.
+func systemDest(tmpDir string) destination {
+	if system.Writable(system.SystemSharePath) && system.Writable(system.SystemBinPath) {
+		return localDest{share: system.SystemSharePath, bin: system.SystemBinPath}
+	}
+	return sudoDest{share: system.SystemSharePath, bin: system.SystemBinPath, tmpDir: tmpDir}
+}
+
+// localDest installs into the store of <share>, makes <share>/<name> a link
+// to the program's entry and links into <bin>: by default ~/.local/share and
+// ~/.local/bin, or the system roots when they are writable. See storeRoot.
+type localDest struct {
+	share string
+	bin   string
+}
+
+func (d localDest) installedVersion(_ context.Context, name string) string {
+	return readVersion(d.share, name)
+}
+
+func readVersion(share, name string) string {
+	current, err := os.ReadFile(filepath.Join(share, name, ".version"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(current))
+}
+
+func (d localDest) stored(key string) string {
+	entry := filepath.Join(storeRoot(d.share), key)
+	if _, err := os.Stat(filepath.Join(entry, ".version")); err != nil {
+		return ""
+	}
+	return entry
+}
+
+// prepare extracts into a fresh dir in the store, on the same filesystem as
+// the entry it becomes.
+func (d localDest) prepare(name string) (string, func(), error) {
+	root := storeRoot(d.share)
+	if err := os.MkdirAll(root, system.DirPerm()); err != nil {
+		return "", func() {}, err
+	}
+	dir, err := os.MkdirTemp(root, "."+name+"-*")
+	if err != nil {
+		return "", func() {}, err
+	}
+	// MkdirTemp creates it 0700; it becomes the install dir.
+	if err := os.Chmod(dir, system.DirPerm()); err != nil {
+		os.RemoveAll(dir)
+		return "", func() {}, err
+	}
+	return dir, func() { os.RemoveAll(dir) }, nil
+}
+
+// commit moves dir into the store as the entry for key and points the install
+// dir at it. A new entry is relabelled for SELinux, best effort: under a
+// nonstandard prefix the labels inherited at extraction may not allow
+// executing the binaries.
+func (d localDest) commit(ctx context.Context, name, key, dir string, old *trash.Batch) (string, error) {
+	entry := filepath.Join(storeRoot(d.share), key)
+	if dir != entry {
+		if err := os.Rename(dir, entry); err != nil {
+			// Another install of this run stored the same tree first.
+			if d.stored(key) == "" {
+				return "", err
+			}
+		} else {
+			system.Restorecon(ctx, entry)
+		}
+	}
+	live := filepath.Join(d.share, name)
+	return live, point(live, entry, old)
+}
+
+func (d localDest) link(_ context.Context, _, _, src, dst string, mode linker.Mode, owned bool) error {
+	return linker.Place(mode, src, d.bin, dst, owned)
+}
+
+func (d localDest) remove(_ context.Context, name string, bins []string, mode linker.Mode, keep bool, tr *trash.Batch) ([]string, error) {
+	dir := filepath.Join(d.share, name)
+	var removed []string
+	for _, b := range bins {
+		if tr != nil {
+			if !linker.Owns(mode, d.bin, b, dir) {
+				continue
+			}
+			if err := tr.Move(filepath.Join(d.bin, b)); err != nil {
+				return removed, err
+			}
+			removed = append(removed, filepath.Join(d.bin, b))
+			continue
+		}
+		ok, err := linker.Remove(mode, d.bin, b, dir)
+		if err != nil {
+			return removed, err
+		}
+		if ok {
+			removed = append(removed, filepath.Join(d.bin, b))
+		}
+	}
+	if keep {
+		return removed, nil
+	}
+	if _, err := os.Stat(dir); err == nil {
+		if tr != nil {
+			err = tr.Move(dir)
+		} else {
+			err = os.RemoveAll(dir)
+		}
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, dir)
+	}
+	return removed, nil
+}
+
+func (d localDest) localBin() string { return d.bin }
+
+func (d localDest) liveDir(name string) string { return filepath.Join(d.share, name) }
+
+// sudoDest installs into root-owned system dirs. Downloading and extracting
+// happen unprivileged in a staging dir; only copying the result into place,
+// linking and removal go through sudo.
+type sudoDest struct {
+	share  string
+	bin    string
+	tmpDir string // parent of the staging dirs; "" means os.TempDir()
+}
+
+func (d sudoDest) installedVersion(_ context.Context, name string) string {
+	return readVersion(d.share, name)
+}
+
+// Installs through sudo are copied into place; there is no store.
+func (sudoDest) stored(string) string { return "" }
+
+func (d sudoDest) prepare(name string) (string, func(), error) {
+	dir, err := os.MkdirTemp(d.tmpDir, "installer-system-"+name+"-*")
+	if err != nil {
+		return "", func() {}, err
+	}
+	return dir, func() { os.RemoveAll(dir) }, nil
+}
+
+func (d sudoDest) commit(ctx context.Context, name, _, dir string, _ *trash.Batch) (string, error) {
+	// MkdirTemp creates the staging dir 0700; the installed copy must be
+	// readable by every user, whatever the umask setting.
+	if err := os.Chmod(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, system.Sudo(ctx, "sh", "-c", `rm -rf "$1" && mkdir -p "$1" && cp -R "$2"/. "$1"`,
+		"sh", filepath.Join(d.share, name), dir)
+}
+
+func (d sudoDest) link(ctx context.Context, name, dir, src, dst string, mode linker.Mode, _ bool) error {
+	if mode != linker.Symlink {
+		return fmt.Errorf("link_mode %s is not supported when installing through sudo", mode)
+	}
+	rel, err := filepath.Rel(dir, src)
+	if err != nil {
+		return err
+	}
+	return system.Sudo(ctx, "sh", "-c",
+		`if [ -e "$1" ] && [ ! -L "$1" ]; then echo "$1 already exists as a regular file" >&2; exit 1; fi; ln -sfn "$2" "$1"`,
+		"sh", filepath.Join(d.bin, dst), filepath.Join(d.share, name, rel))
+}
+
+func (d sudoDest) remove(ctx context.Context, name string, bins []string, _ linker.Mode, keep bool, _ *trash.Batch) ([]string, error) {
+	dir := filepath.Join(d.share, name)
+	var removed []string
+	for _, b := range bins {
+		target := filepath.Join(d.bin, b)
+		src, err := os.Readlink(target)
+		if err != nil || !strings.HasPrefix(src, dir+string(filepath.Separator)) {
+			continue
+		}
+		if err := system.Sudo(ctx, "rm", "-f", target); err != nil {
+			return removed, err
+		}
+		removed = append(removed, target)
+	}
+	if keep {
+		return removed, nil
+	}
+	if _, err := os.Stat(dir); err == nil {
+		if err := system.Sudo(ctx, "rm", "-rf", dir); err != nil {
+			return removed, err
+		}
+		removed = append(removed, dir)
+	}
+	return removed, nil
+}
+
+// Links in root-owned dirs could not be repaired without sudo.
+func (sudoDest) localBin() string { return "" }
+
+func (d sudoDest) liveDir(name string) string { return filepath.Join(d.share, name) }
+
+// sharedDest keeps extracted trees in the system share root, where every user
+// on the box can reuse them, but links into the current user's bin dir.
+// Removing a program only drops this user's links: others may still use the
+// shared tree.
+type sharedDest struct {
+	artifacts destination // installs the shared tree
+	share     string
+	bin       string
+}
+
+func (d sharedDest) dir(name string) string {
+	return filepath.Join(d.share, name)
+}
+
+func (d sharedDest) installedVersion(ctx context.Context, name string) string {
+	return d.artifacts.installedVersion(ctx, name)
+}
+
+func (d sharedDest) stored(key string) string {
+	return d.artifacts.stored(key)
+}
+
+func (d sharedDest) prepare(name string) (string, func(), error) {
+	return d.artifacts.prepare(name)
+}
+
+func (d sharedDest) commit(ctx context.Context, name, key, dir string, old *trash.Batch) (string, error) {
+	return d.artifacts.commit(ctx, name, key, dir, old)
+}
+
+func (d sharedDest) link(_ context.Context, name, dir, src, dst string, mode linker.Mode, owned bool) error {
+	rel, err := filepath.Rel(dir, src)
+	if err != nil {
+		return err
+	}
+	return linker.Place(mode, filepath.Join(d.dir(name), rel), d.bin, dst, owned)
+}
+
+func (d sharedDest) remove(_ context.Context, name string, bins []string, mode linker.Mode, _ bool, _ *trash.Batch) ([]string, error) {
+	var removed []string
+	for _, b := range bins {
+		ok, err := linker.Remove(mode, d.bin, b, d.dir(name))
+		if err != nil {
+			return removed, err
+		}
+		if ok {
+			removed = append(removed, filepath.Join(d.bin, b))
+		}
+	}
+	return removed, nil
+}
+
+func (d sharedDest) localBin() string { return d.bin }
+
+func (d sharedDest) liveDir(name string) string { return d.dir(name) }
+
+// remoteDest extracts into a local staging dir and pushes it over ssh.
+type remoteDest struct {
+	target *remote.Target
+	tmpDir string // parent of the staging dirs; "" means os.TempDir()
+}
+
+func (d remoteDest) installedVersion(ctx context.Context, name string) string {
+	return d.target.InstalledVersion(ctx, name)
+}
+
+// Remote targets get a copy of the tree; there is no store.
+func (remoteDest) stored(string) string { return "" }
+
+func (d remoteDest) prepare(name string) (string, func(), error) {
+	dir, err := os.MkdirTemp(d.tmpDir, "installer-remote-"+name+"-*")
+	if err != nil {
+		return "", func() {}, err
+	}
+	return dir, func() { os.RemoveAll(dir) }, nil
+}
+
+func (d remoteDest) commit(ctx context.Context, name, _, dir string, _ *trash.Batch) (string, error) {
+	return dir, d.target.Push(ctx, dir, name)
+}
+
+func (d remoteDest) link(ctx context.Context, name, dir, src, dst string, mode linker.Mode, _ bool) error {
+	if mode != linker.Symlink {
+		return fmt.Errorf("link_mode %s is not supported for remote targets", mode)
+	}
+	rel, err := filepath.Rel(dir, src)
+	if err != nil {
+		return err
+	}
+	return d.target.Link(ctx, name, rel, dst)
+}
+
+// Remote installs never share a dir (see runner.sharer), so keep is unused.
+func (d remoteDest) remove(ctx context.Context, name string, bins []string, _ linker.Mode, _ bool, _ *trash.Batch) ([]string, error) {
+	return d.target.Remove(ctx, name, bins)
+}
+
+func (remoteDest) localBin() string { return "" }
+
+func (remoteDest) liveDir(string) string { return "" }
@@ -0,0 +1,233 @@
+package installer_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+	"github.com/dsaleh/david-dotfiles/internal/trash"
+)
+
+// harness runs installer.Run against a fake GitHub: an HTTPS test server
+// answering the release API and serving the assets, with faults injected per
+// asset. $HOME is a fresh temp dir, and the run installs one program at a
+// time so the order of events is the same every time.
+type harness struct {
+	t     *testing.T
+	srv   *httptest.Server
+	state *state.State
+
+	mu       sync.Mutex
+	releases map[string]fakeRelease // repo → its latest release
+	hits     map[string]int         // request path → GETs and HEADs served
+}
+
+type fakeRelease struct {
+	tag    string
+	assets map[string]fakeAsset // name → asset
+}
+
+type fakeAsset struct {
+	body  []byte
+	fault fault
+}
+
+// fault is what goes wrong serving an asset.
+type fault int
+
+const (
+	faultNone     fault = iota
+	faultMissing        // listed in the release, but the download is a 404
+	faultTruncate       // the connection drops before the body is complete
+)
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "")
+	if err := system.EnsureBaseDirs(); err != nil {
+		t.Fatal(err)
+	}
+	st, err := state.Load(filepath.Join(home, "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &harness{t: t, state: st, releases: map[string]fakeRelease{}, hits: map[string]int{}}
+	h.srv = httptest.NewTLSServer(http.HandlerFunc(h.serve))
+	t.Cleanup(h.srv.Close)
+	return h
+}
+
+// release makes tag the latest release of repo, with assets.
+func (h *harness) release(repo, tag string, assets map[string]fakeAsset) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.releases[repo] = fakeRelease{tag: tag, assets: assets}
+}
+
+// program returns a catalog entry for owner/name on the fake GitHub, whose
+// asset is name-{version}.tar.gz holding the binary name.
+func (h *harness) program(name string) catalog.Program {
+	return catalog.Program{
+		Name:         name,
+		Repo:         "owner/" + name,
+		APIBase:      h.srv.URL,
+		AssetPattern: name + "-{version}.tar.gz",
+		Bin:          []catalog.Bin{{Src: name, Dst: name}},
+	}
+}
+
+// hitCount returns how many requests for path were served.
+func (h *harness) hitCount(path string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.hits[path]
+}
+
+func (h *harness) serve(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	h.hits[r.URL.Path]++
+	h.mu.Unlock()
+
+	// /repos/<owner>/<repo>/releases/latest
+	if rest, ok := strings.CutPrefix(r.URL.Path, "/repos/"); ok {
+		repo, ok := strings.CutSuffix(rest, "/releases/latest")
+		h.mu.Lock()
+		rel, found := h.releases[repo]
+		h.mu.Unlock()
+		if !ok || !found {
+			http.NotFound(w, r)
+			return
+		}
+		type asset struct {
+			Name string `json:"name"`
+			Size int    `json:"size"`
+			URL  string `json:"browser_download_url"`
+		}
+		out := struct {
+			TagName string  `json:"tag_name"`
+			Assets  []asset `json:"assets"`
+		}{TagName: rel.tag}
+		for name, a := range rel.assets {
+			url := fmt.Sprintf("%s/%s/releases/download/%s/%s", h.srv.URL, repo, rel.tag, name)
+			out.Assets = append(out.Assets, asset{Name: name, Size: len(a.body), URL: url})
+		}
+		json.NewEncoder(w).Encode(out)
+		return
+	}
+
+	// /<owner>/<repo>/releases/download/<tag>/<asset>
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(parts) != 6 || parts[2] != "releases" || parts[3] != "download" {
+		http.NotFound(w, r)
+		return
+	}
+	h.mu.Lock()
+	rel, found := h.releases[parts[0]+"/"+parts[1]]
+	h.mu.Unlock()
+	a, ok := rel.assets[parts[5]]
+	if !found || rel.tag != parts[4] || !ok || a.fault == faultMissing {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if a.fault == faultTruncate {
+		w.Header().Set("Content-Length", strconv.Itoa(len(a.body)+4096))
+	} else {
+		w.Header().Set("Content-Length", strconv.Itoa(len(a.body)))
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(a.body)
+}
+
+// run installs programs and returns the last message of each. Bins are
+// picked the way a headless run picks them.
+func (h *harness) run(programs ...catalog.Program) map[string]installer.ProgressMsg {
+	h.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	opts := installer.Options{
+		State:       h.state,
+		HTTPClient:  h.srv.Client(),
+		RetryDelay:  time.Millisecond,
+		Trash:       trash.Path(),
+		BinTimeout:  time.Second,
+		Downloads:   1,
+		Extractions: 1,
+	}
+	byName := map[string]catalog.Program{}
+	for _, p := range programs {
+		byName[p.Name] = p
+	}
+	last := map[string]installer.ProgressMsg{}
+	for msg := range installer.Run(ctx, programs, opts) {
+		if msg.State == installer.StateAwaitingBinSelection {
+			if bins := installer.SuggestBins(byName[msg.Program], msg.InstallDir, msg.Version); bins != nil {
+				msg.BinCh <- bins
+			}
+		}
+		last[msg.Program] = msg
+	}
+	installer.Cleanup()
+	return last
+}
+
+// tarGz returns a tar.gz archive of executable files, name → content.
+func tarGz(files map[string]string) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gw.Close()
+	return buf.Bytes()
+}
+
+// binary returns the archive of a program name whose binary prints version.
+func binary(name, version string) []byte {
+	return tarGz(map[string]string{name: "#!/bin/sh\necho " + name + " " + version + "\n"})
+}
+
+// minisig returns a minisign signature of data by priv, not prehashed, and
+// the encoded public key to check it with.
+func minisig(priv ed25519.PrivateKey, data []byte) (sig []byte, pubkey string) {
+	id := []byte("keyid-01")
+	s := append(append([]byte("Ed"), id...), ed25519.Sign(priv, data)...)
+	comment := "timestamp:1700000000"
+	global := ed25519.Sign(priv, append(s[10:], comment...))
+	b64 := base64.StdEncoding.EncodeToString
+	sig = []byte("untrusted comment: test\n" + b64(s) + "\ntrusted comment: " + comment + "\n" + b64(global) + "\n")
+	return sig, b64(append(append([]byte("Ed"), id...), priv.Public().(ed25519.PublicKey)...))
+}
+
+func TestMain(m *testing.M) {
+	// Plugins, the journal and the download cache live under $HOME, which
+	// each test points at a temp dir; nothing may leak from the real one.
+	os.Unsetenv("XDG_DATA_HOME")
+	os.Exit(m.Run())
+}
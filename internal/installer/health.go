@@ -0,0 +1,82 @@
+package installer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/sandbox"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// healthTimeout bounds a health_cmd.
+const healthTimeout = 30 * time.Second
+
+// Health is the outcome of running a program's health_cmd.
+type Health struct {
+	Program  string
+	Cmd      string
+	Duration time.Duration
+	Output   string // the command's output, cleaned by sandbox.Clean
+	Err      error  // nil if the command exited 0
+	Hint     string // what to try when SELinux or AppArmor likely caused Err; see system.ExecHint
+}
+
+// Reason is the one-line failure to show for h: the error and, if there is
+// one, the last line of output.
+func (h Health) Reason() string {
+	if h.Err == nil {
+		return ""
+	}
+	if line := sandbox.LastLine(h.Output); line != "" {
+		return h.Err.Error() + ": " + line
+	}
+	return h.Err.Error()
+}
+
+// errUntrustedHealth fails the health check of a program whose catalog may
+// not run commands.
+var errUntrustedHealth = errors.New("not run: its catalog is not trusted")
+
+// CheckHealth runs p's health_cmd, e.g. "nvim --headless +q", in the sandbox
+// without network access. A first word naming a bin in binDir runs that
+// binary, so the check exercises the linked install rather than whatever
+// else is on PATH.
+func CheckHealth(ctx context.Context, p catalog.Program, binDir string) Health {
+	h := Health{Program: p.Name, Cmd: p.HealthCmd}
+	if p.Untrusted {
+		h.Err = errUntrustedHealth
+		return h
+	}
+	args := strings.Fields(p.HealthCmd)
+	if binDir != "" && len(args) > 0 && !strings.ContainsRune(args[0], os.PathSeparator) {
+		if bin := filepath.Join(binDir, args[0]); fileExists(bin) {
+			args[0] = bin
+		}
+	}
+	dir, err := os.MkdirTemp("", "installer-health-*")
+	if err != nil {
+		h.Err = err
+		return h
+	}
+	defer os.RemoveAll(dir)
+	start := time.Now()
+	h.Output, h.Err = sandbox.Run(ctx, args, sandbox.Options{Dir: dir, Timeout: healthTimeout, NoNetwork: true})
+	h.Duration = time.Since(start)
+	if h.Err != nil {
+		if path, err := exec.LookPath(args[0]); err == nil {
+			h.Hint = system.ExecHint(path, h.Err, h.Output)
+		}
+	}
+	return h
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
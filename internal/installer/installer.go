@@ -2,20 +2,30 @@ package installer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/detect"
 	"github.com/dsaleh/david-dotfiles/internal/extractor"
 	gh "github.com/dsaleh/david-dotfiles/internal/github"
 	"github.com/dsaleh/david-dotfiles/internal/linker"
+	"github.com/dsaleh/david-dotfiles/internal/plugin"
+	"github.com/dsaleh/david-dotfiles/internal/remote"
+	"github.com/dsaleh/david-dotfiles/internal/sandbox"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/sumdb"
 	"github.com/dsaleh/david-dotfiles/internal/system"
+	"github.com/dsaleh/david-dotfiles/internal/trash"
 )
 
 // State represents the current install state of a program.
@@ -31,157 +41,1121 @@ const (
 	StateDone
 	StateSkipped
 	StateError
+	StateRemoving // uninstalling a program dropped from the desired set (Options.Apply)
+	StateRemoved
+	StateAwaitingConfirm // an upgrade found the program's binaries running; waiting on ConfirmCh
+	StateAwaitingTriage  // the run's first failure; waiting on TriageCh (Options.Triage)
 )
 
 func (s State) String() string {
 	return [...]string{
 		"pending", "fetching version", "downloading",
 		"extracting", "awaiting bin selection", "linking", "done", "skipped", "error",
+		"removing", "removed", "awaiting confirmation", "awaiting triage",
 	}[s]
 }
 
+// Terminal reports whether s is a final state for a program.
+func (s State) Terminal() bool {
+	switch s {
+	case StateDone, StateSkipped, StateError, StateRemoved:
+		return true
+	}
+	return false
+}
+
+// SkipReason says why a program ended in StateSkipped, so a summary can tell
+// programs that need nothing from ones that were not checked at all.
+type SkipReason int
+
+const (
+	SkipNone        SkipReason = iota
+	SkipUpToDate               // the resolved version is already installed
+	SkipHeld                   // installed at the version pinned in the catalog or state
+	SkipOffline                // GitHub was unreachable; the installed version was kept
+	SkipUnsupported            // the program is not for this system, e.g. a Windows build outside WSL
+)
+
+func (s SkipReason) String() string {
+	return [...]string{"", "up to date", "held", "offline", "unsupported"}[s]
+}
+
 // ProgressMsg is sent over the progress channel for each state transition.
 // When State is StateAwaitingBinSelection, BinCh is non-nil. The receiver
 // must send the selected []catalog.Bin on BinCh (or close it to abort).
+// When State is StateAwaitingConfirm, ConfirmCh is non-nil and Running lists
+// the processes using the current install; the receiver sends true to upgrade
+// anyway or false to skip the program.
+// When State is StateAwaitingTriage, TriageCh is non-nil and Err is the
+// failure; the receiver sends a Triage saying what to do about it.
+// Seq increases by one per message within a run, in channel order.
 type ProgressMsg struct {
-	Program    string
-	State      State
-	Version    string
-	InstallDir string               // set when State == StateAwaitingBinSelection
-	BinCh      chan<- []catalog.Bin // set when State == StateAwaitingBinSelection
-	Err        error
+	Program      string
+	State        State
+	Seq          uint64
+	Time         time.Time
+	Version      string
+	InstallDir   string               // set when State == StateAwaitingBinSelection
+	BinCh        chan<- []catalog.Bin // set when State == StateAwaitingBinSelection
+	Preselect    []catalog.Bin        // bins picked for an earlier release with the same tree layout; set with BinCh if any
+	Bytes        int64                // size of the downloaded asset; set when State == StateExtracting
+	Received     int64                // bytes downloaded so far; set on the StateDownloading updates sent while the built-in client downloads
+	Size         int64                // the asset's size as announced by the server; set with Received, 0 if unknown
+	Running      []system.Process     // set when State == StateAwaitingConfirm
+	ConfirmCh    chan<- bool          // set when State == StateAwaitingConfirm
+	TriageCh     chan<- Triage        // set when State == StateAwaitingTriage
+	Removed      []string             // paths deleted, or moved to the trash; set when State == StateRemoved
+	BinBefore    string               // what the binary's --version reported before an upgrade; set on StateDone
+	BinAfter     string               // what it reports after the upgrade; set with BinBefore
+	Health       *Health              // the program's health_cmd run after an upgrade; set on StateDone if it has one
+	Skip         SkipReason           // set when State == StateSkipped
+	MovedTo      string               // the repo's new owner/name; set on a second StateFetchingVersion when it was renamed
+	AssetPattern string               // the asset_pattern of the asset downloaded instead when the catalog's matched none; set on a later StateFetchingVersion
+	Err          error
+}
+
+// Options configures a Run.
+type Options struct {
+	State   *state.State   // records successful installs; required
+	Verbose bool           // print resolved download URLs and version info to stderr
+	Target  *remote.Target // install on this host over ssh instead of locally
+	System  bool           // install into /usr/local for all users, via sudo if needed
+	Shared  bool           // extract into /usr/local/share but link into this user's ~/.local/bin
+
+	// LinkMode is how binaries are placed in the bin dir unless a program's
+	// catalog entry sets its own link_mode. Empty means symlinks.
+	LinkMode linker.Mode
+
+	// Apply treats the programs passed to Run as the complete desired set:
+	// every program recorded in State but absent from it is uninstalled first.
+	Apply bool
+
+	// Remove lists programs recorded in State to uninstall before the
+	// installs start, as Apply does with the ones it drops. Programs State
+	// does not know fail: nothing of theirs is touched.
+	Remove []string
+
+	// Pauser, if set, lets the caller suspend and resume the run.
+	Pauser *Pauser
+
+	// BinTimeout, if non-zero, bounds how long a program waits on BinCh for
+	// its binaries. When it expires the program fails instead of blocking
+	// forever; callers without a picker (batch runs) rely on it.
+	BinTimeout time.Duration
+
+	// LockLinks fingerprints the symlinks each install makes, for CheckLocks.
+	LockLinks bool
+
+	// DownloadCmd, if set, fetches assets with an external command instead of
+	// the built-in HTTP client, unless a program sets its own download_cmd.
+	// See catalog.CheckDownloadCmd.
+	DownloadCmd string
+
+	// Trash, if set, is the dir (trash.Path) that uninstalled programs and the
+	// install dirs replaced by upgrades are moved to instead of being
+	// deleted. See TrashDir.
+	Trash string
+
+	// Checksums, if set, is the shared checksum database: assets are also
+	// verified against the hashes it holds, recorded on any machine, and
+	// new hashes are appended to it.
+	Checksums *sumdb.DB
+
+	// Authenticated lists the GitHub hosts (catalog.Program.GitHubHost) a
+	// token goes to with requests; see transport.Transport. Release assets
+	// of repos on them are downloaded through the API rather than their
+	// browser URLs, which is what makes assets of private repos
+	// downloadable.
+	Authenticated []string
+
+	// Downloads and Extractions limit how many programs resolve and download,
+	// and how many extract, at the same time. 0 means 3 downloads and one
+	// extraction per CPU, up to 4.
+	Downloads   int
+	Extractions int
+
+	// ConfirmBusy makes an upgrade whose current binaries are running (a
+	// language server, an open editor) ask before replacing them, with a
+	// StateAwaitingConfirm message. Without it such upgrades go ahead.
+	ConfirmBusy bool
+
+	// Triage holds back the run's first failure and asks what to do about
+	// it with a StateAwaitingTriage message: retry, skip or abort. The run
+	// is paused until it is answered.
+	Triage bool
+
+	// WindowsBin is the dir programs marked windows copy their bins into,
+	// set when running under WSL with windows_bin_dir configured. Without
+	// it, or when installing anywhere but the user's own dirs, they are
+	// skipped.
+	WindowsBin string
+
+	// Platform is what system.Platform reports, set when installing into
+	// this machine's own dirs: programs listing it as unsupported are
+	// skipped, and on "termux" Android builds of assets are preferred.
+	Platform string
+
+	// Lock, if set, makes every program install the release it holds for
+	// it (a catalog.lock) instead of its latest or pinned one, and the
+	// download match the recorded sha256. Programs it has no entry for fail.
+	Lock *catalog.Lock
+
+	// HTTPClient, if set, sends the run's GitHub API requests and downloads
+	// instead of http.DefaultClient, e.g. to a fake GitHub in tests.
+	HTTPClient *http.Client
+
+	// RetryDelay is how long a failed download waits before its first
+	// retry, doubling for the next. 0 means one second.
+	RetryDelay time.Duration
 }
 
-const workerCount = 3
+// TrashDir returns the trash dir a Run with o moves files to, or "" if it
+// deletes them: only installs into the user's own dirs use Trash.
+func (o Options) TrashDir() string {
+	if o.Target != nil || o.System || o.Shared {
+		return ""
+	}
+	return o.Trash
+}
+
+// runner holds the dependencies shared by every install in one Run.
+type runner struct {
+	client  *gh.Client
+	state   *state.State
+	plugins *plugin.Runner
+	dest    destination
+	verbose bool
+	tmpDir  string // per-run dir for downloads; "" means os.TempDir()
+	pause   *Pauser
+	mode    linker.Mode // global link mode; see modeFor
+	binWait time.Duration
+	lock    bool
+	confirm bool     // see Options.ConfirmBusy
+	journal *journal // nil for runs that cannot be resumed
+	claims  assetClaims
+	latest  latestReleases
+	trash   string // see Options.Trash; "" when the destination does not use it
+	fetch   string // global download command; see downloadCmdFor
+	sums    *sumdb.DB
+	auth    []string      // see Options.Authenticated
+	winBin  string        // see Options.WindowsBin
+	plat    string        // see Options.Platform
+	locked  *catalog.Lock // see Options.Lock
+	http    *http.Client  // see Options.HTTPClient; nil means http.DefaultClient
+	retry   time.Duration // see Options.RetryDelay
+	triage  *triage       // nil unless Options.Triage
+	cancel  context.CancelFunc
+	net     stage // resolving and downloading; see stages.go
+	disk    stage // extracting
+	e       *emitter
+}
 
 // Run installs the given programs concurrently, sending progress updates to the returned channel.
-// The channel is closed when all installs complete.
-// When verbose is true, resolved download URLs and version info are printed to stderr.
-func Run(ctx context.Context, programs []catalog.Program, verbose bool) <-chan ProgressMsg {
+// The channel is closed when all installs complete. Successful installs are recorded in opts.State.
+// Plugins found in plugin.Dir() are invoked at each lifecycle hook.
+// Downloads go to a per-run dir under system.CachePath(), removed once the run
+// ends (including after a cancelled context or a panicking install).
+func Run(ctx context.Context, programs []catalog.Program, opts Options) <-chan ProgressMsg {
 	ch := make(chan ProgressMsg, len(programs)*8)
-	client := gh.NewClient("")
+	ctx, cancel := context.WithCancel(ctx)
+	r := &runner{
+		cancel:  cancel,
+		state:   opts.State,
+		verbose: opts.Verbose,
+		pause:   opts.Pauser,
+		mode:    opts.LinkMode,
+		binWait: opts.BinTimeout,
+		lock:    opts.LockLinks,
+		confirm: opts.ConfirmBusy,
+		fetch:   opts.DownloadCmd,
+		sums:    opts.Checksums,
+		auth:    opts.Authenticated,
+		winBin:  opts.WindowsBin,
+		plat:    opts.Platform,
+		locked:  opts.Lock,
+		http:    opts.HTTPClient,
+		retry:   opts.RetryDelay,
+		net:     newStage(opts.Downloads, defaultDownloads),
+		disk:    newStage(opts.Extractions, defaultExtractions()),
+		e:       &emitter{ch: ch, last: map[string]ProgressMsg{}},
+	}
+	r.client = r.newClient("")
+	if opts.Triage {
+		r.triage = &triage{}
+	}
+	if dir, err := newRunDir(); err == nil {
+		r.tmpDir = dir
+	} else if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "[verbose] run dir: %v; using %s\n", err, os.TempDir())
+	}
+	r.dest = newDestination(opts, r.tmpDir)
+	r.trash = opts.TrashDir()
+	plugins, err := plugin.Discover(plugin.Dir())
+	if err != nil {
+		if opts.Verbose {
+			fmt.Fprintf(os.Stderr, "[verbose] plugins disabled: %v\n", err)
+		}
+		plugins = &plugin.Runner{}
+	}
+	r.plugins = plugins
+
+	programs = Prioritize(programs)
+
+	removals := removals(opts, programs)
+
+	// Remote installs stage downloads per run; only local runs are journaled.
+	if opts.Target == nil {
+		names := make([]string, len(programs))
+		for i, p := range programs {
+			names[i] = p.Name
+		}
+		r.journal = newJournal(names)
+	}
 
 	go func() {
 		defer close(ch)
-		sem := make(chan struct{}, workerCount)
+		defer cancel()
+		defer r.journal.close()
+		if r.tmpDir != "" {
+			defer os.RemoveAll(r.tmpDir)
+		}
+		// Removals run first so freed bin names can be reused by new installs.
+		for _, name := range removals {
+			r.uninstall(ctx, name)
+		}
+
 		var wg sync.WaitGroup
 
 		for _, p := range programs {
 			p := p
 			wg.Add(1)
-			sem <- struct{}{}
+			r.net <- struct{}{}
+			slot := &netSlot{s: r.net}
 			go func() {
 				defer wg.Done()
-				defer func() { <-sem }()
-				install(ctx, client, p, ch, verbose)
+				defer slot.release()
+				// A panic in one install must not take down the process
+				// (skipping the run dir cleanup) or the other installs.
+				defer func() {
+					if rec := recover(); rec != nil {
+						r.send(ProgressMsg{Program: p.Name, State: StateError, Err: fmt.Errorf("internal error: %v", rec)})
+						r.flush(p.Name)
+					}
+				}()
+				if err := r.pause.wait(ctx); err != nil {
+					r.send(ProgressMsg{Program: p.Name, State: StateError, Err: err})
+					return
+				}
+				r.install(ctx, p, slot)
+				r.triageFailure(ctx, p)
 			}()
 		}
 		wg.Wait()
+		if err := r.state.Save(); err != nil && r.verbose {
+			fmt.Fprintf(os.Stderr, "[verbose] save state: %v\n", err)
+		}
+		r.postRun(ctx, programs)
+		if share := storeShare(r.dest); share != "" {
+			if _, err := pruneStore(share, r.trash); err != nil && r.verbose {
+				fmt.Fprintf(os.Stderr, "[verbose] prune store: %v\n", err)
+			}
+		}
 	}()
 
 	return ch
 }
 
-func send(ch chan<- ProgressMsg, msg ProgressMsg) {
-	ch <- msg
+// Prioritize returns programs in install queue order: higher Priority first,
+// keeping the given order among equal priorities. Workers pick programs up in
+// this order, so critical tools start downloading before the rest.
+func Prioritize(programs []catalog.Program) []catalog.Program {
+	out := slices.Clone(programs)
+	slices.SortStableFunc(out, func(a, b catalog.Program) int {
+		return b.Priority - a.Priority
+	})
+	return out
 }
 
-func install(ctx context.Context, client *gh.Client, p catalog.Program, ch chan<- ProgressMsg, verbose bool) {
-	send(ch, ProgressMsg{Program: p.Name, State: StateFetchingVersion})
+// emitter stamps each message with a timestamp and sequence number before
+// sending it. The lock spans the send so Seq order matches channel order.
+// It also remembers the last message per program for the post-run hook.
+type emitter struct {
+	mu   sync.Mutex
+	seq  uint64
+	ch   chan<- ProgressMsg
+	last map[string]ProgressMsg
+}
 
-	rel, err := client.LatestRelease(ctx, p.Repo)
+func (r *runner) send(msg ProgressMsg) {
+	if msg.State == StateError && r.triage.hold(msg) {
+		return
+	}
+	e := r.e
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.seq++
+	msg.Seq = e.seq
+	msg.Time = time.Now()
+	e.last[msg.Program] = msg
+	switch {
+	case msg.State == StateDone || msg.State == StateSkipped && msg.Skip != SkipOffline:
+		r.state.RecordResult(msg.Program, nil)
+	case msg.State == StateError && !errors.Is(msg.Err, context.Canceled):
+		r.state.RecordResult(msg.Program, msg.Err)
+	}
+	// A cancelled program is left in the journal so it can be resumed.
+	if msg.State.Terminal() && !errors.Is(msg.Err, context.Canceled) {
+		r.journal.finish(msg.Program)
+	}
+	e.ch <- msg
+}
+
+// postRun invokes the post-run plugin hook with each program's final outcome.
+// Failures are only reported in verbose mode: every install has already finished.
+func (r *runner) postRun(ctx context.Context, programs []catalog.Program) {
+	if r.plugins.Len() == 0 {
+		return
+	}
+	results := make([]plugin.Result, 0, len(programs))
+	r.e.mu.Lock()
+	for _, p := range programs {
+		msg := r.e.last[p.Name]
+		res := plugin.Result{Program: p.Name, State: msg.State.String(), Version: msg.Version}
+		if msg.Err != nil {
+			res.Error = msg.Err.Error()
+		}
+		results = append(results, res)
+	}
+	r.e.mu.Unlock()
+	if err := r.plugins.Run(ctx, plugin.Payload{Hook: plugin.PostRun, Results: results}); err != nil && r.verbose {
+		fmt.Fprintf(os.Stderr, "[verbose] %v\n", err)
+	}
+}
+
+// resolveRelease picks the release to install: an explicit catalog/TUI version
+// wins, then a version pinned in state, then the latest GitHub release (or the
+// newest version tag, with use_tags). A held program whose hold_until is
+// satisfied by the latest release gets that release instead. The returned
+// bool reports whether the result is pinned.
+func (r *runner) resolveRelease(ctx context.Context, p catalog.Program) (gh.Release, bool, error) {
+	if r.locked != nil {
+		return r.lockedRelease(p)
+	}
+	held, ok := r.held(p)
+	if !ok {
+		return r.latestRelease(ctx, p)
+	}
+	if p.HoldUntil != "" {
+		rel, _, err := r.latestRelease(ctx, p)
+		if err == nil && p.HoldReleased(rel.Version) {
+			return rel, false, nil
+		}
+		if err != nil && r.verbose {
+			fmt.Fprintf(os.Stderr, "[verbose] %s: hold_until: %v\n", p.Name, err)
+		}
+	}
+	return held, true, nil
+}
+
+// held returns the release p is held at: its catalog/TUI version, else the
+// tag pinned in state.
+func (r *runner) held(p catalog.Program) (gh.Release, bool) {
+	if p.Version != "" {
+		return gh.Release{Tag: p.Version, Version: strings.TrimPrefix(p.Version, "v")}, true
+	}
+	if ps, ok := r.state.Get(p.Name); ok && ps.Pinned && ps.Tag != "" {
+		return gh.Release{Tag: ps.Tag, Version: ps.Version}, true
+	}
+	return gh.Release{}, false
+}
+
+// latestRelease returns the latest GitHub release of p's repo, or its newest
+// version tag with use_tags.
+func (r *runner) latestRelease(ctx context.Context, p catalog.Program) (gh.Release, bool, error) {
+	key := p.GitHubHost() + "/" + p.Repo
+	if p.UseTags {
+		rel, err := r.latest.get(ctx, "tags:"+key, func() (gh.Release, error) { return r.github(p).LatestTag(ctx, p.Repo) })
+		return rel, false, err
+	}
+	rel, err := r.latest.get(ctx, key, func() (gh.Release, error) { return r.github(p).LatestRelease(ctx, p.Repo) })
+	return rel, false, err
+}
+
+// github returns the client for p's repo: the run's own for github.com, one
+// for the API of its GitHub Enterprise Server otherwise.
+func (r *runner) github(p catalog.Program) *gh.Client {
+	if api := p.GitHubAPI(); api != "" {
+		return r.newClient(api)
+	}
+	return r.client
+}
+
+// newClient returns a GitHub client for the API at baseURL ("" for
+// api.github.com) that sends its requests with the run's HTTP client.
+func (r *runner) newClient(baseURL string) *gh.Client {
+	c := gh.NewClient(baseURL)
+	if r.http != nil {
+		c = c.WithHTTPClient(r.http)
+	}
+	return c
+}
+
+// httpClient returns the client for the run's downloads.
+func (r *runner) httpClient() *http.Client {
+	if r.http != nil {
+		return r.http
+	}
+	return http.DefaultClient
+}
+
+// offline reports whether err is a failure to reach GitHub at all, as
+// opposed to an error response, while ctx is still live.
+func offline(ctx context.Context, err error) bool {
+	var uerr *url.Error
+	return ctx.Err() == nil && errors.As(err, &uerr)
+}
+
+// modeFor returns the link mode for p: its catalog link_mode, else the
+// global setting, else symlinks. Under an alternate --root, absolute symlinks
+// would point at host paths, so they are made relative.
+func (r *runner) modeFor(p catalog.Program) linker.Mode {
+	if p.Windows {
+		// Windows cannot follow the symlinks WSL makes.
+		return linker.Copy
+	}
+	mode := linker.Symlink
+	if p.LinkMode != "" {
+		mode = linker.Mode(p.LinkMode)
+	} else if r.mode != "" {
+		mode = r.mode
+	}
+	if mode == linker.Symlink && system.Root() != "" {
+		return linker.Relative
+	}
+	return mode
+}
+
+// unsupported reports whether p is not for this platform: it lists it as
+// unsupported, or is a Windows build with nowhere to go.
+func (r *runner) unsupported(p catalog.Program) bool {
+	return (r.plat != "" && slices.Contains(p.Unsupported, r.plat)) || (p.Windows && r.binDirFor(p) == "")
+}
+
+// binDirFor returns the bin dir p's bins go to when it is not the
+// destination's own: windows_bin_dir for programs marked windows. It is ""
+// otherwise, and for a windows program that cannot be installed here.
+func (r *runner) binDirFor(p catalog.Program) string {
+	if _, local := r.dest.(localDest); !p.Windows || !local {
+		return ""
+	}
+	return r.winBin
+}
+
+// destFor returns the run's destination, with its bin dir replaced by binDir
+// if set.
+func (r *runner) destFor(binDir string) destination {
+	if d, ok := r.dest.(localDest); ok && binDir != "" {
+		d.bin = binDir
+		return d
+	}
+	return r.dest
+}
+
+// record stores a completed install in state. linked lists the bin names
+// created by this install; they are merged with those recorded earlier, since
+// older links still point into the install dir. at is where the files were
+// placed, nil if they were already in place. A failure to persist state does
+// not fail the install — the program is on disk either way.
+func (r *runner) record(p catalog.Program, rel gh.Release, pinned bool, linked []string, at *placement) {
+	prev, _ := r.state.Get(p.Name)
+	asset, sharedWith := prev.Asset, prev.SharedWith
+	if at != nil {
+		asset, sharedWith = at.asset, ""
+		if at.owner != p.Name {
+			sharedWith = at.owner
+		}
+	}
+	bins := prev.Bins
+	for _, b := range linked {
+		if !slices.Contains(bins, b) {
+			bins = append(bins, b)
+		}
+	}
+	mode := prev.LinkMode
+	if len(linked) > 0 {
+		mode = string(r.modeFor(p))
+	}
+	locks := prev.Locks
+	if r.lock && len(linked) > 0 {
+		locks = r.lockLinks(prev.Locks, linked)
+	}
+	r.state.Set(p.Name, state.ProgramState{
+		Version:     rel.Version,
+		Tag:         rel.Tag,
+		Pinned:      pinned,
+		InstalledAt: time.Now(),
+		Bins:        bins,
+		LinkMode:    mode,
+		Asset:       asset,
+		SharedWith:  sharedWith,
+		Locks:       locks,
+		BinDir:      r.binDirFor(p),
+	})
+	if err := r.state.Save(); err != nil && r.verbose {
+		fmt.Fprintf(os.Stderr, "[verbose] %s: save state: %v\n", p.Name, err)
+	}
+}
+
+// install installs p. It gives up its place in the download stage, slot,
+// once the asset is downloaded and verified.
+func (r *runner) install(ctx context.Context, p catalog.Program, slot *netSlot) {
+	if r.unsupported(p) {
+		r.send(ProgressMsg{Program: p.Name, State: StateSkipped, Skip: SkipUnsupported})
+		return
+	}
+	r.send(ProgressMsg{Program: p.Name, State: StateFetchingVersion})
+
+	if err := r.plugins.Run(ctx, plugin.Payload{Hook: plugin.PreResolve, Program: p.Name, Repo: p.Repo}); err != nil {
+		r.send(ProgressMsg{Program: p.Name, State: StateError, Err: err})
+		return
+	}
+
+	rel, pinned, err := r.resolveRelease(ctx, p)
 	if err != nil {
-		send(ch, ProgressMsg{Program: p.Name, State: StateError, Err: err})
+		// Without a network an installed program is still usable; only a
+		// missing one is a failure.
+		if installed := r.dest.installedVersion(ctx, r.state.DirOf(p.Name)); installed != "" && offline(ctx, err) {
+			r.send(ProgressMsg{Program: p.Name, State: StateSkipped, Skip: SkipOffline, Version: installed})
+			return
+		}
+		r.send(ProgressMsg{Program: p.Name, State: StateError, Err: err})
 		return
 	}
 	version := rel.Version
+	if rel.MovedTo != "" {
+		r.send(ProgressMsg{Program: p.Name, State: StateFetchingVersion, MovedTo: rel.MovedTo})
+	}
 
 	// Check if already installed at this version.
-	installDir := filepath.Join(system.SharePath(), p.Name)
-	versionFile := filepath.Join(installDir, ".version")
-	if current, err := os.ReadFile(versionFile); err == nil {
-		if strings.TrimSpace(string(current)) == version {
-			send(ch, ProgressMsg{Program: p.Name, State: StateSkipped, Version: version})
+	dirName := r.state.DirOf(p.Name)
+	installed := r.dest.installedVersion(ctx, dirName)
+	if r.upToDate(ctx, p, rel, installed) {
+		// In a shared root another user may have extracted this version;
+		// link it for this user instead of downloading it again.
+		if sd, ok := r.dest.(sharedDest); ok {
+			if ps, _ := r.state.Get(p.Name); ps.Version != version {
+				_, url := r.assetURL(p, rel)
+				r.linkBins(ctx, p, rel, pinned, placement{dir: sd.dir(dirName), owner: dirName, asset: url}, "")
+				return
+			}
+		}
+		r.record(p, rel, pinned, nil, nil)
+		skip := SkipUpToDate
+		if pinned {
+			skip = SkipHeld
+		}
+		r.send(ProgressMsg{Program: p.Name, State: StateSkipped, Skip: skip, Version: version})
+		return
+	}
+
+	var before string // what the current binary reports, for the summary
+	if installed != "" {
+		if !r.confirmBusy(ctx, p, version) {
 			return
 		}
+		before = r.binVersion(ctx, p.Name)
 	}
 
-	// Resolve download URL.
-	// Use the raw tag (e.g. "v15.1.0" or "15.1.0") as the path segment so the
-	// URL matches exactly what GitHub has, regardless of whether the repo uses
-	// a "v"-prefixed tag or a bare version tag.
-	assetName := strings.ReplaceAll(p.AssetPattern, "{version}", version)
-	downloadURL := fmt.Sprintf("https://github.com/%s/releases/download/%s/%s", p.Repo, rel.Tag, assetName)
+	assetName, downloadURL := r.assetURL(p, rel)
 
-	if verbose {
+	if r.verbose {
 		fmt.Fprintf(os.Stderr, "[verbose] %s: version=%s url=%s\n", p.Name, version, downloadURL)
 	}
 
-	// Download with retry.
-	send(ch, ProgressMsg{Program: p.Name, State: StateDownloading, Version: version})
-	tmpFile, err := downloadWithRetry(ctx, downloadURL, assetName)
+	// Another program may have installed the same asset already, in an
+	// earlier run or concurrently in this one; link from its dir instead.
+	var claim *assetClaim
+	if r.dest.liveDir(p.Name) != "" {
+		if dir, ok := r.sharer(ctx, p, downloadURL, version); ok {
+			r.linkBins(ctx, p, rel, pinned, placement{dir: r.dest.liveDir(dir), owner: dir, asset: downloadURL}, before)
+			return
+		}
+		var mine bool
+		if claim, mine = r.claims.claim(downloadURL, p.Name); !mine {
+			select {
+			case <-claim.done:
+			case <-ctx.Done():
+				r.send(ProgressMsg{Program: p.Name, State: StateError, Err: ctx.Err()})
+				return
+			}
+			if !claim.ok {
+				r.send(ProgressMsg{Program: p.Name, State: StateError, Err: fmt.Errorf("shares its asset with %s, which failed to install", claim.owner)})
+				return
+			}
+			r.linkBins(ctx, p, rel, pinned, placement{dir: r.dest.liveDir(claim.owner), owner: claim.owner, asset: downloadURL}, before)
+			return
+		}
+		defer claim.finish(false)
+	}
+
+	// downloadURL identifies the asset (in the state, the checksum database
+	// and the download cache); fetchURL is where the built-in client gets it.
+	cmd := r.downloadCmdFor(p)
+	if r.authenticated(p) && cmd == "" && p.AssetURL == "" && rel.Assets == nil {
+		// Pinned releases are resolved without an API call; fetch the list now.
+		if full, err := r.github(p).ReleaseByTag(ctx, p.Repo, rel.Tag); err == nil {
+			rel = full
+		}
+	}
+	fetchURL := r.assetAPIURL(p, rel, assetName, cmd)
+	// An asset the API lists exists; only the others need checking.
+	if fetchURL == "" {
+		fallback, err := r.checkAsset(ctx, p, rel, assetName, downloadURL)
+		if err != nil {
+			r.send(ProgressMsg{Program: p.Name, State: StateError, Err: err})
+			return
+		}
+		if fallback.name != "" {
+			if r.verbose {
+				fmt.Fprintf(os.Stderr, "[verbose] %s: %s not in release %s; using %s\n", p.Name, assetName, rel.Tag, fallback.url)
+			}
+			assetName, downloadURL = fallback.name, fallback.url
+			r.send(ProgressMsg{Program: p.Name, State: StateFetchingVersion, Version: version, AssetPattern: fallback.pattern})
+		}
+		fetchURL = r.assetAPIURL(p, rel, assetName, cmd)
+	}
+	if fetchURL == "" {
+		fetchURL = downloadURL
+	} else if r.verbose {
+		fmt.Fprintf(os.Stderr, "[verbose] %s: downloading through the API: %s\n", p.Name, fetchURL)
+	}
+
+	// Reuse an asset downloaded by an interrupted run, else download with retry.
+	tmpFile := cachedAsset(downloadURL, assetName)
+	var size int64
+	if extractor.Validate(tmpFile) != nil {
+		r.send(ProgressMsg{Program: p.Name, State: StateDownloading, Version: version})
+		progress := func(received, size int64) {
+			r.send(ProgressMsg{Program: p.Name, State: StateDownloading, Version: version, Received: received, Size: size})
+		}
+		downloaded, err := r.downloadWithRetry(ctx, fetchURL, assetName, cmd, progress)
+		if err != nil {
+			r.send(ProgressMsg{Program: p.Name, State: StateError, Err: fmt.Errorf("download: %w", err)})
+			return
+		}
+		if err := extractor.Validate(downloaded); err != nil {
+			os.Remove(downloaded)
+			r.send(ProgressMsg{Program: p.Name, State: StateError, Err: fmt.Errorf("download: %w", err)})
+			return
+		}
+		if info, err := os.Stat(downloaded); err == nil {
+			size = info.Size()
+		}
+		// Keep it outside the run dir until installed, for a resumed run.
+		if os.MkdirAll(downloadsDir(), system.DirPerm()) != nil || os.Rename(downloaded, tmpFile) != nil {
+			tmpFile = downloaded
+		}
+	}
+	defer os.Remove(tmpFile)
+
+	sum, err := fileSHA256(tmpFile)
 	if err != nil {
-		send(ch, ProgressMsg{Program: p.Name, State: StateError, Err: fmt.Errorf("download: %w", err)})
+		r.send(ProgressMsg{Program: p.Name, State: StateError, Err: fmt.Errorf("checksum: %w", err)})
 		return
 	}
-	defer os.Remove(tmpFile)
+	// Rolling builds change under the same URL by design; nothing to compare.
+	if !p.Rolling {
+		if err := r.verifyChecksum(p.Name, version, downloadURL, sum); err != nil {
+			r.send(ProgressMsg{Program: p.Name, State: StateError, Err: err})
+			return
+		}
+	}
+
+	if p.Signature != "" {
+		if err := r.verifySignature(ctx, p, rel, assetName, downloadURL, tmpFile); err != nil {
+			r.send(ProgressMsg{Program: p.Name, State: StateError, Err: err})
+			return
+		}
+	}
 
 	// Extract / copy.
-	send(ch, ProgressMsg{Program: p.Name, State: StateExtracting, Version: version})
-	if err := os.MkdirAll(installDir, 0755); err != nil {
-		send(ch, ProgressMsg{Program: p.Name, State: StateError, Err: err})
-		return
+	slot.release()
+	var old *trash.Batch
+	if ps, ok := r.state.Get(p.Name); ok {
+		old = r.batch(p.Name, "upgrade", &ps)
 	}
-	if err := extractor.Extract(tmpFile, installDir); err != nil {
-		send(ch, ProgressMsg{Program: p.Name, State: StateError, Err: fmt.Errorf("extract: %w", err)})
+	key := storeKey(sum, version)
+	installDir := r.dest.stored(key)
+	if installDir != "" {
+		// Extracted before: an earlier release restored, or the same asset
+		// under another program or URL.
+		if r.verbose {
+			fmt.Fprintf(os.Stderr, "[verbose] %s: %s is in the store\n", p.Name, key)
+		}
+	} else {
+		dir, cleanup, err := r.dest.prepare(p.Name)
+		defer cleanup()
+		if err != nil {
+			r.send(ProgressMsg{Program: p.Name, State: StateError, Err: err})
+			return
+		}
+		installDir = dir
+		extracting := ProgressMsg{Program: p.Name, State: StateExtracting, Version: version, Bytes: size}
+		if err := r.unpack(ctx, p, tmpFile, installDir, extracting); err != nil {
+			r.send(ProgressMsg{Program: p.Name, State: StateError, Err: fmt.Errorf("extract: %w", err)})
+			return
+		}
+
+		if err := r.plugins.Run(ctx, plugin.Payload{Hook: plugin.PostExtract, Program: p.Name, Repo: p.Repo, Version: version, InstallDir: installDir}); err != nil {
+			r.send(ProgressMsg{Program: p.Name, State: StateError, Err: err})
+			return
+		}
+
+		// Write version file.
+		os.WriteFile(filepath.Join(installDir, ".version"), []byte(version), system.FilePerm())
+	}
+
+	installDir, err = r.dest.commit(ctx, p.Name, key, installDir, old)
+	if err != nil {
+		r.send(ProgressMsg{Program: p.Name, State: StateError, Err: fmt.Errorf("push: %w", err)})
 		return
 	}
+	if claim != nil {
+		claim.finish(true)
+	}
+
+	r.linkBins(ctx, p, rel, pinned, placement{dir: installDir, owner: p.Name, asset: downloadURL}, before)
+}
+
+// unpack extracts archive into installDir once the extraction stage has
+// room, sending extracting as it starts.
+func (r *runner) unpack(ctx context.Context, p catalog.Program, archive, installDir string, extracting ProgressMsg) error {
+	if err := r.disk.enter(ctx); err != nil {
+		return err
+	}
+	defer r.disk.leave()
+	r.send(extracting)
+	return r.extract(ctx, p, archive, installDir)
+}
+
+// confirmBusy checks whether binaries of p's current install are running and,
+// if so and Options.ConfirmBusy is set, asks whether to upgrade anyway. It
+// reports whether the install should go on; when not, p has been failed.
+func (r *runner) confirmBusy(ctx context.Context, p catalog.Program, version string) bool {
+	dir := r.dest.liveDir(r.state.DirOf(p.Name))
+	if dir == "" {
+		return true
+	}
+	running := system.RunningIn(dir)
+	if len(running) == 0 {
+		return true
+	}
+	if !r.confirm {
+		if r.verbose {
+			fmt.Fprintf(os.Stderr, "[verbose] %s: upgrading while %d process(es) run from %s\n", p.Name, len(running), dir)
+		}
+		return true
+	}
+	confirmCh := make(chan bool, 1)
+	r.send(ProgressMsg{Program: p.Name, State: StateAwaitingConfirm, Version: version, Running: running, ConfirmCh: confirmCh})
+	select {
+	case ok := <-confirmCh:
+		if ok {
+			return true
+		}
+		r.send(ProgressMsg{Program: p.Name, State: StateError, Err: fmt.Errorf(
+			"not upgraded: %s is running (pid %d) — close it and re-run", filepath.Base(running[0].Exe), running[0].PID)})
+	case <-ctx.Done():
+		r.send(ProgressMsg{Program: p.Name, State: StateError, Err: ctx.Err()})
+	}
+	return false
+}
 
-	// Write version file.
-	os.WriteFile(versionFile, []byte(version), 0644)
+// linkBins asks for the binaries to link from the install dir at, links them
+// and records the install. before is the version the previous install's
+// binary reported, if this is an upgrade; the new one is then reported
+// alongside it.
+func (r *runner) linkBins(ctx context.Context, p catalog.Program, rel gh.Release, pinned bool, at placement, before string) {
+	version := rel.Version
+	installDir := at.dir
 
 	// Ask the TUI to let the user select which binaries to symlink.
+	lay := layout(installDir, version)
 	binCh := make(chan []catalog.Bin, 1)
-	send(ch, ProgressMsg{
+	r.send(ProgressMsg{
 		Program:    p.Name,
 		State:      StateAwaitingBinSelection,
 		Version:    version,
 		InstallDir: installDir,
 		BinCh:      binCh,
+		Preselect:  r.remembered(p, lay, installDir, version),
 	})
 
 	// Block until the TUI sends back the selected bins (or closes the channel).
-	bins, ok := <-binCh
+	var timeout <-chan time.Time
+	if r.binWait > 0 {
+		t := time.NewTimer(r.binWait)
+		defer t.Stop()
+		timeout = t.C
+	}
+	var bins []catalog.Bin
+	ok := false
+	select {
+	case bins, ok = <-binCh:
+	case <-timeout:
+		r.send(ProgressMsg{Program: p.Name, State: StateError, Err: fmt.Errorf(
+			"no binaries selected within %s and none could be guessed from %s — add a bin list to its catalog entry", r.binWait, installDir)})
+		return
+	case <-ctx.Done():
+		r.send(ProgressMsg{Program: p.Name, State: StateError, Err: ctx.Err()})
+		return
+	}
 	if !ok || len(bins) == 0 {
 		// User cancelled or chose nothing — mark as done without linking.
-		send(ch, ProgressMsg{Program: p.Name, State: StateDone, Version: version})
+		r.record(p, rel, pinned, nil, &at)
+		r.send(ProgressMsg{Program: p.Name, State: StateDone, Version: version})
 		return
 	}
+	r.remember(p, lay, installDir, version, bins)
 
 	// Symlink binaries.
-	send(ch, ProgressMsg{Program: p.Name, State: StateLinking, Version: version})
-	binDir := system.BinPath()
+	r.send(ProgressMsg{Program: p.Name, State: StateLinking, Version: version})
+	pluginBins := make([]plugin.Bin, len(bins))
+	for i, b := range bins {
+		pluginBins[i] = plugin.Bin{Src: b.Src, Dst: b.Dst}
+	}
+	if err := r.plugins.Run(ctx, plugin.Payload{Hook: plugin.PreLink, Program: p.Name, Repo: p.Repo, Version: version, InstallDir: installDir, Bins: pluginBins}); err != nil {
+		r.send(ProgressMsg{Program: p.Name, State: StateError, Err: err})
+		return
+	}
+	prev, _ := r.state.Get(p.Name)
+	mode := r.modeFor(p)
+	linked := make([]string, 0, len(bins))
 	for _, b := range bins {
-		if err := linker.Link(b.Src, binDir, b.Dst); err != nil {
-			send(ch, ProgressMsg{Program: p.Name, State: StateError, Err: fmt.Errorf("link %s: %w", b.Dst, err)})
+		if err := r.destFor(r.binDirFor(p)).link(ctx, at.owner, installDir, b.Src, b.Dst, mode, slices.Contains(prev.Bins, b.Dst)); err != nil {
+			r.send(ProgressMsg{Program: p.Name, State: StateError, Err: fmt.Errorf("link %s: %w", b.Dst, err)})
 			return
 		}
+		linked = append(linked, b.Dst)
+	}
+
+	r.record(p, rel, pinned, linked, &at)
+	done := ProgressMsg{Program: p.Name, State: StateDone, Version: version}
+	if before != "" {
+		done.BinBefore, done.BinAfter = before, r.binVersion(ctx, p.Name)
+	}
+	// Catch an upgrade that broke the program now rather than at its next
+	// use.
+	if binDir := r.dest.localBin(); prev.Version != "" && p.HealthCmd != "" && binDir != "" {
+		h := CheckHealth(ctx, p, binDir)
+		done.Health = &h
+	}
+	r.send(done)
+}
+
+// Removals returns the programs recorded in st that are not in programs,
+// i.e. what an Apply run would uninstall.
+func Removals(st *state.State, programs []catalog.Program) []string {
+	keep := make(map[string]bool, len(programs))
+	for _, p := range programs {
+		keep[p.Name] = true
+	}
+	var out []string
+	for _, name := range st.Names() {
+		if !keep[name] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// removals returns what a Run with opts uninstalls before installing
+// programs: what Apply drops, then Options.Remove.
+func removals(opts Options, programs []catalog.Program) []string {
+	var out []string
+	if opts.Apply {
+		out = Removals(opts.State, programs)
+	}
+	for _, name := range opts.Remove {
+		if !slices.Contains(out, name) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// uninstall removes a program's install dir and the bin links recorded for it,
+// then forgets it in state. An install dir other programs share is kept until
+// the last of them is removed.
+func (r *runner) uninstall(ctx context.Context, name string) {
+	r.send(ProgressMsg{Program: name, State: StateRemoving})
+	ps, ok := r.state.Get(name)
+	if !ok {
+		r.send(ProgressMsg{Program: name, State: StateError, Err: fmt.Errorf("%s is not installed", name)})
+		return
+	}
+	dir := r.state.DirOf(name)
+	keep := len(r.state.DirUsers(dir, name)) > 0
+	removed, err := r.destFor(ps.BinDir).remove(ctx, dir, ps.Bins, linker.Mode(ps.LinkMode), keep, r.batch(name, "uninstall", &ps))
+	if err != nil {
+		r.send(ProgressMsg{Program: name, State: StateError, Err: fmt.Errorf("remove: %w", err)})
+		return
+	}
+	r.state.Delete(name)
+	if err := r.state.Save(); err != nil && r.verbose {
+		fmt.Fprintf(os.Stderr, "[verbose] %s: save state: %v\n", name, err)
+	}
+	r.send(ProgressMsg{Program: name, State: StateRemoved, Version: ps.Version, Removed: removed})
+}
+
+// batch opens a trash batch for program, or returns nil when this run does
+// not keep removed files.
+func (r *runner) batch(program, reason string, ps *state.ProgramState) *trash.Batch {
+	if r.trash == "" {
+		return nil
+	}
+	return trash.Open(r.trash, program, reason, ps)
+}
+
+// AssetMismatchError reports that a program's asset_pattern matched no asset
+// of the resolved release, typically because upstream renamed its artifacts.
+type AssetMismatchError struct {
+	Program   string
+	Pattern   string   // the catalog asset_pattern
+	Asset     string   // the name the pattern expanded to
+	Tag       string   // release that was checked
+	Available []string // the release's real asset names; empty if unknown
+
+	// Suggestion is the asset_pattern of the closest available asset, or ""
+	// if none is close enough to guess.
+	Suggestion string
+}
+
+func (e *AssetMismatchError) Error() string {
+	if len(e.Available) == 0 {
+		return fmt.Sprintf("asset %s not found in release %s — check asset_pattern %q", e.Asset, e.Tag, e.Pattern)
+	}
+	msg := fmt.Sprintf("asset not found for pattern %q (%s) — available assets are: %s", e.Pattern, e.Asset, strings.Join(e.Available, ", "))
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf("; did you mean %q?", e.Suggestion)
+	}
+	return msg
+}
+
+// verifyChecksum compares sum, the SHA-256 of the asset downloaded from url,
+// with the one recorded when it was first installed (here, or on the machine
+// the state file came from) and with the one in the checksum database,
+// recording it where there is none yet.
+func (r *runner) verifyChecksum(program, version, url, sum string) error {
+	if e, ok := r.lockedAsset(program, url); ok && e.SHA256 != "" && e.SHA256 != sum {
+		return fmt.Errorf("checksum mismatch for %s: got sha256 %s, the lock file has %s — the release may have been re-tagged or tampered with", url, sum, e.SHA256)
+	}
+	known, ok := r.state.Checksum(url)
+	if ok && known != sum {
+		return fmt.Errorf("checksum mismatch for %s: got sha256 %s, recorded %s at first install — the release may have been re-tagged or tampered with", url, sum, known)
+	}
+	if r.sums != nil {
+		if e, ok := r.sums.Lookup(url); ok && e.SHA256 != sum {
+			return fmt.Errorf("checksum mismatch for %s: got sha256 %s, recorded %s by %s on %s — the release may have been re-tagged or tampered with", url, sum, e.SHA256, e.Host, e.Time.Format("2006-01-02"))
+		}
+		if err := r.sums.Add(sumdb.Entry{URL: url, SHA256: sum, Program: program, Version: version}); err != nil && r.verbose {
+			fmt.Fprintf(os.Stderr, "[verbose] checksum database: %v\n", err)
+		}
 	}
+	if !ok {
+		r.state.SetChecksum(url, sum)
+	}
+	return nil
+}
 
-	send(ch, ProgressMsg{Program: p.Name, State: StateDone, Version: version})
+// assetFallback is the release asset used in place of one that the
+// asset_pattern named but the release does not have.
+type assetFallback struct {
+	name, url string
+	pattern   string // asset_pattern matching name
 }
 
-func downloadWithRetry(ctx context.Context, url, assetName string) (string, error) {
+// checkAsset issues a HEAD request for the asset URL so that a wrong
+// asset_pattern is caught before three download retries end in a bare 404.
+// Upstreams sometimes rename their artifacts between releases; when the
+// closest asset of the release is for the same platform, it is returned to be
+// downloaded instead. Otherwise the error lists the real asset names. Any
+// outcome other than 404 is left for the download itself to report.
+func (r *runner) checkAsset(ctx context.Context, p catalog.Program, rel gh.Release, assetName, url string) (assetFallback, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return assetFallback{}, err
+	}
+	resp, err := r.httpClient().Do(req)
+	if err != nil {
+		return assetFallback{}, nil
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		return assetFallback{}, nil
+	}
+	if p.AssetURL != "" {
+		// Not a release asset, so there is no list to suggest from.
+		return assetFallback{}, fmt.Errorf("%s: %s not found at %s", p.Name, assetName, url)
+	}
+
+	if rel.Assets == nil {
+		// Pinned releases are resolved without an API call; fetch the list now.
+		if full, err := r.github(p).ReleaseByTag(ctx, p.Repo, rel.Tag); err == nil {
+			rel = full
+		}
+	}
+	mismatch := &AssetMismatchError{Program: p.Name, Pattern: p.AssetPattern, Asset: assetName, Tag: rel.Tag, Available: rel.Assets}
+	if closest, ok := detect.Closest(assetName, rel.Assets); ok {
+		mismatch.Suggestion = detect.Pattern(closest, rel.Version)
+		if u := rel.URLs[closest]; u != "" && detect.SamePlatform(assetName, closest) {
+			return assetFallback{name: closest, url: u, pattern: mismatch.Suggestion}, nil
+		}
+	}
+	return assetFallback{}, mismatch
+}
+
+// assetAPIURL returns the API URL of the release asset assetName when the run
+// is authenticated and the built-in client downloads it, and "" otherwise.
+// A browser URL of a private repo 404s even with a token; the API URL works.
+func (r *runner) assetAPIURL(p catalog.Program, rel gh.Release, assetName, cmd string) string {
+	if !r.authenticated(p) || cmd != "" || p.AssetURL != "" {
+		return ""
+	}
+	return rel.APIURLs[assetName]
+}
+
+// authenticated reports whether a token goes with requests for p's repo.
+func (r *runner) authenticated(p catalog.Program) bool {
+	return slices.Contains(r.auth, p.GitHubHost())
+}
+
+// downloadWithRetry downloads url, with the external command cmd if set.
+// The built-in client calls progress as the download advances.
+func (r *runner) downloadWithRetry(ctx context.Context, url, assetName, cmd string, progress func(received, size int64)) (string, error) {
+	delay := r.retry
+	if delay == 0 {
+		delay = time.Second
+	}
 	var lastErr error
 	for attempt := 0; attempt < 3; attempt++ {
 		if attempt > 0 {
 			select {
 			case <-ctx.Done():
 				return "", ctx.Err()
-			case <-time.After(time.Duration(1<<uint(attempt-1)) * time.Second):
+			case <-time.After(delay << uint(attempt-1)):
 			}
 		}
-		path, err := download(ctx, url, assetName)
+		if err := r.pause.wait(ctx); err != nil {
+			return "", err
+		}
+		var path string
+		var err error
+		if cmd != "" {
+			path, err = r.downloadWith(ctx, cmd, url, assetName)
+		} else {
+			path, err = r.download(ctx, url, assetName, progress)
+		}
 		if err == nil {
 			return path, nil
 		}
@@ -190,12 +1164,105 @@ func downloadWithRetry(ctx context.Context, url, assetName string) (string, erro
 	return "", lastErr
 }
 
-func download(ctx context.Context, url, assetName string) (string, error) {
+// extract unpacks archive into installDir, with p's extract_cmd if it has
+// one and the built-in extractor otherwise.
+func (r *runner) extract(ctx context.Context, p catalog.Program, archive, installDir string) error {
+	if p.ExtractCmd == "" {
+		return extractor.Extract(archive, installDir)
+	}
+	if p.Untrusted {
+		return fmt.Errorf("extract_cmd not run: its catalog is not trusted to run commands (approve it interactively or set catalog_trust)")
+	}
+	// Run in a fresh dir beside installDir, so its contents can be renamed
+	// into place and nothing half-extracted lands there on failure.
+	dir, err := os.MkdirTemp(filepath.Dir(installDir), "."+p.Name+"-extract-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Chmod(archive, system.ExecPerm()); err != nil {
+		return err
+	}
+	fill := strings.NewReplacer("{archive}", archive, "{dir}", dir)
+	args := strings.Fields(p.ExtractCmd)
+	for i, a := range args {
+		args[i] = fill.Replace(a)
+	}
+	if r.verbose {
+		fmt.Fprintf(os.Stderr, "[verbose] %s: extract: %s\n", p.Name, strings.Join(args, " "))
+	}
+	// Unpacking needs no network; keep a catalog's command off it.
+	if output, err := sandbox.Run(ctx, args, sandbox.Options{Dir: dir, NoNetwork: true}); err != nil {
+		return fmt.Errorf("extract_cmd %s: %v: %s", args[0], err, sandbox.LastLine(output))
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("extract_cmd %s exited successfully but left nothing in its directory", args[0])
+	}
+	for _, e := range entries {
+		dst := filepath.Join(installDir, e.Name())
+		if err := os.RemoveAll(dst); err != nil {
+			return err
+		}
+		if err := os.Rename(filepath.Join(dir, e.Name()), dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// downloadCmdFor returns the download command for p: its own download_cmd
+// unless its catalog is untrusted, else the global one, else "" for the
+// built-in client.
+func (r *runner) downloadCmdFor(p catalog.Program) string {
+	if p.DownloadCmd != "" && !p.Untrusted {
+		return p.DownloadCmd
+	}
+	return r.fetch
+}
+
+// downloadWith downloads url by running the download_cmd template tmpl,
+// filled in as described by catalog.CheckDownloadCmd. The output path does
+// not exist beforehand, so tools that refuse to overwrite (or rename around)
+// existing files write it as asked. Unlike the built-in client, a running
+// command is not paused by the Pauser.
+func (r *runner) downloadWith(ctx context.Context, tmpl, url, assetName string) (string, error) {
+	dir, err := os.MkdirTemp(r.tmpDir, "installer-fetch-*")
+	if err != nil {
+		return "", err
+	}
+	out := filepath.Join(dir, assetName)
+	fill := strings.NewReplacer("{url}", url, "{out}", out, "{dir}", dir, "{file}", assetName)
+	args := strings.Fields(tmpl)
+	for i, a := range args {
+		args[i] = fill.Replace(a)
+	}
+	if r.verbose {
+		fmt.Fprintf(os.Stderr, "[verbose] download: %s\n", strings.Join(args, " "))
+	}
+	output, err := sandbox.Run(ctx, args, sandbox.Options{Dir: dir})
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("download_cmd %s: %v: %s", args[0], err, sandbox.LastLine(output))
+	}
+	if info, err := os.Stat(out); err != nil || info.Size() == 0 {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("download_cmd %s exited successfully but did not write %s", args[0], out)
+	}
+	return out, nil
+}
+
+func (r *runner) download(ctx context.Context, url, assetName string, progress func(received, size int64)) (string, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return "", err
 	}
-	resp, err := http.DefaultClient.Do(req)
+	// Harmless for browser URLs; required for API asset URLs.
+	req.Header.Set("Accept", gh.DownloadAccept)
+	resp, err := r.httpClient().Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -207,16 +1274,43 @@ func download(ctx context.Context, url, assetName string) (string, error) {
 	if resp.ContentLength == 0 {
 		return "", fmt.Errorf("empty response body")
 	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return "", fmt.Errorf("got HTML, not an archive — GitHub may be rate limiting or asking for authentication")
+	}
 
-	tmp, err := os.CreateTemp("", "installer-*-"+assetName)
+	tmp, err := os.CreateTemp(r.tmpDir, "installer-*-"+assetName)
 	if err != nil {
 		return "", err
 	}
 	defer tmp.Close()
 
-	if _, err := io.Copy(tmp, resp.Body); err != nil {
+	body := &progressReader{r: pausableReader{ctx: ctx, r: resp.Body, p: r.pause}, size: max(resp.ContentLength, 0), report: progress}
+	if _, err := io.Copy(tmp, body); err != nil {
 		os.Remove(tmp.Name())
 		return "", err
 	}
 	return tmp.Name(), nil
 }
+
+// progressInterval is how often a download reports its progress.
+const progressInterval = 250 * time.Millisecond
+
+// progressReader counts the bytes read through it and passes the count to
+// report at most every progressInterval, and once more at EOF.
+type progressReader struct {
+	r      io.Reader
+	n      int64
+	size   int64
+	last   time.Time
+	report func(received, size int64)
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	pr.n += int64(n)
+	if pr.report != nil && (err == io.EOF || time.Since(pr.last) >= progressInterval) {
+		pr.last = time.Now()
+		pr.report(pr.n, pr.size)
+	}
+	return n, err
+}
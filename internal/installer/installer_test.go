@@ -0,0 +1,152 @@
+package installer_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+func TestRun_installs(t *testing.T) {
+	h := newHarness(t)
+	h.release("owner/tool", "v1.0.0", map[string]fakeAsset{"tool-1.0.0.tar.gz": {body: binary("tool", "1.0.0")}})
+
+	msg := h.run(h.program("tool"))["tool"]
+	if msg.State != installer.StateDone || msg.Version != "1.0.0" {
+		t.Fatalf("last message %s %s, err %v", msg.State, msg.Version, msg.Err)
+	}
+	if _, err := os.Stat(filepath.Join(system.BinPath(), "tool")); err != nil {
+		t.Errorf("bin not linked: %v", err)
+	}
+	if ps, ok := h.state.Get("tool"); !ok || ps.Tag != "v1.0.0" {
+		t.Errorf("state = %+v, %v", ps, ok)
+	}
+}
+
+func TestRun_assetNotFound(t *testing.T) {
+	h := newHarness(t)
+	h.release("owner/tool", "v1.0.0", map[string]fakeAsset{"checksums.txt": {body: []byte("x")}})
+
+	msg := h.run(h.program("tool"))["tool"]
+	var mismatch *installer.AssetMismatchError
+	if msg.State != installer.StateError || !errors.As(msg.Err, &mismatch) {
+		t.Fatalf("last message %s, err %v; want an AssetMismatchError", msg.State, msg.Err)
+	}
+	if len(mismatch.Available) != 1 || mismatch.Available[0] != "checksums.txt" {
+		t.Errorf("available assets %v", mismatch.Available)
+	}
+}
+
+func TestRun_missingDownload(t *testing.T) {
+	h := newHarness(t)
+	// Listed in the release, but gone from the download host.
+	h.release("owner/tool", "v1.0.0", map[string]fakeAsset{"tool-1.0.0.tar.gz": {body: binary("tool", "1.0.0"), fault: faultMissing}})
+
+	msg := h.run(h.program("tool"))["tool"]
+	if msg.State != installer.StateError || msg.Err == nil {
+		t.Fatalf("last message %s, err %v; want a failure", msg.State, msg.Err)
+	}
+	if _, ok := h.state.Get("tool"); ok {
+		t.Error("a failed install was recorded in the state")
+	}
+}
+
+func TestRun_truncatedDownload(t *testing.T) {
+	h := newHarness(t)
+	h.release("owner/tool", "v1.0.0", map[string]fakeAsset{"tool-1.0.0.tar.gz": {body: binary("tool", "1.0.0"), fault: faultTruncate}})
+
+	msg := h.run(h.program("tool"))["tool"]
+	if msg.State != installer.StateError || !strings.HasPrefix(msg.Err.Error(), "download:") {
+		t.Fatalf("last message %s, err %v; want a download error", msg.State, msg.Err)
+	}
+	if n := h.hitCount("/owner/tool/releases/download/v1.0.0/tool-1.0.0.tar.gz"); n < 3 {
+		t.Errorf("asset requested %d times; want the download retried", n)
+	}
+}
+
+func TestRun_extractError(t *testing.T) {
+	h := newHarness(t)
+	// Right magic bytes, so it passes the download check, but no archive.
+	corrupt := append([]byte{0x1f, 0x8b, 0x08, 0x00}, []byte(strings.Repeat("not gzip ", 64))...)
+	h.release("owner/tool", "v1.0.0", map[string]fakeAsset{"tool-1.0.0.tar.gz": {body: corrupt}})
+
+	msg := h.run(h.program("tool"))["tool"]
+	if msg.State != installer.StateError || !strings.HasPrefix(msg.Err.Error(), "extract:") {
+		t.Fatalf("last message %s, err %v; want an extract error", msg.State, msg.Err)
+	}
+}
+
+func TestRun_linkConflict(t *testing.T) {
+	h := newHarness(t)
+	h.release("owner/tool", "v1.0.0", map[string]fakeAsset{"tool-1.0.0.tar.gz": {body: binary("tool", "1.0.0")}})
+	// Another installer's binary of the same name.
+	theirs := filepath.Join(system.BinPath(), "tool")
+	os.WriteFile(theirs, []byte("#!/bin/sh\necho theirs\n"), 0755)
+
+	msg := h.run(h.program("tool"))["tool"]
+	if msg.State != installer.StateError || !strings.Contains(msg.Err.Error(), "already exists") {
+		t.Fatalf("last message %s, err %v; want a link conflict", msg.State, msg.Err)
+	}
+	if data, _ := os.ReadFile(theirs); string(data) != "#!/bin/sh\necho theirs\n" {
+		t.Errorf("the existing binary was changed: %q", data)
+	}
+}
+
+func TestRun_signature(t *testing.T) {
+	h := newHarness(t)
+	asset := binary("tool", "1.0.0")
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	sig, pub := minisig(priv, asset)
+	h.release("owner/tool", "v1.0.0", map[string]fakeAsset{
+		"tool-1.0.0.tar.gz":         {body: asset},
+		"tool-1.0.0.tar.gz.minisig": {body: sig},
+	})
+	p := h.program("tool")
+	p.Signature, p.Pubkey = "{asset}.minisig", pub
+
+	if msg := h.run(p)["tool"]; msg.State != installer.StateDone {
+		t.Fatalf("last message %s, err %v", msg.State, msg.Err)
+	}
+}
+
+func TestRun_badSignature(t *testing.T) {
+	h := newHarness(t)
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	// A valid signature, but of another build.
+	sig, pub := minisig(priv, binary("tool", "0.9.0"))
+	h.release("owner/tool", "v1.0.0", map[string]fakeAsset{
+		"tool-1.0.0.tar.gz":         {body: binary("tool", "1.0.0")},
+		"tool-1.0.0.tar.gz.minisig": {body: sig},
+	})
+	p := h.program("tool")
+	p.Signature, p.Pubkey = "{asset}.minisig", pub
+
+	msg := h.run(p)["tool"]
+	if msg.State != installer.StateError || !strings.HasPrefix(msg.Err.Error(), "signature:") {
+		t.Fatalf("last message %s, err %v; want a signature error", msg.State, msg.Err)
+	}
+	if _, err := os.Stat(filepath.Join(system.BinPath(), "tool")); err == nil {
+		t.Error("a binary with a bad signature was linked")
+	}
+}
+
+// A failing program does not take the others down with it.
+func TestRun_failureIsolated(t *testing.T) {
+	h := newHarness(t)
+	h.release("owner/good", "v2.0.0", map[string]fakeAsset{"good-2.0.0.tar.gz": {body: binary("good", "2.0.0")}})
+	h.release("owner/bad", "v1.0.0", map[string]fakeAsset{"bad-1.0.0.tar.gz": {body: binary("bad", "1.0.0"), fault: faultTruncate}})
+
+	last := h.run(h.program("bad"), h.program("good"))
+	if last["bad"].State != installer.StateError {
+		t.Errorf("bad: %s, err %v; want a failure", last["bad"].State, last["bad"].Err)
+	}
+	if last["good"].State != installer.StateDone {
+		t.Errorf("good: %s, err %v; want it installed", last["good"].State, last["good"].Err)
+	}
+}
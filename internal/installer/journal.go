@@ -0,0 +1,114 @@
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// Journal records the programs of a Run that have not finished yet, so that a
+// run cut short by a crash, an OOM kill or a closed terminal can be resumed.
+// It is removed when the run ends normally.
+type Journal struct {
+	PID      int       `json:"pid"`
+	Started  time.Time `json:"started"`
+	Programs []string  `json:"programs"` // programs of the run not yet finished
+}
+
+func journalPath() string {
+	return filepath.Join(system.CachePath(), "journal.json")
+}
+
+// downloadsDir holds downloaded assets until their program is installed. The
+// files outlive a crashed run, so a resumed run does not download them again.
+func downloadsDir() string {
+	return filepath.Join(system.CachePath(), "downloads")
+}
+
+// Interrupted returns the journal left by a run whose process died before it
+// finished. It returns false if there is none, or if that process is still
+// running.
+func Interrupted() (Journal, bool) {
+	data, err := os.ReadFile(journalPath())
+	if err != nil {
+		return Journal{}, false
+	}
+	var j Journal
+	if json.Unmarshal(data, &j) != nil || len(j.Programs) == 0 {
+		return Journal{}, false
+	}
+	if j.PID != os.Getpid() && processAlive(j.PID) {
+		return Journal{}, false
+	}
+	return j, true
+}
+
+// DiscardInterrupted forgets an interrupted run and the assets it had
+// downloaded.
+func DiscardInterrupted() {
+	os.Remove(journalPath())
+	os.RemoveAll(downloadsDir())
+}
+
+// journal keeps the on-disk Journal of the current run up to date.
+type journal struct {
+	mu sync.Mutex
+	j  Journal
+}
+
+func newJournal(programs []string) *journal {
+	jr := &journal{j: Journal{PID: os.Getpid(), Started: time.Now(), Programs: slices.Clone(programs)}}
+	jr.save()
+	return jr
+}
+
+// finish drops name from the journal once it reaches a terminal state.
+func (jr *journal) finish(name string) {
+	if jr == nil {
+		return
+	}
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	if i := slices.Index(jr.j.Programs, name); i >= 0 {
+		jr.j.Programs = slices.Delete(jr.j.Programs, i, i+1)
+		jr.save()
+	}
+}
+
+// close removes the journal at the end of the run.
+func (jr *journal) close() {
+	if jr == nil {
+		return
+	}
+	os.Remove(journalPath())
+}
+
+// save writes the journal; failures are ignored, since it only serves
+// recovery. The caller holds mu, except in newJournal.
+func (jr *journal) save() {
+	data, err := json.Marshal(jr.j)
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(filepath.Dir(journalPath()), system.DirPerm()) != nil {
+		return
+	}
+	tmp := journalPath() + ".tmp"
+	if os.WriteFile(tmp, data, system.FilePerm()) == nil {
+		os.Rename(tmp, journalPath())
+	}
+}
+
+// cachedAsset returns where the asset downloaded from url is kept until its
+// program is installed.
+func cachedAsset(url, assetName string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(downloadsDir(), hex.EncodeToString(sum[:8])+"-"+assetName)
+}
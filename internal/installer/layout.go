@@ -0,0 +1,83 @@
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+)
+
+// layout fingerprints the tree extracted into installDir: the SHA-256 of its
+// sorted relative paths, with version written as {version} so that releases
+// which only bump the version in directory names hash alike. Bins picked for
+// one release are offered again for the next when the layouts match.
+func layout(installDir, version string) string {
+	var paths []string
+	filepath.WalkDir(walkRoot(installDir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(installDir, path)
+		if rel == "." || rel == ".version" {
+			return nil
+		}
+		if d.IsDir() {
+			rel += "/"
+		}
+		paths = append(paths, templated(rel, version))
+		return nil
+	})
+	sort.Strings(paths)
+	h := sha256.New()
+	for _, p := range paths {
+		h.Write([]byte(p + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// templated replaces version in path with {version}.
+func templated(path, version string) string {
+	if version == "" {
+		return path
+	}
+	return strings.ReplaceAll(path, version, "{version}")
+}
+
+// remembered returns the bins picked for p from an earlier release with the
+// same layout, resolved against installDir, or nil if there are none or one
+// of them is missing.
+func (r *runner) remembered(p catalog.Program, lay, installDir, version string) []catalog.Bin {
+	picks, ok := r.state.Picks(p.Name, lay)
+	if !ok {
+		return nil
+	}
+	bins := make([]catalog.Bin, 0, len(picks))
+	for _, pk := range picks {
+		src := filepath.Join(installDir, strings.ReplaceAll(pk.Src, "{version}", version))
+		if _, err := os.Stat(src); err != nil {
+			return nil
+		}
+		bins = append(bins, catalog.Bin{Src: src, Dst: pk.Dst})
+	}
+	return bins
+}
+
+// remember records bins as picked for p from a tree with layout lay. It is
+// saved with the install record.
+func (r *runner) remember(p catalog.Program, lay, installDir, version string, bins []catalog.Bin) {
+	picks := make([]state.Pick, 0, len(bins))
+	for _, b := range bins {
+		rel, err := filepath.Rel(installDir, b.Src)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return
+		}
+		picks = append(picks, state.Pick{Src: templated(rel, version), Dst: b.Dst})
+	}
+	r.state.SetPicks(p.Name, lay, picks)
+}
@@ -0,0 +1,134 @@
+package installer
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/dsaleh/david-dotfiles/internal/linker"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+)
+
+// Tampered is a locked link that no longer matches its fingerprint, e.g.
+// because pipx or cargo installed a binary of the same name over it.
+type Tampered struct {
+	Program string
+	Bin     string
+	Lock    state.LinkLock
+	Current string // the entry's current link target; "" if it is missing or not a symlink
+}
+
+// Reason describes what happened to the link.
+func (t Tampered) Reason() string {
+	if t.Current != "" {
+		return "now points to " + t.Current
+	}
+	if _, err := os.Lstat(t.Lock.Path); err == nil {
+		return "was replaced by a regular file"
+	}
+	return "was removed"
+}
+
+// lockLinks returns prev with fingerprints for the linked bins added.
+// Entries that are not symlinks (hardlink and copy modes) are not locked.
+func (r *runner) lockLinks(prev map[string]state.LinkLock, linked []string) map[string]state.LinkLock {
+	binDir := r.dest.localBin()
+	if binDir == "" {
+		return prev
+	}
+	locks := maps.Clone(prev)
+	if locks == nil {
+		locks = make(map[string]state.LinkLock, len(linked))
+	}
+	for _, b := range linked {
+		target, hash, err := linker.Fingerprint(binDir, b)
+		if err != nil {
+			delete(locks, b)
+			continue
+		}
+		content, _ := linker.HashContent(binDir, b)
+		locks[b] = state.LinkLock{Path: filepath.Join(binDir, b), Target: target, Hash: hash, Content: content}
+	}
+	return locks
+}
+
+// CheckLocks returns the locked links recorded in st that were changed or
+// removed since the installer made them, sorted by program and bin.
+func CheckLocks(st *state.State) []Tampered {
+	var out []Tampered
+	for _, name := range st.Names() {
+		ps, _ := st.Get(name)
+		for _, bin := range slices.Sorted(maps.Keys(ps.Locks)) {
+			l := ps.Locks[bin]
+			target, hash, err := linker.Fingerprint(filepath.Dir(l.Path), filepath.Base(l.Path))
+			if err == nil && hash == l.Hash {
+				continue
+			}
+			out = append(out, Tampered{Program: name, Bin: bin, Lock: l, Current: target})
+		}
+	}
+	return out
+}
+
+// BinStatus is the outcome of checking one linked binary with VerifyBins.
+type BinStatus int
+
+const (
+	BinOK         BinStatus = iota // the link and the binary are as installed
+	BinModified                    // the binary's contents changed
+	BinRelinked                    // the link was repointed, replaced or removed
+	BinMissing                     // the link is intact but its binary is gone
+	BinUnrecorded                  // locked before binary hashes were recorded
+	BinUnlocked                    // not locked: lock_links was off at install
+)
+
+func (s BinStatus) String() string {
+	return [...]string{"ok", "modified", "relinked", "missing", "no hash recorded", "not locked"}[s]
+}
+
+// BinCheck is the integrity of one bin of an installed program.
+type BinCheck struct {
+	Bin    string
+	Status BinStatus
+}
+
+// VerifyBins hashes the binaries the bins of the installed program name
+// resolve to and compares them with the lock recorded in st, in the order of
+// its bins.
+func VerifyBins(st *state.State, name string) []BinCheck {
+	ps, _ := st.Get(name)
+	out := make([]BinCheck, 0, len(ps.Bins))
+	for _, bin := range ps.Bins {
+		l, ok := ps.Locks[bin]
+		if !ok {
+			out = append(out, BinCheck{bin, BinUnlocked})
+			continue
+		}
+		binDir, dst := filepath.Dir(l.Path), filepath.Base(l.Path)
+		status := BinOK
+		if _, hash, err := linker.Fingerprint(binDir, dst); err != nil || hash != l.Hash {
+			status = BinRelinked
+		} else if content, err := linker.HashContent(binDir, dst); err != nil {
+			status = BinMissing
+		} else if l.Content == "" {
+			status = BinUnrecorded
+		} else if content != l.Content {
+			status = BinModified
+		}
+		out = append(out, BinCheck{bin, status})
+	}
+	return out
+}
+
+// RepairLock points t's link back at its recorded target, replacing whatever
+// is at its path now.
+func RepairLock(t Tampered) error {
+	if _, err := os.Lstat(t.Lock.Path); err == nil {
+		if err := os.Remove(t.Lock.Path); err != nil {
+			return fmt.Errorf("remove %s: %w", t.Lock.Path, err)
+		}
+	}
+	return linker.Link(t.Lock.Target, filepath.Dir(t.Lock.Path), filepath.Base(t.Lock.Path))
+}
@@ -0,0 +1,82 @@
+package installer
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// Pauser suspends a Run without abandoning it. While paused, workers do not
+// start new programs and in-flight downloads stop reading from the network,
+// freeing the bandwidth until Resume. A nil *Pauser is never paused.
+type Pauser struct {
+	mu     sync.Mutex
+	resume chan struct{} // non-nil while paused; closed by Resume
+}
+
+// NewPauser returns a Pauser in the running state.
+func NewPauser() *Pauser {
+	return &Pauser{}
+}
+
+// Pause suspends the run. It is a no-op if already paused.
+func (p *Pauser) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resume == nil {
+		p.resume = make(chan struct{})
+	}
+}
+
+// Resume lets the run continue. It is a no-op if not paused.
+func (p *Pauser) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resume != nil {
+		close(p.resume)
+		p.resume = nil
+	}
+}
+
+// Paused reports whether the run is currently paused.
+func (p *Pauser) Paused() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.resume != nil
+}
+
+// wait blocks while paused. It returns ctx.Err() if ctx ends first.
+func (p *Pauser) wait(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	resume := p.resume
+	p.mu.Unlock()
+	if resume == nil {
+		return nil
+	}
+	select {
+	case <-resume:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pausableReader blocks each Read while the Pauser is paused.
+type pausableReader struct {
+	ctx context.Context
+	r   io.Reader
+	p   *Pauser
+}
+
+func (pr pausableReader) Read(b []byte) (int, error) {
+	if err := pr.p.wait(pr.ctx); err != nil {
+		return 0, err
+	}
+	return pr.r.Read(b)
+}
@@ -0,0 +1,124 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/linker"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+)
+
+// ChangeKind classifies what a run would do to one program.
+type ChangeKind int
+
+const (
+	ChangeInstall   ChangeKind = iota // not installed yet
+	ChangeUpgrade                     // installed at a different version
+	ChangeUnchanged                   // already at the resolved version
+	ChangeRemove                      // installed but outside the desired set (Apply), or in Options.Remove
+	ChangeUnknown                     // version could not be resolved
+)
+
+// Change is one line of a Plan.
+type Change struct {
+	Program string
+	Kind    ChangeKind
+	From    string   // installed version, "" if none
+	To      string   // resolved version, "" for removals
+	Links   []string // bin names that would be created (install) or deleted (remove)
+	Err     error    // set when Kind == ChangeUnknown
+
+	// Paths lists every path a removal would delete (the bin entries that
+	// still belong to the program, then its install dir unless another
+	// program shares it) or an upgrade would replace (the install dir). For
+	// remote targets only the bin names are known.
+	Paths []string
+}
+
+// Plan lists the changes a Run with the same arguments would make, without
+// touching the filesystem. Removals come first, mirroring Run.
+func Plan(ctx context.Context, programs []catalog.Program, opts Options) []Change {
+	r := &runner{state: opts.State, dest: newDestination(opts, ""), winBin: opts.WindowsBin, plat: opts.Platform, locked: opts.Lock, http: opts.HTTPClient}
+	r.client = r.newClient("")
+
+	var changes []Change
+	for _, name := range removals(opts, programs) {
+		ps, ok := opts.State.Get(name)
+		if !ok {
+			changes = append(changes, Change{Program: name, Kind: ChangeUnknown, Err: fmt.Errorf("%s is not installed", name)})
+			continue
+		}
+		changes = append(changes, Change{Program: name, Kind: ChangeRemove, From: ps.Version, Links: ps.Bins, Paths: r.removalPaths(name, ps)})
+	}
+
+	planned := make([]Change, len(programs))
+	sem := make(chan struct{}, defaultDownloads)
+	var wg sync.WaitGroup
+	for i, p := range programs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			planned[i] = r.planOne(ctx, p)
+		}()
+	}
+	wg.Wait()
+	return append(changes, planned...)
+}
+
+func (r *runner) planOne(ctx context.Context, p catalog.Program) Change {
+	c := Change{Program: p.Name, From: r.dest.installedVersion(ctx, r.state.DirOf(p.Name))}
+	if r.unsupported(p) {
+		c.Kind = ChangeUnchanged // skipped as unsupported
+		return c
+	}
+	rel, _, err := r.resolveRelease(ctx, p)
+	if err != nil {
+		c.Kind, c.Err = ChangeUnknown, err
+		return c
+	}
+	c.To = rel.Version
+	switch {
+	case c.From == "":
+		c.Kind = ChangeInstall
+	case r.upToDate(ctx, p, rel, c.From):
+		c.Kind = ChangeUnchanged
+		return c
+	default:
+		c.Kind = ChangeUpgrade
+		if dir := r.dest.liveDir(r.state.DirOf(p.Name)); dir != "" && r.state.DirOf(p.Name) == p.Name {
+			c.Paths = []string{dir}
+		}
+	}
+	for _, b := range p.Bin {
+		c.Links = append(c.Links, b.Dst)
+	}
+	return c
+}
+
+// removalPaths returns what uninstalling name would delete. Bin entries are
+// checked as uninstall would when the bin dir is local; otherwise every
+// recorded name is listed.
+func (r *runner) removalPaths(name string, ps state.ProgramState) []string {
+	dir := r.state.DirOf(name)
+	live := r.dest.liveDir(dir)
+	var paths []string
+	if bin := r.destFor(ps.BinDir).localBin(); bin != "" {
+		for _, b := range ps.Bins {
+			if linker.Owns(linker.Mode(ps.LinkMode), bin, b, live) {
+				paths = append(paths, filepath.Join(bin, b))
+			}
+		}
+	} else {
+		paths = append(paths, ps.Bins...)
+	}
+	// --shared removals only drop this user's links; see sharedDest.
+	if _, shared := r.dest.(sharedDest); !shared && live != "" && len(r.state.DirUsers(dir, name)) == 0 {
+		paths = append(paths, live)
+	}
+	return paths
+}
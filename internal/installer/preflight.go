@@ -0,0 +1,30 @@
+package installer
+
+import (
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// Preflight returns what the programs need from the system that it lacks:
+// packages not on PATH, then the unmet entries of their requires tables. A
+// package is also satisfied by one of the programs providing it.
+func Preflight(programs []catalog.Program) []string {
+	var packages []string
+	var requires catalog.Requires
+	seen := map[string]bool{}
+	for _, p := range programs {
+		for _, t := range p.Tools() {
+			seen[t] = true
+		}
+	}
+	for _, p := range programs {
+		for _, pkg := range p.Packages {
+			if !seen[pkg] {
+				seen[pkg] = true
+				packages = append(packages, pkg)
+			}
+		}
+		requires = requires.Merge(p.Requires)
+	}
+	return append(system.CheckPackages(packages), system.CheckRequires(requires)...)
+}
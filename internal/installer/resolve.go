@@ -0,0 +1,153 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/detect"
+	gh "github.com/dsaleh/david-dotfiles/internal/github"
+)
+
+// Resolution is the exact release asset a Run would download for a program.
+type Resolution struct {
+	Program   catalog.Program
+	Tag       string
+	Version   string
+	AssetName string
+	URL       string
+	Released  time.Time // when the release (or, for rolling tags, its newest asset) was published; zero if unknown
+	Size      int64     // size of the asset in bytes; 0 if unknown
+	Err       error     // resolution failed; the other fields are unset
+}
+
+// Resolve looks up the release each program would install with opts (catalog
+// version, then state pin, then latest) without downloading anything.
+// Results are in the order of programs.
+func Resolve(ctx context.Context, programs []catalog.Program, opts Options) []Resolution {
+	return NewResolver(opts).Resolve(ctx, programs)
+}
+
+// Resolver resolves releases like Resolve, remembering the latest release of
+// each repo across calls the way a run does within itself, so resolving the
+// same programs again makes no API requests for those that are not pinned.
+type Resolver struct {
+	r *runner
+}
+
+// NewResolver returns a Resolver that knows no releases yet.
+func NewResolver(opts Options) *Resolver {
+	r := &runner{state: opts.State, plat: opts.Platform, locked: opts.Lock, http: opts.HTTPClient}
+	r.client = r.newClient("")
+	return &Resolver{r: r}
+}
+
+// Resolve is the package-level Resolve with rv's memory of latest releases.
+func (rv *Resolver) Resolve(ctx context.Context, programs []catalog.Program) []Resolution {
+	out := make([]Resolution, len(programs))
+	sem := make(chan struct{}, defaultDownloads)
+	var wg sync.WaitGroup
+	for i, p := range programs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			out[i] = rv.r.resolution(ctx, p, false)
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+// Describe resolves the release p would install, like Resolve, and makes
+// sure its date and asset size are filled in: a pinned release is known only
+// by its tag, so it is looked up. Releases found through use_tags have
+// neither.
+func Describe(ctx context.Context, p catalog.Program, opts Options) Resolution {
+	r := &runner{state: opts.State, plat: opts.Platform, locked: opts.Lock, http: opts.HTTPClient}
+	r.client = r.newClient("")
+	return r.resolution(ctx, p, true)
+}
+
+func (r *runner) resolution(ctx context.Context, p catalog.Program, full bool) Resolution {
+	rel, pinned, err := r.resolveRelease(ctx, p)
+	if err == nil && full && pinned && !p.UseTags {
+		rel, err = r.github(p).ReleaseByTag(ctx, p.Repo, rel.Tag)
+	}
+	if err != nil {
+		return Resolution{Program: p, Err: err}
+	}
+	name, url := r.assetURL(p, rel)
+	res := Resolution{Program: p, Tag: rel.Tag, Version: rel.Version, AssetName: name, URL: url, Released: rel.Updated}
+	if p.AssetURL == "" {
+		res.Size = rel.Sizes[name]
+	}
+	return res
+}
+
+// assetURL is the package-level assetURL for this platform: on Termux it
+// uses termux_asset_pattern, else the Android or arm64 build of the asset
+// when the release lists one.
+func (r *runner) assetURL(p catalog.Program, rel gh.Release) (name, url string) {
+	if e, ok := r.lockedAsset(p.Name, ""); ok && e.Tag == rel.Tag {
+		name, url = assetURL(p, rel)
+		if e.URL != url {
+			// The asset the lock was written with replaced the one
+			// asset_pattern names, e.g. after a rename upstream.
+			name, url = path.Base(e.URL), e.URL
+		}
+		return name, url
+	}
+	if r.plat != "termux" || p.AssetURL != "" {
+		return assetURL(p, rel)
+	}
+	if p.TermuxAssetPattern != "" {
+		p.AssetPattern = p.TermuxAssetPattern
+		return assetURL(p, rel)
+	}
+	name, url = assetURL(p, rel)
+	if alt, ok := detect.Android(name, rel.Assets); ok {
+		p.AssetPattern = detect.Pattern(alt, rel.Version)
+		return assetURL(p, rel)
+	}
+	return name, url
+}
+
+// lockedRelease returns the release p is locked at (Options.Lock), which is
+// not a pin: the state records it like a latest release.
+func (r *runner) lockedRelease(p catalog.Program) (gh.Release, bool, error) {
+	e, ok := r.locked.Programs[p.Name]
+	if !ok {
+		return gh.Release{}, false, fmt.Errorf("%s has no entry in the lock file", p.Name)
+	}
+	return gh.Release{Tag: e.Tag, Version: strings.TrimPrefix(e.Tag, "v")}, false, nil
+}
+
+// lockedAsset returns the lock entry of program, if the run is locked and
+// the lock has one for the asset at url; any asset when url is "".
+func (r *runner) lockedAsset(program, url string) (catalog.Locked, bool) {
+	if r.locked == nil {
+		return catalog.Locked{}, false
+	}
+	e, ok := r.locked.Programs[program]
+	return e, ok && (url == "" || e.URL == url)
+}
+
+// assetURL returns the release asset name and download URL for p at rel.
+// The raw tag (e.g. "v15.1.0" or "15.1.0") is used as the path segment so the
+// URL matches exactly what GitHub has, regardless of whether the repo uses a
+// "v"-prefixed tag or a bare version tag. A catalog asset_url replaces the
+// release URL, for artifacts hosted elsewhere.
+func assetURL(p catalog.Program, rel gh.Release) (name, url string) {
+	name = strings.ReplaceAll(p.AssetPattern, "{version}", rel.Version)
+	if p.AssetURL != "" {
+		url = strings.NewReplacer("{version}", rel.Version, "{tag}", rel.Tag, "{asset}", name).Replace(p.AssetURL)
+		return name, url
+	}
+	return name, fmt.Sprintf("https://%s/%s/releases/download/%s/%s", p.GitHubHost(), p.Repo, rel.Tag, name)
+}
@@ -0,0 +1,34 @@
+package installer
+
+import (
+	"context"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	gh "github.com/dsaleh/david-dotfiles/internal/github"
+)
+
+// upToDate reports whether the installed version of p is the one rel would
+// install. For rolling releases the tag never changes, so the release must
+// also not have been rebuilt since the recorded install time.
+func (r *runner) upToDate(ctx context.Context, p catalog.Program, rel gh.Release, installed string) bool {
+	if installed != rel.Version {
+		return false
+	}
+	return !p.Rolling || !r.rebuilt(ctx, p, rel)
+}
+
+// rebuilt reports whether rel was published, or had an asset replaced, after
+// p was last installed. Releases resolved without an API call (a pinned
+// version such as "nightly") are looked up for their dates first.
+func (r *runner) rebuilt(ctx context.Context, p catalog.Program, rel gh.Release) bool {
+	updated := rel.Updated
+	if updated.IsZero() {
+		full, err := r.github(p).ReleaseByTag(ctx, p.Repo, rel.Tag)
+		if err != nil {
+			return false
+		}
+		updated = full.Updated
+	}
+	ps, ok := r.state.Get(p.Name)
+	return !ok || updated.After(ps.InstalledAt)
+}
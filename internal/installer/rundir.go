@@ -0,0 +1,61 @@
+package installer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// runDirPrefix names per-run temp dirs as run-<pid>-<random> so a later run
+// can tell whether the process that created one is still alive.
+const runDirPrefix = "run-"
+
+// newRunDir creates the directory that holds every download and staging dir
+// of one Run, under system.CachePath(). Leftovers from runs that crashed or
+// were killed before their cleanup ran are removed first.
+func newRunDir() (string, error) {
+	base := system.CachePath()
+	if err := os.MkdirAll(base, system.DirPerm()); err != nil {
+		return "", err
+	}
+	sweepRunDirs(base, false)
+	return os.MkdirTemp(base, fmt.Sprintf("%s%d-*", runDirPrefix, os.Getpid()))
+}
+
+// Cleanup removes the run dirs of the current process. Callers that may exit
+// while a Run is still in flight (e.g. the TUI quitting on ctrl+c) call it
+// before returning, since the Run's own cleanup would never get to execute.
+func Cleanup() {
+	sweepRunDirs(system.CachePath(), true)
+}
+
+// sweepRunDirs removes run dirs in base whose owning process no longer exists,
+// and also those of the current process when own is set.
+func sweepRunDirs(base string, own bool) {
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		rest, ok := strings.CutPrefix(e.Name(), runDirPrefix)
+		if !ok || !e.IsDir() {
+			continue
+		}
+		pidStr, _, _ := strings.Cut(rest, "-")
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil || (pid == os.Getpid() && !own) || (pid != os.Getpid() && processAlive(pid)) {
+			continue
+		}
+		os.RemoveAll(filepath.Join(base, e.Name()))
+	}
+}
+
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,46 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	gh "github.com/dsaleh/david-dotfiles/internal/github"
+	"github.com/dsaleh/david-dotfiles/internal/sign"
+)
+
+// verifySignature downloads the signature p's catalog entry names for the
+// asset assetName, downloaded from url to file, and checks it with p's
+// pubkey. The signature sits beside the asset unless it is a full URL.
+func (r *runner) verifySignature(ctx context.Context, p catalog.Program, rel gh.Release, assetName, url, file string) error {
+	sigURL := strings.NewReplacer("{asset}", assetName, "{version}", rel.Version, "{tag}", rel.Tag).Replace(p.Signature)
+	if !strings.Contains(sigURL, "://") {
+		sigURL = url[:strings.LastIndex(url, "/")+1] + sigURL
+	}
+	name := path.Base(sigURL)
+	fetchURL := r.assetAPIURL(p, rel, name, "")
+	if fetchURL == "" {
+		fetchURL = sigURL
+	}
+	sig, err := r.downloadWithRetry(ctx, fetchURL, name, "", nil)
+	if err != nil {
+		return fmt.Errorf("signature: %w", err)
+	}
+	defer os.Remove(sig)
+
+	if catalog.SignatureFormat(name) == catalog.Minisign {
+		err = sign.VerifyMinisign(p.Pubkey, file, sig)
+	} else {
+		err = sign.VerifyGPG(ctx, p.Pubkey, file, sig)
+	}
+	if err != nil {
+		return fmt.Errorf("signature: %w", err)
+	}
+	if r.verbose {
+		fmt.Fprintf(os.Stderr, "[verbose] %s: %s verified with %s\n", p.Name, assetName, name)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package installer
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// An install goes through two stages with limits of their own: resolving
+// and downloading, which wait on the network, then extracting, which keeps a
+// CPU and the disk busy. Separate limits let a big xz archive unpack while
+// the next downloads run, and a slow download hold up only other downloads.
+// Linking, and waiting for the user to pick binaries, count against neither.
+
+// defaultDownloads is how many programs resolve and download at once unless
+// Options.Downloads says otherwise.
+const defaultDownloads = 3
+
+// defaultExtractions returns how many programs extract at once unless
+// Options.Extractions says otherwise: one per CPU, up to 4.
+func defaultExtractions() int {
+	return min(runtime.NumCPU(), 4)
+}
+
+// stage bounds how many installs are in one stage at a time.
+type stage chan struct{}
+
+func newStage(n, def int) stage {
+	if n <= 0 {
+		n = def
+	}
+	return make(stage, n)
+}
+
+// enter waits for a free place in the stage, or for ctx to be done.
+func (s stage) enter(ctx context.Context) error {
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s stage) leave() { <-s }
+
+// netSlot is one install's place in the download stage. It is taken before
+// the install starts, so programs begin in priority order, and given up as
+// soon as the asset is on disk, or when the install ends without one.
+type netSlot struct {
+	s    stage
+	once sync.Once
+}
+
+func (n *netSlot) release() {
+	if n != nil {
+		n.once.Do(n.s.leave)
+	}
+}
@@ -0,0 +1,160 @@
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/trash"
+)
+
+// The store keeps the trees extracted into a local share root, one per
+// asset, named after the SHA-256 of the asset and its version:
+// <share>/david-dotfiles/store/<hash>-<version>. The program's install dir,
+// <share>/<name>, is a symlink to the entry it currently uses, and bins link
+// through it. So an upgrade extracts beside the running release and switches
+// over in one rename, restoring the upgrade from the trash switches back
+// without copying anything, and programs whose assets are byte-identical
+// share one tree.
+
+// storeRoot returns the store of the share root share.
+func storeRoot(share string) string {
+	return filepath.Join(share, "david-dotfiles", "store")
+}
+
+// storeShare returns the share root whose store dest installs into, or ""
+// for destinations without one.
+func storeShare(dest destination) string {
+	switch d := dest.(type) {
+	case localDest:
+		return d.share
+	case sharedDest:
+		return storeShare(d.artifacts)
+	}
+	return ""
+}
+
+// storeKey returns the name of the store entry for an asset with SHA-256 sum
+// (hex) installed as version.
+func storeKey(sum, version string) string {
+	if version == "" {
+		return sum[:32]
+	}
+	return sum[:32] + "-" + strings.ReplaceAll(version, "/", "_")
+}
+
+// walkRoot returns dir with a trailing separator, which makes
+// filepath.WalkDir descend into it when it is a link to a store entry.
+func walkRoot(dir string) string {
+	return strings.TrimSuffix(dir, string(filepath.Separator)) + string(filepath.Separator)
+}
+
+// fileSHA256 returns the hex SHA-256 of the file at path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// point makes live a symlink to the store entry, replacing what is there in
+// one rename so the program is never missing. What live was goes into old,
+// if non-nil: a link is copied there, so restoring it switches back; an
+// install dir from before the store is moved there.
+func point(live, entry string, old *trash.Batch) error {
+	info, err := os.Lstat(live)
+	switch {
+	case err != nil:
+		// Not installed yet.
+	case info.Mode()&os.ModeSymlink != 0:
+		if target, _ := os.Readlink(live); target == entry {
+			return nil
+		}
+		if old != nil {
+			if err := old.Keep(live); err != nil {
+				return err
+			}
+		}
+	case old != nil:
+		if err := old.Move(live); err != nil {
+			return err
+		}
+	default:
+		if err := os.RemoveAll(live); err != nil {
+			return err
+		}
+	}
+	tmp := filepath.Join(filepath.Dir(live), "."+filepath.Base(live)+".link")
+	os.Remove(tmp)
+	if err := os.Symlink(entry, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, live)
+}
+
+// storeGrace is how long a store entry is kept after it last changed even
+// with nothing linking to it: another run may be about to link it.
+const storeGrace = time.Hour
+
+// pruneStore deletes the entries of the store of share that no install dir
+// links to, neither in share nor in the trash dir trashDir, where the links
+// replaced by upgrades are kept for restoring. It returns how many it
+// deleted.
+func pruneStore(share, trashDir string) (int, error) {
+	root := storeRoot(share)
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	used := map[string]bool{}
+	mark := func(link string) {
+		if target, err := os.Readlink(link); err == nil && filepath.Dir(target) == root {
+			used[filepath.Base(target)] = true
+		}
+	}
+	if dirs, err := os.ReadDir(share); err == nil {
+		for _, d := range dirs {
+			if d.Type()&fs.ModeSymlink != 0 {
+				mark(filepath.Join(share, d.Name()))
+			}
+		}
+	}
+	if trashDir != "" {
+		filepath.WalkDir(trashDir, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && d.Type()&fs.ModeSymlink != 0 {
+				mark(path)
+			}
+			return nil
+		})
+	}
+
+	cutoff := time.Now().Add(-storeGrace)
+	n := 0
+	for _, e := range entries {
+		if used[e.Name()] {
+			continue
+		}
+		if info, err := e.Info(); err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(root, e.Name())); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,111 @@
+package installer_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+	"github.com/dsaleh/david-dotfiles/internal/trash"
+)
+
+// storeEntries returns the names in the store of ~/.local/share.
+func storeEntries(t *testing.T) []string {
+	t.Helper()
+	entries, _ := os.ReadDir(filepath.Join(system.SharePath(), "david-dotfiles", "store"))
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestRun_upgradeAndRestore(t *testing.T) {
+	h := newHarness(t)
+	live := filepath.Join(system.SharePath(), "tool")
+	h.release("owner/tool", "v1.0.0", map[string]fakeAsset{"tool-1.0.0.tar.gz": {body: binary("tool", "1.0.0")}})
+	if msg := h.run(h.program("tool"))["tool"]; msg.State != installer.StateDone {
+		t.Fatalf("install: %s, err %v", msg.State, msg.Err)
+	}
+	v1, err := os.Readlink(live)
+	if err != nil {
+		t.Fatalf("install dir is not a link into the store: %v", err)
+	}
+
+	h.release("owner/tool", "v2.0.0", map[string]fakeAsset{"tool-2.0.0.tar.gz": {body: binary("tool", "2.0.0")}})
+	if msg := h.run(h.program("tool"))["tool"]; msg.State != installer.StateDone || msg.Version != "2.0.0" {
+		t.Fatalf("upgrade: %s %s, err %v", msg.State, msg.Version, msg.Err)
+	}
+	if v2, _ := os.Readlink(live); v2 == v1 {
+		t.Fatal("the upgrade did not switch the install dir")
+	}
+	// The release it replaced stays in the store while the trash has it.
+	if _, err := os.Stat(filepath.Join(v1, ".version")); err != nil {
+		t.Fatalf("the replaced release left the store: %v", err)
+	}
+
+	batches, _ := trash.List(trash.Path())
+	if len(batches) != 1 || batches[0].Reason != "upgrade" {
+		t.Fatalf("trash = %+v", batches)
+	}
+	if _, _, err := trash.Restore(trash.Path(), batches[0].ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.Readlink(live); got != v1 {
+		t.Errorf("after restore the install dir links to %s, want %s", got, v1)
+	}
+	script, _ := os.ReadFile(filepath.Join(system.BinPath(), "tool"))
+	if string(script) != "#!/bin/sh\necho tool 1.0.0\n" {
+		t.Errorf("the bin runs %q after restore", script)
+	}
+}
+
+// Programs whose assets are byte-identical share one store entry.
+func TestRun_storeDedup(t *testing.T) {
+	h := newHarness(t)
+	asset := binary("tool", "1.0.0")
+	h.release("owner/tool", "v1.0.0", map[string]fakeAsset{"tool-1.0.0.tar.gz": {body: asset}})
+	h.release("owner/mirror", "v1.0.0", map[string]fakeAsset{"mirror-1.0.0.tar.gz": {body: asset}})
+	mirror := h.program("mirror")
+	mirror.Bin[0].Src = "tool"
+
+	h.run(h.program("tool"))
+	last := h.run(mirror)
+	if last["mirror"].State != installer.StateDone {
+		t.Fatalf("mirror: %s, err %v", last["mirror"].State, last["mirror"].Err)
+	}
+	if n := len(storeEntries(t)); n != 1 {
+		t.Errorf("%d store entries, want 1: %v", n, storeEntries(t))
+	}
+	a, _ := os.Readlink(filepath.Join(system.SharePath(), "tool"))
+	b, _ := os.Readlink(filepath.Join(system.SharePath(), "mirror"))
+	if a != b {
+		t.Errorf("tool links to %s, mirror to %s", a, b)
+	}
+}
+
+func TestRun_pruneStore(t *testing.T) {
+	h := newHarness(t)
+	h.release("owner/tool", "v1.0.0", map[string]fakeAsset{"tool-1.0.0.tar.gz": {body: binary("tool", "1.0.0")}})
+	h.run(h.program("tool"))
+	h.release("owner/tool", "v2.0.0", map[string]fakeAsset{"tool-2.0.0.tar.gz": {body: binary("tool", "2.0.0")}})
+	h.run(h.program("tool"))
+	if n := len(storeEntries(t)); n != 2 {
+		t.Fatalf("%d store entries after the upgrade, want 2", n)
+	}
+
+	// Once the trash entry is gone and the grace period over, only the
+	// release in use is kept.
+	os.RemoveAll(trash.Path())
+	old := time.Now().Add(-2 * time.Hour)
+	for _, name := range storeEntries(t) {
+		os.Chtimes(filepath.Join(system.SharePath(), "david-dotfiles", "store", name), old, old)
+	}
+	h.run(h.program("tool"))
+	live, _ := os.Readlink(filepath.Join(system.SharePath(), "tool"))
+	if got := storeEntries(t); len(got) != 1 || got[0] != filepath.Base(live) {
+		t.Errorf("store = %v, want only %s", got, filepath.Base(live))
+	}
+}
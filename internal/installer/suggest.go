@@ -0,0 +1,79 @@
+package installer
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+)
+
+// suggestDepth bounds how deep SuggestBins looks inside an install dir; the
+// binary of a release archive is rarely more than a few levels down.
+const suggestDepth = 4
+
+// SuggestBins guesses the binaries to link for p from what was extracted into
+// installDir, for callers that cannot show the bin picker. In order:
+//   - the catalog's bin list, if every src exists;
+//   - executables named after the program (or its repo), shallowest first;
+//   - the only executable in the tree, if there is exactly one.
+//
+// It returns nil when none of these apply.
+func SuggestBins(p catalog.Program, installDir, version string) []catalog.Bin {
+	if len(p.Bin) > 0 {
+		bins := make([]catalog.Bin, 0, len(p.Bin))
+		for _, b := range p.Bin {
+			src := filepath.Join(installDir, strings.ReplaceAll(b.Src, "{version}", version))
+			if _, err := os.Stat(src); err != nil {
+				return nil
+			}
+			bins = append(bins, catalog.Bin{Src: src, Dst: b.Dst})
+		}
+		return bins
+	}
+
+	names := map[string]bool{p.Name: true}
+	if i := strings.LastIndex(p.Repo, "/"); i >= 0 {
+		names[p.Repo[i+1:]] = true
+	}
+	var named, all []string
+	filepath.WalkDir(walkRoot(installDir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(installDir, path)
+		if d.IsDir() {
+			if rel != "." && strings.Count(rel, string(filepath.Separator)) >= suggestDepth-1 {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Mode()&0111 == 0 {
+			return nil
+		}
+		all = append(all, path)
+		if names[d.Name()] {
+			named = append(named, path)
+		}
+		return nil
+	})
+
+	pick := func(path string) []catalog.Bin {
+		return []catalog.Bin{{Src: path, Dst: filepath.Base(path)}}
+	}
+	if len(named) > 0 {
+		best := named[0]
+		for _, path := range named[1:] {
+			if strings.Count(path, string(filepath.Separator)) < strings.Count(best, string(filepath.Separator)) {
+				best = path
+			}
+		}
+		return pick(best)
+	}
+	if len(all) == 1 {
+		return pick(all[0])
+	}
+	return nil
+}
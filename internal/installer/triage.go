@@ -0,0 +1,138 @@
+package installer
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+)
+
+// TriageAction is the answer to a StateAwaitingTriage message.
+type TriageAction int
+
+const (
+	TriageSkip  TriageAction = iota // report the failure and carry on with the rest
+	TriageRetry                     // install the program again
+	TriageAbort                     // report the failure and cancel the rest of the run
+)
+
+// Triage answers a StateAwaitingTriage message.
+type Triage struct {
+	Action TriageAction
+
+	// Program, if set with TriageRetry, replaces the program's catalog
+	// entry for the retry, e.g. after the user fixed its asset_pattern.
+	Program *catalog.Program
+}
+
+// triage holds back the first failure of a run (Options.Triage) until the
+// caller decides what to do with it. Failures of other programs meanwhile,
+// and all failures once it is settled, are reported as usual.
+type triage struct {
+	mu      sync.Mutex
+	owner   string // the program whose failure is being triaged
+	held    *ProgressMsg
+	settled bool
+}
+
+// hold keeps msg back if it is the first failure of the run, or a failure of
+// its retry, reporting whether it did. A nil triage holds nothing.
+func (t *triage) hold(msg ProgressMsg) bool {
+	if t == nil || errors.Is(msg.Err, context.Canceled) {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.settled || t.owner != "" && t.owner != msg.Program {
+		return false
+	}
+	t.owner, t.held = msg.Program, &msg
+	return true
+}
+
+// take returns and clears the failure held back for program, if any.
+func (t *triage) take(program string) (ProgressMsg, bool) {
+	if t == nil {
+		return ProgressMsg{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.owner != program || t.held == nil {
+		return ProgressMsg{}, false
+	}
+	msg := *t.held
+	t.held = nil
+	return msg, true
+}
+
+// settle ends the triage: later failures are reported straight away.
+func (t *triage) settle() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.settled = true
+}
+
+// triageFailure asks what to do about p's failure, if it was held back, and
+// does it: p is installed again, with the edited entry if one came back,
+// until it succeeds or the answer is to skip or abort. The run is paused
+// while the question is open, so no further programs start.
+func (r *runner) triageFailure(ctx context.Context, p catalog.Program) {
+	msg, ok := r.triage.take(p.Name)
+	if !ok {
+		return
+	}
+	defer r.triage.settle()
+	for {
+		var t Triage
+		if err := ctx.Err(); err != nil {
+			t = Triage{Action: TriageSkip}
+		} else {
+			t = r.askTriage(ctx, msg)
+		}
+		if t.Action != TriageRetry {
+			r.triage.settle()
+			r.send(msg)
+			if t.Action == TriageAbort {
+				r.cancel()
+			}
+			return
+		}
+		if t.Program != nil {
+			p = *t.Program
+		}
+		r.net <- struct{}{}
+		slot := &netSlot{s: r.net}
+		r.install(ctx, p, slot)
+		slot.release()
+		if msg, ok = r.triage.take(p.Name); !ok {
+			return
+		}
+	}
+}
+
+// askTriage sends msg's failure as a StateAwaitingTriage message and waits
+// for the answer, pausing the run meanwhile unless it already is.
+func (r *runner) askTriage(ctx context.Context, msg ProgressMsg) Triage {
+	if r.pause != nil && !r.pause.Paused() {
+		r.pause.Pause()
+		defer r.pause.Resume()
+	}
+	triageCh := make(chan Triage, 1)
+	r.send(ProgressMsg{Program: msg.Program, State: StateAwaitingTriage, Version: msg.Version, Err: msg.Err, TriageCh: triageCh})
+	select {
+	case t := <-triageCh:
+		return t
+	case <-ctx.Done():
+		return Triage{Action: TriageSkip}
+	}
+}
+
+// flush reports the failure held back for program, if any, without asking
+// about it; an install that panicked cannot be retried.
+func (r *runner) flush(program string) {
+	if msg, ok := r.triage.take(program); ok {
+		r.triage.settle()
+		r.send(msg)
+	}
+}
@@ -0,0 +1,153 @@
+package inventory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dsaleh/david-dotfiles/internal/gitsync"
+	"github.com/dsaleh/david-dotfiles/internal/sign"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// Machine is one exported state file, named after the machine it came from.
+type Machine struct {
+	Name  string
+	State *state.State
+}
+
+// Row is one program across every machine. Versions is parallel to the
+// machines slice passed to Matrix; "" means not installed there.
+type Row struct {
+	Program  string
+	Versions []string
+	Drift    bool // not every machine has the same version
+}
+
+// IsGitURL reports whether src should be cloned rather than read as a directory.
+func IsGitURL(src string) bool {
+	return strings.HasPrefix(src, "git@") ||
+		strings.HasPrefix(src, "ssh://") ||
+		strings.HasPrefix(src, "https://") ||
+		strings.HasSuffix(src, ".git")
+}
+
+// Fetch resolves src to a local directory. Directories are returned as-is; git
+// URLs are cloned into the data dir on first use and fast-forwarded afterwards.
+func Fetch(ctx context.Context, src string) (string, error) {
+	if !IsGitURL(src) {
+		return src, nil
+	}
+	sum := sha256.Sum256([]byte(src))
+	dir := filepath.Join(system.DataPath(), "inventory", hex.EncodeToString(sum[:8]))
+
+	if repo, err := gitsync.Open(dir); err == nil {
+		return dir, repo.Pull(ctx)
+	}
+	if _, err := gitsync.Clone(ctx, src, dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// LoadDir reads every *.json state file in dir. The file name without the
+// extension becomes the machine name. Machines are sorted by name.
+func LoadDir(dir string) ([]Machine, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	var machines []Machine
+	for _, path := range paths {
+		st, err := state.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		machines = append(machines, Machine{Name: name, State: st})
+	}
+	if len(machines) == 0 {
+		return nil, fmt.Errorf("no state files (*.json) found in %s", dir)
+	}
+	return machines, nil
+}
+
+// LoadVerified is LoadDir for state files fetched from a shared location:
+// each one must carry a signature by one of the trusted keys (see package
+// sign), or nothing is loaded.
+func LoadVerified(dir string, trusted []string) ([]Machine, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		if err := sign.Verify(trusted, path); err != nil {
+			return nil, err
+		}
+	}
+	return LoadDir(dir)
+}
+
+// Export copies st into dir as <name>.json, for collection by LoadDir.
+func Export(st *state.State, dir, name string) (string, error) {
+	path := filepath.Join(dir, name+".json")
+	out := state.New(path)
+	for _, prog := range st.Names() {
+		ps, _ := st.Get(prog)
+		out.Set(prog, ps)
+	}
+	return path, out.Save()
+}
+
+// Matrix builds one Row per program found on any machine, sorted by name.
+func Matrix(machines []Machine) []Row {
+	seen := map[string]bool{}
+	var names []string
+	for _, m := range machines {
+		for _, name := range m.State.Names() {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	rows := make([]Row, 0, len(names))
+	for _, name := range names {
+		row := Row{Program: name, Versions: make([]string, len(machines))}
+		for i, m := range machines {
+			if ps, ok := m.State.Get(name); ok {
+				row.Versions[i] = ps.Version
+			}
+			if row.Versions[i] != row.Versions[0] {
+				row.Drift = true
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// Majority returns the most common non-empty version in the row, used as the
+// reference when highlighting drifting cells. Ties go to the first seen.
+func (r Row) Majority() string {
+	counts := map[string]int{}
+	best := ""
+	for _, v := range r.Versions {
+		if v == "" {
+			continue
+		}
+		counts[v]++
+		if counts[v] > counts[best] {
+			best = v
+		}
+	}
+	return best
+}
@@ -0,0 +1,100 @@
+package inventory_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dsaleh/david-dotfiles/internal/inventory"
+	"github.com/dsaleh/david-dotfiles/internal/sign"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+)
+
+func TestExportLoadAndMatrix(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "inventory-*")
+	defer os.RemoveAll(dir)
+
+	laptop := state.New("")
+	laptop.Set("fzf", state.ProgramState{Version: "0.60.0"})
+	laptop.Set("nvim", state.ProgramState{Version: "0.10.1"})
+	server := state.New("")
+	server.Set("fzf", state.ProgramState{Version: "0.60.0"})
+	server.Set("nvim", state.ProgramState{Version: "0.9.5"})
+	desktop := state.New("")
+	desktop.Set("nvim", state.ProgramState{Version: "0.10.1"})
+
+	for name, st := range map[string]*state.State{"laptop": laptop, "server": server, "desktop": desktop} {
+		if _, err := inventory.Export(st, dir, name); err != nil {
+			t.Fatalf("export %s: %v", name, err)
+		}
+	}
+
+	machines, err := inventory.LoadDir(dir)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(machines) != 3 || machines[0].Name != "desktop" {
+		t.Fatalf("unexpected machines: %+v", machines)
+	}
+
+	rows := inventory.Matrix(machines)
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	fzf, nvim := rows[0], rows[1]
+	if !fzf.Drift || fzf.Versions[0] != "" {
+		t.Errorf("fzf missing on desktop should be drift: %+v", fzf)
+	}
+	if !nvim.Drift || nvim.Majority() != "0.10.1" {
+		t.Errorf("unexpected nvim row: %+v (majority %q)", nvim, nvim.Majority())
+	}
+}
+
+func TestLoadDir_empty(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "inventory-*")
+	defer os.RemoveAll(dir)
+
+	if _, err := inventory.LoadDir(dir); err == nil {
+		t.Fatal("expected error for a directory without state files")
+	}
+}
+
+func TestLoadVerified(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "inventory-*")
+	defer os.RemoveAll(dir)
+	key := filepath.Join(dir, "keys", "signing.key")
+	pub, err := sign.GenerateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := state.New("")
+	st.Set("fzf", state.ProgramState{Version: "0.60.0"})
+	states := filepath.Join(dir, "states")
+	laptop, _ := inventory.Export(st, states, "laptop")
+	server, _ := inventory.Export(st, states, "server")
+	sign.Sign(key, laptop)
+
+	if _, err := inventory.LoadVerified(states, []string{pub}); err == nil {
+		t.Fatal("expected an error for the unsigned server state")
+	}
+	sign.Sign(key, server)
+	machines, err := inventory.LoadVerified(states, []string{pub})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(machines) != 2 {
+		t.Errorf("expected 2 machines, got %+v", machines)
+	}
+}
+
+func TestIsGitURL(t *testing.T) {
+	for _, s := range []string{"git@github.com:me/inv.git", "https://github.com/me/inv", "ssh://host/inv", "/srv/inv.git"} {
+		if !inventory.IsGitURL(s) {
+			t.Errorf("expected %q to be a git URL", s)
+		}
+	}
+	if inventory.IsGitURL("/home/me/inventory") {
+		t.Error("plain directory treated as git URL")
+	}
+}
@@ -1,11 +1,139 @@
 package linker
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// Mode selects how a binary is placed in the bin dir.
+type Mode string
+
+const (
+	Symlink  Mode = "symlink"  // default
+	Relative Mode = "relative" // symlink with a target relative to the bin dir
+	Hardlink Mode = "hardlink"
+	Copy     Mode = "copy"
 )
 
+// ParseMode validates a link_mode setting. The empty string means Symlink.
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "", Symlink:
+		return Symlink, nil
+	case Relative, Hardlink, Copy:
+		return Mode(s), nil
+	}
+	return "", fmt.Errorf("invalid link_mode %q: expected symlink, relative, hardlink or copy", s)
+}
+
+// Place puts src at binDir/dst using mode. Symlink mode behaves exactly like
+// Link; Relative does the same with a link target relative to binDir
+// (e.g. ../share/prog/bin/x), so moving or bind-mounting the whole tree keeps
+// it valid. For Hardlink and Copy, existing symlinks are replaced; an existing
+// regular file is only replaced when owned is set, i.e. the caller placed it
+// there in an earlier install — otherwise an error is returned.
+func Place(mode Mode, src, binDir, dst string, owned bool) error {
+	switch mode {
+	case Symlink, "":
+		return Link(src, binDir, dst)
+	case Relative:
+		rel, err := filepath.Rel(binDir, src)
+		if err != nil {
+			return fmt.Errorf("relative path to %s: %w", src, err)
+		}
+		return Link(rel, binDir, dst)
+	}
+	target := filepath.Join(binDir, dst)
+	if info, err := os.Lstat(target); err == nil {
+		if info.Mode()&os.ModeSymlink == 0 && !owned {
+			return fmt.Errorf("%s already exists as a regular file — remove it manually before installing", target)
+		}
+		if err := os.Remove(target); err != nil {
+			return fmt.Errorf("remove existing %s: %w", target, err)
+		}
+	}
+
+	if mode == Hardlink {
+		if err := os.Link(src, target); err != nil {
+			return fmt.Errorf("create hard link %s -> %s: %w", target, src, err)
+		}
+		return nil
+	}
+	if err := copyFile(src, target); err != nil {
+		return fmt.Errorf("copy %s -> %s: %w", src, target, err)
+	}
+	return nil
+}
+
+// copyFile copies src to dst via a temp file in dst's directory, so a
+// half-written binary never appears under dst's name.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+"-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), system.Perm(info.Mode().Perm())); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// Remove deletes binDir/dst placed by Place with mode, if Owns reports it
+// belongs to ownerDir. It reports whether anything was removed.
+func Remove(mode Mode, binDir, dst, ownerDir string) (bool, error) {
+	if !Owns(mode, binDir, dst, ownerDir) {
+		return false, nil
+	}
+	target := filepath.Join(binDir, dst)
+	if err := os.Remove(target); err != nil {
+		return false, fmt.Errorf("remove %s: %w", target, err)
+	}
+	return true, nil
+}
+
+// Owns reports whether binDir/dst, placed by Place with mode, belongs to
+// ownerDir. Symlinks do if they point inside ownerDir, as with Unlink. Hard
+// links and copies carry no reference to their origin, so for those modes any
+// regular file does — callers only pass names they recorded as placed.
+func Owns(mode Mode, binDir, dst, ownerDir string) bool {
+	info, err := os.Lstat(filepath.Join(binDir, dst))
+	if err != nil {
+		return false
+	}
+	if mode == Symlink || mode == Relative || mode == "" || info.Mode()&os.ModeSymlink != 0 {
+		src, err := readlink(binDir, dst)
+		return err == nil && strings.HasPrefix(src, filepath.Clean(ownerDir)+string(filepath.Separator))
+	}
+	return true
+}
+
 // Link creates a symlink at binDir/dst pointing to src.
 // If dst is an existing symlink it is replaced.
 // If dst is a regular file, an error is returned.
@@ -28,3 +156,90 @@ func Link(src, binDir, dst string) error {
 	}
 	return nil
 }
+
+// Unlink removes binDir/dst if it is a symlink pointing inside ownerDir.
+// Links that are missing or have been repointed elsewhere are left alone.
+// It reports whether a link was removed.
+func Unlink(binDir, dst, ownerDir string) (bool, error) {
+	target := filepath.Join(binDir, dst)
+	src, err := readlink(binDir, dst)
+	if err != nil {
+		return false, nil
+	}
+	if !strings.HasPrefix(src, filepath.Clean(ownerDir)+string(filepath.Separator)) {
+		return false, nil
+	}
+	if err := os.Remove(target); err != nil {
+		return false, fmt.Errorf("remove symlink %s: %w", target, err)
+	}
+	return true, nil
+}
+
+// Owned returns the symlinks in binDir that point inside ownerDir, as
+// catalog-style pairs: Dst is the link name, Src its absolute target. They are
+// returned in directory order (sorted by name).
+func Owned(binDir, ownerDir string) ([]catalog.Bin, error) {
+	entries, err := os.ReadDir(binDir)
+	if err != nil {
+		return nil, err
+	}
+	prefix := filepath.Clean(ownerDir) + string(filepath.Separator)
+	var out []catalog.Bin
+	for _, e := range entries {
+		if e.Type()&os.ModeSymlink == 0 {
+			continue
+		}
+		src, err := readlink(binDir, e.Name())
+		if err != nil || !strings.HasPrefix(src, prefix) {
+			continue
+		}
+		out = append(out, catalog.Bin{Src: src, Dst: e.Name()})
+	}
+	return out, nil
+}
+
+// Fingerprint returns the current target of the symlink binDir/dst, as
+// written (relative targets are not resolved), and a SHA-256 hash of it. It
+// fails if dst is missing or not a symlink.
+func Fingerprint(binDir, dst string) (target, hash string, err error) {
+	target, err = os.Readlink(filepath.Join(binDir, dst))
+	if err != nil {
+		return "", "", err
+	}
+	return target, HashTarget(target), nil
+}
+
+// HashTarget hashes a symlink target path for Fingerprint.
+func HashTarget(target string) string {
+	sum := sha256.Sum256([]byte(target))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashContent returns the SHA-256 of what the bin entry binDir/dst resolves
+// to: the binary's contents, for telling a binary modified in place from the
+// one installed.
+func HashContent(binDir, dst string) (string, error) {
+	f, err := os.Open(filepath.Join(binDir, dst))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readlink returns the absolute target of the symlink binDir/name, resolving
+// relative targets against binDir.
+func readlink(binDir, name string) (string, error) {
+	src, err := os.Readlink(filepath.Join(binDir, name))
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(src) {
+		src = filepath.Join(binDir, src)
+	}
+	return src, nil
+}
@@ -73,3 +73,195 @@ func TestLink_errorsOnRegularFile(t *testing.T) {
 		t.Fatal("expected error when dst is a regular file")
 	}
 }
+
+func TestUnlink_onlyRemovesOwnedLinks(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "linker-*")
+	defer os.RemoveAll(dir)
+
+	owner := filepath.Join(dir, "share", "tool")
+	os.MkdirAll(owner, 0755)
+	os.WriteFile(filepath.Join(owner, "tool"), []byte("binary"), 0755)
+	other := filepath.Join(dir, "other")
+	os.WriteFile(other, []byte("binary"), 0755)
+
+	binDir := filepath.Join(dir, "bin")
+	os.MkdirAll(binDir, 0755)
+	os.Symlink(filepath.Join(owner, "tool"), filepath.Join(binDir, "tool"))
+	os.Symlink(other, filepath.Join(binDir, "repointed"))
+
+	removed, err := linker.Unlink(binDir, "tool", owner)
+	if err != nil || !removed {
+		t.Fatalf("expected owned link removed, got removed=%v err=%v", removed, err)
+	}
+	if _, err := os.Lstat(filepath.Join(binDir, "tool")); !os.IsNotExist(err) {
+		t.Error("owned link still present")
+	}
+
+	removed, err = linker.Unlink(binDir, "repointed", owner)
+	if err != nil || removed {
+		t.Errorf("foreign link should be kept, got removed=%v err=%v", removed, err)
+	}
+	removed, _ = linker.Unlink(binDir, "missing", owner)
+	if removed {
+		t.Error("missing link reported as removed")
+	}
+}
+
+func TestOwned(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "linker-*")
+	defer os.RemoveAll(dir)
+
+	owner := filepath.Join(dir, "share", "tool")
+	other := filepath.Join(dir, "share", "other")
+	binDir := filepath.Join(dir, "bin")
+	os.MkdirAll(owner, 0755)
+	os.MkdirAll(other, 0755)
+	os.MkdirAll(binDir, 0755)
+	os.WriteFile(filepath.Join(owner, "tool"), []byte("binary"), 0755)
+	os.WriteFile(filepath.Join(other, "other"), []byte("binary"), 0755)
+
+	os.Symlink(filepath.Join(owner, "tool"), filepath.Join(binDir, "t"))
+	os.Symlink(filepath.Join(owner, "tool"), filepath.Join(binDir, "tool"))
+	os.Symlink(filepath.Join(other, "other"), filepath.Join(binDir, "other"))
+	os.WriteFile(filepath.Join(binDir, "plain"), []byte("file"), 0755)
+
+	bins, err := linker.Owned(binDir, owner)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(bins) != 2 || bins[0].Dst != "t" || bins[1].Dst != "tool" {
+		t.Fatalf("unexpected links: %+v", bins)
+	}
+	if bins[0].Src != filepath.Join(owner, "tool") {
+		t.Errorf("unexpected src %s", bins[0].Src)
+	}
+}
+
+func TestPlace_copyAndHardlink(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "linker-*")
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "mybinary")
+	os.WriteFile(src, []byte("binary"), 0755)
+	binDir := filepath.Join(dir, "bin")
+	os.MkdirAll(binDir, 0755)
+
+	if err := linker.Place(linker.Copy, src, binDir, "copied", false); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	info, err := os.Lstat(filepath.Join(binDir, "copied"))
+	if err != nil || !info.Mode().IsRegular() || info.Mode().Perm() != 0755 {
+		t.Fatalf("expected executable regular file, got %v (%v)", info, err)
+	}
+
+	if err := linker.Place(linker.Hardlink, src, binDir, "hard", false); err != nil {
+		t.Fatalf("hardlink: %v", err)
+	}
+	srcInfo, _ := os.Stat(src)
+	hardInfo, _ := os.Stat(filepath.Join(binDir, "hard"))
+	if !os.SameFile(srcInfo, hardInfo) {
+		t.Error("expected hard link to share the source inode")
+	}
+
+	// A regular file is only replaced when the caller owns it.
+	if err := linker.Place(linker.Copy, src, binDir, "copied", false); err == nil {
+		t.Error("expected error replacing an unowned regular file")
+	}
+	if err := linker.Place(linker.Copy, src, binDir, "copied", true); err != nil {
+		t.Errorf("replace owned copy: %v", err)
+	}
+
+	if ok, err := linker.Remove(linker.Copy, binDir, "copied", dir); !ok || err != nil {
+		t.Errorf("remove copy: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	if m, err := linker.ParseMode(""); err != nil || m != linker.Symlink {
+		t.Errorf("empty mode: got %q, %v", m, err)
+	}
+	if _, err := linker.ParseMode("junction"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestPlace_relative(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "linker-*")
+	defer os.RemoveAll(dir)
+
+	owner := filepath.Join(dir, "share", "prog")
+	binDir := filepath.Join(dir, "bin")
+	os.MkdirAll(filepath.Join(owner, "bin"), 0755)
+	os.MkdirAll(binDir, 0755)
+	os.WriteFile(filepath.Join(owner, "bin", "x"), []byte("binary"), 0755)
+
+	if err := linker.Place(linker.Relative, filepath.Join(owner, "bin", "x"), binDir, "x", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, _ := os.Readlink(filepath.Join(binDir, "x"))
+	if got != filepath.Join("..", "share", "prog", "bin", "x") {
+		t.Errorf("expected relative target, got %s", got)
+	}
+	if _, err := os.Stat(filepath.Join(binDir, "x")); err != nil {
+		t.Errorf("relative link does not resolve: %v", err)
+	}
+
+	if bins, _ := linker.Owned(binDir, owner); len(bins) != 1 {
+		t.Errorf("expected Owned to resolve relative link, got %+v", bins)
+	}
+	if ok, err := linker.Remove(linker.Relative, binDir, "x", owner); !ok || err != nil {
+		t.Errorf("remove: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestFingerprint_changesWhenRepointed(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "linker-*")
+	defer os.RemoveAll(dir)
+	binDir := filepath.Join(dir, "bin")
+	os.MkdirAll(binDir, 0755)
+
+	if err := linker.Link(filepath.Join(dir, "a"), binDir, "tool"); err != nil {
+		t.Fatal(err)
+	}
+	target, hash, err := linker.Fingerprint(binDir, "tool")
+	if err != nil {
+		t.Fatalf("fingerprint: %v", err)
+	}
+	if target != filepath.Join(dir, "a") || hash != linker.HashTarget(target) {
+		t.Errorf("got %q %q", target, hash)
+	}
+
+	linker.Link(filepath.Join(dir, "b"), binDir, "tool")
+	if _, again, _ := linker.Fingerprint(binDir, "tool"); again == hash {
+		t.Error("expected a different hash after the link was repointed")
+	}
+
+	os.Remove(filepath.Join(binDir, "tool"))
+	os.WriteFile(filepath.Join(binDir, "tool"), []byte("x"), 0755)
+	if _, _, err := linker.Fingerprint(binDir, "tool"); err == nil {
+		t.Error("expected an error for a regular file")
+	}
+}
+
+func TestHashContent_followsLink(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "linker-*")
+	defer os.RemoveAll(dir)
+	binDir := filepath.Join(dir, "bin")
+	os.MkdirAll(binDir, 0755)
+	src := filepath.Join(dir, "tool")
+	os.WriteFile(src, []byte("v1"), 0755)
+	linker.Link(src, binDir, "tool")
+
+	before, err := linker.HashContent(binDir, "tool")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	os.WriteFile(src, []byte("v2"), 0755)
+	if after, _ := linker.HashContent(binDir, "tool"); after == before {
+		t.Error("expected a different hash after the binary changed")
+	}
+	os.Remove(src)
+	if _, err := linker.HashContent(binDir, "tool"); err == nil {
+		t.Error("expected an error for a dangling link")
+	}
+}
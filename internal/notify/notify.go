@@ -0,0 +1,121 @@
+// Package notify streams the progress of a TUI run as JSON lines over a local
+// Unix socket, so status bars and tmux segments can show "installing 3/12"
+// without scraping the terminal.
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/report"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// writeTimeout bounds each write to a listener, so a stuck client cannot
+// stall the TUI; it is dropped instead.
+const writeTimeout = 100 * time.Millisecond
+
+// Path returns the default socket location: $XDG_RUNTIME_DIR/david-dotfiles.sock,
+// falling back to the data dir.
+func Path() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "david-dotfiles.sock")
+	}
+	return filepath.Join(system.DataPath(), "progress.sock")
+}
+
+// Status is one line sent to listeners: the progress event in its --json
+// form plus the run's overall counts.
+type Status struct {
+	report.Event
+	Done  int `json:"done"`  // programs in a terminal state
+	Total int `json:"total"` // programs in the run
+}
+
+// Server accepts listeners on a Unix socket and sends them every published
+// line. A listener that connects mid-run first receives the latest line.
+type Server struct {
+	ln   net.Listener
+	path string
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	last  []byte
+}
+
+// Listen serves on the socket at path. A socket left behind by a crashed run
+// is replaced; one another run is still serving on is an error.
+func Listen(path string) (*Server, error) {
+	if c, err := net.Dial("unix", path); err == nil {
+		c.Close()
+		return nil, fmt.Errorf("another run is serving progress on %s", path)
+	}
+	os.Remove(path)
+	if err := os.MkdirAll(filepath.Dir(path), system.DirPerm()); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", path, err)
+	}
+	s := &Server{ln: ln, path: path, conns: map[net.Conn]struct{}{}}
+	go s.accept()
+	return s, nil
+}
+
+func (s *Server) accept() {
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		if s.last != nil {
+			s.write(c, s.last)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Publish sends v as one JSON line to every listener.
+func (s *Server) Publish(v any) {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last = line
+	for c := range s.conns {
+		s.write(c, line)
+	}
+}
+
+// write sends line to c, dropping c if it fails. s.mu must be held.
+func (s *Server) write(c net.Conn, line []byte) {
+	c.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.Write(line); err != nil {
+		c.Close()
+		delete(s.conns, c)
+	}
+}
+
+// Close disconnects all listeners and removes the socket.
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	s.mu.Lock()
+	for c := range s.conns {
+		c.Close()
+		delete(s.conns, c)
+	}
+	s.mu.Unlock()
+	os.Remove(s.path)
+	return err
+}
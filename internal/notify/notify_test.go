@@ -0,0 +1,77 @@
+package notify_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/notify"
+	"github.com/dsaleh/david-dotfiles/internal/report"
+)
+
+func readStatus(t *testing.T, r *bufio.Reader) notify.Status {
+	t.Helper()
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	var st notify.Status
+	if err := json.Unmarshal(line, &st); err != nil {
+		t.Fatalf("decode %s: %v", line, err)
+	}
+	return st
+}
+
+func TestServer(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "notify-*")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "progress.sock")
+
+	srv, err := notify.Listen(path)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer srv.Close()
+	if _, err := notify.Listen(path); err == nil {
+		t.Error("expected an error while another server is listening")
+	}
+
+	srv.Publish(notify.Status{Event: report.Event{Type: "progress", Program: "fzf", State: "downloading"}, Done: 2, Total: 5})
+
+	// A listener connecting mid-run gets the latest status, then new ones.
+	c, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(c)
+	if st := readStatus(t, r); st.Program != "fzf" || st.Done != 2 || st.Total != 5 {
+		t.Errorf("unexpected first status %+v", st)
+	}
+
+	srv.Publish(notify.Status{Event: report.Event{Type: "progress", Program: "fzf", State: "done"}, Done: 3, Total: 5})
+	if st := readStatus(t, r); st.State != "done" || st.Done != 3 {
+		t.Errorf("unexpected second status %+v", st)
+	}
+}
+
+func TestListen_staleSocket(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "notify-*")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "progress.sock")
+	os.WriteFile(path, nil, 0644) // left behind by a crashed run
+
+	srv, err := notify.Listen(path)
+	if err != nil {
+		t.Fatalf("listen over stale socket: %v", err)
+	}
+	srv.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket not removed on Close: %v", err)
+	}
+}
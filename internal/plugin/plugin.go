@@ -0,0 +1,118 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// Hook names a lifecycle point at which plugins are invoked.
+type Hook string
+
+const (
+	PreResolve  Hook = "pre-resolve"  // before the release version is looked up
+	PostExtract Hook = "post-extract" // after the asset is extracted into InstallDir
+	PreLink     Hook = "pre-link"     // before Bins are symlinked
+	PostRun     Hook = "post-run"     // once, after every program has finished
+)
+
+// Bin is a binary about to be linked.
+type Bin struct {
+	Src string `json:"src"`
+	Dst string `json:"dst"`
+}
+
+// Result is the final outcome of one program, sent with PostRun.
+type Result struct {
+	Program string `json:"program"`
+	State   string `json:"state"`
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Payload is written as JSON to each plugin's stdin. Fields that do not apply
+// to the hook are omitted.
+type Payload struct {
+	Hook       Hook     `json:"hook"`
+	Program    string   `json:"program,omitempty"`
+	Repo       string   `json:"repo,omitempty"`
+	Version    string   `json:"version,omitempty"`
+	InstallDir string   `json:"install_dir,omitempty"`
+	Bins       []Bin    `json:"bins,omitempty"`
+	Results    []Result `json:"results,omitempty"`
+}
+
+// Dir returns the directory scanned for plugins.
+func Dir() string {
+	return filepath.Join(system.ConfigPath(), "plugins")
+}
+
+// Runner invokes every plugin executable for each hook.
+type Runner struct {
+	plugins []string
+}
+
+// Discover returns a Runner for the executable regular files in dir, in
+// lexical order (so users can prefix names with numbers to order them).
+// A missing directory yields a Runner with no plugins.
+func Discover(dir string) (*Runner, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return &Runner{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read plugin dir: %w", err)
+	}
+	var plugins []string
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		info, err := os.Stat(path) // follow symlinks to the real executable
+		if err != nil || !info.Mode().IsRegular() || info.Mode()&0111 == 0 {
+			continue
+		}
+		plugins = append(plugins, path)
+	}
+	sort.Strings(plugins)
+	return &Runner{plugins: plugins}, nil
+}
+
+// Len reports how many plugins were discovered.
+func (r *Runner) Len() int {
+	return len(r.plugins)
+}
+
+// Run invokes each plugin with the hook name as its only argument and the
+// JSON payload on stdin. It stops at the first plugin that exits non-zero and
+// returns an error including that plugin's stderr.
+func (r *Runner) Run(ctx context.Context, payload Payload) error {
+	if len(r.plugins) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode plugin payload: %w", err)
+	}
+	for _, path := range r.plugins {
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, path, string(payload.Hook))
+		cmd.Stdin = bytes.NewReader(data)
+		cmd.Stderr = &stderr
+		cmd.Env = append(os.Environ(), "DOTFILES_HOOK="+string(payload.Hook))
+		if err := cmd.Run(); err != nil {
+			msg := strings.TrimSpace(stderr.String())
+			if msg == "" {
+				return fmt.Errorf("plugin %s (%s): %w", filepath.Base(path), payload.Hook, err)
+			}
+			return fmt.Errorf("plugin %s (%s): %w: %s", filepath.Base(path), payload.Hook, err, msg)
+		}
+	}
+	return nil
+}
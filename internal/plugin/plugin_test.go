@@ -0,0 +1,75 @@
+package plugin_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dsaleh/david-dotfiles/internal/plugin"
+)
+
+func TestDiscover_missingDir(t *testing.T) {
+	r, err := plugin.Discover("/nonexistent/plugins-xyzzy")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Len() != 0 {
+		t.Errorf("expected no plugins, got %d", r.Len())
+	}
+}
+
+func TestRun_passesPayload(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "plugins-*")
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "payload.json")
+	script := "#!/bin/sh\ncat > " + out + "\n"
+	os.WriteFile(filepath.Join(dir, "10-record"), []byte(script), 0755)
+	// Non-executable files are ignored.
+	os.WriteFile(filepath.Join(dir, "README"), []byte("not a plugin"), 0644)
+
+	r, err := plugin.Discover(dir)
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	if r.Len() != 1 {
+		t.Fatalf("expected 1 plugin, got %d", r.Len())
+	}
+
+	err = r.Run(context.Background(), plugin.Payload{Hook: plugin.PostExtract, Program: "fzf", InstallDir: "/tmp/fzf"})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("plugin did not run: %v", err)
+	}
+	var got plugin.Payload
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if got.Hook != plugin.PostExtract || got.Program != "fzf" || got.InstallDir != "/tmp/fzf" {
+		t.Errorf("unexpected payload: %+v", got)
+	}
+}
+
+func TestRun_failureIncludesStderr(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "plugins-*")
+	defer os.RemoveAll(dir)
+
+	script := "#!/bin/sh\necho 'virus found' >&2\nexit 3\n"
+	os.WriteFile(filepath.Join(dir, "scan"), []byte(script), 0755)
+
+	r, _ := plugin.Discover(dir)
+	err := r.Run(context.Background(), plugin.Payload{Hook: plugin.PostExtract, Program: "fzf"})
+	if err == nil {
+		t.Fatal("expected error from failing plugin")
+	}
+	if !strings.Contains(err.Error(), "virus found") {
+		t.Errorf("expected stderr in error, got: %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package remote
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// Target is a remote machine reached over the system ssh client. Programs are
+// installed under the remote user's ~/.local/share and linked into ~/.local/bin,
+// mirroring the local layout.
+type Target struct {
+	Host string // [user@]host, passed verbatim to ssh
+	SSH  string // ssh executable; defaults to "ssh"
+}
+
+// Parse validates a --target value of the form [user@]host.
+func Parse(s string) (*Target, error) {
+	s = strings.TrimSpace(s)
+	host := s
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		if i == 0 {
+			return nil, fmt.Errorf("invalid target %q: empty user", s)
+		}
+		host = s[i+1:]
+	}
+	if host == "" || strings.ContainsAny(s, " /:") {
+		return nil, fmt.Errorf("invalid target %q: expected user@host", s)
+	}
+	return &Target{Host: s}, nil
+}
+
+// ShareDir is the remote install root for a program, as a shell expression.
+func ShareDir(name string) string {
+	return `"$HOME"/` + shellQuote(filepath.Join(system.ShareDir, name))
+}
+
+// BinDir is the remote symlink directory, as a shell expression.
+func BinDir() string {
+	return `"$HOME"/` + shellQuote(system.BinDir)
+}
+
+// Run executes script with POSIX sh on the remote host and returns its stdout.
+// stdin may be nil.
+func (t *Target) Run(ctx context.Context, script string, stdin io.Reader) ([]byte, error) {
+	ssh := t.SSH
+	if ssh == "" {
+		ssh = "ssh"
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ssh, "-o", "BatchMode=yes", t.Host, "sh -c "+shellQuote(script))
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("ssh %s: %w: %s", t.Host, err, msg)
+		}
+		return nil, fmt.Errorf("ssh %s: %w", t.Host, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// EnsureBaseDirs creates ~/.local/share and ~/.local/bin on the remote host.
+func (t *Target) EnsureBaseDirs(ctx context.Context) error {
+	_, err := t.Run(ctx, `mkdir -p "$HOME"/`+shellQuote(system.ShareDir)+` `+BinDir(), nil)
+	return err
+}
+
+// InstalledVersion returns the contents of the program's remote .version file,
+// or "" if it is not installed.
+func (t *Target) InstalledVersion(ctx context.Context, name string) string {
+	out, err := t.Run(ctx, `cat `+ShareDir(name)+`/.version 2>/dev/null || true`, nil)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Push replaces the program's remote install dir with the contents of
+// localDir, streamed as a tar archive over the ssh connection.
+func (t *Target) Push(ctx context.Context, localDir, name string) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(WriteTar(pw, localDir))
+	}()
+	dir := ShareDir(name)
+	_, err := t.Run(ctx, `rm -rf `+dir+` && mkdir -p `+dir+` && tar -xf - -C `+dir, pr)
+	pr.Close()
+	return err
+}
+
+// Link creates ~/.local/bin/dst on the remote host pointing at src, a path
+// relative to the program's remote install dir. Like linker.Link, an existing
+// symlink is replaced but a regular file is left alone and reported.
+func (t *Target) Link(ctx context.Context, name, src, dst string) error {
+	target := BinDir() + `/` + shellQuote(dst)
+	script := `t=` + target + `; ` +
+		`if [ -e "$t" ] && [ ! -L "$t" ]; then echo "$t already exists as a regular file" >&2; exit 1; fi; ` +
+		`ln -sfn ` + ShareDir(name) + `/` + shellQuote(src) + ` "$t"`
+	_, err := t.Run(ctx, script, nil)
+	return err
+}
+
+// Remove deletes the program's remote install dir and those of the named bin
+// links that still point into it, returning the remote paths removed.
+func (t *Target) Remove(ctx context.Context, name string, bins []string) ([]string, error) {
+	dir := ShareDir(name)
+	var script strings.Builder
+	for _, b := range bins {
+		script.WriteString(`t=` + BinDir() + `/` + shellQuote(b) + `; ` +
+			`case "$(readlink "$t")" in ` + dir + `/*) rm -f "$t" && echo "$t";; esac; `)
+	}
+	script.WriteString(`if [ -d ` + dir + ` ]; then rm -rf ` + dir + ` && echo ` + dir + `; fi`)
+	out, err := t.Run(ctx, script.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+	return strings.Split(string(bytes.TrimSpace(out)), "\n"), nil
+}
+
+// WriteTar writes the tree rooted at dir to w as an uncompressed tar stream,
+// preserving file modes and symlinks.
+func WriteTar(w io.Writer, dir string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// shellQuote wraps s in single quotes for POSIX sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
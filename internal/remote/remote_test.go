@@ -0,0 +1,107 @@
+package remote_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dsaleh/david-dotfiles/internal/remote"
+)
+
+func TestParse(t *testing.T) {
+	for _, s := range []string{"david@server", "server", "me@10.0.0.2"} {
+		if _, err := remote.Parse(s); err != nil {
+			t.Errorf("Parse(%q): unexpected error: %v", s, err)
+		}
+	}
+	for _, s := range []string{"", "@host", "user@", "a b@host", "host:/path"} {
+		if _, err := remote.Parse(s); err == nil {
+			t.Errorf("Parse(%q): expected error", s)
+		}
+	}
+}
+
+// fakeTarget returns a Target whose "ssh" runs the remote command locally with
+// HOME pointed at a temp dir, so the full push/link path can be exercised.
+func fakeTarget(t *testing.T) (*remote.Target, string) {
+	t.Helper()
+	dir, _ := os.MkdirTemp("", "remote-*")
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	home := filepath.Join(dir, "home")
+	os.MkdirAll(home, 0755)
+	ssh := filepath.Join(dir, "ssh")
+	script := "#!/bin/sh\nfor a; do last=$a; done\nHOME=" + home + " exec sh -c \"$last\"\n"
+	os.WriteFile(ssh, []byte(script), 0755)
+	return &remote.Target{Host: "test@host", SSH: ssh}, home
+}
+
+func TestPushAndLink(t *testing.T) {
+	target, home := fakeTarget(t)
+	ctx := context.Background()
+
+	staging, _ := os.MkdirTemp("", "staging-*")
+	defer os.RemoveAll(staging)
+	os.MkdirAll(filepath.Join(staging, "bin"), 0755)
+	os.WriteFile(filepath.Join(staging, "bin", "tool"), []byte("#!/bin/sh\n"), 0755)
+	os.WriteFile(filepath.Join(staging, ".version"), []byte("1.2.3"), 0644)
+
+	if err := target.EnsureBaseDirs(ctx); err != nil {
+		t.Fatalf("ensure dirs: %v", err)
+	}
+	if err := target.Push(ctx, staging, "tool"); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if v := target.InstalledVersion(ctx, "tool"); v != "1.2.3" {
+		t.Errorf("expected remote version 1.2.3, got %q", v)
+	}
+	if err := target.Link(ctx, "tool", "bin/tool", "tool"); err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	got, err := os.Readlink(filepath.Join(home, ".local/bin/tool"))
+	if err != nil {
+		t.Fatalf("remote symlink missing: %v", err)
+	}
+	if got != filepath.Join(home, ".local/share/tool/bin/tool") {
+		t.Errorf("unexpected symlink target: %s", got)
+	}
+}
+
+func TestLink_refusesRegularFile(t *testing.T) {
+	target, home := fakeTarget(t)
+	ctx := context.Background()
+	target.EnsureBaseDirs(ctx)
+	os.WriteFile(filepath.Join(home, ".local/bin/tool"), []byte("mine"), 0755)
+
+	if err := target.Link(ctx, "tool", "tool", "tool"); err == nil {
+		t.Fatal("expected error when a regular file is in the way")
+	}
+}
+
+func TestRemove(t *testing.T) {
+	target, home := fakeTarget(t)
+	ctx := context.Background()
+	target.EnsureBaseDirs(ctx)
+
+	staging, _ := os.MkdirTemp("", "staging-*")
+	defer os.RemoveAll(staging)
+	os.WriteFile(filepath.Join(staging, "tool"), []byte("#!/bin/sh\n"), 0755)
+	target.Push(ctx, staging, "tool")
+	target.Link(ctx, "tool", "tool", "tool")
+	os.Symlink("/elsewhere", filepath.Join(home, ".local/bin/other"))
+
+	removed, err := target.Remove(ctx, "tool", []string{"tool", "other"})
+	if err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("expected link and dir removed, got %v", removed)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".local/share/tool")); !os.IsNotExist(err) {
+		t.Error("remote install dir still present")
+	}
+	if _, err := os.Lstat(filepath.Join(home, ".local/bin/other")); err != nil {
+		t.Error("foreign link was removed")
+	}
+}
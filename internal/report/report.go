@@ -0,0 +1,149 @@
+package report
+
+import (
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+)
+
+// Event is the JSON form of a single installer.ProgressMsg.
+type Event struct {
+	Type    string    `json:"type"` // always "progress"
+	Seq     uint64    `json:"seq"`
+	Time    time.Time `json:"time"`
+	Program string    `json:"program"`
+	State   string    `json:"state"`
+	Skip    string    `json:"skip,omitempty"`          // why a skipped program was skipped
+	MovedTo string    `json:"moved_to,omitempty"`      // the repo's new owner/name when GitHub reports it renamed
+	Pattern string    `json:"asset_pattern,omitempty"` // the asset_pattern of a renamed asset downloaded instead of the catalog's
+	Health  string    `json:"health,omitempty"`        // "ok", or why the health_cmd run after an upgrade failed
+	Version string    `json:"version,omitempty"`
+	Bytes   int64     `json:"bytes,omitempty"`
+	Error   string    `json:"error,omitempty"`
+
+	// Received and Size report a download in progress: bytes so far and the
+	// asset size, if the server announced it.
+	Received int64 `json:"received,omitempty"`
+	Size     int64 `json:"size,omitempty"`
+}
+
+// ProgramReport summarises one program's run. Durations maps each
+// non-terminal state to the seconds spent in it.
+type ProgramReport struct {
+	Program   string             `json:"program"`
+	State     string             `json:"state"`
+	Skip      string             `json:"skip,omitempty"`
+	Version   string             `json:"version,omitempty"`
+	Error     string             `json:"error,omitempty"`
+	Bytes     int64              `json:"bytes,omitempty"` // downloaded, 0 if nothing was fetched
+	Started   time.Time          `json:"started"`
+	Finished  time.Time          `json:"finished"`
+	Durations map[string]float64 `json:"durations"`
+}
+
+// Report is the end-of-run summary emitted after the last event.
+type Report struct {
+	Type     string          `json:"type"` // always "report"
+	Started  time.Time       `json:"started"`
+	Finished time.Time       `json:"finished"`
+	Programs []ProgramReport `json:"programs"`
+}
+
+// Recorder accumulates progress messages into per-program timelines.
+type Recorder struct {
+	order    []string
+	programs map[string]*timeline
+}
+
+type timeline struct {
+	last      installer.ProgressMsg
+	seen      bool
+	started   time.Time
+	bytes     int64
+	durations map[string]float64
+}
+
+// NewRecorder returns a Recorder that reports programs in the given order.
+func NewRecorder(order []string) *Recorder {
+	r := &Recorder{order: order, programs: make(map[string]*timeline, len(order))}
+	for _, name := range order {
+		r.programs[name] = &timeline{durations: map[string]float64{}}
+	}
+	return r
+}
+
+// Record folds msg into the timeline and returns its Event form.
+func (r *Recorder) Record(msg installer.ProgressMsg) Event {
+	tl, ok := r.programs[msg.Program]
+	if !ok {
+		tl = &timeline{durations: map[string]float64{}}
+		r.programs[msg.Program] = tl
+		r.order = append(r.order, msg.Program)
+	}
+	if tl.seen {
+		tl.durations[tl.last.State.String()] += msg.Time.Sub(tl.last.Time).Seconds()
+	} else {
+		tl.started = msg.Time
+		tl.seen = true
+	}
+	tl.last = msg
+	tl.bytes += msg.Bytes
+
+	ev := Event{
+		Type:    "progress",
+		Seq:     msg.Seq,
+		Time:    msg.Time,
+		Program: msg.Program,
+		State:   msg.State.String(),
+		Skip:    msg.Skip.String(),
+		MovedTo: msg.MovedTo,
+		Pattern: msg.AssetPattern,
+		Version: msg.Version,
+		Bytes:   msg.Bytes,
+
+		Received: msg.Received,
+		Size:     msg.Size,
+	}
+	if msg.Err != nil {
+		ev.Error = msg.Err.Error()
+	}
+	if h := msg.Health; h != nil {
+		ev.Health = "ok"
+		if h.Err != nil {
+			ev.Health = h.Reason()
+		}
+	}
+	return ev
+}
+
+// Report builds the summary of everything recorded so far.
+func (r *Recorder) Report() Report {
+	rep := Report{Type: "report"}
+	for _, name := range r.order {
+		tl := r.programs[name]
+		pr := ProgramReport{
+			Program:   name,
+			State:     tl.last.State.String(),
+			Skip:      tl.last.Skip.String(),
+			Version:   tl.last.Version,
+			Started:   tl.started,
+			Finished:  tl.last.Time,
+			Bytes:     tl.bytes,
+			Durations: tl.durations,
+		}
+		if !tl.seen {
+			pr.State = installer.StatePending.String()
+		}
+		if tl.last.Err != nil {
+			pr.Error = tl.last.Err.Error()
+		}
+		if tl.seen && (rep.Started.IsZero() || tl.started.Before(rep.Started)) {
+			rep.Started = tl.started
+		}
+		if tl.last.Time.After(rep.Finished) {
+			rep.Finished = tl.last.Time
+		}
+		rep.Programs = append(rep.Programs, pr)
+	}
+	return rep
+}
@@ -0,0 +1,93 @@
+package report_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/report"
+)
+
+func TestRecorder_durations(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := report.NewRecorder([]string{"fzf", "nvim"})
+
+	msgs := []installer.ProgressMsg{
+		{Program: "fzf", State: installer.StateFetchingVersion, Seq: 1, Time: t0},
+		{Program: "fzf", State: installer.StateDownloading, Seq: 2, Time: t0.Add(1 * time.Second), Version: "0.60.0"},
+		{Program: "nvim", State: installer.StateFetchingVersion, Seq: 3, Time: t0.Add(2 * time.Second)},
+		{Program: "fzf", State: installer.StateDone, Seq: 4, Time: t0.Add(4 * time.Second), Version: "0.60.0"},
+		{Program: "nvim", State: installer.StateError, Seq: 5, Time: t0.Add(5 * time.Second), Err: errors.New("boom")},
+	}
+	for _, m := range msgs {
+		ev := r.Record(m)
+		if ev.Seq != m.Seq || ev.Program != m.Program {
+			t.Fatalf("event does not mirror message: %+v", ev)
+		}
+	}
+
+	rep := r.Report()
+	if len(rep.Programs) != 2 {
+		t.Fatalf("expected 2 programs, got %d", len(rep.Programs))
+	}
+	fzf := rep.Programs[0]
+	if fzf.State != "done" || fzf.Durations["fetching version"] != 1 || fzf.Durations["downloading"] != 3 {
+		t.Errorf("unexpected fzf report: %+v", fzf)
+	}
+	nvim := rep.Programs[1]
+	if nvim.State != "error" || nvim.Error != "boom" || nvim.Durations["fetching version"] != 3 {
+		t.Errorf("unexpected nvim report: %+v", nvim)
+	}
+	if !rep.Started.Equal(t0) || !rep.Finished.Equal(t0.Add(5*time.Second)) {
+		t.Errorf("unexpected run bounds: %v – %v", rep.Started, rep.Finished)
+	}
+}
+
+func TestRecorder_bytes(t *testing.T) {
+	r := report.NewRecorder([]string{"fzf"})
+	r.Record(installer.ProgressMsg{Program: "fzf", State: installer.StateDownloading})
+	partial := r.Record(installer.ProgressMsg{Program: "fzf", State: installer.StateDownloading, Received: 1024, Size: 2048})
+	ev := r.Record(installer.ProgressMsg{Program: "fzf", State: installer.StateExtracting, Bytes: 2048})
+	r.Record(installer.ProgressMsg{Program: "fzf", State: installer.StateDone})
+
+	if ev.Bytes != 2048 {
+		t.Errorf("event bytes = %d, want 2048", ev.Bytes)
+	}
+	if partial.Received != 1024 || partial.Size != 2048 || partial.Bytes != 0 {
+		t.Errorf("progress event = %+v", partial)
+	}
+	if got := r.Report().Programs[0].Bytes; got != 2048 {
+		t.Errorf("report bytes = %d, want 2048", got)
+	}
+}
+
+func TestRecorder_skipReason(t *testing.T) {
+	r := report.NewRecorder([]string{"fzf"})
+	ev := r.Record(installer.ProgressMsg{Program: "fzf", State: installer.StateSkipped, Skip: installer.SkipOffline, Version: "0.60.0"})
+	if ev.Skip != "offline" {
+		t.Errorf("event skip = %q, want offline", ev.Skip)
+	}
+	if got := r.Report().Programs[0].Skip; got != "offline" {
+		t.Errorf("report skip = %q, want offline", got)
+	}
+}
+
+func TestRecorder_assetPattern(t *testing.T) {
+	r := report.NewRecorder([]string{"lazygit"})
+	ev := r.Record(installer.ProgressMsg{Program: "lazygit", State: installer.StateFetchingVersion, AssetPattern: "lazygit_{version}_linux_x86_64.tar.gz"})
+	if ev.Pattern != "lazygit_{version}_linux_x86_64.tar.gz" {
+		t.Errorf("event asset_pattern = %q", ev.Pattern)
+	}
+}
+
+func TestRecorder_health(t *testing.T) {
+	r := report.NewRecorder([]string{"nvim"})
+	ev := r.Record(installer.ProgressMsg{Program: "nvim", State: installer.StateDone, Health: &installer.Health{Err: errors.New("exit status 1"), Output: "E5113: Error while calling lua chunk\n"}})
+	if ev.Health != "exit status 1: E5113: Error while calling lua chunk" {
+		t.Errorf("event health = %q", ev.Health)
+	}
+	if ev := r.Record(installer.ProgressMsg{Program: "nvim", State: installer.StateDone, Health: &installer.Health{}}); ev.Health != "ok" {
+		t.Errorf("event health = %q, want ok", ev.Health)
+	}
+}
@@ -0,0 +1,156 @@
+// Package sandbox runs the commands a catalog can specify (download_cmd,
+// extract_cmd). Catalogs may come from shared or remote sources, so these run
+// under restrictions: a timeout, a minimal environment, a working dir the
+// caller confines them to and, where the system allows it, no network.
+// Their output is bounded and stripped of control sequences before it is
+// shown.
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultTimeout bounds a command whose Options set no Timeout.
+const DefaultTimeout = 10 * time.Minute
+
+// maxOutput is how much of a command's combined output is kept: the tail,
+// where errors usually are.
+const maxOutput = 64 << 10
+
+// Options restricts one command.
+type Options struct {
+	Dir       string        // working dir; also its TMPDIR
+	Timeout   time.Duration // 0 means DefaultTimeout
+	NoNetwork bool          // cut it off from the network if the system supports it; see Isolates
+}
+
+// env lists the variables passed through from the installer's environment.
+// Everything else — tokens, SSH agent sockets, the user's shell setup — is
+// withheld.
+var env = []string{
+	"PATH", "HOME", "LANG", "LC_ALL", "USER",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy",
+}
+
+// Run runs args with opts and returns its output, cleaned as by Clean. A
+// command that outlives its timeout is killed and reported as timed out.
+func Run(ctx context.Context, args []string, opts Options) (string, error) {
+	if len(args) == 0 {
+		return "", errors.New("empty command")
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if opts.NoNetwork && Isolates() {
+		args = isolate(args)
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = opts.Dir
+	cmd.Env = []string{"TERM=dumb"}
+	if opts.Dir != "" {
+		cmd.Env = append(cmd.Env, "TMPDIR="+opts.Dir)
+	}
+	for _, k := range env {
+		if v, ok := os.LookupEnv(k); ok {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+	var out tail
+	cmd.Stdout, cmd.Stderr = &out, &out
+	// Children that keep the pipes open must not hang the install.
+	cmd.WaitDelay = 5 * time.Second
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	return Clean(out.Bytes()), err
+}
+
+// Clean makes untrusted output safe to print: terminal escape sequences and
+// other control characters are dropped, keeping newlines and tabs, and
+// invalid UTF-8 is replaced.
+func Clean(b []byte) string {
+	b = bytes.ToValidUTF8(b, []byte("�"))
+	var sb strings.Builder
+	const (
+		text = iota
+		esc  // after ESC
+		csi  // in ESC [ … final byte
+		osc  // in ESC ] … BEL or ESC \
+	)
+	state := text
+	for _, r := range string(b) {
+		switch state {
+		case esc:
+			switch r {
+			case '[':
+				state = csi
+			case ']':
+				state = osc
+			default:
+				state = text // a two-byte sequence
+			}
+			continue
+		case csi:
+			if r >= '@' && r <= '~' {
+				state = text
+			}
+			continue
+		case osc:
+			if r == 0x07 {
+				state = text
+			} else if r == 0x1b {
+				state = esc
+			}
+			continue
+		}
+		switch {
+		case r == 0x1b:
+			state = esc
+		case r == '\n' || r == '\t':
+			sb.WriteRune(r)
+		case unicode.IsControl(r):
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// LastLine returns the last non-empty line of cleaned output, which is
+// usually where a command says what went wrong.
+func LastLine(out string) string {
+	out = strings.TrimSpace(out)
+	if i := strings.LastIndexByte(out, '\n'); i >= 0 {
+		out = out[i+1:]
+	}
+	return strings.TrimSpace(out)
+}
+
+// tail is an io.Writer keeping the last maxOutput bytes written to it.
+type tail struct {
+	buf []byte
+}
+
+func (t *tail) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > maxOutput {
+		t.buf = t.buf[len(t.buf)-maxOutput:]
+	}
+	return len(p), nil
+}
+
+func (t *tail) Bytes() []byte { return t.buf }
@@ -0,0 +1,27 @@
+package sandbox
+
+import (
+	"os/exec"
+	"sync"
+)
+
+var (
+	isolatesOnce sync.Once
+	isolates     bool
+)
+
+// Isolates reports whether NoNetwork takes effect: on Linux, when unshare can
+// create an unprivileged user and network namespace (some distributions and
+// most containers disable that).
+func Isolates() bool {
+	isolatesOnce.Do(func() {
+		isolates = exec.Command("unshare", "--user", "--map-root-user", "--net", "true").Run() == nil
+	})
+	return isolates
+}
+
+// isolate wraps args to run in a network namespace with only a loopback
+// device.
+func isolate(args []string) []string {
+	return append([]string{"unshare", "--user", "--map-root-user", "--net", "--"}, args...)
+}
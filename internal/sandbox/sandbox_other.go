@@ -0,0 +1,9 @@
+//go:build !linux
+
+package sandbox
+
+// Isolates reports whether NoNetwork takes effect. Outside Linux there is no
+// unprivileged way to drop network access, so it never does.
+func Isolates() bool { return false }
+
+func isolate(args []string) []string { return args }
@@ -0,0 +1,54 @@
+package sandbox_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/sandbox"
+)
+
+func TestRun_dirAndEnv(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "sandbox-*")
+	defer os.RemoveAll(dir)
+	t.Setenv("GITHUB_TOKEN", "secret")
+
+	out, err := sandbox.Run(context.Background(), []string{"sh", "-c", "pwd; echo token=$GITHUB_TOKEN; echo tmp=$TMPDIR"}, sandbox.Options{Dir: dir})
+	if err != nil {
+		t.Fatalf("run: %v (%s)", err, out)
+	}
+	real, _ := filepath.EvalSymlinks(dir)
+	if !strings.Contains(out, real) && !strings.Contains(out, dir) {
+		t.Errorf("not run in %s: %q", dir, out)
+	}
+	if !strings.Contains(out, "token=\n") {
+		t.Errorf("GITHUB_TOKEN leaked: %q", out)
+	}
+	if !strings.Contains(out, "tmp="+dir) {
+		t.Errorf("TMPDIR not set to the dir: %q", out)
+	}
+}
+
+func TestRun_timeout(t *testing.T) {
+	start := time.Now()
+	_, err := sandbox.Run(context.Background(), []string{"sleep", "10"}, sandbox.Options{Timeout: 100 * time.Millisecond})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("timeout took %s", time.Since(start))
+	}
+}
+
+func TestClean(t *testing.T) {
+	got := sandbox.Clean([]byte("\x1b[31mred\x1b[0m\tok\r\n\x07bell\x1b]0;title\x07"))
+	if got != "red\tok\nbell" {
+		t.Errorf("Clean = %q", got)
+	}
+	if sandbox.LastLine("a\nb\n\n") != "b" {
+		t.Error("LastLine should skip trailing blank lines")
+	}
+}
@@ -0,0 +1,46 @@
+package sign
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// VerifyGPG checks that sigPath, a detached OpenPGP signature (.asc or
+// .sig), is a signature of the file at path by pubkey: an ASCII-armored
+// public key, or the path of a file holding one. It runs gpg against a
+// throwaway keyring holding only that key, so the user's own keyring and
+// trust settings play no part.
+func VerifyGPG(ctx context.Context, pubkey, path, sigPath string) error {
+	gpg, err := exec.LookPath("gpg")
+	if err != nil {
+		return fmt.Errorf("gpg not found; it is needed to verify %s", filepath.Base(sigPath))
+	}
+	home, err := os.MkdirTemp("", "gpg-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(home)
+
+	keyFile := pubkey
+	if strings.HasPrefix(strings.TrimSpace(pubkey), "-----BEGIN PGP PUBLIC KEY BLOCK-----") {
+		keyFile = filepath.Join(home, "key.asc")
+		if err := os.WriteFile(keyFile, []byte(pubkey), 0600); err != nil {
+			return err
+		}
+	}
+	run := func(args ...string) ([]byte, error) {
+		args = append([]string{"--batch", "--no-tty", "--homedir", home}, args...)
+		return exec.CommandContext(ctx, gpg, args...).CombinedOutput()
+	}
+	if out, err := run("--import", keyFile); err != nil {
+		return fmt.Errorf("import pubkey: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	if out, err := run("--verify", sigPath, path); err != nil {
+		return fmt.Errorf("%s: bad or unknown signature: %s", filepath.Base(sigPath), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package sign
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/system"
+	"golang.org/x/crypto/blake2b"
+)
+
+// minisign signature algorithms: the file itself, or its BLAKE2b-512 hash
+// (the default since minisign 0.8).
+const (
+	minisignPure      = "Ed"
+	minisignPrehashed = "ED"
+)
+
+// minisign secret key fields: the key derivation function of an encrypted
+// key, or none for one made with `minisign -G -W`, and the checksum hash.
+const (
+	minisignKDFScrypt = "Sc"
+	minisignKDFNone   = "\x00\x00"
+	minisignChecksum  = "B2"
+)
+
+// minisignKey is a minisign public key.
+type minisignKey struct {
+	id  [8]byte
+	pub ed25519.PublicKey
+}
+
+// parseMinisignKey parses a minisign public key: its base64 line, e.g.
+// "RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3", or the whole
+// .pub file with its comment line.
+func parseMinisignKey(s string) (minisignKey, error) {
+	b, err := base64.StdEncoding.DecodeString(lastLine(s))
+	if err != nil || len(b) != 2+8+ed25519.PublicKeySize {
+		return minisignKey{}, errors.New("not a minisign public key")
+	}
+	if string(b[:2]) != minisignPure {
+		return minisignKey{}, fmt.Errorf("unsupported minisign key algorithm %q", b[:2])
+	}
+	var k minisignKey
+	copy(k.id[:], b[2:10])
+	k.pub = ed25519.PublicKey(b[10:])
+	return k, nil
+}
+
+// encode returns the key's base64 line.
+func (k minisignKey) encode() string {
+	return base64.StdEncoding.EncodeToString(append(append([]byte(minisignPure), k.id[:]...), k.pub...))
+}
+
+// minisig is a parsed .minisig file.
+type minisig struct {
+	alg     string
+	keyID   [8]byte
+	sig     []byte
+	comment string // the trusted comment
+	global  []byte // signature over sig and comment
+}
+
+// readMinisig parses the minisign signature file at path.
+func readMinisig(path string) (minisig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return minisig{}, err
+	}
+	invalid := fmt.Errorf("%s: not a minisign signature", path)
+	// untrusted comment, signature, trusted comment, global signature
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(string(data), "\r\n", "\n")), "\n")
+	if len(lines) < 4 {
+		return minisig{}, invalid
+	}
+	sig, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return minisig{}, invalid
+	}
+	m := minisig{alg: string(sig[:2]), sig: sig[10:]}
+	copy(m.keyID[:], sig[2:10])
+	if m.alg != minisignPure && m.alg != minisignPrehashed {
+		return minisig{}, fmt.Errorf("%s: unsupported signature algorithm %q", path, m.alg)
+	}
+	var ok bool
+	if m.comment, ok = strings.CutPrefix(lines[2], "trusted comment: "); !ok {
+		return minisig{}, invalid
+	}
+	m.global, err = base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(m.global) != ed25519.SignatureSize {
+		return minisig{}, invalid
+	}
+	return m, nil
+}
+
+// verify checks that m, read from sigPath, signs the file at path with key.
+func (m minisig) verify(key minisignKey, path, sigPath string) error {
+	if m.keyID != key.id {
+		return fmt.Errorf("%s: signed with key %s, not %s", sigPath, keyIDString(m.keyID), keyIDString(key.id))
+	}
+	msg, err := minisignMessage(m.alg, path)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key.pub, msg, m.sig) {
+		return fmt.Errorf("%s: signature does not match the file", sigPath)
+	}
+	if !ed25519.Verify(key.pub, append(m.sig, m.comment...), m.global) {
+		return fmt.Errorf("%s: trusted comment was tampered with", sigPath)
+	}
+	return nil
+}
+
+// minisignMessage returns what a signature with algorithm alg signs for the
+// file at path: its BLAKE2b-512 hash, or its contents.
+func minisignMessage(alg, path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if alg != minisignPrehashed {
+		return io.ReadAll(f)
+	}
+	h, _ := blake2b.New512(nil)
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// VerifyMinisign checks that sigPath, a .minisig file, is a signature of the
+// file at path by the minisign public key pubkey, including the signature
+// over its trusted comment.
+func VerifyMinisign(pubkey, path, sigPath string) error {
+	key, err := parseMinisignKey(pubkey)
+	if err != nil {
+		return err
+	}
+	m, err := readMinisig(sigPath)
+	if err != nil {
+		return err
+	}
+	return m.verify(key, path, sigPath)
+}
+
+// minisignSecretKey is an unencrypted minisign secret key.
+type minisignSecretKey struct {
+	id   [8]byte
+	priv ed25519.PrivateKey
+}
+
+// public returns the key's public half.
+func (k minisignSecretKey) public() minisignKey {
+	return minisignKey{id: k.id, pub: k.priv.Public().(ed25519.PublicKey)}
+}
+
+// checksum returns the BLAKE2b-256 checksum minisign keeps in a secret key
+// file over the algorithm, key ID and key.
+func (k minisignSecretKey) checksum() []byte {
+	h, _ := blake2b.New256(nil)
+	h.Write([]byte(minisignPure))
+	h.Write(k.id[:])
+	h.Write(k.priv)
+	return h.Sum(nil)
+}
+
+// encode returns the base64 line of a secret key file: the algorithms, an
+// unused KDF salt and limits, then the key ID, key and checksum.
+func (k minisignSecretKey) encode() string {
+	b := []byte(minisignPure + minisignKDFNone + minisignChecksum)
+	b = append(b, make([]byte, 32+8+8)...)
+	b = append(b, k.id[:]...)
+	b = append(b, k.priv...)
+	b = append(b, k.checksum()...)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// readMinisignSecretKey reads the minisign secret key file at path. Keys
+// encrypted with a password are not supported.
+func readMinisignSecretKey(path string) (minisignSecretKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return minisignSecretKey{}, err
+	}
+	b, err := base64.StdEncoding.DecodeString(lastLine(string(data)))
+	if err != nil || len(b) != 2+2+2+32+8+8+8+ed25519.PrivateKeySize+32 {
+		return minisignSecretKey{}, fmt.Errorf("%s: not a minisign secret key", path)
+	}
+	if string(b[:2]) != minisignPure || string(b[4:6]) != minisignChecksum {
+		return minisignSecretKey{}, fmt.Errorf("%s: unsupported minisign key algorithm", path)
+	}
+	switch string(b[2:4]) {
+	case minisignKDFNone:
+	case minisignKDFScrypt:
+		return minisignSecretKey{}, fmt.Errorf("%s is encrypted with a password; use a key made by `minisign -G -W` or `installer inventory keygen`", path)
+	default:
+		return minisignSecretKey{}, fmt.Errorf("%s: unsupported key derivation %q", path, b[2:4])
+	}
+	rest := b[2+2+2+32+8+8:]
+	var k minisignSecretKey
+	copy(k.id[:], rest[:8])
+	k.priv = ed25519.PrivateKey(bytes.Clone(rest[8 : 8+ed25519.PrivateKeySize]))
+	if !bytes.Equal(k.checksum(), rest[8+ed25519.PrivateKeySize:]) {
+		return minisignSecretKey{}, fmt.Errorf("%s: key checksum mismatch", path)
+	}
+	return k, nil
+}
+
+// generateMinisignKey returns a new key with a random key ID.
+func generateMinisignKey() (minisignSecretKey, error) {
+	var k minisignSecretKey
+	if _, err := rand.Read(k.id[:]); err != nil {
+		return k, err
+	}
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	k.priv = priv
+	return k, err
+}
+
+// signMinisign writes a prehashed minisign signature of the file at path,
+// made with k, to sigPath, with the time and file name as trusted comment
+// like minisign itself.
+func signMinisign(k minisignSecretKey, path, sigPath string) error {
+	msg, err := minisignMessage(minisignPrehashed, path)
+	if err != nil {
+		return err
+	}
+	sig := ed25519.Sign(k.priv, msg)
+	comment := fmt.Sprintf("timestamp:%d\tfile:%s\thashed", time.Now().Unix(), filepath.Base(path))
+	global := ed25519.Sign(k.priv, append(bytes.Clone(sig), comment...))
+	b64 := base64.StdEncoding.EncodeToString
+	content := "untrusted comment: signature from minisign secret key\n" +
+		b64(append(append([]byte(minisignPrehashed), k.id[:]...), sig...)) + "\n" +
+		"trusted comment: " + comment + "\n" +
+		b64(global) + "\n"
+	return os.WriteFile(sigPath, []byte(content), system.FilePerm())
+}
+
+// keyIDString formats a key ID the way minisign prints it: little-endian,
+// in hex.
+func keyIDString(id [8]byte) string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(id[:]))
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,87 @@
+// Package sign signs exported state files in minisign's format, so
+// pipelines that fetch them from a shared location can check who wrote them,
+// and can sign or check them with minisign itself. A file's signature lives
+// next to it as <file>.minisig.
+//
+// It also verifies the signatures upstreams publish beside release assets:
+// minisign's .minisig files natively, and OpenPGP .asc/.sig files with gpg.
+package sign
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// ErrUnsigned is returned by Verify for a file without a signature.
+var ErrUnsigned = errors.New("not signed")
+
+// KeyPath returns the default secret key location; the public key is next to
+// it with a .pub suffix. Both are minisign key files.
+func KeyPath() string {
+	return filepath.Join(system.ConfigPath(), "signing.key")
+}
+
+// SigPath returns where the signature of path is stored.
+func SigPath(path string) string {
+	return path + ".minisig"
+}
+
+// GenerateKey writes a new key pair to keyPath and keyPath+".pub" and returns
+// the public key line. The secret key is not password-protected, like one
+// from `minisign -G -W`. An existing key is never overwritten.
+func GenerateKey(keyPath string) (string, error) {
+	if _, err := os.Stat(keyPath); err == nil {
+		return "", fmt.Errorf("%s already exists", keyPath)
+	}
+	k, err := generateMinisignKey()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+		return "", err
+	}
+	secret := "untrusted comment: minisign secret key " + keyIDString(k.id) + "\n" + k.encode() + "\n"
+	if err := os.WriteFile(keyPath, []byte(secret), 0600); err != nil {
+		return "", err
+	}
+	pub := k.public().encode()
+	public := "untrusted comment: minisign public key " + keyIDString(k.id) + "\n" + pub + "\n"
+	return pub, os.WriteFile(keyPath+".pub", []byte(public), system.FilePerm())
+}
+
+// Sign signs the file at path with the minisign secret key at keyPath,
+// writing the signature to SigPath(path).
+func Sign(keyPath, path string) error {
+	k, err := readMinisignSecretKey(keyPath)
+	if err != nil {
+		return fmt.Errorf("read key: %w", err)
+	}
+	return signMinisign(k, path, SigPath(path))
+}
+
+// Verify checks the signature of the file at path against trusted, a list of
+// minisign public keys. It succeeds if any of them signed the file.
+func Verify(trusted []string, path string) error {
+	sigPath := SigPath(path)
+	m, err := readMinisig(sigPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s: %w", path, ErrUnsigned)
+	}
+	if err != nil {
+		return err
+	}
+	for _, k := range trusted {
+		key, err := parseMinisignKey(k)
+		if err != nil {
+			return fmt.Errorf("trusted key %q: %w", k, err)
+		}
+		if key.id == m.keyID {
+			return m.verify(key, path, sigPath)
+		}
+	}
+	return fmt.Errorf("%s: signed with key %s, which is not trusted", path, keyIDString(m.keyID))
+}
@@ -0,0 +1,198 @@
+package sign_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dsaleh/david-dotfiles/internal/sign"
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "sign-*")
+	defer os.RemoveAll(dir)
+	key := filepath.Join(dir, "keys", "signing.key")
+	pub, err := sign.GenerateKey(key)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if _, err := sign.GenerateKey(key); err == nil {
+		t.Error("expected an error when the key already exists")
+	}
+	other, _ := sign.GenerateKey(filepath.Join(dir, "other.key"))
+
+	file := filepath.Join(dir, "host.json")
+	os.WriteFile(file, []byte(`{"programs": {}}`), 0644)
+	if err := sign.Verify([]string{pub}, file); !errors.Is(err, sign.ErrUnsigned) {
+		t.Errorf("expected ErrUnsigned, got %v", err)
+	}
+
+	if err := sign.Sign(key, file); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if err := sign.Verify([]string{other, pub}, file); err != nil {
+		t.Errorf("verify: %v", err)
+	}
+	if err := sign.Verify([]string{other}, file); err == nil {
+		t.Error("expected an error for an untrusted key")
+	}
+
+	// The signature is a standard .minisig, checkable with `minisign -V`.
+	if err := sign.VerifyMinisign(pub, file, file+".minisig"); err != nil {
+		t.Errorf("verify as minisign: %v", err)
+	}
+	data, _ := os.ReadFile(file + ".minisig")
+	if lines := strings.Split(string(data), "\n"); len(lines) != 5 || !strings.HasPrefix(lines[2], "trusted comment: timestamp:") || !strings.HasSuffix(lines[2], "\tfile:host.json\thashed") {
+		t.Errorf("unexpected signature file:\n%s", data)
+	}
+
+	os.WriteFile(file, []byte(`{"programs": {"evil": {}}}`), 0644)
+	if err := sign.Verify([]string{pub}, file); err == nil {
+		t.Error("expected an error for a modified file")
+	}
+}
+
+// TestSignWithMinisignKey signs with a secret key as `minisign -G -W` writes
+// it, and checks a signature minisign made with Verify.
+func TestSignWithMinisignKey(t *testing.T) {
+	dir := t.TempDir()
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	id := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	chk, _ := blake2b.New256(nil)
+	chk.Write([]byte("Ed"))
+	chk.Write(id)
+	chk.Write(priv)
+	sk := append([]byte("Ed\x00\x00B2"), make([]byte, 32+8+8)...)
+	sk = append(append(append(sk, id...), priv...), chk.Sum(nil)...)
+	key := filepath.Join(dir, "minisign.key")
+	os.WriteFile(key, []byte("untrusted comment: minisign encrypted secret key\n"+base64.StdEncoding.EncodeToString(sk)+"\n"), 0600)
+
+	file := filepath.Join(dir, "host.json")
+	os.WriteFile(file, []byte(`{"programs": {}}`), 0644)
+	if err := sign.Sign(key, file); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	h, _ := blake2b.New512(nil)
+	h.Write([]byte(`{"programs": {}}`))
+	pub := minisign(t, filepath.Join(dir, "other.minisig"), priv, id, "ED", nil, h.Sum(nil))
+	if err := sign.Verify([]string{pub}, file); err != nil {
+		t.Errorf("verify: %v", err)
+	}
+
+	// A signature by minisign itself replaces ours and still verifies.
+	os.Rename(filepath.Join(dir, "other.minisig"), sign.SigPath(file))
+	if err := sign.Verify([]string{pub}, file); err != nil {
+		t.Errorf("verify minisign's signature: %v", err)
+	}
+
+	sk[2], sk[3] = 'S', 'c'
+	os.WriteFile(key, []byte(base64.StdEncoding.EncodeToString(sk)), 0600)
+	if err := sign.Sign(key, file); err == nil || !strings.Contains(err.Error(), "encrypted") {
+		t.Errorf("expected an error for an encrypted key, got %v", err)
+	}
+}
+
+// minisign writes a minisign signature of data, made by priv with key ID id,
+// to path and returns the encoded public key. alg is "Ed" to sign data
+// itself, "ED" to sign digest, its BLAKE2b-512 hash.
+func minisign(t *testing.T, path string, priv ed25519.PrivateKey, id []byte, alg string, data, digest []byte) string {
+	t.Helper()
+	msg := data
+	if alg == "ED" {
+		msg = digest
+	}
+	sig := append(append([]byte(alg), id...), ed25519.Sign(priv, msg)...)
+	comment := "timestamp:1700000000\tfile:asset"
+	global := ed25519.Sign(priv, append(sig[10:], comment...))
+	b64 := base64.StdEncoding.EncodeToString
+	content := "untrusted comment: signature from minisign secret key\n" + b64(sig) + "\ntrusted comment: " + comment + "\n" + b64(global) + "\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return "untrusted comment: minisign public key\n" + b64(append(append([]byte("Ed"), id...), priv.Public().(ed25519.PublicKey)...))
+}
+
+func TestVerifyMinisign(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte("fzf 0.56.0\n")
+	file := filepath.Join(dir, "asset")
+	os.WriteFile(file, data, 0644)
+	// BLAKE2b-512 of data, from a reference implementation.
+	digest, _ := hex.DecodeString("29060066f89bbb25262907ccd7f60979a414290860a19d4664fa2b2525c7d09f8a333ad0f3701f496828e8f7d954d326024a0d58c72d2244d47d6e2096c8196e")
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	id := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	for _, alg := range []string{"Ed", "ED"} {
+		sig := filepath.Join(dir, alg+".minisig")
+		pub := minisign(t, sig, priv, id, alg, data, digest)
+		if err := sign.VerifyMinisign(pub, file, sig); err != nil {
+			t.Errorf("%s: %v", alg, err)
+		}
+		// The bare key line works as well as the .pub file.
+		if err := sign.VerifyMinisign(pub[strings.LastIndex(pub, "\n")+1:], file, sig); err != nil {
+			t.Errorf("%s, key line only: %v", alg, err)
+		}
+	}
+
+	sig := filepath.Join(dir, "ED.minisig")
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	otherPub := minisign(t, filepath.Join(dir, "other.minisig"), other, id, "ED", data, digest)
+	if err := sign.VerifyMinisign(otherPub, file, sig); err == nil {
+		t.Error("expected an error for another key with the same ID")
+	}
+	otherID := minisign(t, filepath.Join(dir, "id.minisig"), priv, []byte{8, 7, 6, 5, 4, 3, 2, 1}, "ED", data, digest)
+	if err := sign.VerifyMinisign(otherID, file, sig); err == nil || !strings.Contains(err.Error(), "signed with key") {
+		t.Errorf("expected a key ID mismatch, got %v", err)
+	}
+
+	os.WriteFile(file, []byte("fzf 0.56.1\n"), 0644)
+	if err := sign.VerifyMinisign(minisign(t, sig, priv, id, "ED", data, digest), file, sig); err == nil {
+		t.Error("expected an error for a modified file")
+	}
+}
+
+func TestVerifyGPG(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+	// A short path: gpg-agent's socket lives in the home dir.
+	home, _ := os.MkdirTemp("", "gpg-*")
+	defer os.RemoveAll(home)
+	defer exec.Command("gpgconf", "--homedir", home, "--kill", "gpg-agent").Run()
+	gpg := func(args ...string) []byte {
+		out, err := exec.Command("gpg", append([]string{"--batch", "--homedir", home, "--passphrase", ""}, args...)...).Output()
+		if err != nil {
+			t.Fatalf("gpg %v: %v", args, err)
+		}
+		return out
+	}
+	gpg("--quick-gen-key", "Release <release@example.com>", "ed25519", "sign", "never")
+	pub := string(gpg("--armor", "--export"))
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "asset.tar.gz")
+	os.WriteFile(file, []byte("fzf 0.56.0\n"), 0644)
+	gpg("--armor", "--detach-sign", "-o", file+".asc", file)
+
+	if err := sign.VerifyGPG(context.Background(), pub, file, file+".asc"); err != nil {
+		t.Errorf("inline key: %v", err)
+	}
+	keyFile := filepath.Join(dir, "release.asc")
+	os.WriteFile(keyFile, []byte(pub), 0644)
+	if err := sign.VerifyGPG(context.Background(), keyFile, file, file+".asc"); err != nil {
+		t.Errorf("key file: %v", err)
+	}
+	os.WriteFile(file, []byte("fzf 0.56.1\n"), 0644)
+	if err := sign.VerifyGPG(context.Background(), pub, file, file+".asc"); err == nil {
+		t.Error("expected an error for a modified file")
+	}
+}
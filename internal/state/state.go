@@ -0,0 +1,300 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// ProgramState records what the installer last did for a single program.
+type ProgramState struct {
+	Version     string    `json:"version"`
+	Tag         string    `json:"tag"`
+	Pinned      bool      `json:"pinned,omitempty"` // install Tag instead of the latest release
+	InstalledAt time.Time `json:"installed_at"`
+	Bins        []string  `json:"bins,omitempty"`      // names linked into the bin dir
+	LinkMode    string    `json:"link_mode,omitempty"` // how Bins were placed; "" means symlink
+	Asset       string    `json:"asset,omitempty"`     // download URL of the installed release asset
+	BinDir      string    `json:"bin_dir,omitempty"`   // where Bins were placed when not the usual bin dir, e.g. windows_bin_dir
+
+	// SharedWith names the program whose install dir holds this one's files,
+	// when both resolved to the same asset; "" if it has its own.
+	SharedWith string `json:"shared_with,omitempty"`
+
+	// Locks fingerprints the symlinks in Bins when lock_links is set, keyed
+	// by bin name, so links changed outside the installer can be detected.
+	Locks map[string]LinkLock `json:"locks,omitempty"`
+}
+
+// LinkLock is what a managed symlink pointed at when the installer made it.
+type LinkLock struct {
+	Path   string `json:"path"`   // the bin entry
+	Target string `json:"target"` // link target as created, used to repair it
+	Hash   string `json:"hash"`   // SHA-256 of Target
+
+	// Content is the SHA-256 of the binary Target pointed at when the link
+	// was made; "" for links locked before it was recorded.
+	Content string `json:"content,omitempty"`
+}
+
+// Pick is a binary chosen in the bin picker, remembered for the next release
+// with the same tree layout. Src is relative to the install dir, with the
+// release version written as {version}.
+type Pick struct {
+	Src string `json:"src"`
+	Dst string `json:"dst"`
+}
+
+// Stats is a program's install history across runs, kept whether or not the
+// program is currently installed.
+type Stats struct {
+	Successes           int       `json:"successes"`
+	Failures            int       `json:"failures"`
+	ConsecutiveFailures int       `json:"consecutive_failures,omitempty"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastRun             time.Time `json:"last_run"`
+}
+
+// State is the persisted install state. It is safe for concurrent use by the
+// installer's worker goroutines.
+type State struct {
+	mu       sync.Mutex
+	path     string
+	programs map[string]ProgramState
+	history  map[string]Stats
+	sums     map[string]string // asset URL → SHA-256, see Checksum
+	picks    map[string][]Pick // program and layout → bins picked, see Picks
+}
+
+// file is the on-disk JSON layout.
+type file struct {
+	Programs  map[string]ProgramState `json:"programs"`
+	History   map[string]Stats        `json:"history,omitempty"`
+	Checksums map[string]string       `json:"checksums,omitempty"`
+	Picks     map[string][]Pick       `json:"picks,omitempty"`
+}
+
+// Path returns the default state file location.
+func Path() string {
+	return filepath.Join(system.DataPath(), "state.json")
+}
+
+// TargetPath returns the state file for installs on a remote --target host,
+// kept apart from the local machine's state.
+func TargetPath(host string) string {
+	return filepath.Join(system.DataPath(), "targets", host+".json")
+}
+
+// SystemPath returns the state file for --system installs into /usr/local.
+func SystemPath() string {
+	return filepath.Join(system.DataPath(), "system.json")
+}
+
+// SharedPath returns this user's state file for --shared installs, whose
+// extracted trees live in the system share root.
+func SharedPath() string {
+	return filepath.Join(system.DataPath(), "shared.json")
+}
+
+// New returns an empty State that will be saved to path.
+func New(path string) *State {
+	return &State{path: path, programs: map[string]ProgramState{}, history: map[string]Stats{}, sums: map[string]string{}, picks: map[string][]Pick{}}
+}
+
+// Load reads the state file at path. A missing file yields an empty State.
+func Load(path string) (*State, error) {
+	s := New(path)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read state: %w", err)
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse state %s: %w", path, err)
+	}
+	if f.Programs != nil {
+		s.programs = f.Programs
+	}
+	if f.History != nil {
+		s.history = f.History
+	}
+	if f.Checksums != nil {
+		s.sums = f.Checksums
+	}
+	if f.Picks != nil {
+		s.picks = f.Picks
+	}
+	return s, nil
+}
+
+// Get returns the recorded state for a program.
+func (s *State) Get(name string) (ProgramState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ps, ok := s.programs[name]
+	return ps, ok
+}
+
+// Set records the state for a program, replacing any previous entry.
+func (s *State) Set(name string, ps ProgramState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.programs[name] = ps
+}
+
+// Delete forgets a program.
+func (s *State) Delete(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.programs, name)
+}
+
+// DirOf returns the name of the install dir the program's files are in: its
+// own name, or that of the program it shares an asset with.
+func (s *State) DirOf(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ps := s.programs[name]; ps.SharedWith != "" {
+		return ps.SharedWith
+	}
+	return name
+}
+
+// DirUsers returns the programs other than name whose files are in the
+// install dir dir, sorted.
+func (s *State) DirUsers(dir, name string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []string
+	for n, ps := range s.programs {
+		if n == name {
+			continue
+		}
+		if n == dir && ps.SharedWith == "" || ps.SharedWith == dir {
+			out = append(out, n)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// SetPinned flips the pinned flag of an already-recorded program.
+// It is a no-op for programs that have never been installed.
+func (s *State) SetPinned(name string, pinned bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ps, ok := s.programs[name]; ok {
+		ps.Pinned = pinned
+		s.programs[name] = ps
+	}
+}
+
+// RecordResult adds the outcome of one install attempt to name's history.
+// A nil err counts as a success and resets the consecutive failure count.
+func (s *State) RecordResult(name string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.history[name]
+	st.LastRun = time.Now()
+	if err == nil {
+		st.Successes++
+		st.ConsecutiveFailures = 0
+	} else {
+		st.Failures++
+		st.ConsecutiveFailures++
+		st.LastError = err.Error()
+	}
+	s.history[name] = st
+}
+
+// Stats returns name's install history.
+func (s *State) Stats(name string) (Stats, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.history[name]
+	return st, ok
+}
+
+// Checksum returns the SHA-256 (hex) recorded for the asset at url when it was
+// first installed. Release assets never change legitimately, so a different
+// sum for the same URL means the release was re-tagged or tampered with.
+func (s *State) Checksum(url string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sum, ok := s.sums[url]
+	return sum, ok
+}
+
+// SetChecksum records the SHA-256 of the asset at url.
+func (s *State) SetChecksum(url, sum string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sums[url] = sum
+}
+
+// Picks returns the bins last picked for program name from an extracted tree
+// with the given layout fingerprint.
+func (s *State) Picks(name, layout string) ([]Pick, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.picks[name+"@"+layout]
+	return p, ok
+}
+
+// SetPicks records the bins picked for program name from a tree with the
+// given layout fingerprint.
+func (s *State) SetPicks(name, layout string, picks []Pick) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.picks[name+"@"+layout] = picks
+}
+
+// Names returns the recorded program names in sorted order.
+func (s *State) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.programs))
+	for name := range s.programs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save writes the state atomically (temp file + rename). The lock is held for
+// the whole write so concurrent saves cannot reorder and drop updates.
+func (s *State) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := json.MarshalIndent(file{Programs: s.programs, History: s.history, Checksums: s.sums, Picks: s.picks}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), system.DirPerm()); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".state-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
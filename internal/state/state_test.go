@@ -0,0 +1,180 @@
+package state_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/state"
+)
+
+func TestLoad_missingFile(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "state-*")
+	defer os.RemoveAll(dir)
+
+	st, err := state.Load(filepath.Join(dir, "state.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(st.Names()) != 0 {
+		t.Errorf("expected empty state, got %v", st.Names())
+	}
+}
+
+func TestSaveAndLoad_roundTrip(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "state-*")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "nested", "state.json")
+
+	st := state.New(path)
+	st.Set("fzf", state.ProgramState{Version: "0.60.0", Tag: "v0.60.0", Pinned: true, InstalledAt: time.Now()})
+	if err := st.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, err := state.Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	ps, ok := loaded.Get("fzf")
+	if !ok {
+		t.Fatal("fzf missing after round trip")
+	}
+	if ps.Tag != "v0.60.0" || !ps.Pinned {
+		t.Errorf("unexpected state: %+v", ps)
+	}
+}
+
+func TestSetPinned(t *testing.T) {
+	st := state.New("")
+	st.SetPinned("missing", true)
+	if _, ok := st.Get("missing"); ok {
+		t.Error("SetPinned should not create entries")
+	}
+
+	st.Set("fzf", state.ProgramState{Version: "0.60.0", Pinned: true})
+	st.SetPinned("fzf", false)
+	if ps, _ := st.Get("fzf"); ps.Pinned {
+		t.Error("expected fzf to be unpinned")
+	}
+}
+
+func TestLoad_corrupt(t *testing.T) {
+	f, _ := os.CreateTemp("", "state-*.json")
+	f.WriteString("{not json")
+	f.Close()
+	defer os.Remove(f.Name())
+
+	if _, err := state.Load(f.Name()); err == nil {
+		t.Fatal("expected error for corrupt state file")
+	}
+}
+
+func TestRecordResult(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "state-*")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	s := state.New(path)
+	s.RecordResult("fzf", nil)
+	s.RecordResult("fzf", errors.New("404 not found"))
+	s.RecordResult("fzf", errors.New("rate limited"))
+	if err := s.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, err := state.Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	st, ok := loaded.Stats("fzf")
+	if !ok {
+		t.Fatal("expected history for fzf")
+	}
+	if st.Successes != 1 || st.Failures != 2 || st.ConsecutiveFailures != 2 || st.LastError != "rate limited" {
+		t.Errorf("unexpected stats %+v", st)
+	}
+
+	loaded.RecordResult("fzf", nil)
+	if st, _ := loaded.Stats("fzf"); st.ConsecutiveFailures != 0 || st.LastError != "rate limited" {
+		t.Errorf("success should reset the streak but keep the last error: %+v", st)
+	}
+}
+
+func TestChecksum_roundTrip(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "state-*")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	url := "https://github.com/junegunn/fzf/releases/download/v0.60.0/fzf-0.60.0-linux_amd64.tar.gz"
+	s := state.New(path)
+	if _, ok := s.Checksum(url); ok {
+		t.Fatal("expected no checksum in a new state")
+	}
+	s.SetChecksum(url, "abc123")
+	if err := s.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, err := state.Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if sum, ok := loaded.Checksum(url); !ok || sum != "abc123" {
+		t.Errorf("checksum not round-tripped: %q %v", sum, ok)
+	}
+}
+
+func TestPicks_roundTrip(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "state-*")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	s := state.New(path)
+	if _, ok := s.Picks("node", "abc"); ok {
+		t.Fatal("expected no picks in a new state")
+	}
+	s.SetPicks("node", "abc", []state.Pick{{Src: "node-v{version}-linux-x64/bin/node", Dst: "node"}})
+	if err := s.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, err := state.Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	picks, ok := loaded.Picks("node", "abc")
+	if !ok || len(picks) != 1 || picks[0].Dst != "node" {
+		t.Errorf("picks not round-tripped: %+v %v", picks, ok)
+	}
+	if _, ok := loaded.Picks("node", "def"); ok {
+		t.Error("picks should be keyed by layout")
+	}
+}
+
+func TestDirUsers(t *testing.T) {
+	s := state.New("")
+	s.Set("kubectl", state.ProgramState{Version: "1.30.0"})
+	s.Set("kubectx", state.ProgramState{Version: "1.30.0", SharedWith: "kubectl"})
+	s.Set("kubens", state.ProgramState{Version: "1.30.0", SharedWith: "kubectl"})
+	s.Set("fzf", state.ProgramState{Version: "0.60.0"})
+
+	if got := s.DirOf("kubens"); got != "kubectl" {
+		t.Errorf("DirOf(kubens) = %q, want kubectl", got)
+	}
+	if got := s.DirOf("fzf"); got != "fzf" {
+		t.Errorf("DirOf(fzf) = %q, want fzf", got)
+	}
+	if got := s.DirUsers("kubectl", "kubectl"); strings.Join(got, ",") != "kubectx,kubens" {
+		t.Errorf("DirUsers(kubectl) without the owner = %v", got)
+	}
+	if got := s.DirUsers("kubectl", "kubens"); strings.Join(got, ",") != "kubectl,kubectx" {
+		t.Errorf("DirUsers(kubectl) without kubens = %v", got)
+	}
+	if got := s.DirUsers("fzf", "fzf"); len(got) != 0 {
+		t.Errorf("DirUsers(fzf) = %v, want none", got)
+	}
+}
@@ -0,0 +1,167 @@
+// Package stats keeps an opt-in local history of run reports and summarizes
+// it. Nothing here is ever sent anywhere; the history is a JSON-lines file in
+// the data dir.
+package stats
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/report"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// Path returns the default history file location.
+func Path() string {
+	return filepath.Join(system.DataPath(), "stats.jsonl")
+}
+
+// Append adds rep to the history at path, creating the file if needed.
+func Append(path string, rep report.Report) error {
+	if err := os.MkdirAll(filepath.Dir(path), system.DirPerm()); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, system.FilePerm())
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(rep); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads every report in the history at path, oldest first. A missing
+// file yields no reports; malformed lines are skipped.
+func Load(path string) ([]report.Report, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var reps []report.Report
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		var rep report.Report
+		if json.Unmarshal(sc.Bytes(), &rep) == nil {
+			reps = append(reps, rep)
+		}
+	}
+	return reps, sc.Err()
+}
+
+// Month counts the outcomes of one calendar month.
+type Month struct {
+	Month    string // "2006-01"
+	Installs int    // first successful install of a program
+	Updates  int    // later successful installs of a program
+	Failures int
+}
+
+// Slow is a program's mean time to a successful download-and-install.
+type Slow struct {
+	Program string
+	Seconds float64
+	Runs    int
+}
+
+// Summary aggregates a history.
+type Summary struct {
+	Runs   int
+	Months []Month // oldest first
+	Bytes  int64   // total downloaded
+
+	// Hits counts programs that finished without downloading (already up to
+	// date, or reused from a shared root); Fetches those that downloaded.
+	Hits, Fetches int
+
+	Slowest []Slow // slowest first, at most 5
+}
+
+// HitRatio returns Hits / (Hits + Fetches), or 0 with no data.
+func (s Summary) HitRatio() float64 {
+	if s.Hits+s.Fetches == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Fetches)
+}
+
+// Summarize aggregates reps, which must be oldest first: a program's first
+// success in the history counts as an install and later ones as updates.
+func Summarize(reps []report.Report) Summary {
+	sum := Summary{Runs: len(reps)}
+	months := map[string]*Month{}
+	seen := map[string]bool{}
+	type total struct {
+		seconds float64
+		runs    int
+	}
+	times := map[string]*total{}
+
+	for _, rep := range reps {
+		key := rep.Started.Format("2006-01")
+		m, ok := months[key]
+		if !ok {
+			m = &Month{Month: key}
+			months[key] = m
+		}
+		for _, p := range rep.Programs {
+			sum.Bytes += p.Bytes
+			_, downloaded := p.Durations[installer.StateDownloading.String()]
+			switch p.State {
+			case installer.StateSkipped.String():
+				sum.Hits++
+			case installer.StateDone.String():
+				if downloaded {
+					sum.Fetches++
+					t, ok := times[p.Program]
+					if !ok {
+						t = &total{}
+						times[p.Program] = t
+					}
+					t.seconds += p.Finished.Sub(p.Started).Seconds()
+					t.runs++
+				} else {
+					sum.Hits++
+				}
+				if seen[p.Program] {
+					m.Updates++
+				} else {
+					m.Installs++
+				}
+				seen[p.Program] = true
+			case installer.StateError.String():
+				m.Failures++
+			}
+		}
+	}
+
+	for _, m := range months {
+		sum.Months = append(sum.Months, *m)
+	}
+	sort.Slice(sum.Months, func(i, j int) bool { return sum.Months[i].Month < sum.Months[j].Month })
+
+	for name, t := range times {
+		sum.Slowest = append(sum.Slowest, Slow{Program: name, Seconds: t.seconds / float64(t.runs), Runs: t.runs})
+	}
+	sort.Slice(sum.Slowest, func(i, j int) bool {
+		if sum.Slowest[i].Seconds != sum.Slowest[j].Seconds {
+			return sum.Slowest[i].Seconds > sum.Slowest[j].Seconds
+		}
+		return sum.Slowest[i].Program < sum.Slowest[j].Program
+	})
+	if len(sum.Slowest) > 5 {
+		sum.Slowest = sum.Slowest[:5]
+	}
+	return sum
+}
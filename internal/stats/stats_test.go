@@ -0,0 +1,67 @@
+package stats_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/report"
+	"github.com/dsaleh/david-dotfiles/internal/stats"
+)
+
+func run(start time.Time, programs ...report.ProgramReport) report.Report {
+	return report.Report{Type: "report", Started: start, Programs: programs}
+}
+
+func TestAppendLoad(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "stats-*")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sub", "stats.jsonl")
+
+	reps, err := stats.Load(path)
+	if err != nil || reps != nil {
+		t.Fatalf("missing file: got %v, %v", reps, err)
+	}
+	t0 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		if err := stats.Append(path, run(t0, report.ProgramReport{Program: "fzf", State: "done"})); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	reps, err = stats.Load(path)
+	if err != nil || len(reps) != 2 || reps[1].Programs[0].Program != "fzf" {
+		t.Fatalf("unexpected history %+v, %v", reps, err)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	t0 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	downloaded := map[string]float64{"downloading": 2}
+	reps := []report.Report{
+		run(t0,
+			report.ProgramReport{Program: "fzf", State: "done", Bytes: 100, Started: t0, Finished: t0.Add(4 * time.Second), Durations: downloaded},
+			report.ProgramReport{Program: "nvim", State: "done", Bytes: 300, Started: t0, Finished: t0.Add(10 * time.Second), Durations: downloaded},
+		),
+		run(t0.AddDate(0, 1, 0),
+			report.ProgramReport{Program: "fzf", State: "done", Bytes: 100, Started: t0, Finished: t0.Add(2 * time.Second), Durations: downloaded},
+			report.ProgramReport{Program: "nvim", State: "skipped"},
+			report.ProgramReport{Program: "kitty", State: "error"},
+		),
+	}
+
+	sum := stats.Summarize(reps)
+	if sum.Runs != 2 || sum.Bytes != 500 {
+		t.Errorf("runs/bytes = %d/%d", sum.Runs, sum.Bytes)
+	}
+	if len(sum.Months) != 2 || sum.Months[0] != (stats.Month{Month: "2026-03", Installs: 2}) ||
+		sum.Months[1] != (stats.Month{Month: "2026-04", Updates: 1, Failures: 1}) {
+		t.Errorf("unexpected months %+v", sum.Months)
+	}
+	if sum.Hits != 1 || sum.Fetches != 3 || sum.HitRatio() != 0.25 {
+		t.Errorf("hits/fetches = %d/%d", sum.Hits, sum.Fetches)
+	}
+	if len(sum.Slowest) != 2 || sum.Slowest[0].Program != "nvim" || sum.Slowest[1].Seconds != 3 {
+		t.Errorf("unexpected slowest %+v", sum.Slowest)
+	}
+}
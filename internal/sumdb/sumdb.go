@@ -0,0 +1,195 @@
+// Package sumdb keeps an append-only database of the SHA-256 sums of release
+// assets the installer has downloaded. Unlike the sums in the state file,
+// which only protect reinstalls on the same machine, the database can be
+// exported and imported, so a machine installing a release another machine
+// already installed verifies the asset against that machine's hash — also
+// for upstreams that publish no checksum files.
+package sumdb
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// Path returns the default location of the database.
+func Path() string {
+	return filepath.Join(system.DataPath(), "checksums.jsonl")
+}
+
+// Entry is the recorded hash of one asset.
+type Entry struct {
+	URL     string    `json:"url"`
+	SHA256  string    `json:"sha256"`
+	Program string    `json:"program,omitempty"`
+	Version string    `json:"version,omitempty"`
+	Host    string    `json:"host,omitempty"` // machine that recorded it
+	Time    time.Time `json:"time"`
+}
+
+// DB is a checksum database backed by a JSON-lines file. Entries are only
+// ever appended; the first entry for a URL is the one that counts.
+type DB struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]Entry
+}
+
+// Open loads the database at path. A missing file yields an empty database,
+// created on the first Add. Lines that do not parse, such as one cut short
+// by a crash mid-append, are skipped.
+func Open(path string) (*DB, error) {
+	db := &DB{path: path, entries: map[string]Entry{}}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, err := read(f)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	for _, e := range entries {
+		if _, ok := db.entries[e.URL]; !ok {
+			db.entries[e.URL] = e
+		}
+	}
+	return db, nil
+}
+
+func read(r io.Reader) ([]Entry, error) {
+	var out []Entry
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		var e Entry
+		if json.Unmarshal(sc.Bytes(), &e) != nil || e.URL == "" || e.SHA256 == "" {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out, sc.Err()
+}
+
+// Lookup returns the entry recorded for url.
+func (db *DB) Lookup(url string) (Entry, bool) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	e, ok := db.entries[url]
+	return e, ok
+}
+
+// Add appends e unless its URL is already recorded. Host and Time default to
+// this machine and now.
+func (db *DB) Add(e Entry) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.entries[e.URL]; ok {
+		return nil
+	}
+	if e.Host == "" {
+		e.Host, _ = os.Hostname()
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if err := db.append([]Entry{e}); err != nil {
+		return err
+	}
+	db.entries[e.URL] = e
+	return nil
+}
+
+func (db *DB) append(entries []Entry) error {
+	if err := os.MkdirAll(filepath.Dir(db.path), system.DirPerm()); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(db.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, system.FilePerm())
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// Entries returns every entry, ordered by URL.
+func (db *DB) Entries() []Entry {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	out := make([]Entry, 0, len(db.entries))
+	for _, e := range db.entries {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].URL < out[j].URL })
+	return out
+}
+
+// Export writes every entry to w in the database's own format, for Import on
+// another machine.
+func (db *DB) Export(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, e := range db.Entries() {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Conflict is an imported entry whose hash differs from the recorded one.
+type Conflict struct {
+	Recorded, Imported Entry
+}
+
+// Import appends the entries read from r whose URLs are not recorded yet,
+// returning how many were added. Entries that disagree with a recorded hash
+// are not added but returned as conflicts: one of the two machines
+// downloaded something other than the original release.
+func (db *DB) Import(r io.Reader) (int, []Conflict, error) {
+	entries, err := read(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var added []Entry
+	var conflicts []Conflict
+	seen := map[string]bool{}
+	for _, e := range entries {
+		if known, ok := db.entries[e.URL]; ok {
+			if known.SHA256 != e.SHA256 {
+				conflicts = append(conflicts, Conflict{Recorded: known, Imported: e})
+			}
+			continue
+		}
+		if !seen[e.URL] {
+			seen[e.URL] = true
+			added = append(added, e)
+		}
+	}
+	if len(added) > 0 {
+		if err := db.append(added); err != nil {
+			return 0, conflicts, err
+		}
+		for _, e := range added {
+			db.entries[e.URL] = e
+		}
+	}
+	return len(added), conflicts, nil
+}
@@ -0,0 +1,87 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+// SELinuxEnforcing reports whether SELinux is enabled and enforcing.
+func SELinuxEnforcing() bool {
+	data, err := os.ReadFile("/sys/fs/selinux/enforce")
+	return err == nil && strings.TrimSpace(string(data)) == "1"
+}
+
+// AppArmorEnabled reports whether the AppArmor module is loaded.
+func AppArmorEnabled() bool {
+	data, err := os.ReadFile("/sys/module/apparmor/parameters/enabled")
+	return err == nil && strings.TrimSpace(string(data)) == "Y"
+}
+
+// Restorecon resets the SELinux labels under paths to what the policy
+// expects there, so files that were moved in or extracted under an unusual
+// prefix can be executed. It does nothing unless SELinux is enforcing and
+// restorecon is installed.
+func Restorecon(ctx context.Context, paths ...string) error {
+	if !SELinuxEnforcing() || len(paths) == 0 {
+		return nil
+	}
+	bin, err := exec.LookPath("restorecon")
+	if err != nil {
+		return nil
+	}
+	out, err := exec.CommandContext(ctx, bin, append([]string{"-R"}, paths...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("restorecon: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// SELinuxType returns the type of path's SELinux label, e.g. "user_home_t",
+// or "" if it has none.
+func SELinuxType(path string) string {
+	buf := make([]byte, 256)
+	n, err := syscall.Getxattr(path, "security.selinux", buf)
+	if err != nil || n == 0 {
+		return ""
+	}
+	// user:role:type:level
+	fields := strings.Split(strings.TrimRight(string(buf[:n]), "\x00"), ":")
+	if len(fields) < 3 {
+		return ""
+	}
+	return fields[2]
+}
+
+// noExecTypes are labels confined domains commonly may not execute files
+// with.
+var noExecTypes = []string{"user_home_t", "user_tmp_t", "tmp_t", "cache_home_t", "default_t", "unlabeled_t"}
+
+// ExecHint returns advice for the program at path failing with err and
+// output when a security module is the likely cause, or "".
+func ExecHint(path string, err error, output string) string {
+	if err == nil {
+		return ""
+	}
+	denied := errors.Is(err, fs.ErrPermission) || strings.Contains(output, "Permission denied")
+	switch {
+	case SELinuxEnforcing():
+		for _, perm := range []string{"execheap", "execstack", "execmod"} {
+			if strings.Contains(output, perm) || (perm == "execstack" && strings.Contains(output, "cannot enable executable stack")) {
+				return fmt.Sprintf("SELinux denies %s; check `ausearch -m avc -ts recent`, and allow it with `setsebool -P selinuxuser_%s 1` if you trust the program", perm, perm)
+			}
+		}
+		if t := SELinuxType(path); denied && slices.Contains(noExecTypes, t) {
+			return fmt.Sprintf("SELinux labels %s %s, which may not be executed; run `restorecon -Rv %s`, or `chcon -t bin_t` it outside the standard dirs", path, t, path)
+		}
+	case AppArmorEnabled() && denied:
+		return `AppArmor may be confining it; look for apparmor="DENIED" in ` + "`journalctl -k`"
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// OpenURL opens url in the user's browser with the platform's opener:
+// open on macOS, termux-open-url on Termux, wslview or explorer.exe on WSL
+// and xdg-open elsewhere. It fails when none is installed, or when there is
+// no display for xdg-open to use, as over SSH.
+func OpenURL(url string) error {
+	var candidates []string
+	switch {
+	case runtime.GOOS == "darwin":
+		candidates = []string{"open"}
+	case Termux():
+		candidates = []string{"termux-open-url"}
+	case WSL():
+		candidates = []string{"wslview", "explorer.exe"}
+	default:
+		if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			return errors.New("no display to open a browser on")
+		}
+		candidates = []string{"xdg-open"}
+	}
+	for _, c := range candidates {
+		path, err := exec.LookPath(c)
+		if err != nil {
+			continue
+		}
+		if err := exec.Command(path, url).Run(); err != nil {
+			// explorer.exe exits 1 even when it opened the page.
+			if c == "explorer.exe" {
+				return nil
+			}
+			return fmt.Errorf("%s: %w", c, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s is not installed", candidates[0])
+}
@@ -0,0 +1,52 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// mask is cleared from the modes the installer creates files and dirs with.
+// The default matches the 0755/0644 it has always used; see SetUmask.
+// Modes taken from elsewhere (Perm) are only masked once it is set.
+var (
+	mask    os.FileMode = 0022
+	maskSet bool
+)
+
+// SetUmask makes m the process umask, which also applies to the commands the
+// installer runs, and the mask cleared from the modes it sets itself. With
+// 077, for example, install dirs are created 0700 on a shared host.
+func SetUmask(m os.FileMode) {
+	mask, maskSet = m&os.ModePerm, true
+	syscall.Umask(int(mask))
+}
+
+// ParseUmask parses an octal umask such as "077" or "0027".
+func ParseUmask(s string) (os.FileMode, error) {
+	n, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || n > 0777 {
+		return 0, fmt.Errorf("umask %q must be an octal mode such as 022 or 077", s)
+	}
+	return os.FileMode(n), nil
+}
+
+// DirPerm is the mode for dirs the installer creates: 0755 by default.
+func DirPerm() os.FileMode { return 0777 &^ mask }
+
+// FilePerm is the mode for data files the installer writes: 0644 by default.
+func FilePerm() os.FileMode { return 0666 &^ mask }
+
+// ExecPerm is the mode for executables the installer writes: 0755 by
+// default.
+func ExecPerm() os.FileMode { return 0777 &^ mask }
+
+// Perm clears the mask set by SetUmask from mode, e.g. one recorded in an
+// archive. Without it mode is returned as is, for the process umask to apply.
+func Perm(mode os.FileMode) os.FileMode {
+	if !maskSet {
+		return mode
+	}
+	return mode &^ mask
+}
@@ -0,0 +1,25 @@
+package system
+
+import (
+	"os"
+	"strings"
+)
+
+// Platform names the environment the installer runs in when it needs
+// different treatment from a plain Linux system: "termux", "wsl", or "".
+// Catalog entries list the ones they do not support; see
+// catalog.Program.Unsupported.
+func Platform() string {
+	switch {
+	case Termux():
+		return "termux"
+	case WSL():
+		return "wsl"
+	}
+	return ""
+}
+
+// Termux reports whether the process runs in Termux on Android.
+func Termux() bool {
+	return os.Getenv("TERMUX_VERSION") != "" || strings.Contains(os.Getenv("PREFIX"), "com.termux")
+}
@@ -9,22 +9,88 @@ import (
 const (
 	ShareDir = ".local/share"
 	BinDir   = ".local/bin"
+	DataDir  = ".local/share/david-dotfiles"
 )
 
+// root replaces $HOME as the base of the install layout when set (--root).
+var root string
+
+// prefix, when set, holds installed programs and their links instead of
+// ~/.local; see SetPrefix.
+var prefix string
+
+// SetRoot makes SharePath, BinPath and DataPath resolve under dir instead of
+// $HOME, e.g. to populate a container rootfs. Config and cache paths stay on
+// the host. An empty dir restores the default.
+func SetRoot(dir string) {
+	root = dir
+}
+
+// SetPrefix makes SharePath and BinPath resolve to <dir>/opt and <dir>/bin,
+// the layout of Termux's $PREFIX, unless a root is set. DataPath stays in
+// $HOME. An empty dir restores the default.
+func SetPrefix(dir string) {
+	prefix = dir
+}
+
+// Root returns the directory set by SetRoot, or "".
+func Root() string {
+	return root
+}
+
+func home() string {
+	if root != "" {
+		return root
+	}
+	return os.Getenv("HOME")
+}
+
 // SharePath returns the absolute path to ~/.local/share.
 func SharePath() string {
-	return filepath.Join(os.Getenv("HOME"), ShareDir)
+	if prefix != "" && root == "" {
+		return filepath.Join(prefix, "opt")
+	}
+	return filepath.Join(home(), ShareDir)
 }
 
 // BinPath returns the absolute path to ~/.local/bin.
 func BinPath() string {
-	return filepath.Join(os.Getenv("HOME"), BinDir)
+	if prefix != "" && root == "" {
+		return filepath.Join(prefix, "bin")
+	}
+	return filepath.Join(home(), BinDir)
+}
+
+// DataPath returns the absolute path to ~/.local/share/david-dotfiles, where
+// the installer keeps its own bookkeeping (state file, caches).
+func DataPath() string {
+	return filepath.Join(home(), DataDir)
+}
+
+// ConfigPath returns the installer's config directory:
+// $XDG_CONFIG_HOME/david-dotfiles, falling back to ~/.config/david-dotfiles.
+func ConfigPath() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "david-dotfiles")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".config", "david-dotfiles")
+}
+
+// CachePath returns the installer's cache directory, used for in-flight
+// downloads: $XDG_CACHE_HOME/david-dotfiles, falling back to
+// ~/.cache/david-dotfiles. It lives on the home filesystem rather than /tmp,
+// which is often a small tmpfs.
+func CachePath() string {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "david-dotfiles")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".cache", "david-dotfiles")
 }
 
 // EnsureBaseDirs creates ~/.local/share and ~/.local/bin if they don't exist.
 func EnsureBaseDirs() error {
 	for _, dir := range []string{SharePath(), BinPath()} {
-		if err := os.MkdirAll(dir, 0755); err != nil {
+		if err := os.MkdirAll(dir, DirPerm()); err != nil {
 			return err
 		}
 	}
@@ -1,8 +1,12 @@
 package system_test
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
 	"github.com/dsaleh/david-dotfiles/internal/system"
 )
 
@@ -24,6 +28,36 @@ func TestCheckPackages_missing(t *testing.T) {
 	}
 }
 
+func TestCheckRequires(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "requires-*")
+	defer os.RemoveAll(dir)
+	os.WriteFile(filepath.Join(dir, "tool"), []byte("#!/bin/sh\necho 'tool version 2.5.1 (build 7)'\n"), 0755)
+	os.WriteFile(filepath.Join(dir, "quiet"), []byte("#!/bin/sh\n"), 0755)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	missing := system.CheckRequires(catalog.Requires{
+		Libraries: []string{"libxyzzy-does-not-exist.so.9"},
+		Commands: map[string]string{
+			"tool":        "2.5",
+			"quiet":       "1.0",
+			"no-such-cmd": "1.0",
+		},
+	})
+	want := []string{
+		"libxyzzy-does-not-exist.so.9 (shared library)",
+		"no-such-cmd >= 1.0 (not found)",
+		"quiet >= 1.0 (version unknown)",
+	}
+	if strings.Join(missing, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", missing, want)
+	}
+
+	missing = system.CheckRequires(catalog.Requires{Commands: map[string]string{"tool": "2.10"}})
+	if len(missing) != 1 || missing[0] != "tool >= 2.10 (found 2.5.1)" {
+		t.Errorf("newer minimum: got %q", missing)
+	}
+}
+
 func TestEnsureBaseDirs_creates(t *testing.T) {
 	// This is a smoke test — just verify it doesn't error on real paths
 	// (the real dirs may already exist, that's fine)
@@ -31,3 +65,99 @@ func TestEnsureBaseDirs_creates(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestOnWindowsDrive(t *testing.T) {
+	for path, want := range map[string]bool{
+		"/mnt/c/Users/david/bin": true,
+		"/mnt/d":                 true,
+		"/mnt/data/bin":          false,
+		"/home/david/.local/bin": false,
+	} {
+		if got := system.OnWindowsDrive(path); got != want {
+			t.Errorf("OnWindowsDrive(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestParseUmask(t *testing.T) {
+	for s, want := range map[string]os.FileMode{"022": 0022, "0077": 0077, "7": 0007} {
+		if got, err := system.ParseUmask(s); err != nil || got != want {
+			t.Errorf("ParseUmask(%q) = %o, %v; want %o", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "088", "1777", "u=rwx"} {
+		if _, err := system.ParseUmask(s); err == nil {
+			t.Errorf("ParseUmask(%q): expected an error", s)
+		}
+	}
+	if system.DirPerm() != 0755 || system.FilePerm() != 0644 {
+		t.Errorf("default modes = %o, %o; want 755, 644", system.DirPerm(), system.FilePerm())
+	}
+}
+
+func TestExecHint(t *testing.T) {
+	if hint := system.ExecHint("/bin/sh", nil, "cannot enable executable stack"); hint != "" {
+		t.Errorf("hint for a program that worked: %q", hint)
+	}
+	if !system.SELinuxEnforcing() && !system.AppArmorEnabled() {
+		if hint := system.ExecHint("/bin/sh", os.ErrPermission, ""); hint != "" {
+			t.Errorf("hint without a security module: %q", hint)
+		}
+	}
+}
+
+func TestSetRoot(t *testing.T) {
+	system.SetRoot("/rootfs/home/david")
+	defer system.SetRoot("")
+
+	if got := system.SharePath(); got != "/rootfs/home/david/.local/share" {
+		t.Errorf("unexpected share path %s", got)
+	}
+	if got := system.BinPath(); got != "/rootfs/home/david/.local/bin" {
+		t.Errorf("unexpected bin path %s", got)
+	}
+}
+
+func TestSetPrefix(t *testing.T) {
+	system.SetPrefix("/data/data/com.termux/files/usr")
+	defer system.SetPrefix("")
+
+	if got := system.SharePath(); got != "/data/data/com.termux/files/usr/opt" {
+		t.Errorf("unexpected share path %s", got)
+	}
+	if got := system.BinPath(); got != "/data/data/com.termux/files/usr/bin" {
+		t.Errorf("unexpected bin path %s", got)
+	}
+	system.SetRoot("/rootfs/home/david")
+	defer system.SetRoot("")
+	if got := system.BinPath(); got != "/rootfs/home/david/.local/bin" {
+		t.Errorf("root should win over the prefix, got %s", got)
+	}
+}
+
+func TestRunningIn(t *testing.T) {
+	if _, err := os.Stat("/proc/self/exe"); err != nil {
+		t.Skip("no /proc on this system")
+	}
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, p := range system.RunningIn(filepath.Dir(exe)) {
+		if p.PID == os.Getpid() {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("test process not found running in %s", filepath.Dir(exe))
+	}
+	empty, _ := os.MkdirTemp("", "procs-*")
+	defer os.RemoveAll(empty)
+	if procs := system.RunningIn(empty); len(procs) != 0 {
+		t.Errorf("expected nothing running in an empty dir, got %v", procs)
+	}
+}
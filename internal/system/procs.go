@@ -0,0 +1,47 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Process is a running process found by RunningIn.
+type Process struct {
+	PID int
+	Exe string // absolute path of its executable
+}
+
+// RunningIn returns the processes whose executable lives under dir, read from
+// /proc/<pid>/exe. Processes of other users are not visible without root, and
+// on systems without /proc nothing is found.
+func RunningIn(dir string) []Process {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	// Executables are reported by their real path; dir may be a link to a
+	// store entry.
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = real
+	}
+	prefix := filepath.Clean(dir) + string(filepath.Separator)
+	var out []Process
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		exe, err := os.Readlink(filepath.Join("/proc", e.Name(), "exe"))
+		if err != nil {
+			continue
+		}
+		// The kernel marks executables replaced since the process started.
+		exe = strings.TrimSuffix(exe, " (deleted)")
+		if strings.HasPrefix(exe, prefix) {
+			out = append(out, Process{PID: pid, Exe: exe})
+		}
+	}
+	return out
+}
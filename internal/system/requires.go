@@ -0,0 +1,149 @@
+package system
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+)
+
+// versionTimeout bounds each `<command> --version` run by CheckRequires.
+const versionTimeout = 5 * time.Second
+
+// libDirs are searched for shared libraries when ldconfig is not available,
+// as on musl-based systems.
+var libDirs = []string{"/lib", "/lib64", "/usr/lib", "/usr/lib64", "/usr/local/lib"}
+
+// CheckRequires checks the requirements in r that go beyond CheckPackages
+// and returns a description of each one the system does not meet, e.g.
+// "libfuse.so.2 (shared library)" or "git >= 2.30 (found 2.25.1)".
+func CheckRequires(r catalog.Requires) []string {
+	var missing []string
+	if len(r.Libraries) > 0 {
+		have := sharedLibraries()
+		for _, lib := range r.Libraries {
+			if !have[lib] {
+				missing = append(missing, lib+" (shared library)")
+			}
+		}
+	}
+	for _, mod := range r.PkgConfig {
+		if err := exec.Command("pkg-config", "--exists", mod).Run(); err != nil {
+			missing = append(missing, mod+" (pkg-config module)")
+		}
+	}
+	for _, cmd := range slices.Sorted(maps.Keys(r.Commands)) {
+		min := r.Commands[cmd]
+		have, err := commandVersion(cmd)
+		switch c, ok := catalog.CompareVersions(have, min); {
+		case err != nil:
+			missing = append(missing, fmt.Sprintf("%s >= %s (%v)", cmd, min, err))
+		case !ok:
+			missing = append(missing, fmt.Sprintf("%s >= %s (version unknown)", cmd, min))
+		case c < 0:
+			missing = append(missing, fmt.Sprintf("%s >= %s (found %s)", cmd, min, have))
+		}
+	}
+	for _, f := range r.Kernel {
+		if reason := kernelFeature(f); reason != "" {
+			missing = append(missing, fmt.Sprintf("kernel %s (%s)", f, reason))
+		}
+	}
+	return missing
+}
+
+// sharedLibraries returns the sonames known to the dynamic linker cache,
+// falling back to the files in libDirs without ldconfig.
+func sharedLibraries() map[string]bool {
+	libs := map[string]bool{}
+	ldconfig, err := exec.LookPath("ldconfig")
+	if err != nil {
+		for _, p := range []string{"/sbin/ldconfig", "/usr/sbin/ldconfig"} {
+			if _, err := os.Stat(p); err == nil {
+				ldconfig = p
+				break
+			}
+		}
+	}
+	if ldconfig != "" {
+		if out, err := exec.Command(ldconfig, "-p").Output(); err == nil {
+			// Lines look like "\tlibfuse.so.2 (libc6,x86-64) => /lib/x86_64-linux-gnu/libfuse.so.2".
+			sc := bufio.NewScanner(bytes.NewReader(out))
+			for sc.Scan() {
+				if name, _, ok := strings.Cut(strings.TrimSpace(sc.Text()), " ("); ok {
+					libs[name] = true
+				}
+			}
+			return libs
+		}
+	}
+	dirs := libDirs
+	if multiarch, _ := filepath.Glob("/usr/lib/*-linux-*"); len(multiarch) > 0 {
+		dirs = append(slices.Clone(dirs), multiarch...)
+	}
+	for _, dir := range dirs {
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			libs[e.Name()] = true
+		}
+	}
+	return libs
+}
+
+var versionPattern = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// commandVersion returns the first version number in the output of
+// `cmd --version`, or "" if there is none.
+func commandVersion(cmd string) (string, error) {
+	path, err := exec.LookPath(cmd)
+	if err != nil {
+		return "", errors.New("not found")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+	defer cancel()
+	out, _ := exec.CommandContext(ctx, path, "--version").CombinedOutput()
+	return versionPattern.FindString(string(out)), nil
+}
+
+// kernelFeature returns why feature is unavailable, or "" if it is usable.
+func kernelFeature(feature string) string {
+	switch feature {
+	case "fuse":
+		if _, err := os.Stat("/dev/fuse"); err != nil {
+			return "/dev/fuse is missing"
+		}
+	case "userns":
+		if _, err := os.Stat("/proc/self/ns/user"); err != nil {
+			return "not supported"
+		}
+		if n, err := readInt("/proc/sys/user/max_user_namespaces"); err == nil && n == 0 {
+			return "user.max_user_namespaces is 0"
+		}
+		if n, err := readInt("/proc/sys/kernel/unprivileged_userns_clone"); err == nil && n == 0 {
+			return "kernel.unprivileged_userns_clone is 0"
+		}
+	default:
+		return "unknown feature"
+	}
+	return ""
+}
+
+func readInt(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
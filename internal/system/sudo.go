@@ -0,0 +1,48 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// System-wide install roots used by --system, shared by every user on the box.
+const (
+	SystemSharePath = "/usr/local/share"
+	SystemBinPath   = "/usr/local/bin"
+)
+
+// Writable reports whether the current process can create files in dir.
+func Writable(dir string) bool {
+	return syscall.Access(dir, 0x2) == nil // W_OK
+}
+
+// SudoValidate asks sudo to cache credentials, prompting on the terminal if
+// needed. Call it before starting the TUI so later Sudo calls never prompt.
+func SudoValidate() error {
+	cmd := exec.Command("sudo", "-v")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sudo: %w", err)
+	}
+	return nil
+}
+
+// Sudo runs args as root without prompting (sudo -n); credentials must have
+// been cached by SudoValidate. The error includes sudo's stderr.
+func Sudo(ctx context.Context, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sudo", append([]string{"-n", "--"}, args...)...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("sudo %s: %w: %s", args[0], err, msg)
+		}
+		return fmt.Errorf("sudo %s: %w", args[0], err)
+	}
+	return nil
+}
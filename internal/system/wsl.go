@@ -0,0 +1,33 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// WSL reports whether the process runs under the Windows Subsystem for Linux.
+func WSL() bool {
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	if _, err := os.Stat("/proc/sys/fs/binfmt_misc/WSLInterop"); err == nil {
+		return true
+	}
+	version, err := os.ReadFile("/proc/version")
+	return err == nil && strings.Contains(strings.ToLower(string(version)), "microsoft")
+}
+
+// windowsDrive matches the mount points WSL gives Windows drives.
+var windowsDrive = regexp.MustCompile(`^/mnt/[a-z](/|$)`)
+
+// OnWindowsDrive reports whether path, once symlinks are resolved, is on a
+// Windows drive mounted by WSL such as /mnt/c, where Linux symlinks and the
+// executable bit are unreliable.
+func OnWindowsDrive(path string) bool {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return windowsDrive.MatchString(path)
+}
@@ -0,0 +1,189 @@
+// Package transport is the HTTP transport behind every request the installer
+// makes — GitHub API calls, asset downloads, the community index. It sets a
+// User-Agent, which some proxies and mirrors require, authenticates to GitHub
+// when a token is set, and can trace each request for diagnosing proxy and
+// API issues.
+package transport
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// DefaultUserAgent identifies the installer and the version it was built
+// from.
+func DefaultUserAgent() string {
+	version := "dev"
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		version = info.Main.Version
+	}
+	return "david-dotfiles-installer/" + version + " (+https://github.com/dsaleh/david-dotfiles)"
+}
+
+// LogPath returns the default location of the trace log.
+func LogPath() string {
+	return filepath.Join(system.CachePath(), "debug.log")
+}
+
+// TokenFromEnv returns the github.com token in $GITHUB_TOKEN or, failing
+// that, $GH_TOKEN (the one the gh CLI uses); "" if neither is set.
+func TokenFromEnv() string {
+	return firstEnv("GITHUB_TOKEN", "GH_TOKEN")
+}
+
+// EnterpriseTokenFromEnv returns the token for GitHub Enterprise Server
+// hosts, in $GH_ENTERPRISE_TOKEN or $GITHUB_ENTERPRISE_TOKEN as with the gh
+// CLI; "" if neither is set.
+func EnterpriseTokenFromEnv() string {
+	return firstEnv("GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN")
+}
+
+func firstEnv(names ...string) string {
+	for _, n := range names {
+		if v := os.Getenv(n); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// GitHubHosts are the hosts a github.com token is sent to.
+var GitHubHosts = []string{"github.com", "api.github.com"}
+
+// Transport wraps Base, setting UserAgent on requests that have none and,
+// if Trace is set, writing a line per request to it.
+//
+// Tokens (see SetToken) are sent to their own host only. The http.Client
+// hands each hop of a redirect chain to RoundTrip separately, so when GitHub
+// redirects an asset download to its CDN (objects.githubusercontent.com, or
+// an S3 bucket) the redirected request goes without one — the CDN URL is
+// pre-signed and rejects requests that carry an Authorization header as well.
+type Transport struct {
+	Base      http.RoundTripper
+	UserAgent string
+	Trace     io.Writer
+
+	mu       sync.Mutex        // serializes Trace writes and guards tokens and requests
+	tokens   map[string]string // host → token
+	requests map[string]int    // host → requests sent; see Requests
+}
+
+// Requests returns how many requests t has sent to each host, counting each
+// hop of a redirect chain.
+func (t *Transport) Requests() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]int, len(t.requests))
+	for host, n := range t.requests {
+		out[host] = n
+	}
+	return out
+}
+
+// SetToken makes t send token with requests for host (a host name, or
+// host:port); an empty token stops that.
+func (t *Transport) SetToken(host, token string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tokens == nil {
+		t.tokens = map[string]string{}
+	}
+	if token == "" {
+		delete(t.tokens, host)
+	} else {
+		t.tokens[host] = token
+	}
+}
+
+// Install wraps http.DefaultTransport in t and puts t in its place, so every
+// client without a transport of its own goes through it.
+func Install(t *Transport) {
+	if t.Base == nil {
+		t.Base = http.DefaultTransport
+	}
+	http.DefaultTransport = t
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.UserAgent != "" && req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.UserAgent)
+	}
+	req = t.authorize(req)
+	t.mu.Lock()
+	if t.requests == nil {
+		t.requests = map[string]int{}
+	}
+	t.requests[req.URL.Host]++
+	t.mu.Unlock()
+	start := time.Now()
+	resp, err := t.Base.RoundTrip(req)
+	if t.Trace != nil {
+		t.trace(req, resp, err, time.Since(start))
+	}
+	return resp, err
+}
+
+// authorize adds the token of the request's host, if it has one and the
+// request carries no Authorization header yet. While tokens are set, an
+// Authorization header on a request for a host without one is stripped.
+func (t *Transport) authorize(req *http.Request) *http.Request {
+	t.mu.Lock()
+	token, mine := t.tokens[req.URL.Host]
+	if !mine {
+		token, mine = t.tokens[req.URL.Hostname()]
+	}
+	none := len(t.tokens) == 0
+	t.mu.Unlock()
+	if none {
+		return req
+	}
+	switch {
+	case mine && req.Header.Get("Authorization") == "":
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+token)
+	case !mine && req.Header.Get("Authorization") != "":
+		req = req.Clone(req.Context())
+		req.Header.Del("Authorization")
+	}
+	return req
+}
+
+// trace logs the request line, the outcome, the time until the response
+// headers arrived and, for GitHub API responses, the rate limit, e.g.
+//
+//	2026-10-16T10:00:00Z GET https://api.github.com/repos/junegunn/fzf/releases/latest 200 312ms ratelimit=57/60 reset=10:42:00
+func (t *Transport) trace(req *http.Request, resp *http.Response, err error, d time.Duration) {
+	u := *req.URL
+	u.User = nil
+	line := fmt.Sprintf("%s %s %s", time.Now().UTC().Format(time.RFC3339), req.Method, u.String())
+	if err != nil {
+		line += fmt.Sprintf(" error %v after %s", err, d.Round(time.Millisecond))
+	} else {
+		line += fmt.Sprintf(" %d %s", resp.StatusCode, d.Round(time.Millisecond))
+		if n := resp.ContentLength; n >= 0 {
+			line += " length=" + strconv.FormatInt(n, 10)
+		}
+		if rem := resp.Header.Get("X-RateLimit-Remaining"); rem != "" {
+			line += fmt.Sprintf(" ratelimit=%s/%s", rem, resp.Header.Get("X-RateLimit-Limit"))
+			if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+				line += " reset=" + time.Unix(reset, 0).Format("15:04:05")
+			}
+		}
+		if after := resp.Header.Get("Retry-After"); after != "" {
+			line += " retry-after=" + after
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintln(t.Trace, line)
+}
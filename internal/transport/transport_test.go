@@ -0,0 +1,115 @@
+package transport_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dsaleh/david-dotfiles/internal/transport"
+)
+
+func TestRoundTrip(t *testing.T) {
+	var gotUA string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUA = r.Header.Get("User-Agent")
+		w.Header().Set("X-RateLimit-Remaining", "57")
+		w.Header().Set("X-RateLimit-Limit", "60")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	var log bytes.Buffer
+	client := &http.Client{Transport: &transport.Transport{Base: http.DefaultTransport, UserAgent: "test-agent/1", Trace: &log}}
+	resp, err := client.Get(srv.URL + "/repos/x/y")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+
+	if gotUA != "test-agent/1" {
+		t.Errorf("User-Agent = %q", gotUA)
+	}
+	line := log.String()
+	for _, want := range []string{"GET " + srv.URL + "/repos/x/y", " 404 ", "ratelimit=57/60"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("trace %q lacks %q", line, want)
+		}
+	}
+}
+
+func TestRoundTrip_keepsUserAgent(t *testing.T) {
+	var gotUA string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUA = r.Header.Get("User-Agent")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &transport.Transport{Base: http.DefaultTransport, UserAgent: "test-agent/1"}}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("User-Agent", "custom")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if gotUA != "custom" {
+		t.Errorf("User-Agent = %q, want the request's own", gotUA)
+	}
+}
+
+// GitHub redirects asset downloads to a CDN that rejects the token.
+func TestRoundTrip_tokenStaysOnGitHub(t *testing.T) {
+	var cdnAuth string
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cdnAuth = r.Header.Get("Authorization")
+	}))
+	defer cdn.Close()
+	var apiAuth string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiAuth = r.Header.Get("Authorization")
+		http.Redirect(w, r, strings.Replace(cdn.URL, "127.0.0.1", "localhost", 1)+"/asset?sig=x", http.StatusFound)
+	}))
+	defer api.Close()
+
+	host := strings.TrimPrefix(api.URL, "http://")
+	tr := &transport.Transport{Base: http.DefaultTransport}
+	tr.SetToken(host, "secret")
+	client := &http.Client{Transport: tr}
+	resp, err := client.Get(api.URL + "/repos/x/y/releases/assets/1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+
+	if apiAuth != "Bearer secret" {
+		t.Errorf("API got Authorization %q", apiAuth)
+	}
+	if cdnAuth != "" {
+		t.Errorf("CDN got Authorization %q", cdnAuth)
+	}
+}
+
+func TestRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	tr := &transport.Transport{Base: http.DefaultTransport}
+	client := &http.Client{Transport: tr}
+	for _, path := range []string{"/old", "/new"} {
+		resp, err := client.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		resp.Body.Close()
+	}
+	host := strings.TrimPrefix(srv.URL, "http://")
+	if got := tr.Requests(); len(got) != 1 || got[host] != 3 {
+		t.Errorf("Requests() = %v, want 3 for %s", got, host)
+	}
+}
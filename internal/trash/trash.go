@@ -0,0 +1,275 @@
+// Package trash keeps what the installer removes or replaces — the install
+// dirs of uninstalled programs and of the previous release on an upgrade, and
+// the bin entries that pointed into them — for a retention window, so a
+// mistaken uninstall or a bad upgrade can be undone with `installer restore`.
+package trash
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// Path returns the default trash dir.
+func Path() string {
+	return filepath.Join(system.DataPath(), "trash")
+}
+
+// Batch is the files one operation moved to the trash, restored together.
+type Batch struct {
+	ID      string    `json:"id"`
+	Program string    `json:"program"`
+	Reason  string    `json:"reason"` // what trashed them: "uninstall", "upgrade", "restore" or "dotfiles"
+	Time    time.Time `json:"time"`
+	Items   []string  `json:"items"` // original paths, in the order they were trashed
+
+	// State is the program's install record when the batch was made, put
+	// back on restore; nil if it had none.
+	State *state.ProgramState `json:"state,omitempty"`
+
+	root string
+	dir  string // "" until the first Move
+}
+
+const metaFile = "batch.json"
+
+// Open starts a batch in the trash dir root for program. Nothing is written
+// until the first Move, so an operation that trashes nothing leaves no trace.
+func Open(root, program, reason string, ps *state.ProgramState) *Batch {
+	return &Batch{Program: program, Reason: reason, Time: time.Now(), State: ps, root: root}
+}
+
+// Move moves path into the batch. Moving a path that does not exist is a
+// no-op.
+func (b *Batch) Move(path string) error {
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if b.dir == "" {
+		if err := b.create(); err != nil {
+			return err
+		}
+	}
+	if err := move(path, filepath.Join(b.dir, strconv.Itoa(len(b.Items)))); err != nil {
+		return fmt.Errorf("move %s to trash: %w", path, err)
+	}
+	b.Items = append(b.Items, path)
+	return b.save()
+}
+
+// Keep copies path into the batch, leaving it in place, for an item that is
+// about to be replaced rather than removed: an install dir that is a link
+// into the store, which costs nothing to copy. Restoring puts the copy back.
+// Keeping a path that does not exist is a no-op.
+func (b *Batch) Keep(path string) error {
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if b.dir == "" {
+		if err := b.create(); err != nil {
+			return err
+		}
+	}
+	if err := copyTree(path, filepath.Join(b.dir, strconv.Itoa(len(b.Items)))); err != nil {
+		return fmt.Errorf("copy %s to trash: %w", path, err)
+	}
+	b.Items = append(b.Items, path)
+	return b.save()
+}
+
+// create makes the batch dir, named after the time and program and made
+// unique with a counter.
+func (b *Batch) create() error {
+	if err := os.MkdirAll(b.root, system.DirPerm()); err != nil {
+		return err
+	}
+	base := b.Time.Format("20060102-150405") + "-" + b.Program
+	for n := 1; ; n++ {
+		id := base
+		if n > 1 {
+			id = fmt.Sprintf("%s-%d", base, n)
+		}
+		err := os.Mkdir(filepath.Join(b.root, id), system.DirPerm())
+		if err == nil {
+			b.ID, b.dir = id, filepath.Join(b.root, id)
+			return nil
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+	}
+}
+
+func (b *Batch) save() error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(b.dir, metaFile), data, system.FilePerm())
+}
+
+// List returns the batches in root, newest first. A missing dir yields none.
+func List(root string) ([]Batch, error) {
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []Batch
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		b, err := load(root, e.Name())
+		if err != nil {
+			continue // half-written or foreign; Purge removes it eventually
+		}
+		out = append(out, b)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Time.After(out[j].Time) })
+	return out, nil
+}
+
+func load(root, id string) (Batch, error) {
+	dir := filepath.Join(root, id)
+	data, err := os.ReadFile(filepath.Join(dir, metaFile))
+	if err != nil {
+		return Batch{}, err
+	}
+	var b Batch
+	if err := json.Unmarshal(data, &b); err != nil {
+		return Batch{}, fmt.Errorf("parse %s: %w", id, err)
+	}
+	b.ID, b.root, b.dir = id, root, dir
+	return b, nil
+}
+
+// Restore moves the items of batch id back to where they were and deletes
+// the batch. Whatever is in the way — typically the newer install dir that
+// replaced the one being restored — is first moved into a new batch with
+// reason "restore" and state current, so a restore can itself be undone. It
+// returns the restored batch and the new one, whose ID is "" if nothing was
+// in the way.
+func Restore(root, id string, current *state.ProgramState) (restored, displaced Batch, err error) {
+	b, err := load(root, id)
+	if err != nil {
+		return Batch{}, Batch{}, fmt.Errorf("no trash entry %s: %w", id, err)
+	}
+	aside := Open(root, b.Program, "restore", current)
+	for _, path := range b.Items {
+		if err := aside.Move(path); err != nil {
+			return Batch{}, *aside, err
+		}
+	}
+	for i, path := range b.Items {
+		if err := os.MkdirAll(filepath.Dir(path), system.DirPerm()); err != nil {
+			return Batch{}, *aside, err
+		}
+		if err := move(filepath.Join(b.dir, strconv.Itoa(i)), path); err != nil {
+			return Batch{}, *aside, fmt.Errorf("restore %s: %w", path, err)
+		}
+	}
+	return b, *aside, os.RemoveAll(b.dir)
+}
+
+// Purge deletes the batches in root older than maxAge, and any dir in root
+// that is not a readable batch, returning how many were deleted.
+func Purge(root string, maxAge time.Duration) (int, error) {
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-maxAge)
+	n := 0
+	for _, e := range entries {
+		b, err := load(root, e.Name())
+		if err == nil && b.Time.After(cutoff) {
+			continue
+		}
+		if err != nil {
+			// Leave a batch another process may be writing right now.
+			if info, ierr := e.Info(); ierr != nil || info.ModTime().After(cutoff) {
+				continue
+			}
+		}
+		if err := os.RemoveAll(filepath.Join(root, e.Name())); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// move renames src to dst, copying and deleting when they are on different
+// filesystems.
+func move(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyTree(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyTree copies the file, symlink or directory at src to dst, keeping
+// permissions.
+func copyTree(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	case info.IsDir():
+		if err := os.Mkdir(dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := copyTree(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
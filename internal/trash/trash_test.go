@@ -0,0 +1,109 @@
+package trash_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/trash"
+)
+
+func TestMoveAndRestore(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "trash-*")
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "trash")
+	install := filepath.Join(dir, "share", "fzf")
+	os.MkdirAll(install, 0755)
+	os.WriteFile(filepath.Join(install, "fzf"), []byte("v1"), 0755)
+	link := filepath.Join(dir, "bin", "fzf")
+	os.MkdirAll(filepath.Dir(link), 0755)
+	os.Symlink(filepath.Join(install, "fzf"), link)
+
+	b := trash.Open(root, "fzf", "uninstall", &state.ProgramState{Version: "0.60.0"})
+	for _, path := range []string{link, install, filepath.Join(dir, "missing")} {
+		if err := b.Move(path); err != nil {
+			t.Fatalf("move %s: %v", path, err)
+		}
+	}
+	if _, err := os.Lstat(install); !os.IsNotExist(err) {
+		t.Fatal("install dir still in place")
+	}
+
+	batches, err := trash.List(root)
+	if err != nil || len(batches) != 1 {
+		t.Fatalf("List = %+v, %v", batches, err)
+	}
+	if got := batches[0]; got.Program != "fzf" || len(got.Items) != 2 || got.State.Version != "0.60.0" {
+		t.Errorf("batch = %+v", got)
+	}
+
+	// A newer install took the place in the meantime.
+	os.MkdirAll(install, 0755)
+	os.WriteFile(filepath.Join(install, "fzf"), []byte("v2"), 0755)
+
+	restored, displaced, err := trash.Restore(root, batches[0].ID, &state.ProgramState{Version: "0.61.0"})
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if restored.State.Version != "0.60.0" {
+		t.Errorf("restored state = %+v", restored.State)
+	}
+	if data, _ := os.ReadFile(link); string(data) != "v1" {
+		t.Errorf("restored link reads %q, want v1", data)
+	}
+	if displaced.ID == "" || len(displaced.Items) != 1 || displaced.Items[0] != install {
+		t.Errorf("displaced = %+v", displaced)
+	}
+	batches, _ = trash.List(root)
+	if len(batches) != 1 || batches[0].Reason != "restore" {
+		t.Errorf("after restore: %+v", batches)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "trash-*")
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "trash")
+	f := filepath.Join(dir, "f")
+	os.WriteFile(f, nil, 0644)
+	trash.Open(root, "bat", "upgrade", nil).Move(f)
+
+	if n, err := trash.Purge(root, time.Hour); err != nil || n != 0 {
+		t.Errorf("Purge(1h) = %d, %v; want nothing purged", n, err)
+	}
+	if n, err := trash.Purge(root, 0); err != nil || n != 1 {
+		t.Errorf("Purge(0) = %d, %v; want 1", n, err)
+	}
+	if batches, _ := trash.List(root); len(batches) != 0 {
+		t.Errorf("left %+v", batches)
+	}
+}
+
+func TestKeep(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "trash")
+	v1, v2 := filepath.Join(dir, "store", "v1"), filepath.Join(dir, "store", "v2")
+	live := filepath.Join(dir, "share", "fzf")
+	os.MkdirAll(filepath.Dir(live), 0755)
+	os.Symlink(v1, live)
+
+	b := trash.Open(root, "fzf", "upgrade", nil)
+	if err := b.Keep(live); err != nil {
+		t.Fatal(err)
+	}
+	if target, _ := os.Readlink(live); target != v1 {
+		t.Fatalf("kept link changed: now %q", target)
+	}
+	// The upgrade switches the link.
+	os.Remove(live)
+	os.Symlink(v2, live)
+
+	if _, _, err := trash.Restore(root, b.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if target, _ := os.Readlink(live); target != v1 {
+		t.Errorf("restored link points to %q, want %q", target, v1)
+	}
+}
@@ -0,0 +1,129 @@
+// Package trust decides whether the commands a catalog specifies
+// (download_cmd, extract_cmd, health_cmd) may run. A catalog shared by
+// others, synced from a remote or downloaded from somewhere could otherwise
+// execute arbitrary commands on this machine, so each catalog has a trust
+// level and, at the default level, its commands need an explicit approval
+// that is remembered until they change.
+package trust
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// Level is how far a catalog's commands are trusted.
+type Level string
+
+const (
+	Ask       Level = "ask"       // run them once approved; the default
+	Trusted   Level = "trusted"   // run them without asking
+	Untrusted Level = "untrusted" // never run them
+)
+
+// ParseLevel validates a catalog_trust setting. The empty string means Ask.
+func ParseLevel(s string) (Level, error) {
+	switch Level(s) {
+	case "", Ask:
+		return Ask, nil
+	case Trusted, Untrusted:
+		return Level(s), nil
+	}
+	return "", fmt.Errorf("invalid trust level %q: expected ask, trusted or untrusted", s)
+}
+
+// Command is one command a catalog entry would run.
+type Command struct {
+	Program string
+	Field   string // catalog field, e.g. "extract_cmd"
+	Cmd     string
+}
+
+// Commands lists the commands in programs, ordered by program and field.
+func Commands(programs []catalog.Program) []Command {
+	var out []Command
+	for _, p := range programs {
+		if p.DownloadCmd != "" {
+			out = append(out, Command{p.Name, "download_cmd", p.DownloadCmd})
+		}
+		if p.ExtractCmd != "" {
+			out = append(out, Command{p.Name, "extract_cmd", p.ExtractCmd})
+		}
+		if p.HealthCmd != "" {
+			out = append(out, Command{p.Name, "health_cmd", p.HealthCmd})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Program < out[j].Program })
+	return out
+}
+
+// Fingerprint identifies a set of commands, so an approval lapses as soon as
+// any of them is added or changed.
+func Fingerprint(cmds []Command) string {
+	h := sha256.New()
+	for _, c := range cmds {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\n", c.Program, c.Field, c.Cmd)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Path returns the default location of the approvals file.
+func Path() string {
+	return filepath.Join(system.DataPath(), "trust.json")
+}
+
+// Approvals records which catalogs' commands were approved, as the
+// Fingerprint of the commands approved per catalog path.
+type Approvals struct {
+	mu       sync.Mutex
+	path     string
+	approved map[string]string
+}
+
+// Load reads the approvals at path. A missing file yields none.
+func Load(path string) (*Approvals, error) {
+	a := &Approvals{path: path, approved: map[string]string{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &a.approved); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return a, nil
+}
+
+// Approved reports whether the commands with fingerprint fp were approved for
+// the catalog at path.
+func (a *Approvals) Approved(path, fp string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.approved[path] == fp
+}
+
+// Approve records the commands with fingerprint fp as approved for the
+// catalog at path, replacing an earlier approval, and saves the file.
+func (a *Approvals) Approve(path, fp string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.approved[path] = fp
+	data, err := json.MarshalIndent(a.approved, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.path), system.DirPerm()); err != nil {
+		return err
+	}
+	return os.WriteFile(a.path, data, 0600)
+}
@@ -0,0 +1,61 @@
+package trust_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/trust"
+)
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]trust.Level{"": trust.Ask, "ask": trust.Ask, "trusted": trust.Trusted, "untrusted": trust.Untrusted} {
+		if got, err := trust.ParseLevel(in); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := trust.ParseLevel("yes"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestCommandsAndFingerprint(t *testing.T) {
+	programs := []catalog.Program{
+		{Name: "fzf"},
+		{Name: "cmake", ExtractCmd: "{archive} --prefix={dir}", DownloadCmd: "curl -o {out} {url}"},
+	}
+	cmds := trust.Commands(programs)
+	if len(cmds) != 2 || cmds[0].Field != "download_cmd" || cmds[1].Field != "extract_cmd" {
+		t.Fatalf("Commands = %+v", cmds)
+	}
+	fp := trust.Fingerprint(cmds)
+	programs[1].ExtractCmd = "{archive} --prefix={dir} && curl evil"
+	if trust.Fingerprint(trust.Commands(programs)) == fp {
+		t.Error("changing a command should change the fingerprint")
+	}
+}
+
+func TestApprovals(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "trust-*")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "trust.json")
+
+	a, err := trust.Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if a.Approved("/c/work.toml", "abc") {
+		t.Fatal("nothing should be approved yet")
+	}
+	if err := a.Approve("/c/work.toml", "abc"); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	loaded, err := trust.Load(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if !loaded.Approved("/c/work.toml", "abc") || loaded.Approved("/c/work.toml", "def") {
+		t.Error("approval not round-tripped or not keyed by fingerprint")
+	}
+}
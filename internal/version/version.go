@@ -0,0 +1,60 @@
+// Package version parses and compares the plain numeric release versions
+// used across the catalog: resolved tags (use_tags), hold_until conditions
+// and minimum command versions, so they all follow the same rules.
+package version
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Parse parses a version such as "1.4.2", "v1.4" or "2" into its numeric
+// parts. It accepts one to four parts; prerelease and build suffixes such as
+// "1.5.0-rc1" are rejected.
+func Parse(s string) ([]int, bool) {
+	fields := strings.Split(strings.TrimPrefix(s, "v"), ".")
+	if len(fields) > 4 {
+		return nil, false
+	}
+	parts := make([]int, len(fields))
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// ParseTag parses a release tag such as "v1.2.3" or "1.2" like Parse, but
+// only with two or three parts, so date tags like "20240101" and four-part
+// build tags are not taken for release versions.
+func ParseTag(tag string) ([]int, bool) {
+	parts, ok := Parse(tag)
+	if !ok || len(parts) < 2 || len(parts) > 3 {
+		return nil, false
+	}
+	return parts, true
+}
+
+// Compare compares two parsed versions, treating missing parts as 0: the
+// result is -1, 0 or 1 as a is older than, the same as or newer than b.
+func Compare(a, b []int) int {
+	for i := 0; i < max(len(a), len(b)); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
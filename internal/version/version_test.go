@@ -0,0 +1,66 @@
+package version_test
+
+import (
+	"testing"
+
+	"github.com/dsaleh/david-dotfiles/internal/version"
+)
+
+func TestParse(t *testing.T) {
+	for _, c := range []struct {
+		in string
+		ok bool
+	}{
+		{"1.4.2", true},
+		{"v1.4", true},
+		{"2", true},
+		{"1.2.3.4", true},
+		{"1.2.3.4.5", false},
+		{"1.5.0-rc1", false},
+		{"v1.2+build", false},
+		{"nightly", false},
+		{"", false},
+		{"1..2", false},
+	} {
+		if _, ok := version.Parse(c.in); ok != c.ok {
+			t.Errorf("Parse(%q) ok = %v, want %v", c.in, ok, c.ok)
+		}
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	for _, c := range []struct {
+		in string
+		ok bool
+	}{
+		{"v1.2.3", true},
+		{"1.2", true},
+		{"2023", false},
+		{"20240101", false},
+		{"1.2.3.4", false},
+		{"v2.0.0-rc1", false},
+	} {
+		if _, ok := version.ParseTag(c.in); ok != c.ok {
+			t.Errorf("ParseTag(%q) ok = %v, want %v", c.in, ok, c.ok)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.4.2", "1.4.2", 0},
+		{"1.4", "1.4.0", 0},
+		{"1.10.0", "1.9.0", 1},
+		{"v1.2", "1.2.1", -1},
+		{"2", "1.99.99", 1},
+	} {
+		a, _ := version.Parse(c.a)
+		b, _ := version.Parse(c.b)
+		if got := version.Compare(a, b); got != c.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
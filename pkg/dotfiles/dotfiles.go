@@ -0,0 +1,143 @@
+// Package dotfiles is the embeddable API of the installer engine. It lets
+// other Go programs load a catalog, install programs and read install state
+// without the TUI. The types are aliases of the internal packages, so values
+// can be passed between this package and the engine without conversion.
+package dotfiles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/report"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+type (
+	Program       = catalog.Program
+	Bin           = catalog.Bin
+	State         = state.State
+	ProgramState  = state.ProgramState
+	ProgressMsg   = installer.ProgressMsg
+	InstallState  = installer.State
+	SkipReason    = installer.SkipReason
+	Report        = report.Report
+	ProgramReport = report.ProgramReport
+)
+
+const (
+	StatePending              = installer.StatePending
+	StateFetchingVersion      = installer.StateFetchingVersion
+	StateDownloading          = installer.StateDownloading
+	StateExtracting           = installer.StateExtracting
+	StateAwaitingBinSelection = installer.StateAwaitingBinSelection
+	StateLinking              = installer.StateLinking
+	StateDone                 = installer.StateDone
+	StateSkipped              = installer.StateSkipped
+	StateError                = installer.StateError
+
+	SkipUpToDate    = installer.SkipUpToDate
+	SkipHeld        = installer.SkipHeld
+	SkipOffline     = installer.SkipOffline
+	SkipUnsupported = installer.SkipUnsupported
+)
+
+// LoadCatalog parses and validates a catalog.toml file.
+func LoadCatalog(path string) ([]Program, error) {
+	return catalog.Load(path)
+}
+
+// DefaultStatePath returns the state file used by the installer binary.
+func DefaultStatePath() string {
+	return state.Path()
+}
+
+// LoadState reads a state file. A missing file yields an empty State.
+func LoadState(path string) (*State, error) {
+	return state.Load(path)
+}
+
+// Select returns the programs with the given names, in catalog order.
+// Unknown names are reported together in a single error.
+func Select(programs []Program, names ...string) ([]Program, error) {
+	want := make(map[string]bool, len(names))
+	for _, n := range names {
+		want[n] = true
+	}
+	var out []Program
+	for _, p := range programs {
+		if want[p.Name] {
+			out = append(out, p)
+			delete(want, p.Name)
+		}
+	}
+	if len(want) > 0 {
+		var unknown []string
+		for _, n := range names {
+			if want[n] {
+				unknown = append(unknown, n)
+			}
+		}
+		return nil, fmt.Errorf("unknown programs: %s", strings.Join(unknown, ", "))
+	}
+	return out, nil
+}
+
+// Options configures Install.
+type Options struct {
+	// State records successful installs. If nil, the default state file is loaded.
+	State *State
+	// Verbose prints resolved URLs and version info to stderr.
+	Verbose bool
+	// SelectBins is called for programs whose catalog entry has no bin list,
+	// after extraction. It returns the binaries to link (Src is an absolute path
+	// under msg.InstallDir). If nil, such programs are installed without links.
+	SelectBins func(msg ProgressMsg) []Bin
+	// OnProgress, if set, is called for every progress message in order.
+	OnProgress func(msg ProgressMsg)
+}
+
+// Install installs programs and blocks until every one has finished.
+// The returned Report is always populated; the error joins the failures of
+// individual programs, if any.
+func Install(ctx context.Context, programs []Program, opts Options) (Report, error) {
+	if err := system.EnsureBaseDirs(); err != nil {
+		return Report{}, fmt.Errorf("create base dirs: %w", err)
+	}
+	st := opts.State
+	if st == nil {
+		var err error
+		if st, err = state.Load(state.Path()); err != nil {
+			return Report{}, err
+		}
+	}
+
+	names := make([]string, len(programs))
+	for i, p := range programs {
+		names[i] = p.Name
+	}
+	rec := report.NewRecorder(names)
+
+	var errs []error
+	for msg := range installer.Run(ctx, programs, installer.Options{State: st, Verbose: opts.Verbose}) {
+		rec.Record(msg)
+		if opts.OnProgress != nil {
+			opts.OnProgress(msg)
+		}
+		switch msg.State {
+		case installer.StateAwaitingBinSelection:
+			if opts.SelectBins != nil {
+				msg.BinCh <- opts.SelectBins(msg)
+			} else {
+				close(msg.BinCh)
+			}
+		case installer.StateError:
+			errs = append(errs, fmt.Errorf("%s: %w", msg.Program, msg.Err))
+		}
+	}
+	return rec.Report(), errors.Join(errs...)
+}
@@ -0,0 +1,31 @@
+package dotfiles_test
+
+import (
+	"testing"
+
+	"github.com/dsaleh/david-dotfiles/pkg/dotfiles"
+)
+
+func TestSelect(t *testing.T) {
+	programs := []dotfiles.Program{{Name: "fzf"}, {Name: "nvim"}, {Name: "ripgrep"}}
+
+	got, err := dotfiles.Select(programs, "ripgrep", "fzf")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "fzf" || got[1].Name != "ripgrep" {
+		t.Errorf("expected [fzf ripgrep] in catalog order, got %+v", got)
+	}
+}
+
+func TestSelect_unknown(t *testing.T) {
+	programs := []dotfiles.Program{{Name: "fzf"}}
+
+	_, err := dotfiles.Select(programs, "fzf", "nope", "nada")
+	if err == nil {
+		t.Fatal("expected error for unknown program names")
+	}
+	if err.Error() != "unknown programs: nope, nada" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
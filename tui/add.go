@@ -0,0 +1,281 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	gh "github.com/dsaleh/david-dotfiles/internal/github"
+	"github.com/dsaleh/david-dotfiles/internal/importer"
+)
+
+// searchResultSize is how many repos a search offers.
+const searchResultSize = 10
+
+// searchAgain is the repo choice that returns to the search input.
+const searchAgain = ""
+
+type addStep int
+
+const (
+	addSearch addStep = iota
+	addSearching
+	addPick
+	addDetecting
+	addConfirm
+)
+
+// searchMsg carries the result of a GitHub repo search.
+type searchMsg struct {
+	repos []gh.Repo
+	err   error
+}
+
+// entryMsg carries the catalog entry detected for the chosen repo.
+type entryMsg struct {
+	entry importer.Entry
+	err   error
+}
+
+// AddModel is the add-program wizard: search GitHub for a tool by name, pick
+// the right repo from the results, and review the detected catalog entry.
+// The caller appends the entry to the catalog once the wizard completes.
+type AddModel struct {
+	ctx      context.Context
+	im       *importer.Importer
+	existing map[string]bool
+
+	step addStep
+	err  error
+
+	form    *huh.Form
+	query   *string // heap-allocated; huh writes via pointer
+	repo    *string
+	name    *string
+	confirm *bool
+
+	repos []gh.Repo
+	entry importer.Entry
+	done  bool
+
+	width int
+}
+
+// NewAdd creates the add-program wizard. programs is the current catalog,
+// used to reject names that are already taken.
+func NewAdd(ctx context.Context, programs []catalog.Program) AddModel {
+	existing := make(map[string]bool, len(programs))
+	for _, p := range programs {
+		existing[p.Name] = true
+	}
+	query, repo, name, confirm := "", "", "", true
+	m := AddModel{
+		ctx:      ctx,
+		im:       importer.New(),
+		existing: existing,
+		query:    &query,
+		repo:     &repo,
+		name:     &name,
+		confirm:  &confirm,
+	}
+	m.form = m.searchForm()
+	return m
+}
+
+// Entry returns the entry to add and whether the wizard was completed.
+func (m AddModel) Entry() (importer.Entry, bool) {
+	return m.entry, m.done
+}
+
+func (m AddModel) Init() tea.Cmd { return m.form.Init() }
+
+func (m AddModel) searchForm() *huh.Form {
+	return m.newForm(huh.NewInput().
+		Title("Search GitHub").
+		Description("Tool name or keywords, e.g. \"fuzzy finder\"").
+		Value(m.query).
+		Validate(func(s string) error {
+			if strings.TrimSpace(s) == "" {
+				return fmt.Errorf("query cannot be empty")
+			}
+			return nil
+		}))
+}
+
+func (m AddModel) pickForm() *huh.Form {
+	opts := make([]huh.Option[string], 0, len(m.repos)+1)
+	for _, r := range m.repos {
+		label := fmt.Sprintf("%-36s ★ %-7s %s", r.FullName, formatStars(r.Stars), truncate(r.Description, 60))
+		opts = append(opts, huh.NewOption(label, r.FullName))
+	}
+	opts = append(opts, huh.NewOption("← search again", searchAgain))
+	*m.repo = m.repos[0].FullName
+	return m.newForm(huh.NewSelect[string]().
+		Title(fmt.Sprintf("Results for %q", *m.query)).
+		Options(opts...).
+		Value(m.repo)).WithHeight(searchResultSize + 6)
+}
+
+func (m AddModel) confirmForm() *huh.Form {
+	*m.name = m.entry.Name
+	return m.newForm(
+		huh.NewInput().
+			Title("Program name").
+			Description("Key of the [programs.<name>] table").
+			Value(m.name).
+			Validate(func(s string) error {
+				s = strings.TrimSpace(s)
+				if s == "" {
+					return fmt.Errorf("name cannot be empty")
+				}
+				if m.existing[s] {
+					return fmt.Errorf("%s is already in the catalog", s)
+				}
+				return nil
+			}),
+		huh.NewConfirm().
+			Title("Add to the catalog?").
+			Value(m.confirm),
+	)
+}
+
+func (m AddModel) newForm(fields ...huh.Field) *huh.Form {
+	f := huh.NewForm(huh.NewGroup(fields...)).WithTheme(huhTheme)
+	if m.width > 0 {
+		f = f.WithWidth(m.width)
+	}
+	return f
+}
+
+func (m AddModel) search() tea.Cmd {
+	ctx, client, query := m.ctx, m.im.GitHub, strings.TrimSpace(*m.query)
+	return func() tea.Msg {
+		repos, err := client.SearchRepos(ctx, query, searchResultSize)
+		return searchMsg{repos: repos, err: err}
+	}
+}
+
+func (m AddModel) detect() tea.Cmd {
+	ctx, im, repo := m.ctx, m.im, *m.repo
+	return func() tea.Msg {
+		e, err := im.Entry(ctx, "", repo)
+		return entryMsg{entry: e, err: err}
+	}
+}
+
+func (m AddModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		if m.form != nil {
+			m.form = m.form.WithWidth(msg.Width)
+		}
+		return m, nil
+
+	case searchMsg:
+		m.err = msg.err
+		if msg.err == nil && len(msg.repos) == 0 {
+			m.err = fmt.Errorf("no repositories match %q", *m.query)
+		}
+		if m.err != nil {
+			m.step, m.form = addSearch, m.searchForm()
+			return m, m.form.Init()
+		}
+		m.repos = msg.repos
+		m.step, m.form = addPick, m.pickForm()
+		return m, m.form.Init()
+
+	case entryMsg:
+		if msg.err != nil {
+			// Most often the repo has no release for this platform; let the
+			// user pick another result.
+			m.err = msg.err
+			m.step, m.form = addPick, m.pickForm()
+			return m, m.form.Init()
+		}
+		m.entry = msg.entry
+		m.step, m.form = addConfirm, m.confirmForm()
+		return m, m.form.Init()
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+	}
+
+	if m.step == addSearching || m.step == addDetecting {
+		return m, nil
+	}
+
+	form, cmd := m.form.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.form = f
+	}
+	switch m.form.State {
+	case huh.StateAborted:
+		return m, tea.Quit
+	case huh.StateCompleted:
+		m.err = nil
+		switch m.step {
+		case addSearch:
+			m.step = addSearching
+			return m, m.search()
+		case addPick:
+			if *m.repo == searchAgain {
+				m.step, m.form = addSearch, m.searchForm()
+				return m, m.form.Init()
+			}
+			m.step = addDetecting
+			return m, m.detect()
+		case addConfirm:
+			m.entry.Name = strings.TrimSpace(*m.name)
+			m.done = *m.confirm
+			return m, tea.Quit
+		}
+	}
+	return m, cmd
+}
+
+func (m AddModel) View() string {
+	var sb strings.Builder
+	sb.WriteString("\n  Add a program\n\n")
+	switch m.step {
+	case addSearching:
+		sb.WriteString(stylePending.Render(fmt.Sprintf("  Searching GitHub for %q…", *m.query)) + "\n")
+	case addDetecting:
+		sb.WriteString(stylePending.Render(fmt.Sprintf("  Looking for a release asset of %s…", *m.repo)) + "\n")
+	case addConfirm:
+		var entry strings.Builder
+		importer.WriteTOML(&entry, []importer.Entry{m.entry})
+		for _, line := range strings.Split(strings.TrimRight(entry.String(), "\n"), "\n") {
+			sb.WriteString("    " + line + "\n")
+		}
+		sb.WriteString("\n" + m.form.View())
+	default:
+		sb.WriteString(m.form.View())
+	}
+	if m.err != nil {
+		sb.WriteString("\n" + styleError.Render(fmt.Sprintf("  %v", m.err)) + "\n")
+	}
+	return sb.String()
+}
+
+// formatStars abbreviates a star count, e.g. 12345 → "12.3k".
+func formatStars(n int) string {
+	if n < 1000 {
+		return fmt.Sprint(n)
+	}
+	return fmt.Sprintf("%.1fk", float64(n)/1000)
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,484 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type browserState int
+
+const (
+	browserBrowsing browserState = iota
+	browserPicked
+	browserAborted
+)
+
+// browserEntry is one row of the listing.
+type browserEntry struct {
+	name  string
+	rel   string // path relative to the browser root
+	depth int    // nesting level in the tree view; 0 in the list view
+	dir   bool
+	exec  bool
+	score int // fuzzy match score against the current filter
+}
+
+// fileBrowser is the bin picker's browse phase: a listing of one directory
+// under root that narrows as the user types. Extracted trees such as node or
+// zig hold hundreds of entries per directory, so typing a few letters of the
+// binary's name is faster than scrolling.
+//
+// Keys: type to filter, esc clears the filter (or aborts when empty), enter
+// opens a directory or picks a file, backspace on an empty filter goes up,
+// tab switches between the flat listing and a tree of expandable directories,
+// ctrl+a shows or hides dotfiles, space marks files so that enter on a file
+// picks all the marked ones at once.
+type fileBrowser struct {
+	root        string
+	dir         string
+	title       string
+	description string
+
+	tree     bool            // tree view instead of the flat listing
+	hidden   bool            // list dotfiles too
+	expanded map[string]bool // rel paths of the directories open in the tree
+
+	all     []browserEntry
+	visible []browserEntry
+	filter  string
+	cursor  int
+	offset  int
+	err     error
+
+	marked   map[string]bool // rel paths of files marked with space
+	state    browserState
+	selected []string // absolute paths of the picked files
+
+	height int
+}
+
+func newFileBrowser(root, title, description string) fileBrowser {
+	b := fileBrowser{root: root, title: title, description: description, expanded: map[string]bool{}, marked: map[string]bool{}}
+	b.chdir(root)
+	return b
+}
+
+// readDir returns the entries of the directory at rel, sorted with
+// directories first. Dotfiles are skipped unless hidden is set.
+func (b *fileBrowser) readDir(rel string, depth int) ([]browserEntry, error) {
+	entries, err := os.ReadDir(filepath.Join(b.root, rel))
+	var out []browserEntry
+	for _, e := range entries {
+		if !b.hidden && strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		// Stat follows symlinks, so a link to a directory can be entered.
+		info, err := os.Stat(filepath.Join(b.root, rel, e.Name()))
+		if err != nil {
+			continue
+		}
+		out = append(out, browserEntry{
+			name:  e.Name(),
+			rel:   filepath.Join(rel, e.Name()),
+			depth: depth,
+			dir:   info.IsDir(),
+			exec:  !info.IsDir() && info.Mode()&0111 != 0,
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].dir && !out[j].dir })
+	return out, err
+}
+
+// chdir lists dir and resets the filter and cursor.
+func (b *fileBrowser) chdir(dir string) {
+	b.dir, b.filter, b.cursor, b.offset = dir, "", 0, 0
+	b.reload()
+}
+
+// reload rebuilds the rows: the entries of dir in the list view, or every
+// entry reachable through expanded directories in the tree view.
+func (b *fileBrowser) reload() {
+	b.err = nil
+	if !b.tree {
+		b.all, b.err = b.readDir(b.rel(b.dir), 0)
+		b.applyFilter()
+		return
+	}
+	b.all = nil
+	var walk func(rel string, depth int)
+	walk = func(rel string, depth int) {
+		entries, err := b.readDir(rel, depth)
+		if err != nil && b.err == nil {
+			b.err = err
+		}
+		for _, e := range entries {
+			b.all = append(b.all, e)
+			if e.dir && b.expanded[e.rel] {
+				walk(e.rel, depth+1)
+			}
+		}
+	}
+	walk("", 0)
+	b.applyFilter()
+}
+
+// rel returns path relative to the root ("" for the root itself).
+func (b *fileBrowser) rel(path string) string {
+	rel, err := filepath.Rel(b.root, path)
+	if err != nil || rel == "." {
+		return ""
+	}
+	return rel
+}
+
+// current returns the row under the cursor.
+func (b *fileBrowser) current() (browserEntry, bool) {
+	if b.cursor >= len(b.visible) {
+		return browserEntry{}, false
+	}
+	return b.visible[b.cursor], true
+}
+
+// moveTo puts the cursor on the row with the given rel path, if visible.
+func (b *fileBrowser) moveTo(rel string) {
+	for i, e := range b.visible {
+		if e.rel == rel {
+			b.cursor = i
+		}
+	}
+	b.scroll()
+}
+
+// toggleTree switches views, keeping the cursor's location: the tree opens
+// with the current directory expanded, and the list opens on the directory
+// holding the row under the cursor.
+func (b *fileBrowser) toggleTree() {
+	e, ok := b.current()
+	b.tree = !b.tree
+	b.filter = ""
+	if b.tree {
+		for rel := b.rel(b.dir); rel != "" && rel != "."; rel = filepath.Dir(rel) {
+			b.expanded[rel] = true
+		}
+		b.reload()
+	} else {
+		dir := b.root
+		if ok {
+			dir = filepath.Join(b.root, filepath.Dir(e.rel))
+		}
+		b.chdir(dir)
+	}
+	if ok {
+		b.moveTo(e.rel)
+	}
+}
+
+// setExpanded opens or closes a directory of the tree, keeping the cursor on it.
+func (b *fileBrowser) setExpanded(rel string, open bool) {
+	b.expanded[rel] = open
+	b.reload()
+	b.moveTo(rel)
+}
+
+// applyFilter recomputes the visible entries from the filter, best matches
+// first. With no filter, directories are listed before files.
+func (b *fileBrowser) applyFilter() {
+	b.visible = nil
+	for _, e := range b.all {
+		score, ok := fuzzyMatch(b.filter, e.name)
+		if !ok {
+			continue
+		}
+		e.score = score
+		b.visible = append(b.visible, e)
+	}
+	// The tree keeps its shape while filtering; only the list is ranked.
+	if b.filter != "" && !b.tree {
+		sort.SliceStable(b.visible, func(i, j int) bool {
+			return b.visible[i].score > b.visible[j].score
+		})
+	}
+	b.cursor, b.offset = 0, 0
+}
+
+func (b fileBrowser) Update(msg tea.Msg) (fileBrowser, tea.Cmd) {
+	k, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return b, nil
+	}
+	switch k.Type {
+	case tea.KeyUp, tea.KeyCtrlP:
+		if b.cursor > 0 {
+			b.cursor--
+		}
+	case tea.KeyDown, tea.KeyCtrlN:
+		if b.cursor < len(b.visible)-1 {
+			b.cursor++
+		}
+	case tea.KeyPgUp:
+		b.cursor = max(b.cursor-b.listHeight(), 0)
+	case tea.KeyPgDown:
+		b.cursor = max(min(b.cursor+b.listHeight(), len(b.visible)-1), 0)
+	case tea.KeyEsc:
+		if b.filter != "" {
+			b.filter = ""
+			b.applyFilter()
+		} else {
+			b.state = browserAborted
+		}
+	case tea.KeyTab:
+		b.toggleTree()
+	case tea.KeyCtrlA:
+		// Some archives keep wrappers or libraries in dot-directories.
+		e, _ := b.current()
+		b.hidden = !b.hidden
+		b.reload()
+		b.moveTo(e.rel)
+	case tea.KeyEnter, tea.KeyRight:
+		e, ok := b.current()
+		if !ok && len(b.marked) == 0 {
+			break
+		}
+		// Directories open even while files are marked, so marks can be
+		// collected across the tree.
+		switch {
+		case ok && e.dir && b.tree:
+			b.setExpanded(e.rel, k.Type == tea.KeyRight || !b.expanded[e.rel])
+		case ok && e.dir:
+			b.chdir(filepath.Join(b.root, e.rel))
+		case k.Type != tea.KeyEnter:
+		case len(b.marked) > 0:
+			b.selected = b.markedPaths()
+			b.state = browserPicked
+		default:
+			b.selected = []string{filepath.Join(b.root, e.rel)}
+			b.state = browserPicked
+		}
+	case tea.KeyLeft:
+		b.up()
+	case tea.KeyBackspace:
+		if b.filter == "" {
+			b.up()
+			break
+		}
+		r := []rune(b.filter)
+		b.filter = string(r[:len(r)-1])
+		b.applyFilter()
+	case tea.KeySpace:
+		if e, ok := b.current(); ok && !e.dir {
+			if b.marked[e.rel] {
+				delete(b.marked, e.rel)
+			} else {
+				b.marked[e.rel] = true
+			}
+			if b.cursor < len(b.visible)-1 {
+				b.cursor++
+			}
+		}
+	case tea.KeyRunes:
+		b.filter += string(k.Runes)
+		b.applyFilter()
+	}
+	b.scroll()
+	return b, nil
+}
+
+// up moves to the parent directory, never above root, keeping the cursor on
+// the directory just left. In the tree it collapses the directory under the
+// cursor, or moves to its parent row.
+func (b *fileBrowser) up() {
+	if b.tree {
+		e, ok := b.current()
+		switch {
+		case !ok:
+		case e.dir && b.expanded[e.rel]:
+			b.setExpanded(e.rel, false)
+		case e.depth > 0:
+			b.moveTo(filepath.Dir(e.rel))
+		}
+		return
+	}
+	if b.dir == b.root {
+		return
+	}
+	from := b.rel(b.dir)
+	b.chdir(filepath.Dir(b.dir))
+	b.moveTo(from)
+}
+
+// markedPaths returns the absolute paths of the marked files, sorted.
+func (b *fileBrowser) markedPaths() []string {
+	paths := make([]string, 0, len(b.marked))
+	for rel := range b.marked {
+		paths = append(paths, filepath.Join(b.root, rel))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// mark marks the files at paths and opens the directory of the first, with
+// the cursor on it, so that enter picks them all.
+func (b *fileBrowser) mark(paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	for _, path := range paths {
+		b.marked[b.rel(path)] = true
+	}
+	b.chdir(filepath.Dir(paths[0]))
+	b.moveTo(b.rel(paths[0]))
+}
+
+// clearMarks unmarks every file and returns to browsing.
+func (b *fileBrowser) clearMarks() {
+	b.marked = map[string]bool{}
+	b.state = browserBrowsing
+}
+
+// breadcrumb renders the location being browsed as root › dir › subdir. In
+// the tree view it follows the row under the cursor.
+func (b fileBrowser) breadcrumb() string {
+	rel := b.rel(b.dir)
+	if b.tree {
+		rel = ""
+		if e, ok := b.current(); ok {
+			rel = filepath.Dir(e.rel)
+			if e.dir {
+				rel = e.rel
+			}
+		}
+	}
+	parts := []string{filepath.Base(b.root)}
+	if rel != "" && rel != "." {
+		parts = append(parts, strings.Split(rel, string(filepath.Separator))...)
+	}
+	return strings.Join(parts, " › ")
+}
+
+// listHeight is how many entries fit below the header.
+func (b fileBrowser) listHeight() int {
+	if b.height <= 0 {
+		return 20
+	}
+	return max(b.height-9, 3)
+}
+
+// scroll keeps the cursor inside the visible window.
+func (b *fileBrowser) scroll() {
+	h := b.listHeight()
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+	if b.cursor >= b.offset+h {
+		b.offset = b.cursor - h + 1
+	}
+}
+
+func (b fileBrowser) View() string {
+	var sb strings.Builder
+	sb.WriteString("\n  " + styleDone.Render(b.title) + "\n")
+	for _, line := range strings.Split(b.description, "\n") {
+		sb.WriteString(stylePending.Render("  "+line) + "\n")
+	}
+
+	sb.WriteString("\n  " + b.breadcrumb() + "\n")
+	if b.filter != "" {
+		sb.WriteString(styleSkipped.Render(fmt.Sprintf("  filter: %s", b.filter)) + "\n")
+	} else {
+		sb.WriteString(stylePending.Render("  type to filter") + "\n")
+	}
+	if len(b.marked) > 0 {
+		sb.WriteString(styleSkipped.Render(fmt.Sprintf("  %d marked — enter links them all", len(b.marked))) + "\n")
+	}
+
+	if b.err != nil {
+		sb.WriteString(styleError.Render(fmt.Sprintf("  %v", b.err)) + "\n")
+	}
+	if len(b.visible) == 0 {
+		sb.WriteString(stylePending.Render("  (no matches)") + "\n")
+	}
+	end := min(b.offset+b.listHeight(), len(b.visible))
+	for i := b.offset; i < end; i++ {
+		e := b.visible[i]
+		name := e.name
+		switch {
+		case e.dir && b.tree && b.expanded[e.rel]:
+			name = "▾ " + name + "/"
+		case e.dir && b.tree:
+			name = "▸ " + name + "/"
+		case e.dir:
+			name += "/"
+		case e.exec:
+			name += "*"
+		}
+		if b.tree {
+			name = strings.Repeat("  ", e.depth) + name
+			if !e.dir {
+				name = "  " + name
+			}
+		}
+		if b.marked[e.rel] {
+			name = "✓ " + name
+		} else if len(b.marked) > 0 {
+			name = "  " + name
+		}
+		if i == b.cursor {
+			sb.WriteString(styleDone.Render("  > "+name) + "\n")
+		} else {
+			sb.WriteString("    " + name + "\n")
+		}
+	}
+	if end < len(b.visible) {
+		sb.WriteString(stylePending.Render(fmt.Sprintf("    … %d more", len(b.visible)-end)) + "\n")
+	}
+
+	help := "\n  enter: open/pick  •  space: mark  •  backspace: up  •  tab: tree view"
+	if b.tree {
+		help = "\n  enter: expand/pick  •  space: mark  •  ←/→: collapse/expand  •  tab: list view"
+	}
+	if b.hidden {
+		help += "  •  ctrl+a: hide dotfiles"
+	} else {
+		help += "  •  ctrl+a: show dotfiles"
+	}
+	help += "  •  esc: clear filter / finish"
+	sb.WriteString(stylePending.Render(help) + "\n")
+	return sb.String()
+}
+
+// fuzzyMatch reports whether the runes of pattern appear in order in s,
+// ignoring case, and scores the match: consecutive runes and runes at the
+// start of a word score higher, and shorter names win ties. An empty pattern
+// matches everything.
+func fuzzyMatch(pattern, s string) (int, bool) {
+	if pattern == "" {
+		return 0, true
+	}
+	p := []rune(strings.ToLower(pattern))
+	r := []rune(strings.ToLower(s))
+	score, pi, prev := 0, 0, -2
+	for i := 0; i < len(r) && pi < len(p); i++ {
+		if r[i] != p[pi] {
+			continue
+		}
+		score++
+		if i == prev+1 {
+			score += 3
+		}
+		if i == 0 || !unicode.IsLetter(r[i-1]) && !unicode.IsDigit(r[i-1]) {
+			score += 2
+		}
+		prev = i
+		pi++
+	}
+	if pi < len(p) {
+		return 0, false
+	}
+	return score*100 - len(r), true
+}
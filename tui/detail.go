@@ -0,0 +1,197 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	gh "github.com/dsaleh/david-dotfiles/internal/github"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+)
+
+// releaseListSize is how many recent releases the detail view offers.
+const releaseListSize = 10
+
+// releasesMsg carries the result of fetching recent releases for the detail view.
+type releasesMsg struct {
+	releases []gh.Release
+	err      error
+}
+
+func fetchReleases(ctx context.Context, p catalog.Program) tea.Cmd {
+	return func() tea.Msg {
+		rels, err := gh.NewClient(p.GitHubAPI()).ListReleases(ctx, p.Repo, releaseListSize)
+		return releasesMsg{releases: rels, err: err}
+	}
+}
+
+// detailModel shows one program's catalog entry and install state, and lets
+// the user pick one of its recent releases. Choosing a specific tag installs
+// it as a pinned version; choosing "latest" clears any pin.
+type detailModel struct {
+	program   catalog.Program
+	current   state.ProgramState
+	installed bool
+	stats     state.Stats
+	ctx       context.Context
+
+	loading bool
+	err     error
+
+	form   *huh.Form
+	choice *string // heap-allocated; huh writes the chosen tag here ("" = latest)
+
+	done   bool // user picked a release
+	back   bool // user wants to return to the selector
+	readme bool // user wants to read the README
+	status string
+
+	width  int
+	height int
+}
+
+func newDetailModel(p catalog.Program, st *state.State, ctx context.Context) detailModel {
+	current, installed := st.Get(p.Name)
+	stats, _ := st.Stats(p.Name)
+	choice := ""
+	return detailModel{
+		program:   p,
+		current:   current,
+		installed: installed,
+		stats:     stats,
+		ctx:       ctx,
+		loading:   true,
+		choice:    &choice,
+	}
+}
+
+func (m detailModel) Init() tea.Cmd {
+	return fetchReleases(m.ctx, m.program)
+}
+
+func (m detailModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if ws, ok := msg.(tea.WindowSizeMsg); ok {
+		m.width, m.height = ws.Width, ws.Height
+		if m.form != nil {
+			m.form = m.form.WithWidth(ws.Width)
+		}
+		return m, nil
+	}
+
+	if msg, ok := msg.(releasesMsg); ok {
+		m.loading = false
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.form = huh.NewForm(
+			huh.NewGroup(
+				huh.NewSelect[string]().
+					Title(fmt.Sprintf("Install a release of %s", m.program.Name)).
+					Description("enter: install  •  o: repo page  •  R: readme  •  esc: back").
+					Options(m.releaseOptions(msg.releases)...).
+					Value(m.choice),
+			),
+		).WithTheme(huhTheme).WithHeight(releaseListSize + 5)
+		if m.width > 0 {
+			m.form = m.form.WithWidth(m.width)
+		}
+		return m, m.form.Init()
+	}
+
+	if msg, ok := msg.(repoOpenedMsg); ok {
+		m.status = msg.status()
+		return m, nil
+	}
+	if k, ok := msg.(tea.KeyMsg); ok {
+		switch k.String() {
+		case "o":
+			return m, openRepo(m.program)
+		case "R":
+			m.readme = true
+			return m, nil
+		}
+	}
+
+	if m.form == nil {
+		// Still loading or showing an error — only navigation keys apply.
+		if k, ok := msg.(tea.KeyMsg); ok {
+			switch k.String() {
+			case "ctrl+c":
+				return m, tea.Quit
+			case "esc", "q":
+				m.back = true
+			}
+		}
+		return m, nil
+	}
+
+	form, cmd := m.form.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.form = f
+	}
+	switch m.form.State {
+	case huh.StateCompleted:
+		m.done = true
+	case huh.StateAborted:
+		m.back = true
+	}
+	return m, cmd
+}
+
+func (m detailModel) releaseOptions(releases []gh.Release) []huh.Option[string] {
+	opts := []huh.Option[string]{huh.NewOption("latest (unpinned)", "")}
+	for _, r := range releases {
+		date := "—"
+		if !r.PublishedAt.IsZero() {
+			date = r.PublishedAt.Format("2006-01-02")
+		}
+		label := fmt.Sprintf("%-20s %s", r.Tag, date)
+		if r.Prerelease {
+			label += "  prerelease"
+		}
+		if m.installed && r.Tag == m.current.Tag {
+			label += "  (installed)"
+		}
+		opts = append(opts, huh.NewOption(label, r.Tag))
+	}
+	return opts
+}
+
+func (m detailModel) View() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n  %s\n\n", m.program.Name))
+	sb.WriteString(fmt.Sprintf("  repo:      %s\n", m.program.Repo))
+	sb.WriteString(fmt.Sprintf("  asset:     %s\n", m.program.AssetPattern))
+	switch {
+	case !m.installed:
+		sb.WriteString("  installed: —\n")
+	case m.current.Pinned:
+		sb.WriteString(fmt.Sprintf("  installed: %s (pinned)\n", m.current.Version))
+	default:
+		sb.WriteString(fmt.Sprintf("  installed: %s\n", m.current.Version))
+	}
+	if m.stats.Successes+m.stats.Failures > 0 {
+		sb.WriteString(fmt.Sprintf("  history:   %d ok, %d failed\n", m.stats.Successes, m.stats.Failures))
+		if m.stats.ConsecutiveFailures > 0 {
+			sb.WriteString(styleError.Render("  last error: "+m.stats.LastError) + "\n")
+		}
+	}
+	if m.status != "" {
+		sb.WriteString(m.status + "\n")
+	}
+	sb.WriteString("\n")
+
+	switch {
+	case m.loading:
+		sb.WriteString(stylePending.Render("  Fetching releases…") + "\n")
+	case m.err != nil:
+		sb.WriteString(styleError.Render(fmt.Sprintf("  %v", m.err)) + "\n\n  Press esc to go back.\n")
+	case m.form != nil:
+		sb.WriteString(m.form.View())
+	}
+	return sb.String()
+}
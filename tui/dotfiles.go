@@ -0,0 +1,193 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dsaleh/david-dotfiles/internal/dotfile"
+	"github.com/dsaleh/david-dotfiles/internal/trash"
+)
+
+// DotfileChoice is what was done with a locally modified dotfile.
+type DotfileChoice string
+
+const (
+	DotfileAccepted DotfileChoice = "accepted"
+	DotfileKept     DotfileChoice = "kept"
+	DotfileMerged   DotfileChoice = "merged"
+
+	// DotfileConflicted is a merge whose editor exited with conflict
+	// markers still in the file.
+	DotfileConflicted DotfileChoice = "merged with conflicts left"
+)
+
+// DotfileResult is the outcome for one file shown by DotfilesModel.
+type DotfileResult struct {
+	File   dotfile.File
+	Choice DotfileChoice
+	Err    error
+}
+
+// editedMsg reports that the merge editor exited.
+type editedMsg struct{ err error }
+
+// DotfilesModel walks through dotfiles whose target was changed locally,
+// showing the diff from the local file to the catalog's version in a
+// scrollable viewport. Per file, a accepts the catalog's version, k keeps the
+// local one and m writes both with conflict markers and opens $EDITOR on the
+// result. Replaced targets go to bin. q keeps the rest.
+type DotfilesModel struct {
+	files   []dotfile.File
+	bin     *trash.Batch
+	current int
+	results []DotfileResult
+
+	view viewport.Model
+	err  error
+
+	width  int
+	height int
+}
+
+// NewDotfiles creates the standalone diff screen for files, which should all
+// be Modified. bin may be nil to overwrite without keeping a copy.
+func NewDotfiles(files []dotfile.File, bin *trash.Batch) DotfilesModel {
+	m := DotfilesModel{files: files, bin: bin, view: viewport.New(80, 20)}
+	m.refresh()
+	return m
+}
+
+func (m DotfilesModel) Init() tea.Cmd { return nil }
+
+// Results returns what was done with each file, in order; files left when
+// the screen was quit are reported as kept.
+func (m DotfilesModel) Results() []DotfileResult {
+	out := m.results
+	for _, f := range m.files[min(m.current, len(m.files)):] {
+		out = append(out, DotfileResult{File: f, Choice: DotfileKept})
+	}
+	return out
+}
+
+// refresh loads the current file's diff into the viewport.
+func (m *DotfilesModel) refresh() {
+	if m.current >= len(m.files) {
+		return
+	}
+	f := m.files[m.current]
+	m.view.SetContent(renderDiff(dotfile.Diff(f.Local, f.Incoming)))
+	m.view.GotoTop()
+}
+
+// renderDiff renders diff as unified hunks, coloured like `git diff`.
+func renderDiff(diff []dotfile.Line) string {
+	var sb strings.Builder
+	for _, h := range dotfile.Hunks(diff, 3) {
+		sb.WriteString(stylePending.Render(h.Header()) + "\n")
+		for _, l := range h.Lines {
+			text := string(l.Op) + strings.TrimSuffix(l.Text, "\n")
+			switch l.Op {
+			case '-':
+				text = styleError.Render(text)
+			case '+':
+				text = styleDone.Render(text)
+			}
+			sb.WriteString(text + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// next records choice for the current file and moves on, quitting after
+// the last.
+func (m DotfilesModel) next(choice DotfileChoice, err error) (tea.Model, tea.Cmd) {
+	m.results = append(m.results, DotfileResult{File: m.files[m.current], Choice: choice, Err: err})
+	m.current++
+	if m.current >= len(m.files) {
+		return m, tea.Quit
+	}
+	m.refresh()
+	return m, nil
+}
+
+// editor returns the command line of the user's editor.
+func editor() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if args := strings.Fields(os.Getenv(env)); len(args) > 0 {
+			return args
+		}
+	}
+	return []string{"vi"}
+}
+
+func (m DotfilesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.view.Width = msg.Width - 4
+		m.view.Height = max(msg.Height-7, 3)
+		return m, nil
+
+	case editedMsg:
+		f := m.files[m.current]
+		if msg.err != nil {
+			return m.next(DotfileConflicted, fmt.Errorf("editor: %w", msg.err))
+		}
+		data, err := os.ReadFile(f.Target)
+		if err != nil {
+			return m.next(DotfileConflicted, err)
+		}
+		if dotfile.HasConflicts(data) {
+			return m.next(DotfileConflicted, nil)
+		}
+		return m.next(DotfileMerged, nil)
+
+	case tea.KeyMsg:
+		if m.current >= len(m.files) {
+			return m, tea.Quit
+		}
+		f := m.files[m.current]
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			return m, tea.Quit
+		case "a":
+			return m.next(DotfileAccepted, dotfile.Accept(f, m.bin))
+		case "k":
+			return m.next(DotfileKept, nil)
+		case "m":
+			if err := dotfile.Write(f, dotfile.Merge(f.Local, f.Incoming), m.bin); err != nil {
+				m.err = err
+				return m, nil
+			}
+			args := append(editor(), f.Target)
+			return m, tea.ExecProcess(exec.Command(args[0], args[1:]...), func(err error) tea.Msg {
+				return editedMsg{err}
+			})
+		}
+	}
+	var cmd tea.Cmd
+	m.view, cmd = m.view.Update(msg)
+	return m, cmd
+}
+
+func (m DotfilesModel) View() string {
+	if m.current >= len(m.files) {
+		return ""
+	}
+	f := m.files[m.current]
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n  %s  (%d of %d)\n", f.Target, m.current+1, len(m.files)))
+	sb.WriteString(stylePending.Render(fmt.Sprintf("  - local   + catalog (%s)", f.Source)) + "\n\n")
+	for _, line := range strings.Split(m.view.View(), "\n") {
+		sb.WriteString("  " + line + "\n")
+	}
+	if m.err != nil {
+		sb.WriteString(styleError.Render(fmt.Sprintf("  %v", m.err)) + "\n")
+	}
+	sb.WriteString("\n  a: accept catalog  •  k: keep local  •  m: merge in $EDITOR  •  ↑/↓: scroll  •  q: keep the rest\n")
+	return sb.String()
+}
@@ -0,0 +1,106 @@
+package tui
+
+import (
+	"context"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/muesli/termenv"
+)
+
+// Fixture renders screens of the TUI outside a terminal, deterministically:
+// at a fixed window size and time, without colour, and from the messages and
+// keys given rather than a live install. Golden-file tests compare its
+// output byte for byte, so a layout change shows up as a diff.
+//
+// It is only built into the tests: rendering with a Fixture switches the
+// package to plain output and its clock for the rest of the test binary.
+type Fixture struct {
+	Width, Height int
+	Now           time.Time // what "today" is for release ages
+}
+
+// setup fixes what views read besides their model.
+func (f Fixture) setup() {
+	lipgloss.SetColorProfile(termenv.Ascii)
+	now = func() time.Time { return f.Now }
+}
+
+func (f Fixture) size() tea.WindowSizeMsg {
+	return tea.WindowSizeMsg{Width: f.Width, Height: f.Height}
+}
+
+// Selector renders the program selector over programs after keys, with the
+// release info of res known, as if fetched while hovering.
+func (f Fixture) Selector(programs []catalog.Program, st *state.State, res []installer.Resolution, keys ...string) string {
+	f.setup()
+	info := newReleaseInfo(context.Background(), installer.Options{State: st})
+	for _, r := range res {
+		info.store(releaseInfoMsg{r})
+	}
+	var m tea.Model = newSelectorModel(programs, st, info, nil)
+	m.Init()
+	m, _ = m.Update(f.size())
+	for _, k := range keys {
+		m, _ = m.Update(keyMsg(k))
+	}
+	return m.View()
+}
+
+// Progress renders the progress screen of a run of programs that has sent
+// msgs, in order. A run whose programs are all in a terminal state is shown
+// finished, with its summary.
+func (f Fixture) Progress(programs []string, msgs []installer.ProgressMsg) string {
+	f.setup()
+	m := newProgressModel(programs, nil, nil, nil, nil, nil)
+	m.setHeight(f.Height)
+	for _, msg := range msgs {
+		m.applyMsg(msg)
+	}
+	m.done = m.allTerminal()
+	return m.View()
+}
+
+// Picker renders the bin picker opened on the install dir of req, a
+// StateAwaitingBinSelection message, after keys.
+func (f Fixture) Picker(req installer.ProgressMsg, keys ...string) string {
+	f.setup()
+	var m tea.Model = newPickerModel(req.Program, req.InstallDir, req.Preselect)
+	m, _ = m.Update(f.size())
+	for _, k := range keys {
+		m, _ = m.Update(keyMsg(k))
+	}
+	return m.View()
+}
+
+// keyMsg returns the key press named k as tea.KeyMsg.String reports it, e.g.
+// "down", "enter", " " or "ctrl+a"; anything else is typed as runes.
+func keyMsg(k string) tea.KeyMsg {
+	for t, name := range keyNames {
+		if name == k {
+			return tea.KeyMsg{Type: t}
+		}
+	}
+	if k == " " {
+		return tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+}
+
+var keyNames = map[tea.KeyType]string{
+	tea.KeyUp:        "up",
+	tea.KeyDown:      "down",
+	tea.KeyLeft:      "left",
+	tea.KeyRight:     "right",
+	tea.KeyEnter:     "enter",
+	tea.KeyEsc:       "esc",
+	tea.KeyTab:       "tab",
+	tea.KeyBackspace: "backspace",
+	tea.KeyPgUp:      "pgup",
+	tea.KeyPgDown:    "pgdown",
+	tea.KeyCtrlA:     "ctrl+a",
+}
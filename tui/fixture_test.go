@@ -0,0 +1,100 @@
+package tui_test
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/tui"
+)
+
+// Run `go test ./tui -update` after an intended layout change and review
+// the diff of testdata/.
+var update = flag.Bool("update", false, "rewrite the golden files with the current output")
+
+var fixture = tui.Fixture{Width: 80, Height: 24, Now: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)}
+
+// golden compares got with testdata/<name>.golden.
+func golden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v; run go test ./tui -update to create it", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from %s:\n--- got ---\n%s\n--- want ---\n%s", name, path, got, want)
+	}
+}
+
+func newState(t *testing.T) *state.State {
+	t.Helper()
+	dir, _ := os.MkdirTemp("", "tui-state-*")
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	st, err := state.Load(filepath.Join(dir, "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+var programs = []catalog.Program{
+	{Name: "bat", Repo: "sharkdp/bat"},
+	{Name: "fzf", Repo: "junegunn/fzf"},
+	{Name: "ripgrep", Repo: "BurntSushi/ripgrep"},
+}
+
+func TestSelector(t *testing.T) {
+	st := newState(t)
+	res := []installer.Resolution{{
+		Program:  programs[1],
+		Tag:      "v0.61.0",
+		Version:  "0.61.0",
+		Released: time.Date(2026, 9, 30, 0, 0, 0, 0, time.UTC),
+		Size:     1600 << 10,
+	}}
+	golden(t, "selector", fixture.Selector(programs, st, res, "down", " ", "p"))
+}
+
+func TestProgress_running(t *testing.T) {
+	start := fixture.Now
+	golden(t, "progress_running", fixture.Progress([]string{"bat", "fzf", "ripgrep"}, []installer.ProgressMsg{
+		{Program: "bat", State: installer.StateDone, Version: "0.25.0"},
+		{Program: "fzf", State: installer.StateDownloading, Version: "0.61.0", Received: 1 << 20, Size: 4 << 20, Time: start},
+		{Program: "fzf", State: installer.StateDownloading, Version: "0.61.0", Received: 2 << 20, Size: 4 << 20, Time: start.Add(time.Second)},
+	}))
+}
+
+func TestProgress_done(t *testing.T) {
+	golden(t, "progress_done", fixture.Progress([]string{"bat", "fzf", "ripgrep"}, []installer.ProgressMsg{
+		{Program: "bat", State: installer.StateDone, Version: "0.25.0"},
+		{Program: "fzf", State: installer.StateSkipped, Version: "0.61.0", Skip: installer.SkipUpToDate},
+		{Program: "ripgrep", State: installer.StateError, Err: errors.New("download: 404 not found")},
+	}))
+}
+
+func TestPicker(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "tui-picker-*")
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "tool-1.0")
+	os.MkdirAll(filepath.Join(root, "bin"), 0755)
+	os.MkdirAll(filepath.Join(root, "share", "man"), 0755)
+	os.WriteFile(filepath.Join(root, "bin", "tool"), []byte("#!/bin/sh\n"), 0755)
+	os.WriteFile(filepath.Join(root, "README.md"), nil, 0644)
+
+	req := installer.ProgressMsg{Program: "tool", State: installer.StateAwaitingBinSelection, InstallDir: root}
+	golden(t, "picker", fixture.Picker(req))
+	golden(t, "picker_filtered", fixture.Picker(req, "enter", "t"))
+}
@@ -0,0 +1,124 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dsaleh/david-dotfiles/internal/inventory"
+)
+
+var styleHeader = lipgloss.NewStyle().Bold(true)
+
+// InventoryModel renders a program × machine matrix of installed versions.
+// Rows where machines disagree are highlighted; cells that differ from the
+// row's most common version are shown in red.
+type InventoryModel struct {
+	machines  []inventory.Machine
+	rows      []inventory.Row
+	driftOnly bool
+	offset    int
+	height    int
+}
+
+// NewInventory creates the standalone inventory dashboard.
+func NewInventory(machines []inventory.Machine) InventoryModel {
+	return InventoryModel{machines: machines, rows: inventory.Matrix(machines)}
+}
+
+func (m InventoryModel) Init() tea.Cmd { return nil }
+
+func (m InventoryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			return m, tea.Quit
+		case "up", "k":
+			if m.offset > 0 {
+				m.offset--
+			}
+		case "down", "j":
+			if m.offset < len(m.visibleRows())-1 {
+				m.offset++
+			}
+		case "d":
+			m.driftOnly = !m.driftOnly
+			m.offset = 0
+		}
+	}
+	return m, nil
+}
+
+func (m InventoryModel) visibleRows() []inventory.Row {
+	if !m.driftOnly {
+		return m.rows
+	}
+	var out []inventory.Row
+	for _, r := range m.rows {
+		if r.Drift {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (m InventoryModel) View() string {
+	nameWidth := len("program")
+	for _, r := range m.rows {
+		nameWidth = max(nameWidth, len(r.Program))
+	}
+	colWidths := make([]int, len(m.machines))
+	for i, mc := range m.machines {
+		colWidths[i] = len(mc.Name)
+		for _, r := range m.rows {
+			colWidths[i] = max(colWidths[i], len(r.Versions[i]))
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n  Inventory\n\n")
+	header := fmt.Sprintf("  %-*s", nameWidth+2, "program")
+	for i, mc := range m.machines {
+		header += fmt.Sprintf("%-*s", colWidths[i]+2, mc.Name)
+	}
+	sb.WriteString(styleHeader.Render(header) + "\n")
+
+	rows := m.visibleRows()
+	limit := len(rows)
+	if m.height > 0 {
+		limit = min(limit, m.offset+max(m.height-8, 1))
+	}
+	drifting := 0
+	for _, r := range m.rows {
+		if r.Drift {
+			drifting++
+		}
+	}
+	for _, r := range rows[min(m.offset, len(rows)):limit] {
+		name := fmt.Sprintf("  %-*s", nameWidth+2, r.Program)
+		if r.Drift {
+			name = styleSkipped.Render(name)
+		}
+		line := name
+		ref := r.Majority()
+		for i, v := range r.Versions {
+			cell := fmt.Sprintf("%-*s", colWidths[i]+2, v)
+			switch {
+			case v == "":
+				cell = stylePending.Render(fmt.Sprintf("%-*s", colWidths[i]+2, "—"))
+			case v != ref:
+				cell = styleError.Render(cell)
+			}
+			line += cell
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("\n  %d programs, %d drifting across %d machines\n", len(m.rows), drifting, len(m.machines)))
+	sb.WriteString("\n  ↑/↓: scroll  •  d: toggle drift only  •  q: quit\n")
+	return sb.String()
+}
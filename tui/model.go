@@ -2,13 +2,17 @@ package tui
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dsaleh/david-dotfiles/internal/catalog"
 	"github.com/dsaleh/david-dotfiles/internal/installer"
-	"github.com/dsaleh/david-dotfiles/internal/system"
+	"github.com/dsaleh/david-dotfiles/internal/notify"
+	"github.com/dsaleh/david-dotfiles/internal/report"
 )
 
 var styleRed = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
@@ -20,6 +24,9 @@ const (
 	screenPreflight
 	screenProgress
 	screenBinPicker
+	screenDetail
+	screenPlan
+	screenReadme
 )
 
 // RootModel is the top-level bubbletea model.
@@ -29,14 +36,24 @@ type RootModel struct {
 	preflight preflightModel
 	progress  progressModel
 	picker    pickerModel
+	detail    detailModel
+	plan      planModel
+	readme    readmeModel
 
 	// activePicker is set while the picker screen is open for a program.
 	// Its BinCh is used to send the result back to the installer goroutine.
 	activePicker *installer.ProgressMsg
 
 	programs     []catalog.Program
+	resume       []catalog.Program // installed straight away, skipping the selector
+	catalogPath  string
+	opts         installer.Options
+	notify       *notify.Server
+	profile      *renderProfile
+	reload       func() ([]catalog.Program, error) // see WithReload
+	readmeFrom   screen                            // the screen the README pager returns to
+	info         *releaseInfo
 	ctx          context.Context
-	verbose      bool
 	windowWidth  int
 	windowHeight int
 }
@@ -47,26 +64,109 @@ type preflightModel struct {
 
 func (m preflightModel) View() string {
 	var sb strings.Builder
-	sb.WriteString(styleRed.Render("\n  Missing required packages:\n\n"))
+	sb.WriteString(styleRed.Render("\n  Missing requirements:\n\n"))
 	for _, pkg := range m.missing {
 		sb.WriteString(styleRed.Render("    • " + pkg + "\n"))
 	}
-	sb.WriteString("\n  Install the missing packages and re-run.\n\n  Press any key to exit.\n")
+	sb.WriteString("\n  Install what is missing and re-run.\n\n  Press any key to exit.\n")
 	return sb.String()
 }
 
 // New creates the root TUI model.
-func New(programs []catalog.Program, ctx context.Context, verbose bool) RootModel {
+// catalogPath is the file programs were loaded from; suggested asset_pattern
+// fixes are written back to it, or to a program's Source when several
+// catalogs were loaded.
+// opts is passed to installer.Run for every install started from the TUI.
+func New(programs []catalog.Program, catalogPath string, ctx context.Context, opts installer.Options) RootModel {
+	info := newReleaseInfo(ctx, opts)
 	return RootModel{
-		screen:   screenSelector,
-		selector: newSelectorModel(programs),
-		programs: programs,
-		ctx:      ctx,
-		verbose:  verbose,
+		screen:      screenSelector,
+		selector:    newSelectorModel(programs, opts.State, info, nil),
+		programs:    programs,
+		catalogPath: catalogPath,
+		opts:        opts,
+		info:        info,
+		ctx:         ctx,
 	}
 }
 
+// openReadme shows p's README in the pager, returning to the current screen
+// when it is closed.
+func (m RootModel) openReadme(p catalog.Program) (tea.Model, tea.Cmd) {
+	m.readme = newReadmeModel(p, m.ctx, m.windowWidth, m.windowHeight)
+	m.readmeFrom = m.screen
+	m.screen = screenReadme
+	return m, m.readme.Init()
+}
+
+// resumeMsg starts the install of RootModel.resume.
+type resumeMsg struct{}
+
+// WithResume makes the TUI skip the selector and install programs right away,
+// to finish a run that was interrupted.
+func (m RootModel) WithResume(programs []catalog.Program) RootModel {
+	m.resume = programs
+	return m
+}
+
+// WithNotifier streams the progress of installs started from the TUI to the
+// listeners of srv.
+func (m RootModel) WithNotifier(srv *notify.Server) RootModel {
+	m.notify = srv
+	return m
+}
+
+// WithReload lets the selector reload the catalog from disk with load, e.g.
+// after it was edited in another terminal, keeping the programs marked in
+// the list.
+func (m RootModel) WithReload(load func() ([]catalog.Program, error)) RootModel {
+	m.reload = load
+	return m
+}
+
+// catalogMsg carries the result of reloading the catalog.
+type catalogMsg struct {
+	programs []catalog.Program
+	err      error
+}
+
+// reloadCatalog runs m.reload off the UI goroutine.
+func (m RootModel) reloadCatalog() tea.Cmd {
+	load := m.reload
+	return func() tea.Msg {
+		programs, err := load()
+		return catalogMsg{programs: programs, err: err}
+	}
+}
+
+// WithRenderProfile logs the duration and size of every frame to w, for
+// diagnosing slow or excessive redraws.
+func (m RootModel) WithRenderProfile(w io.Writer) RootModel {
+	m.profile = newRenderProfile(w)
+	return m
+}
+
+// RenderSummary writes the frame totals to the WithRenderProfile writer, if
+// any.
+func (m RootModel) RenderSummary() {
+	if m.profile != nil {
+		m.profile.summary()
+	}
+}
+
+// Report returns the timeline of the install run started from the TUI, and
+// false if none was started.
+func (m RootModel) Report() (report.Report, bool) {
+	if m.progress.rec == nil {
+		return report.Report{}, false
+	}
+	return m.progress.rec.Report(), true
+}
+
 func (m RootModel) Init() tea.Cmd {
+	if len(m.resume) > 0 {
+		return func() tea.Msg { return resumeMsg{} }
+	}
 	return m.selector.Init()
 }
 
@@ -74,6 +174,9 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Track window size globally.
 	if ws, ok := msg.(tea.WindowSizeMsg); ok {
 		m.windowWidth, m.windowHeight = ws.Width, ws.Height
+		// The progress list is sized even while the picker is on screen, so
+		// it is laid out for the current window when the run returns to it.
+		m.progress.setHeight(ws.Height)
 		// Forward to active sub-model.
 		switch m.screen {
 		case screenBinPicker:
@@ -84,10 +187,48 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			next, cmd := m.selector.Update(msg)
 			m.selector = next.(selectorModel)
 			return m, cmd
+		case screenDetail:
+			next, cmd := m.detail.Update(msg)
+			m.detail = next.(detailModel)
+			return m, cmd
+		case screenReadme:
+			next, cmd := m.readme.Update(msg)
+			m.readme = next.(readmeModel)
+			return m, cmd
 		}
 		return m, nil
 	}
 
+	// Release lookups finish on whatever screen is up; the selector shows
+	// them when it is back.
+	if msg, ok := msg.(releaseInfoMsg); ok {
+		m.info.store(msg)
+		return m, nil
+	}
+
+	// A reload finishing after the selector was left still updates the list
+	// it goes back to.
+	if msg, ok := msg.(catalogMsg); ok {
+		if msg.err != nil {
+			m.selector.status = styleError.Render(fmt.Sprintf("  reload failed: %v", msg.err))
+			return m, nil
+		}
+		m.programs = msg.programs
+		m.selector = m.selector.reloaded(msg.programs, m.opts.State)
+		m.selector.setHeight(m.windowHeight)
+		if m.screen != screenSelector {
+			return m, nil
+		}
+		return m, m.selector.Init()
+	}
+
+	if _, ok := msg.(resumeMsg); ok {
+		// The resumed programs are a subset; never uninstall the rest.
+		opts := m.opts
+		opts.Apply = false
+		return m.startInstall(m.resume, opts)
+	}
+
 	switch m.screen {
 	// ── selector ──────────────────────────────────────────────────────────────
 	case screenSelector:
@@ -96,37 +237,110 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.selector.quit {
 			return m, tea.Quit
 		}
+		if m.selector.reload {
+			m.selector.reload = false
+			if m.reload == nil {
+				m.selector.status = styleSkipped.Render("  the built-in catalog cannot be reloaded")
+				return m, cmd
+			}
+			m.selector.status = stylePending.Render("  reloading the catalog…")
+			return m, tea.Batch(cmd, m.reloadCatalog())
+		}
+		if p := m.selector.uninstall; p != nil {
+			m.selector.uninstall = nil
+			if _, ok := m.opts.State.Get(p.Name); !ok {
+				m.selector.status = styleSkipped.Render("  " + p.Name + " is not installed")
+				return m, cmd
+			}
+			// Removals are always planned first, so nothing goes unseen.
+			opts := m.opts
+			opts.Apply, opts.Remove = false, []string{p.Name}
+			m.plan = newPlanModel(nil, m.ctx, opts)
+			m.screen = screenPlan
+			return m, computePlan(m.ctx, nil, opts)
+		}
+		if p := m.selector.readme; p != nil {
+			m.selector.readme = nil
+			return m.openReadme(*p)
+		}
+		if m.selector.detail != nil {
+			m.detail = newDetailModel(*m.selector.detail, m.opts.State, m.ctx)
+			m.detail.width, m.detail.height = m.windowWidth, m.windowHeight
+			m.selector.detail = nil
+			m.screen = screenDetail
+			return m, m.detail.Init()
+		}
 		if m.selector.done {
 			selected := m.selector.selectedPrograms()
 			if len(selected) == 0 {
 				return m, tea.Quit
 			}
-			// Pre-flight check.
-			var allPackages []string
-			seen := map[string]bool{}
-			for _, p := range selected {
-				for _, pkg := range p.Packages {
-					if !seen[pkg] {
-						seen[pkg] = true
-						allPackages = append(allPackages, pkg)
-					}
-				}
+			if m.opts.Apply {
+				// Apply runs can uninstall things — show the plan first.
+				m.plan = newPlanModel(selected, m.ctx, m.opts)
+				m.screen = screenPlan
+				return m, computePlan(m.ctx, selected, m.opts)
 			}
-			if missing := system.CheckPackages(allPackages); len(missing) > 0 {
-				m.screen = screenPreflight
-				m.preflight = preflightModel{missing: missing}
-				return m, nil
+			return m.startInstall(selected, m.opts)
+		}
+		return m, cmd
+
+	// ── plan ──────────────────────────────────────────────────────────────────
+	case screenPlan:
+		next, cmd := m.plan.Update(msg)
+		m.plan = next.(planModel)
+		if m.plan.back {
+			// The selector form already completed; start a fresh one.
+			m.selector = newSelectorModel(m.programs, m.opts.State, m.info, nil)
+			m.selector.setHeight(m.windowHeight)
+			m.screen = screenSelector
+			return m, m.selector.Init()
+		}
+		if m.plan.done {
+			return m.startInstall(m.plan.selected, m.plan.opts)
+		}
+		return m, cmd
+
+	// ── detail ────────────────────────────────────────────────────────────────
+	case screenDetail:
+		next, cmd := m.detail.Update(msg)
+		m.detail = next.(detailModel)
+		if m.detail.back {
+			m.screen = screenSelector
+			// Initializing again is harmless, and needed if the catalog
+			// was reloaded meanwhile.
+			return m, m.selector.Init()
+		}
+		if m.detail.readme {
+			m.detail.readme = false
+			return m.openReadme(m.detail.program)
+		}
+		if m.detail.done {
+			p := m.detail.program
+			if tag := *m.detail.choice; tag != "" {
+				p.Version = tag
+			} else {
+				// "latest" was chosen — drop any pin so the resolver asks GitHub.
+				m.opts.State.SetPinned(p.Name, false)
 			}
-			// Launch installer.
-			names := make([]string, len(selected))
-			for i, p := range selected {
-				names[i] = p.Name
+			// A single-program install from the detail view is never an apply run.
+			opts := m.opts
+			opts.Apply = false
+			return m.startInstall([]catalog.Program{p}, opts)
+		}
+		return m, cmd
+
+	// ── readme ────────────────────────────────────────────────────────────────
+	case screenReadme:
+		next, cmd := m.readme.Update(msg)
+		m.readme = next.(readmeModel)
+		if m.readme.back {
+			// The detail view is left as it was; its form needs no Init.
+			m.screen = m.readmeFrom
+			if m.screen == screenSelector {
+				return m, m.selector.Init()
 			}
-			ch := installer.Run(m.ctx, selected, m.verbose)
-			m.progress = newProgressModel(names, ch)
-			m.screen = screenProgress
-			// The root model drives channel reading from here on.
-			return m, waitForProgress(m.progress.ch)
+			return m, nil
 		}
 		return m, cmd
 
@@ -138,10 +352,19 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// ── progress ──────────────────────────────────────────────────────────────
 	case screenProgress:
+		if m.progress.triageEdit != nil {
+			switch msg.(type) {
+			case progressBatch, progressClosed:
+			default:
+				return m, m.progress.updateTriageEdit(msg)
+			}
+		}
 		switch msg := msg.(type) {
-		case installer.ProgressMsg:
-			// Apply the message to progress state.
-			m.progress.applyMsg(msg)
+		case progressBatch:
+			// Apply the messages to progress state.
+			for _, pm := range msg {
+				m.progress.applyMsg(pm)
+			}
 
 			// If there is now a picker to handle and none is currently active,
 			// open it immediately.
@@ -156,9 +379,9 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 			// Keep reading from the channel.
-			return m, waitForProgress(m.progress.ch)
+			return m, m.progress.sub.next()
 
-		case nil:
+		case progressClosed:
 			// Channel closed — all goroutines finished.
 			if m.progress.allTerminal() {
 				m.progress.done = true
@@ -166,9 +389,22 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 
 		case tea.KeyMsg:
+			if m.progress.scrollKey(msg.String()) || m.progress.confirmKey(msg.String()) {
+				return m, nil
+			}
+			if cmd, ok := m.progress.triageKey(msg.String()); ok {
+				return m, cmd
+			}
 			if m.progress.done {
+				if msg.String() == "f" && !m.progress.fixed && len(m.progress.fixes()) > 0 {
+					m.progress.applyFixes()
+					return m, nil
+				}
 				return m, tea.Quit
 			}
+			if msg.String() == "p" {
+				m.progress.togglePause()
+			}
 		}
 
 	// ── bin picker ────────────────────────────────────────────────────────────
@@ -200,7 +436,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.screen = screenProgress
 			// Resume waiting for progress only if not all done yet.
 			if !m.progress.allTerminal() {
-				return m, waitForProgress(m.progress.ch)
+				return m, m.progress.sub.next()
 			}
 			m.progress.done = true
 			return m, nil
@@ -212,6 +448,37 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// startInstall runs the preflight check for selected and, if it passes,
+// launches the installer with opts and switches to the progress screen.
+func (m RootModel) startInstall(selected []catalog.Program, opts installer.Options) (tea.Model, tea.Cmd) {
+	if missing := installer.Preflight(selected); len(missing) > 0 {
+		m.screen = screenPreflight
+		m.preflight = preflightModel{missing: missing}
+		return m, nil
+	}
+
+	selected = installer.Prioritize(selected)
+	names := make([]string, len(selected))
+	catalogs := make(map[string]string, len(selected))
+	specs := make(map[string]catalog.Program, len(selected))
+	for i, p := range selected {
+		names[i] = p.Name
+		specs[p.Name] = p
+		catalogs[p.Name] = m.catalogPath
+		if p.Source != "" {
+			catalogs[p.Name] = p.Source
+		}
+	}
+	opts.Pauser = installer.NewPauser()
+	opts.ConfirmBusy = true
+	ch := installer.Run(m.ctx, selected, opts)
+	m.progress = newProgressModel(names, ch, opts.Pauser, catalogs, specs, m.notify)
+	m.progress.setHeight(m.windowHeight)
+	m.screen = screenProgress
+	// The root model drives channel reading from here on.
+	return m, m.progress.sub.next()
+}
+
 // openNextPicker dequeues the next picker request, creates the picker model,
 // switches to screenBinPicker, and returns the picker's Init command.
 // It does NOT return a tea.Cmd itself — callers use `return m, m.openNextPicker()`.
@@ -220,16 +487,10 @@ func (m *RootModel) openNextPicker() tea.Cmd {
 	m.progress.pickerQueue = m.progress.pickerQueue[1:]
 	m.activePicker = &req
 
-	picker := newPickerModel(req.Program, req.InstallDir)
+	picker := newPickerModel(req.Program, req.InstallDir, req.Preselect)
 	// Seed window size if we already know it.
 	if m.windowWidth > 0 {
-		picker.width = m.windowWidth
-		picker.height = m.windowHeight
-		if picker.browseForm != nil {
-			picker.browseForm = picker.browseForm.
-				WithWidth(m.windowWidth).
-				WithHeight(m.windowHeight)
-		}
+		picker.setSize(m.windowWidth, m.windowHeight)
 	}
 	m.picker = picker
 	m.screen = screenBinPicker
@@ -237,6 +498,16 @@ func (m *RootModel) openNextPicker() tea.Cmd {
 }
 
 func (m RootModel) View() string {
+	if m.profile == nil {
+		return m.view()
+	}
+	start := time.Now()
+	out := m.view()
+	m.profile.frame(m.screen, time.Since(start), out)
+	return out
+}
+
+func (m RootModel) view() string {
 	switch m.screen {
 	case screenSelector:
 		return m.selector.View()
@@ -246,6 +517,12 @@ func (m RootModel) View() string {
 		return m.progress.View()
 	case screenBinPicker:
 		return m.picker.View()
+	case screenDetail:
+		return m.detail.View()
+	case screenPlan:
+		return m.plan.View()
+	case screenReadme:
+		return m.readme.View()
 	}
 	return ""
 }
@@ -0,0 +1,71 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	gh "github.com/dsaleh/david-dotfiles/internal/github"
+)
+
+// versionEdit is the plan screen's form for overriding the version one
+// selected program installs, without editing the catalog.
+type versionEdit struct {
+	form    *huh.Form
+	program *string // heap-allocated; huh writes the chosen program here
+	tag     *string // and the typed version here
+	err     error   // why the previous attempt was refused
+}
+
+// newVersionEdit builds the form over selected, preset to program and tag
+// (empty for a fresh edit).
+func newVersionEdit(selected []catalog.Program, program, tag string, err error) *versionEdit {
+	e := &versionEdit{program: &program, tag: &tag, err: err}
+	opts := make([]huh.Option[string], len(selected))
+	for i, p := range selected {
+		label := p.Name
+		if p.Version != "" {
+			label += "  (" + p.Version + ")"
+		}
+		opts[i] = huh.NewOption(label, p.Name)
+	}
+	e.form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Program").
+				Options(opts...).
+				Value(e.program),
+			huh.NewInput().
+				Title("Version").
+				Description("A release tag such as v1.2.3 (the v is optional); empty to undo an override.").
+				Value(e.tag),
+		),
+	).WithTheme(huhTheme)
+	return e
+}
+
+// tagMsg carries the result of checking an overridden version.
+type tagMsg struct {
+	program, tag string // tag is the one found, e.g. with the v added
+	input        string // what was typed
+	err          error
+}
+
+// checkTag looks the version typed for p up among its repo's tags. An empty
+// version needs no lookup: it undoes the override.
+func checkTag(ctx context.Context, p catalog.Program, input string) tea.Cmd {
+	input = strings.TrimSpace(input)
+	return func() tea.Msg {
+		if input == "" {
+			return tagMsg{program: p.Name}
+		}
+		tag, err := gh.NewClient(p.GitHubAPI()).FindTag(ctx, p.Repo, input)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", p.Name, err)
+		}
+		return tagMsg{program: p.Name, tag: tag, input: input, err: err}
+	}
+}
@@ -27,25 +27,25 @@ const (
 // ─── pickerModel ─────────────────────────────────────────────────────────────
 
 // pickerModel lets the user:
-//  1. Navigate the extracted dir and pick the binary file  (phaseBrowse)
-//  2. Type / edit the symlink name                         (phaseNaming)
+//  1. Navigate the extracted dir and pick one or more files (phaseBrowse)
+//  2. Type / edit their symlink names in one form           (phaseNaming)
 //  3. Confirm whether to add another binary                (phaseConfirm)
 type pickerModel struct {
 	programName string
 	installDir  string // root of extracted archive
 
-	browseForm   *huh.Form
-	browseResult *string // heap-allocated; huh writes here via pointer
+	browser fileBrowser
 
-	namingForm   *huh.Form
-	namingResult *string // heap-allocated; huh writes here via pointer
+	namingForm    *huh.Form
+	namingResults []*string // one per selectedSrcs; heap-allocated, huh writes via pointer
 
 	confirmForm *huh.Form
 	addAnother  *bool // heap-allocated; huh writes here via pointer
 
-	phase       pickerPhase
-	selectedSrc string        // absolute path chosen in phaseBrowse
-	added       []catalog.Bin // bins confirmed so far
+	phase        pickerPhase
+	selectedSrcs []string          // absolute paths chosen in phaseBrowse
+	names        map[string]string // absolute src → link name picked for an earlier release
+	added        []catalog.Bin     // bins confirmed so far
 
 	done bool
 	quit bool
@@ -54,41 +54,45 @@ type pickerModel struct {
 	height int
 }
 
-func newPickerModel(programName, installDir string) pickerModel {
+// newPickerModel opens the picker on installDir. The files of preselect,
+// picked for an earlier release with the same layout, start out marked and
+// keep their link names, so accepting them again is a run of enters.
+func newPickerModel(programName, installDir string, preselect []catalog.Bin) pickerModel {
 	m := pickerModel{
 		programName: programName,
 		installDir:  installDir,
 		phase:       phaseBrowse,
+		names:       map[string]string{},
 	}
-	browseResult := ""
-	m.browseResult = &browseResult
-	m.browseForm = huh.NewForm(
-		huh.NewGroup(
-			huh.NewFilePicker().
-				Title(fmt.Sprintf("Select binary for %q", programName)).
-				Description("Navigate to the binary inside the extracted archive.\nPress esc to finish without adding more.").
-				CurrentDirectory(installDir).
-				ShowHidden(false).
-				FileAllowed(true).
-				DirAllowed(false).
-				Picking(true).
-				Value(m.browseResult),
-		),
-	).WithTheme(huhTheme)
+	description := "Navigate to the binary inside the extracted archive.\nPress esc to finish without adding more."
+	if len(preselect) > 0 {
+		description = "The files picked for the previous release are marked; enter links them again.\nPress esc to finish without adding more."
+	}
+	m.browser = newFileBrowser(installDir, fmt.Sprintf("Select binary for %q", programName), description)
+	srcs := make([]string, len(preselect))
+	for i, b := range preselect {
+		srcs[i] = b.Src
+		m.names[b.Src] = b.Dst
+	}
+	m.browser.mark(srcs)
 	return m
 }
 
 func (m pickerModel) Init() tea.Cmd {
-	return m.browseForm.Init()
+	return nil
+}
+
+// setSize seeds the window size before the first WindowSizeMsg arrives.
+func (m *pickerModel) setSize(width, height int) {
+	m.width, m.height = width, height
+	m.browser.height = height
 }
 
 func (m pickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Always track window size and resize the active form immediately.
 	if ws, ok := msg.(tea.WindowSizeMsg); ok {
 		m.width, m.height = ws.Width, ws.Height
-		if m.browseForm != nil {
-			m.browseForm = m.browseForm.WithWidth(ws.Width).WithHeight(ws.Height)
-		}
+		m.browser.height = ws.Height
 		if m.namingForm != nil {
 			m.namingForm = m.namingForm.WithWidth(ws.Width).WithHeight(ws.Height)
 		}
@@ -116,39 +120,40 @@ func (m pickerModel) updateBrowse(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Quit
 	}
 
-	form, cmd := m.browseForm.Update(msg)
-	if f, ok := form.(*huh.Form); ok {
-		m.browseForm = f
-	}
-
-	switch m.browseForm.State {
-	case huh.StateCompleted:
-		// m.browseResult was written by huh (full path of selected file)
-		m.selectedSrc = *m.browseResult
-
-		// Build naming form with the selected file's basename as default.
-		namingResult := filepath.Base(*m.browseResult)
-		m.namingResult = &namingResult
-		m.namingForm = huh.NewForm(
-			huh.NewGroup(
-				huh.NewInput().
-					Title("Symlink name for: " + filepath.Base(*m.browseResult)).
-					Description("Name that will appear in ~/.local/bin/").
-					Placeholder(namingResult).
-					Value(m.namingResult).
-					Validate(func(s string) error {
-						if strings.TrimSpace(s) == "" {
-							return fmt.Errorf("name cannot be empty")
-						}
-						return nil
-					}),
-			),
-		).WithTheme(huhTheme)
+	var cmd tea.Cmd
+	m.browser, cmd = m.browser.Update(msg)
+
+	switch m.browser.state {
+	case browserPicked:
+		m.selectedSrcs = m.browser.selected
+
+		// One naming form for the whole batch, each name defaulting to the
+		// one picked last release or else the file's basename, so accepting
+		// the defaults is a run of enters.
+		m.namingResults = make([]*string, len(m.selectedSrcs))
+		fields := make([]huh.Field, len(m.selectedSrcs))
+		for i, src := range m.selectedSrcs {
+			name := filepath.Base(src)
+			if prev, ok := m.names[src]; ok {
+				name = prev
+			}
+			m.namingResults[i] = &name
+			fields[i] = huh.NewInput().
+				Title("Symlink name for: " + m.browser.rel(src)).
+				Description("Name that will appear in ~/.local/bin/").
+				Placeholder(name).
+				Value(m.namingResults[i]).
+				Validate(m.validateName(i))
+		}
+		m.namingForm = huh.NewForm(huh.NewGroup(fields...)).WithTheme(huhTheme)
+		if m.height > 0 {
+			m.namingForm = m.namingForm.WithWidth(m.width).WithHeight(m.height)
+		}
 		m.phase = phaseNaming
 		return m, m.namingForm.Init()
 
-	case huh.StateAborted:
-		// esc/q from file picker → done (no more bins to add)
+	case browserAborted:
+		// esc on an empty filter → done (no more bins to add)
 		m.done = true
 		return m, nil
 	}
@@ -170,16 +175,16 @@ func (m pickerModel) updateNaming(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch m.namingForm.State {
 	case huh.StateCompleted:
-		// m.namingResult was written by huh via the pointer
-		name := ""
-		if m.namingResult != nil {
-			name = strings.TrimSpace(*m.namingResult)
+		// m.namingResults were written by huh via the pointers
+		for i, src := range m.selectedSrcs {
+			name := strings.TrimSpace(*m.namingResults[i])
+			if name == "" {
+				name = filepath.Base(src)
+			}
+			m.added = append(m.added, catalog.Bin{Src: src, Dst: name})
 		}
-		if name == "" {
-			name = filepath.Base(m.selectedSrc)
-		}
-		m.added = append(m.added, catalog.Bin{Src: m.selectedSrc, Dst: name})
 		m.namingForm = nil
+		m.browser.clearMarks()
 
 		// Ask "add another binary?"
 		addAnother := false
@@ -199,29 +204,38 @@ func (m pickerModel) updateNaming(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case huh.StateAborted:
 		// esc/q → back to browse without adding
 		m.namingForm = nil
-		// Rebuild browse form for another pick attempt.
-		browseResult := ""
-		m.browseResult = &browseResult
-		m.browseForm = huh.NewForm(
-			huh.NewGroup(
-				huh.NewFilePicker().
-					Title(fmt.Sprintf("Select binary for %q", m.programName)).
-					Description("Navigate to the binary inside the extracted archive.\nPress esc to finish without adding more.").
-					CurrentDirectory(m.installDir).
-					ShowHidden(false).
-					FileAllowed(true).
-					DirAllowed(false).
-					Picking(true).
-					Value(m.browseResult),
-			),
-		).WithTheme(huhTheme).WithWidth(m.width).WithHeight(m.height)
+		// Back to the same directory for another pick attempt.
+		m.browser.state = browserBrowsing
 		m.phase = phaseBrowse
-		return m, m.browseForm.Init()
+		return m, nil
 	}
 
 	return m, cmd
 }
 
+// validateName returns the validator for the i-th name of the batch: names
+// must be non-empty and unique across the batch and the bins already added.
+func (m pickerModel) validateName(i int) func(string) error {
+	results, added := m.namingResults, m.added
+	return func(s string) error {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return fmt.Errorf("name cannot be empty")
+		}
+		for j, other := range results {
+			if j != i && strings.TrimSpace(*other) == s {
+				return fmt.Errorf("%s is used twice", s)
+			}
+		}
+		for _, b := range added {
+			if b.Dst == s {
+				return fmt.Errorf("%s was already added", s)
+			}
+		}
+		return nil
+	}
+}
+
 func (m pickerModel) updateConfirm(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if k, ok := msg.(tea.KeyMsg); ok && k.String() == "ctrl+c" {
 		m.quit = true
@@ -237,23 +251,13 @@ func (m pickerModel) updateConfirm(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case huh.StateCompleted:
 		m.confirmForm = nil
 		if m.addAnother != nil && *m.addAnother {
-			// Reset browse form for another pick.
-			browseResult := ""
-			m.browseResult = &browseResult
-			m.browseForm = huh.NewForm(
-				huh.NewGroup(
-					huh.NewFilePicker().
-						Title(fmt.Sprintf("Select another binary for %q", m.programName)).
-						CurrentDirectory(m.installDir).
-						ShowHidden(false).
-						FileAllowed(true).
-						DirAllowed(false).
-						Picking(true).
-						Value(m.browseResult),
-				),
-			).WithTheme(huhTheme).WithWidth(m.width).WithHeight(m.height)
+			// Reset the browser for another pick.
+			m.browser = newFileBrowser(m.installDir,
+				fmt.Sprintf("Select another binary for %q", m.programName),
+				"Press esc to finish without adding more.")
+			m.browser.height = m.height
 			m.phase = phaseBrowse
-			return m, m.browseForm.Init()
+			return m, nil
 		}
 		// User said "no" — done.
 		m.done = true
@@ -274,9 +278,7 @@ func (m pickerModel) updateConfirm(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m pickerModel) View() string {
 	switch m.phase {
 	case phaseBrowse:
-		if m.browseForm != nil {
-			return m.browseForm.View()
-		}
+		return m.browser.View()
 	case phaseNaming:
 		if m.namingForm != nil {
 			return m.namingForm.View()
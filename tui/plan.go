@@ -0,0 +1,262 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// planMsg carries the computed plan back to the plan screen.
+type planMsg struct {
+	changes []installer.Change
+}
+
+func computePlan(ctx context.Context, selected []catalog.Program, opts installer.Options) tea.Cmd {
+	return func() tea.Msg {
+		return planMsg{changes: installer.Plan(ctx, selected, opts)}
+	}
+}
+
+// planModel shows what an apply run, or an uninstall, would change — like
+// `terraform plan` — and asks for confirmation before anything is touched. Pressing v overrides
+// the version of a selected program for this run, re-planning with it.
+type planModel struct {
+	selected []catalog.Program
+	original []catalog.Program // selected as it came from the catalog
+	changes  []installer.Change
+	loading  bool
+	trash    bool // removed and replaced paths go to the trash
+	ctx      context.Context
+	opts     installer.Options
+
+	edit     *versionEdit // open while a version is being edited
+	checking bool         // an edited version is being looked up
+
+	form      *huh.Form
+	confirmed *bool // heap-allocated; huh writes here via pointer
+
+	done bool // user confirmed
+	back bool // user declined — return to the selector
+}
+
+func newPlanModel(selected []catalog.Program, ctx context.Context, opts installer.Options) planModel {
+	confirmed := false
+	return planModel{selected: selected, original: selected, loading: true, trash: opts.TrashDir() != "", ctx: ctx, opts: opts, confirmed: &confirmed}
+}
+
+// Init is a no-op: the root model schedules computePlan when opening the screen.
+func (m planModel) Init() tea.Cmd { return nil }
+
+func (m planModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(planMsg); ok {
+		m.loading = false
+		m.changes = msg.changes
+		m.form = huh.NewForm(
+			huh.NewGroup(
+				huh.NewConfirm().
+					Title("Apply these changes?").
+					Affirmative("Apply").
+					Negative("Back").
+					Value(m.confirmed),
+			),
+		).WithTheme(huhTheme)
+		return m, m.form.Init()
+	}
+
+	if msg, ok := msg.(tagMsg); ok {
+		return m.applyTag(msg)
+	}
+	if m.edit != nil {
+		return m.updateEdit(msg)
+	}
+	if k, ok := msg.(tea.KeyMsg); ok && k.String() == "v" && m.form != nil && !m.checking && len(m.selected) > 0 {
+		m.edit = newVersionEdit(m.selected, "", "", nil)
+		return m, m.edit.form.Init()
+	}
+
+	if m.form == nil {
+		if k, ok := msg.(tea.KeyMsg); ok {
+			switch k.String() {
+			case "ctrl+c":
+				return m, tea.Quit
+			case "esc":
+				m.back = true
+			}
+		}
+		return m, nil
+	}
+
+	form, cmd := m.form.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.form = f
+	}
+	switch m.form.State {
+	case huh.StateCompleted:
+		if *m.confirmed {
+			m.done = true
+		} else {
+			m.back = true
+		}
+	case huh.StateAborted:
+		m.back = true
+	}
+	return m, cmd
+}
+
+// updateEdit drives the version form. Submitting it looks the version up;
+// esc closes it.
+func (m planModel) updateEdit(msg tea.Msg) (tea.Model, tea.Cmd) {
+	form, cmd := m.edit.form.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.edit.form = f
+	}
+	switch m.edit.form.State {
+	case huh.StateCompleted:
+		name, input := *m.edit.program, *m.edit.tag
+		m.edit, m.checking = nil, true
+		for _, p := range m.selected {
+			if p.Name == name {
+				return m, checkTag(m.ctx, p, input)
+			}
+		}
+		m.checking = false
+	case huh.StateAborted:
+		m.edit = nil
+	}
+	return m, cmd
+}
+
+// applyTag sets a checked version on its program and plans again, or
+// reopens the form with the error.
+func (m planModel) applyTag(msg tagMsg) (tea.Model, tea.Cmd) {
+	m.checking = false
+	if msg.err != nil {
+		m.edit = newVersionEdit(m.selected, msg.program, msg.input, msg.err)
+		return m, m.edit.form.Init()
+	}
+	selected := slices.Clone(m.selected)
+	for i := range selected {
+		if selected[i].Name != msg.program {
+			continue
+		}
+		selected[i].Version = msg.tag
+		if msg.tag == "" {
+			selected[i].Version = m.original[i].Version
+		}
+	}
+	m.selected, m.loading, m.form = selected, true, nil
+	return m, computePlan(m.ctx, m.selected, m.opts)
+}
+
+func (m planModel) View() string {
+	var sb strings.Builder
+	sb.WriteString("\n  Plan\n\n")
+	if m.loading {
+		sb.WriteString(stylePending.Render("  Resolving versions…") + "\n")
+		return sb.String()
+	}
+
+	lines, summary := planLines(m.changes, m.trash)
+	for _, l := range lines {
+		sb.WriteString(l.style.Render(l.text) + "\n")
+	}
+	sb.WriteString("\n  " + summary + "\n\n")
+
+	switch {
+	case m.edit != nil:
+		if m.edit.err != nil {
+			sb.WriteString(styleError.Render(fmt.Sprintf("  %v", m.edit.err)) + "\n\n")
+		}
+		sb.WriteString(m.edit.form.View())
+	case m.checking:
+		sb.WriteString(stylePending.Render("  Looking up the version…") + "\n")
+	case m.form != nil:
+		sb.WriteString(m.form.View())
+		if len(m.selected) > 0 {
+			sb.WriteString(stylePending.Render("\n  v: install another version of a program") + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// planLine is one line of a rendered plan.
+type planLine struct {
+	style lipgloss.Style
+	text  string
+}
+
+// planLines renders changes as the plan screen shows them, followed by a
+// summary of the counts. Unchanged programs are only counted.
+func planLines(changes []installer.Change, trash bool) ([]planLine, string) {
+	var lines []planLine
+	add, upgrade, remove, unchanged := 0, 0, 0, 0
+	for _, c := range changes {
+		switch c.Kind {
+		case installer.ChangeInstall:
+			add++
+			lines = append(lines, planLine{styleDone, fmt.Sprintf("  + %-20s %s", c.Program, c.To)})
+		case installer.ChangeUpgrade:
+			upgrade++
+			lines = append(lines, planLine{styleSkipped, fmt.Sprintf("  ~ %-20s %s → %s", c.Program, c.From, c.To)})
+		case installer.ChangeRemove:
+			remove++
+			lines = append(lines, planLine{styleError, fmt.Sprintf("  - %-20s %s", c.Program, c.From)})
+		case installer.ChangeUnknown:
+			lines = append(lines, planLine{styleError, fmt.Sprintf("  ? %-20s %v", c.Program, c.Err)})
+		case installer.ChangeUnchanged:
+			unchanged++
+			continue
+		}
+		for _, p := range planPaths(c, trash) {
+			lines = append(lines, planLine{stylePending, "      " + p})
+		}
+	}
+	return lines, fmt.Sprintf("%d to add, %d to upgrade, %d to remove, %d unchanged", add, upgrade, remove, unchanged)
+}
+
+// PrintPlan writes the plan for changes to w without styling, for
+// `--dry-run`. trash reports whether removed and replaced paths would go to
+// the trash (see installer.Options.TrashDir).
+func PrintPlan(w io.Writer, changes []installer.Change, trash bool) {
+	lines, summary := planLines(changes, trash)
+	for _, l := range lines {
+		fmt.Fprintln(w, l.text)
+	}
+	fmt.Fprintln(w, "\n  "+summary)
+}
+
+// planPaths describes the paths c touches, one line each: what a removal
+// deletes (or trashes), the install dir an upgrade replaces and the links an
+// install creates.
+func planPaths(c installer.Change, trash bool) []string {
+	var lines []string
+	gone := "- "
+	if trash {
+		gone = "→ trash "
+	}
+	for _, p := range c.Paths {
+		if c.Kind == installer.ChangeRemove {
+			lines = append(lines, gone+p)
+		} else if trash {
+			lines = append(lines, "~ "+p+" (old release to trash)")
+		} else {
+			lines = append(lines, "~ "+p)
+		}
+	}
+	if c.Kind != installer.ChangeRemove {
+		for _, l := range c.Links {
+			lines = append(lines, "+ "+filepath.Join(system.BinPath(), l))
+		}
+	}
+	return lines
+}
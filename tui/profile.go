@@ -0,0 +1,47 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// renderProfile records how long each View call takes and how much it
+// returns. It is a debugging aid for the redraw rate of large runs, enabled
+// by the --profile-render flag, and not part of the normal UI.
+type renderProfile struct {
+	mu     sync.Mutex
+	w      io.Writer
+	start  time.Time
+	frames int
+	total  time.Duration
+	max    time.Duration
+}
+
+func newRenderProfile(w io.Writer) *renderProfile {
+	return &renderProfile{w: w, start: time.Now()}
+}
+
+// frame logs one View call of screen s that took d and produced out.
+func (p *renderProfile) frame(s screen, d time.Duration, out string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.frames++
+	p.total += d
+	p.max = max(p.max, d)
+	fmt.Fprintf(p.w, "%9.3fs screen=%d render=%s bytes=%d\n", time.Since(p.start).Seconds(), s, d, len(out))
+}
+
+// summary writes the totals of the frames logged so far.
+func (p *renderProfile) summary() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.frames == 0 {
+		return
+	}
+	elapsed := time.Since(p.start)
+	fmt.Fprintf(p.w, "%d frames in %s (%.1f/s), render mean %s, max %s\n",
+		p.frames, elapsed.Round(time.Millisecond), float64(p.frames)/elapsed.Seconds(),
+		p.total/time.Duration(p.frames), p.max)
+}
@@ -1,12 +1,18 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
 	"github.com/dsaleh/david-dotfiles/internal/installer"
+	"github.com/dsaleh/david-dotfiles/internal/notify"
+	"github.com/dsaleh/david-dotfiles/internal/report"
 )
 
 var (
@@ -21,67 +27,356 @@ type progressEntry struct {
 	state   installer.State
 	version string
 	err     error
+	skip    installer.SkipReason
+	movedTo string // new repo slug GitHub redirected to
+
+	// assetPattern matches the renamed asset that was downloaded because
+	// the catalog's asset_pattern matched none.
+	assetPattern string
+
+	// binBefore and binAfter are what the binary reported with --version
+	// around an upgrade, shown in the summary.
+	binBefore string
+	binAfter  string
+	health    *installer.Health // health_cmd run after an upgrade
+
+	removed []string // paths an uninstall deleted or trashed
+
+	// dl measures the download while state is StateDownloading; unset
+	// until the first progress update.
+	dl meter
 }
 
 type progressModel struct {
 	entries map[string]*progressEntry
 	order   []string
-	ch      <-chan installer.ProgressMsg
+	sub     progressSub
+	pauser  *installer.Pauser
+	rec     *report.Recorder // timeline of the run, for the stats history
+	notify  *notify.Server   // listeners on the progress socket; nil if not serving
 	done    bool
 	// pickerQueue holds AwaitingBinSelection messages waiting for the TUI to handle.
 	pickerQueue []installer.ProgressMsg
-}
+	// confirmQueue holds AwaitingConfirm messages, answered one at a time
+	// with y/n on the progress screen.
+	confirmQueue []installer.ProgressMsg
+	// triageQueue holds AwaitingTriage messages, answered one at a time on
+	// the progress screen; triageEdit is open while an entry is edited and
+	// triageErr is why the edit was not saved to the catalog.
+	triageQueue []installer.ProgressMsg
+	triageEdit  *triageEdit
+	triageErr   error
 
-// waitForProgress returns a tea.Cmd that blocks until the next ProgressMsg.
-// It is always driven by the root model — never scheduled from within progressModel.
-func waitForProgress(ch <-chan installer.ProgressMsg) tea.Cmd {
-	return func() tea.Msg {
-		msg, ok := <-ch
-		if !ok {
-			return nil // channel closed
-		}
-		return msg
-	}
+	// catalogs maps each program to the catalog file its fixes are written
+	// to; programs from the built-in catalog have none. specs holds each
+	// program's catalog entry, whose fields the fixes replace.
+	catalogs map[string]string
+	specs    map[string]catalog.Program
+	fixed    bool  // fixes were applied
+	fixErr   error // result of applying the fixes
+
+	// offset is the first visible row. It lives here rather than in the view
+	// so it survives trips to the bin picker screen mid-run.
+	offset int
+	height int
 }
 
-func newProgressModel(programs []string, ch <-chan installer.ProgressMsg) progressModel {
+func newProgressModel(programs []string, ch <-chan installer.ProgressMsg, pauser *installer.Pauser, catalogs map[string]string, specs map[string]catalog.Program, srv *notify.Server) progressModel {
 	entries := make(map[string]*progressEntry, len(programs))
 	for _, name := range programs {
 		entries[name] = &progressEntry{name: name, state: installer.StatePending}
 	}
-	return progressModel{entries: entries, order: programs, ch: ch}
+	return progressModel{entries: entries, order: programs, sub: progressSub{ch: ch}, pauser: pauser, rec: report.NewRecorder(programs), catalogs: catalogs, specs: specs, notify: srv}
+}
+
+// setHeight records the window height and keeps the scroll offset in range
+// for the new number of visible rows.
+func (m *progressModel) setHeight(h int) {
+	m.height = h
+	m.scroll(0)
+}
+
+// rows returns how many program lines fit on screen.
+func (m *progressModel) rows() int {
+	if m.height <= 0 {
+		return len(m.order)
+	}
+	// Header, pause hint or summary, a pending upgrade prompt and the fix
+	// list once done.
+	chrome := 8
+	if len(m.confirmQueue) > 0 {
+		chrome += len(m.confirmQueue[0].Running) + 3
+	}
+	if len(m.triageQueue) > 0 {
+		chrome += 4
+	}
+	if m.done {
+		if n := len(m.fixes()); n > 0 {
+			chrome += n + 4
+		}
+		if n := len(m.upgrades()); n > 0 {
+			chrome += n + 2
+		}
+		removed := 0
+		for _, e := range m.entries {
+			removed += len(e.removed)
+		}
+		if removed > 0 {
+			chrome += removed + 2
+		}
+	}
+	return max(m.height-chrome, 3)
+}
+
+// scroll moves the list by delta rows, clamped to the entries.
+func (m *progressModel) scroll(delta int) {
+	m.offset = max(min(m.offset+delta, len(m.order)-m.rows()), 0)
+}
+
+// scrollKey handles the list navigation keys, reporting whether k was one.
+func (m *progressModel) scrollKey(k string) bool {
+	switch k {
+	case "up", "k":
+		m.scroll(-1)
+	case "down", "j":
+		m.scroll(1)
+	case "pgup":
+		m.scroll(-m.rows())
+	case "pgdown":
+		m.scroll(m.rows())
+	default:
+		return false
+	}
+	return true
+}
+
+// togglePause suspends or resumes the run. Programs already waiting on a bin
+// picker are unaffected; downloads stall until resumed.
+func (m *progressModel) togglePause() {
+	if m.pauser == nil {
+		return
+	}
+	if m.pauser.Paused() {
+		m.pauser.Resume()
+	} else {
+		m.pauser.Pause()
+	}
+}
+
+// catalogFix is a suggested change to one catalog field of a program.
+type catalogFix struct {
+	Program, Field, From, To string
+}
+
+// fixes returns the suggested catalog changes that can be written to a
+// catalog file, in display order: asset_pattern mismatches that came with a
+// replacement or were worked around with a renamed asset, and repos GitHub
+// reported as renamed.
+func (m *progressModel) fixes() []catalogFix {
+	var out []catalogFix
+	for _, name := range m.order {
+		e := m.entries[name]
+		if m.catalogs[name] == "" {
+			continue
+		}
+		if e.movedTo != "" {
+			out = append(out, catalogFix{Program: name, Field: "repo", From: m.specs[name].Repo, To: e.movedTo})
+		}
+		if e.assetPattern != "" {
+			out = append(out, catalogFix{Program: name, Field: "asset_pattern", From: m.specs[name].AssetPattern, To: e.assetPattern})
+		}
+		var mismatch *installer.AssetMismatchError
+		if errors.As(e.err, &mismatch) && mismatch.Suggestion != "" {
+			out = append(out, catalogFix{Program: name, Field: "asset_pattern", From: mismatch.Pattern, To: mismatch.Suggestion})
+		}
+	}
+	return out
+}
+
+// upgrades returns the entries whose binary versions were read around an
+// upgrade, in display order.
+func (m *progressModel) upgrades() []*progressEntry {
+	var out []*progressEntry
+	for _, name := range m.order {
+		if e := m.entries[name]; e.state == installer.StateDone && e.binBefore != "" {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// healthChecks returns the health_cmd results of upgraded programs, in
+// display order.
+func (m *progressModel) healthChecks() []installer.Health {
+	var out []installer.Health
+	for _, name := range m.order {
+		if e := m.entries[name]; e.health != nil {
+			out = append(out, *e.health)
+		}
+	}
+	return out
+}
+
+// applyFixes writes every suggested fix into the catalog. The programs are
+// not retried; the next run picks up the new values.
+func (m *progressModel) applyFixes() {
+	if m.fixed {
+		return
+	}
+	for _, f := range m.fixes() {
+		if err := catalog.SetString(m.catalogs[f.Program], f.Program, f.Field, f.To); err != nil {
+			m.fixErr = fmt.Errorf("update %s: %w", f.Program, err)
+			return
+		}
+	}
+	m.fixed = true
 }
 
 // applyMsg updates state from a ProgressMsg. Returns true if the message was
 // an AwaitingBinSelection (caller should open picker).
 func (m *progressModel) applyMsg(msg installer.ProgressMsg) {
-	if e, ok := m.entries[msg.Program]; ok {
-		e.state = msg.State
-		e.version = msg.Version
-		e.err = msg.Err
+	ev := m.rec.Record(msg)
+	e, ok := m.entries[msg.Program]
+	if !ok {
+		// Programs being removed by an apply run are not part of the selection.
+		e = &progressEntry{name: msg.Program}
+		m.entries[msg.Program] = e
+		m.order = append(m.order, msg.Program)
+	}
+	e.state = msg.State
+	e.version = msg.Version
+	e.skip = msg.Skip
+	if msg.MovedTo != "" {
+		e.movedTo = msg.MovedTo
+	}
+	if msg.AssetPattern != "" {
+		e.assetPattern = msg.AssetPattern
 	}
-	if msg.State == installer.StateAwaitingBinSelection {
+	e.err = msg.Err
+	if msg.BinBefore != "" {
+		e.binBefore, e.binAfter = msg.BinBefore, msg.BinAfter
+	}
+	if msg.Health != nil {
+		e.health = msg.Health
+	}
+	if msg.Removed != nil {
+		e.removed = msg.Removed
+	}
+	if msg.State == installer.StateDownloading && msg.Received > 0 {
+		e.dl.update(msg.Time, msg.Received, msg.Size)
+	}
+	switch msg.State {
+	case installer.StateAwaitingBinSelection:
 		m.pickerQueue = append(m.pickerQueue, msg)
+	case installer.StateAwaitingConfirm:
+		m.confirmQueue = append(m.confirmQueue, msg)
+	case installer.StateAwaitingTriage:
+		m.triageQueue = append(m.triageQueue, msg)
+	}
+	if m.notify != nil {
+		m.notify.Publish(notify.Status{Event: ev, Done: m.finished(), Total: len(m.order)})
+	}
+}
+
+// downloading reports whether e is downloading and has reported progress.
+func (e *progressEntry) downloading() bool {
+	return e.state == installer.StateDownloading && !e.dl.start.IsZero()
+}
+
+// throughput sums the rates of the downloads in progress and estimates when
+// they will all be done; the estimate is missing if any size is unknown.
+func (m *progressModel) throughput() string {
+	var rate float64
+	var left int64
+	active, known := 0, true
+	// In display order, so the float sums come out the same every frame.
+	for _, name := range m.order {
+		e := m.entries[name]
+		if !e.downloading() {
+			continue
+		}
+		active++
+		rate += e.dl.rate
+		n, ok := e.dl.remaining()
+		left += n
+		known = known && ok
+	}
+	if active == 0 {
+		return ""
+	}
+	s := formatSize(int64(rate)) + "/s total"
+	if known && rate > 0 {
+		s += ", ~" + formatETA(time.Duration(float64(left)/rate*float64(time.Second))) + " remaining"
 	}
+	return s
+}
+
+// finished counts the entries in a terminal state.
+func (m *progressModel) finished() int {
+	n := 0
+	for _, e := range m.entries {
+		if e.state.Terminal() {
+			n++
+		}
+	}
+	return n
+}
+
+// confirmKey answers the oldest upgrade confirmation with y or n, reporting
+// whether k was one of them.
+func (m *progressModel) confirmKey(k string) bool {
+	if len(m.confirmQueue) == 0 || (k != "y" && k != "n") {
+		return false
+	}
+	m.confirmQueue[0].ConfirmCh <- k == "y"
+	m.confirmQueue = m.confirmQueue[1:]
+	return true
 }
 
 // allTerminal returns true when every entry has reached a terminal state AND
 // there are no picker interactions still pending.
 func (m *progressModel) allTerminal() bool {
-	if len(m.pickerQueue) > 0 {
+	if len(m.pickerQueue) > 0 || len(m.confirmQueue) > 0 || len(m.triageQueue) > 0 {
 		return false
 	}
 	for _, e := range m.entries {
-		switch e.state {
-		case installer.StateDone, installer.StateSkipped, installer.StateError:
-			// terminal
-		default:
+		if !e.state.Terminal() {
 			return false
 		}
 	}
 	return true
 }
 
+// skipNote explains a skipped entry on its progress line.
+func skipNote(r installer.SkipReason) string {
+	switch r {
+	case installer.SkipHeld:
+		return "held at its pinned version"
+	case installer.SkipOffline:
+		return "offline — kept the installed version"
+	case installer.SkipUnsupported:
+		return "not for this system"
+	}
+	return "already up to date"
+}
+
+// skipSummary renders the skipped count of the summary line, broken down by
+// reason when anything was skipped for a reason other than being up to date.
+func skipSummary(skipped map[installer.SkipReason]int) string {
+	total := 0
+	var parts []string
+	for _, r := range []installer.SkipReason{installer.SkipUpToDate, installer.SkipHeld, installer.SkipOffline, installer.SkipUnsupported} {
+		if n := skipped[r]; n > 0 {
+			total += n
+			parts = append(parts, fmt.Sprintf("%d %s", n, r))
+		}
+	}
+	if skipped[installer.SkipUpToDate] == total {
+		return fmt.Sprintf("%d skipped", total)
+	}
+	return fmt.Sprintf("%d skipped (%s)", total, strings.Join(parts, ", "))
+}
+
 // progressModel.Update is intentionally minimal — it only handles the "press
 // any key to exit" interaction once done=true. ALL channel reading and picker
 // routing is done by the root model.
@@ -96,32 +391,155 @@ func (m progressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m progressModel) View() string {
 	var sb strings.Builder
-	sb.WriteString("\n  Installing programs\n\n")
+	sb.WriteString("\n  Installing programs")
+	if t := m.throughput(); t != "" && !m.done {
+		sb.WriteString(stylePending.Render("  •  " + t))
+	}
+	sb.WriteString("\n\n")
 
-	installed, skipped, failed := 0, 0, 0
-	for _, name := range m.order {
+	installed, failed, removed := 0, 0, 0
+	skipped := map[installer.SkipReason]int{}
+	first, last := m.offset, min(m.offset+m.rows(), len(m.order))
+	if first > 0 {
+		sb.WriteString(stylePending.Render(fmt.Sprintf("  ↑ %d more", first)) + "\n")
+	}
+	for i, name := range m.order {
 		e := m.entries[name]
 		var line string
 		switch e.state {
 		case installer.StateDone:
-			line = styleDone.Render(fmt.Sprintf("  ✓ %-20s %s", e.name, e.version))
+			line = styleDone.Render(fmt.Sprintf("  %s %-20s %s", glyphDone, e.name, e.version))
 			installed++
 		case installer.StateSkipped:
-			line = styleSkipped.Render(fmt.Sprintf("  - %-20s %s (already up to date)", e.name, e.version))
-			skipped++
+			line = styleSkipped.Render(fmt.Sprintf("  %s %-20s %s (%s)", glyphSkipped, e.name, e.version, skipNote(e.skip)))
+			skipped[e.skip]++
 		case installer.StateError:
-			line = styleError.Render(fmt.Sprintf("  ✗ %-20s %v", e.name, e.err))
+			line = styleError.Render(fmt.Sprintf("  %s %-20s %v", glyphError, e.name, e.err))
 			failed++
+		case installer.StateRemoved:
+			line = styleSkipped.Render(fmt.Sprintf("  %s %-20s %s (removed)", glyphRemoved, e.name, e.version))
+			removed++
+		case installer.StateAwaitingTriage:
+			line = styleError.Render(fmt.Sprintf("  %s %-20s %v", glyphPaused, e.name, e.err))
 		case installer.StatePending:
-			line = stylePending.Render(fmt.Sprintf("  · %-20s pending", e.name))
+			line = stylePending.Render(fmt.Sprintf("  %s %-20s pending", glyphPending, e.name))
+		case installer.StateDownloading:
+			if e.downloading() {
+				line = stylePending.Render(fmt.Sprintf("  %s %-20s %s", glyphActive, e.name, e.dl.status()))
+			} else {
+				line = stylePending.Render(fmt.Sprintf("  %s %-20s %s", glyphActive, e.name, e.state.String()))
+			}
 		default:
-			line = stylePending.Render(fmt.Sprintf("  · %-20s %s", e.name, e.state.String()))
+			line = stylePending.Render(fmt.Sprintf("  %s %-20s %s", glyphActive, e.name, e.state.String()))
+		}
+		// Every entry counts towards the summary; only the window is drawn.
+		if i >= first && i < last {
+			sb.WriteString(line + "\n")
+		}
+	}
+	if last < len(m.order) {
+		sb.WriteString(stylePending.Render(fmt.Sprintf("  ↓ %d more", len(m.order)-last)) + "\n")
+	}
+
+	if len(m.confirmQueue) > 0 {
+		req := m.confirmQueue[0]
+		sb.WriteString(styleSkipped.Render(fmt.Sprintf("\n  ⚠ %s is in use by running processes:", req.Program)) + "\n")
+		for _, p := range req.Running {
+			sb.WriteString(fmt.Sprintf("    pid %-8d %s\n", p.PID, p.Exe))
+		}
+		sb.WriteString(fmt.Sprintf("  Upgrade to %s anyway? They keep running the old version. (y/n)\n", req.Version))
+	}
+
+	if m.triageEdit != nil {
+		sb.WriteString("\n" + m.triageEdit.form.View() + "\n")
+	} else if len(m.triageQueue) > 0 {
+		req := m.triageQueue[0]
+		sb.WriteString(styleError.Render(fmt.Sprintf("\n  %s %s failed: %v", glyphError, req.Program, req.Err)) + "\n")
+		sb.WriteString("  The run is paused. r: retry  •  e: edit its catalog entry and retry  •  s: skip  •  a: abort\n")
+	}
+	if m.triageErr != nil {
+		sb.WriteString(styleError.Render(fmt.Sprintf("  %v", m.triageErr)) + "\n")
+	}
+
+	// An open triage prompt lists its own keys.
+	if !m.done && len(m.triageQueue) == 0 {
+		hint := "p: pause"
+		if m.rows() < len(m.order) {
+			hint += "  •  ↑/↓: scroll"
+		}
+		if m.pauser.Paused() {
+			sb.WriteString(styleSkipped.Render("\n  "+glyphPaused+" Paused — press p to resume") + "\n")
+		} else {
+			sb.WriteString(stylePending.Render("\n  "+hint) + "\n")
 		}
-		sb.WriteString(line + "\n")
 	}
 
 	if m.done {
-		sb.WriteString(fmt.Sprintf("\n  %d installed, %d skipped, %d failed\n", installed, skipped, failed))
+		summary := fmt.Sprintf("\n  %d installed, %s, %d failed", installed, skipSummary(skipped), failed)
+		if removed > 0 {
+			summary += fmt.Sprintf(", %d removed", removed)
+		}
+		sb.WriteString(summary + "\n")
+
+		if upgrades := m.upgrades(); len(upgrades) > 0 {
+			sb.WriteString("\n  Upgraded binaries:\n")
+			for _, e := range upgrades {
+				switch e.binAfter {
+				case e.binBefore:
+					sb.WriteString(styleError.Render(fmt.Sprintf("    %-20s still reports %s — check its links", e.name, e.binBefore)) + "\n")
+				case "":
+					sb.WriteString(styleError.Render(fmt.Sprintf("    %-20s %s → no version reported", e.name, e.binBefore)) + "\n")
+				default:
+					sb.WriteString(fmt.Sprintf("    %-20s %s → %s\n", e.name, e.binBefore, e.binAfter))
+				}
+			}
+		}
+
+		var removed []string
+		for _, name := range m.order {
+			removed = append(removed, m.entries[name].removed...)
+		}
+		if len(removed) > 0 {
+			sb.WriteString("\n  Removed:\n")
+			for _, p := range removed {
+				sb.WriteString(stylePending.Render("    "+p) + "\n")
+			}
+		}
+
+		if checks := m.healthChecks(); len(checks) > 0 {
+			sb.WriteString("\n  Health checks:\n")
+			for _, h := range checks {
+				mark, reason := styleDone.Render("✓"), ""
+				if h.Err != nil {
+					mark, reason = styleError.Render("✗"), "  "+styleError.Render(h.Reason())
+				}
+				sb.WriteString(fmt.Sprintf("  %s %-20s %-30s %s%s\n", mark, h.Program, h.Cmd, h.Duration.Round(10*time.Millisecond), reason))
+				if h.Hint != "" {
+					sb.WriteString(styleSkipped.Render("      hint: "+h.Hint) + "\n")
+				}
+			}
+		}
+
+		if fixes := m.fixes(); len(fixes) > 0 {
+			sb.WriteString("\n  Catalog fixes:\n")
+			for _, f := range fixes {
+				sb.WriteString(fmt.Sprintf("    %-20s %s %q → %q\n", f.Program, f.Field, f.From, f.To))
+			}
+			switch {
+			case m.fixErr != nil:
+				sb.WriteString(styleError.Render(fmt.Sprintf("  %v", m.fixErr)) + "\n")
+			case m.fixed:
+				sb.WriteString(styleDone.Render("  ✓ Catalog updated — re-run to install them") + "\n")
+			default:
+				var files []string
+				for _, f := range fixes {
+					if path := m.catalogs[f.Program]; !slices.Contains(files, path) {
+						files = append(files, path)
+					}
+				}
+				sb.WriteString("\n  Press f to apply these fixes to " + strings.Join(files, ", ") + "\n")
+			}
+		}
 		sb.WriteString("\n  Press any key to exit\n")
 	}
 	return sb.String()
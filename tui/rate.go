@@ -0,0 +1,98 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// rateSmoothing weighs the newest sample in a meter's instantaneous rate.
+// Lower values steady the number at the cost of reacting later.
+const rateSmoothing = 0.3
+
+// meter tracks the throughput of one download from its progress updates.
+type meter struct {
+	start, last time.Time
+	base        int64 // bytes already received at start
+	received    int64
+	size        int64   // 0 if unknown
+	rate        float64 // smoothed bytes per second
+}
+
+// update folds in a progress update. A count that went backwards means the
+// download was retried from scratch, so the meter starts over.
+func (m *meter) update(t time.Time, received, size int64) {
+	if m.start.IsZero() || received < m.received {
+		*m = meter{start: t, last: t, base: received, received: received, size: size}
+		return
+	}
+	if dt := t.Sub(m.last).Seconds(); dt > 0 {
+		sample := float64(received-m.received) / dt
+		if m.rate == 0 {
+			m.rate = sample
+		} else {
+			m.rate = rateSmoothing*sample + (1-rateSmoothing)*m.rate
+		}
+	}
+	m.last, m.received, m.size = t, received, size
+}
+
+// average returns the mean rate since the download started.
+func (m *meter) average() float64 {
+	d := m.last.Sub(m.start).Seconds()
+	if d <= 0 {
+		return 0
+	}
+	return float64(m.received-m.base) / d
+}
+
+// remaining returns the bytes still to come, and false if the size is unknown.
+func (m *meter) remaining() (int64, bool) {
+	if m.size <= 0 {
+		return 0, false
+	}
+	return max(m.size-m.received, 0), true
+}
+
+// eta estimates the time left at the current rate.
+func (m *meter) eta() (time.Duration, bool) {
+	left, ok := m.remaining()
+	if !ok || m.rate <= 0 {
+		return 0, false
+	}
+	return time.Duration(float64(left) / m.rate * float64(time.Second)), true
+}
+
+// barWidth is the number of cells in a download's progress bar.
+const barWidth = 20
+
+// status renders the download for its progress line, e.g.
+// "[████████░░░░] 42%  2.9 MiB / 7.0 MiB  2.3 MiB/s (avg 2.0 MiB/s)  ~2s".
+func (m *meter) status() string {
+	var sb strings.Builder
+	if m.size > 0 {
+		frac := min(float64(m.received)/float64(m.size), 1)
+		filled := int(frac * barWidth)
+		fmt.Fprintf(&sb, "[%s%s] %3.0f%%  %s / %s", strings.Repeat("█", filled), strings.Repeat("░", barWidth-filled), frac*100, formatSize(m.received), formatSize(m.size))
+	} else {
+		sb.WriteString(formatSize(m.received))
+	}
+	fmt.Fprintf(&sb, "  %s/s (avg %s/s)", formatSize(int64(m.rate)), formatSize(int64(m.average())))
+	if eta, ok := m.eta(); ok {
+		sb.WriteString("  ~" + formatETA(eta))
+	}
+	return sb.String()
+}
+
+// formatETA renders d coarsely, e.g. "45s", "3m10s", "1h5m".
+func formatETA(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()+0.5))
+	case d < time.Hour:
+		d = d.Round(10 * time.Second)
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,136 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	gh "github.com/dsaleh/david-dotfiles/internal/github"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+// readmeMsg carries the README fetched for the pager.
+type readmeMsg struct {
+	program string
+	text    string
+	err     error
+}
+
+func fetchReadme(ctx context.Context, p catalog.Program) tea.Cmd {
+	return func() tea.Msg {
+		text, err := gh.NewClient(p.GitHubAPI()).Readme(ctx, p.Repo)
+		return readmeMsg{program: p.Name, text: text, err: err}
+	}
+}
+
+// repoOpenedMsg reports the outcome of opening a repo page in the browser.
+type repoOpenedMsg struct {
+	url string
+	err error
+}
+
+// openRepo opens p's repo page in the browser.
+func openRepo(p catalog.Program) tea.Cmd {
+	url := p.RepoURL()
+	return func() tea.Msg {
+		return repoOpenedMsg{url: url, err: system.OpenURL(url)}
+	}
+}
+
+// status renders the outcome for the selector and detail views. A failure
+// still shows the URL, to be copied by hand.
+func (msg repoOpenedMsg) status() string {
+	if msg.err != nil {
+		return styleSkipped.Render(fmt.Sprintf("  could not open a browser (%v): %s", msg.err, msg.url))
+	}
+	return styleDone.Render("  opened " + msg.url)
+}
+
+// readmeModel pages through a program's README, fetched from the GitHub
+// API and shown as its raw markup: install instructions and caveats read
+// fine without rendering.
+//
+// Keys: up/down, pgup/pgdown (or b/space) and u/d scroll, o opens the repo
+// page in the browser, esc or q goes back.
+type readmeModel struct {
+	program catalog.Program
+	ctx     context.Context
+	view    viewport.Model
+
+	loading bool
+	err     error
+	status  string
+
+	back bool // user wants to return to the previous screen
+}
+
+func newReadmeModel(p catalog.Program, ctx context.Context, width, height int) readmeModel {
+	m := readmeModel{program: p, ctx: ctx, loading: true}
+	m.view = viewport.New(width, 0)
+	m.setSize(width, height)
+	return m
+}
+
+// setSize fits the pager to the window, leaving room for the header and
+// footer lines.
+func (m *readmeModel) setSize(width, height int) {
+	m.view.Width = width
+	m.view.Height = max(height-5, 5)
+}
+
+func (m readmeModel) Init() tea.Cmd {
+	return fetchReadme(m.ctx, m.program)
+}
+
+func (m readmeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.setSize(msg.Width, msg.Height)
+		return m, nil
+	case readmeMsg:
+		if msg.program != m.program.Name {
+			return m, nil
+		}
+		m.loading = false
+		m.err = msg.err
+		m.view.SetContent(msg.text)
+		return m, nil
+	case repoOpenedMsg:
+		m.status = msg.status()
+		return m, nil
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc", "q":
+			m.back = true
+			return m, nil
+		case "o":
+			return m, openRepo(m.program)
+		}
+	}
+	var cmd tea.Cmd
+	m.view, cmd = m.view.Update(msg)
+	return m, cmd
+}
+
+func (m readmeModel) View() string {
+	header := fmt.Sprintf("\n  %s — %s\n\n", m.program.Name, m.program.RepoURL())
+	switch {
+	case m.loading:
+		return header + stylePending.Render("  Fetching the README…") + "\n"
+	case m.err != nil:
+		return header + styleError.Render(fmt.Sprintf("  %v", m.err)) + "\n\n  o: open the repo page  •  esc: back\n" + m.statusLine()
+	}
+	footer := fmt.Sprintf("\n  %3.0f%%  •  ↑/↓ pgup/pgdn: scroll  •  o: open the repo page  •  esc: back\n", m.view.ScrollPercent()*100)
+	return header + m.view.View() + "\n" + styleSkipped.Render(footer) + m.statusLine()
+}
+
+func (m readmeModel) statusLine() string {
+	if m.status == "" {
+		return ""
+	}
+	return m.status + "\n"
+}
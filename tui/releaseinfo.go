@@ -0,0 +1,119 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+)
+
+// hoverDelay is how long the selector cursor has to rest on a program before
+// its release info is fetched, so scrolling through the list does not spend
+// an API request on every program passed.
+const hoverDelay = 300 * time.Millisecond
+
+// now is the clock release ages are measured against; tests fix it.
+var now = time.Now
+
+// releaseInfo caches, per program, the release an install would fetch, for
+// the selector's info line. It is shared by every selector of a session, so
+// each program is looked up at most once.
+type releaseInfo struct {
+	ctx     context.Context
+	opts    installer.Options
+	res     map[string]installer.Resolution
+	pending map[string]bool
+}
+
+func newReleaseInfo(ctx context.Context, opts installer.Options) *releaseInfo {
+	return &releaseInfo{ctx: ctx, opts: opts, res: map[string]installer.Resolution{}, pending: map[string]bool{}}
+}
+
+// hoverMsg fires hoverDelay after the cursor moved onto program name.
+type hoverMsg struct{ name string }
+
+// releaseInfoMsg carries a finished lookup.
+type releaseInfoMsg struct{ res installer.Resolution }
+
+// wait schedules a hoverMsg for p, unless its info is known or on the way.
+func (ri *releaseInfo) wait(p catalog.Program) tea.Cmd {
+	if _, ok := ri.res[p.Name]; ok || ri.pending[p.Name] {
+		return nil
+	}
+	return tea.Tick(hoverDelay, func(time.Time) tea.Msg { return hoverMsg{p.Name} })
+}
+
+// fetch starts the lookup of p.
+func (ri *releaseInfo) fetch(p catalog.Program) tea.Cmd {
+	if _, ok := ri.res[p.Name]; ok || ri.pending[p.Name] {
+		return nil
+	}
+	ri.pending[p.Name] = true
+	ctx, opts := ri.ctx, ri.opts
+	return func() tea.Msg {
+		return releaseInfoMsg{installer.Describe(ctx, p, opts)}
+	}
+}
+
+// store records a finished lookup.
+func (ri *releaseInfo) store(msg releaseInfoMsg) {
+	delete(ri.pending, msg.res.Program.Name)
+	ri.res[msg.res.Program.Name] = msg.res
+}
+
+// line describes the release of p for the selector, e.g.
+// "fzf 0.61.0  •  released 2026-09-30 (16 days ago)  •  1.6 MiB download".
+func (ri *releaseInfo) line(p catalog.Program) string {
+	res, ok := ri.res[p.Name]
+	switch {
+	case ri.pending[p.Name]:
+		return stylePending.Render("  " + p.Name + ": fetching release info…")
+	case !ok:
+		return ""
+	case res.Err != nil:
+		return styleSkipped.Render(fmt.Sprintf("  %s: release info unavailable: %v", p.Name, res.Err))
+	}
+	s := "  " + p.Name + " " + res.Version
+	if !res.Released.IsZero() {
+		s += "  •  released " + res.Released.Format("2006-01-02") + " (" + age(now().Sub(res.Released)) + ")"
+	}
+	if res.Size > 0 {
+		s += "  •  " + formatSize(res.Size) + " download"
+	} else {
+		s += "  •  size unknown"
+	}
+	return styleSkipped.Render(s)
+}
+
+// age renders d coarsely, e.g. "today", "3 days ago", "5 months ago".
+func age(d time.Duration) string {
+	days := int(d.Hours() / 24)
+	switch {
+	case days < 1:
+		return "today"
+	case days == 1:
+		return "yesterday"
+	case days < 60:
+		return fmt.Sprintf("%d days ago", days)
+	case days < 730:
+		return fmt.Sprintf("%d months ago", days/30)
+	}
+	return fmt.Sprintf("%d years ago", days/365)
+}
+
+// formatSize renders n bytes with a binary unit, e.g. 1536 → "1.5 KiB".
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
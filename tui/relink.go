@@ -0,0 +1,256 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/linker"
+	"github.com/dsaleh/david-dotfiles/internal/state"
+	"github.com/dsaleh/david-dotfiles/internal/system"
+)
+
+type relinkMode int
+
+const (
+	relinkList relinkMode = iota
+	relinkAdd
+	relinkRename
+)
+
+// RelinkModel manages the symlinks of an already-installed program without
+// reinstalling it: links can be added with the bin picker, renamed or removed.
+// Every change is applied immediately and recorded in the state file.
+type RelinkModel struct {
+	name       string
+	installDir string
+	st         *state.State
+
+	links  []catalog.Bin
+	cursor int
+	mode   relinkMode
+	err    error
+
+	picker      pickerModel
+	renameForm  *huh.Form
+	renameValue *string // heap-allocated; huh writes here via pointer
+
+	width  int
+	height int
+}
+
+// NewRelink creates the standalone relink screen for program name.
+func NewRelink(name string, st *state.State) RelinkModel {
+	m := RelinkModel{
+		name:       name,
+		installDir: filepath.Join(system.SharePath(), st.DirOf(name)),
+		st:         st,
+	}
+	m.reload()
+	return m
+}
+
+func (m RelinkModel) Init() tea.Cmd { return nil }
+
+// reload re-reads the program's links from the bin dir and records their
+// names in state, so the file always reflects what is on disk.
+func (m *RelinkModel) reload() {
+	links, err := linker.Owned(system.BinPath(), m.installDir)
+	if err != nil {
+		m.err = err
+		return
+	}
+	// Links of other programs sharing the install dir are theirs to change.
+	others := map[string]bool{}
+	for _, name := range m.st.DirUsers(m.st.DirOf(m.name), m.name) {
+		ps, _ := m.st.Get(name)
+		for _, b := range ps.Bins {
+			others[b] = true
+		}
+	}
+	links = slices.DeleteFunc(links, func(b catalog.Bin) bool { return others[b.Dst] })
+	m.links = links
+	m.cursor = min(m.cursor, max(len(links)-1, 0))
+
+	ps, ok := m.st.Get(m.name)
+	if !ok {
+		return
+	}
+	ps.Bins = ps.Bins[:0:0]
+	for _, l := range links {
+		ps.Bins = append(ps.Bins, l.Dst)
+	}
+	if ps.Locks != nil {
+		// Links changed here are the installer's own; lock them as they are now.
+		ps.Locks = make(map[string]state.LinkLock, len(links))
+		for _, l := range links {
+			if target, hash, err := linker.Fingerprint(system.BinPath(), l.Dst); err == nil {
+				ps.Locks[l.Dst] = state.LinkLock{Path: filepath.Join(system.BinPath(), l.Dst), Target: target, Hash: hash}
+			}
+		}
+	}
+	m.st.Set(m.name, ps)
+	if err := m.st.Save(); err != nil {
+		m.err = fmt.Errorf("save state: %w", err)
+	}
+}
+
+func (m RelinkModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if ws, ok := msg.(tea.WindowSizeMsg); ok {
+		m.width, m.height = ws.Width, ws.Height
+		if m.mode == relinkAdd {
+			next, cmd := m.picker.Update(msg)
+			m.picker = next.(pickerModel)
+			return m, cmd
+		}
+		return m, nil
+	}
+
+	switch m.mode {
+	case relinkAdd:
+		return m.updateAdd(msg)
+	case relinkRename:
+		return m.updateRename(msg)
+	}
+
+	k, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch k.String() {
+	case "q", "esc", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.links)-1 {
+			m.cursor++
+		}
+	case "a":
+		m.err = nil
+		m.picker = newPickerModel(m.name, m.installDir, nil)
+		if m.width > 0 {
+			m.picker.setSize(m.width, m.height)
+		}
+		m.mode = relinkAdd
+		return m, m.picker.Init()
+	case "r":
+		if len(m.links) == 0 {
+			return m, nil
+		}
+		m.err = nil
+		value := m.links[m.cursor].Dst
+		m.renameValue = &value
+		m.renameForm = huh.NewForm(
+			huh.NewGroup(
+				huh.NewInput().
+					Title("Rename " + value).
+					Description("New name in ~/.local/bin/").
+					Value(m.renameValue).
+					Validate(func(s string) error {
+						if strings.TrimSpace(s) == "" {
+							return fmt.Errorf("name cannot be empty")
+						}
+						return nil
+					}),
+			),
+		).WithTheme(huhTheme)
+		m.mode = relinkRename
+		return m, m.renameForm.Init()
+	case "d":
+		if len(m.links) == 0 {
+			return m, nil
+		}
+		_, m.err = linker.Unlink(system.BinPath(), m.links[m.cursor].Dst, m.installDir)
+		m.reload()
+	}
+	return m, nil
+}
+
+func (m RelinkModel) updateAdd(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.picker.Update(msg)
+	m.picker = next.(pickerModel)
+	if m.picker.quit {
+		return m, tea.Quit
+	}
+	if m.picker.done {
+		for _, b := range m.picker.added {
+			if err := linker.Link(b.Src, system.BinPath(), b.Dst); err != nil {
+				m.err = err
+				break
+			}
+		}
+		m.mode = relinkList
+		m.reload()
+		return m, nil
+	}
+	return m, cmd
+}
+
+func (m RelinkModel) updateRename(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if k, ok := msg.(tea.KeyMsg); ok && k.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	form, cmd := m.renameForm.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.renameForm = f
+	}
+	switch m.renameForm.State {
+	case huh.StateCompleted:
+		old := m.links[m.cursor]
+		name := strings.TrimSpace(*m.renameValue)
+		if name != old.Dst {
+			// Create the new link first so a failure leaves the old one intact.
+			if m.err = linker.Link(old.Src, system.BinPath(), name); m.err == nil {
+				_, m.err = linker.Unlink(system.BinPath(), old.Dst, m.installDir)
+			}
+		}
+		m.mode = relinkList
+		m.renameForm = nil
+		m.reload()
+		return m, nil
+	case huh.StateAborted:
+		m.mode = relinkList
+		m.renameForm = nil
+		return m, nil
+	}
+	return m, cmd
+}
+
+func (m RelinkModel) View() string {
+	switch m.mode {
+	case relinkAdd:
+		return m.picker.View()
+	case relinkRename:
+		return m.renameForm.View()
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n  Links for %s\n\n", m.name))
+	if len(m.links) == 0 {
+		sb.WriteString(stylePending.Render("  (no links in "+system.BinPath()+")") + "\n")
+	}
+	for i, l := range m.links {
+		rel, err := filepath.Rel(m.installDir, l.Src)
+		if err != nil {
+			rel = l.Src
+		}
+		line := fmt.Sprintf("%-20s → %s", l.Dst, rel)
+		if i == m.cursor {
+			sb.WriteString(styleDone.Render("  > "+line) + "\n")
+		} else {
+			sb.WriteString("    " + line + "\n")
+		}
+	}
+	if m.err != nil {
+		sb.WriteString("\n" + styleError.Render(fmt.Sprintf("  %v", m.err)) + "\n")
+	}
+	sb.WriteString("\n  a: add  •  r: rename  •  d: remove  •  q: quit\n")
+	return sb.String()
+}
@@ -1,43 +1,104 @@
 package tui
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
 	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/state"
 )
 
 type selectorModel struct {
 	form     *huh.Form
+	list     *huh.MultiSelect[*catalog.Program]
 	programs []catalog.Program
 	result   *[]*catalog.Program // heap-allocated so the form's captured pointer stays valid
 	done     bool
 	quit     bool
+
+	// priority lists programs the user marked as high priority, in the order
+	// they were marked. They are queued ahead of everything else.
+	priority []string
+
+	// detail is set when the user asks to open the detail view for the
+	// hovered program. The root model consumes and clears it.
+	detail *catalog.Program
+
+	// reload is set when the user asks to reload the catalog from disk.
+	// The root model consumes and clears it; status reports the outcome.
+	reload bool
+	status string
+
+	// uninstall is set when the user asks to uninstall the hovered
+	// program. The root model consumes and clears it.
+	uninstall *catalog.Program
+
+	// readme is set when the user asks to read the hovered program's
+	// README. The root model consumes and clears it.
+	readme *catalog.Program
+
+	// info holds the release date and download size shown for the hovered
+	// program, fetched once the cursor rests on it.
+	info    *releaseInfo
+	hovered string
 }
 
-func newSelectorModel(programs []catalog.Program) selectorModel {
+// newSelectorModel builds the program list, with the programs named in
+// selected already marked.
+func newSelectorModel(programs []catalog.Program, st *state.State, info *releaseInfo, selected map[string]bool) selectorModel {
 	result := make([]*catalog.Program, 0)
 
+	// Programs from several catalogs arrive grouped by catalog; each group
+	// is labelled with its namespace so it reads as a section.
 	opts := make([]huh.Option[*catalog.Program], len(programs))
 	for i := range programs {
 		p := &programs[i]
-		opts[i] = huh.NewOption(p.Name+" — "+p.Repo, p)
+		label := p.Name + " — " + p.Repo + reliability(st, p.Name)
+		if p.Source != "" {
+			label = "[" + catalog.Namespace(p.Source) + "] " + label
+		}
+		opts[i] = huh.NewOption(label, p).Selected(selected[p.Name])
 	}
 
-	form := huh.NewForm(
-		huh.NewGroup(
-			huh.NewMultiSelect[*catalog.Program]().
-				Title("Select programs to install").
-				Description("space: toggle  •  enter: confirm  •  /: filter  •  q: quit").
-				Options(opts...).
-				Filterable(true).
-				Value(&result),
-		),
-	).WithTheme(huhTheme).WithHeight(20)
+	list := huh.NewMultiSelect[*catalog.Program]().
+		Title("Select programs to install").
+		Description("space: toggle  •  enter: confirm  •  /: filter  •  i: details  •  o: repo page  •  R: readme  •  p: prioritize  •  u: uninstall  •  r: reload catalog  •  q: quit").
+		Options(opts...).
+		Filterable(true).
+		Value(&result)
+
+	form := huh.NewForm(huh.NewGroup(list)).WithTheme(huhTheme).WithHeight(20)
 
 	return selectorModel{
 		form:     form,
+		list:     list,
 		programs: programs,
 		result:   &result,
+		info:     info,
+	}
+}
+
+// reliability returns a short warning suffix for programs whose recent
+// installs failed, or "" if the last attempt succeeded.
+func reliability(st *state.State, name string) string {
+	stats, ok := st.Stats(name)
+	if !ok || stats.ConsecutiveFailures == 0 {
+		return ""
+	}
+	if stats.ConsecutiveFailures == 1 {
+		return "  ⚠ failed last run"
+	}
+	return fmt.Sprintf("  ⚠ failed last %d runs", stats.ConsecutiveFailures)
+}
+
+// setHeight fits the program list to the window, leaving room for the
+// release info and priority lines under the form.
+func (m *selectorModel) setHeight(h int) {
+	if h > 0 {
+		m.form = m.form.WithHeight(max(h-4, 8))
 	}
 }
 
@@ -46,6 +107,57 @@ func (m selectorModel) Init() tea.Cmd {
 }
 
 func (m selectorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if ws, ok := msg.(tea.WindowSizeMsg); ok {
+		m.setHeight(ws.Height)
+	}
+	if h, ok := msg.(hoverMsg); ok {
+		if p, ok := m.list.Hovered(); ok && p != nil && p.Name == h.name {
+			return m, m.info.fetch(*p)
+		}
+		return m, nil
+	}
+	if k, ok := msg.(tea.KeyMsg); ok && k.String() == "i" && !m.list.GetFiltering() {
+		if p, ok := m.list.Hovered(); ok && p != nil {
+			m.detail = p
+		}
+		return m, nil
+	}
+	if k, ok := msg.(tea.KeyMsg); ok && k.String() == "p" && !m.list.GetFiltering() {
+		if p, ok := m.list.Hovered(); ok && p != nil {
+			if i := slices.Index(m.priority, p.Name); i >= 0 {
+				m.priority = slices.Delete(slices.Clone(m.priority), i, i+1)
+			} else {
+				m.priority = append(slices.Clone(m.priority), p.Name)
+			}
+		}
+		return m, nil
+	}
+
+	if k, ok := msg.(tea.KeyMsg); ok && k.String() == "u" && !m.list.GetFiltering() {
+		if p, ok := m.list.Hovered(); ok && p != nil {
+			m.uninstall = p
+		}
+		return m, nil
+	}
+	if k, ok := msg.(tea.KeyMsg); ok && (k.String() == "o" || k.String() == "R") && !m.list.GetFiltering() {
+		if p, ok := m.list.Hovered(); ok && p != nil {
+			if k.String() == "R" {
+				m.readme = p
+				return m, nil
+			}
+			return m, openRepo(*p)
+		}
+		return m, nil
+	}
+	if msg, ok := msg.(repoOpenedMsg); ok {
+		m.status = msg.status()
+		return m, nil
+	}
+	if k, ok := msg.(tea.KeyMsg); ok && k.String() == "r" && !m.list.GetFiltering() {
+		m.reload = true
+		return m, nil
+	}
+
 	form, cmd := m.form.Update(msg)
 	if f, ok := form.(*huh.Form); ok {
 		m.form = f
@@ -59,21 +171,68 @@ func (m selectorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Quit
 	}
 
+	if p, ok := m.list.Hovered(); ok && p != nil && p.Name != m.hovered {
+		m.hovered = p.Name
+		cmd = tea.Batch(cmd, m.info.wait(*p))
+	}
 	return m, cmd
 }
 
 func (m selectorModel) View() string {
-	return m.form.View()
+	view := m.form.View()
+	if p, ok := m.list.Hovered(); ok && p != nil {
+		if line := m.info.line(*p); line != "" {
+			view += "\n" + line
+		}
+	}
+	if m.status != "" {
+		view += "\n" + m.status + "\n"
+	}
+	if len(m.priority) > 0 {
+		view += "\n" + styleSkipped.Render("  install first: "+strings.Join(m.priority, " → ")) + "\n"
+	}
+	return view
+}
+
+// reloaded returns a selector over programs, freshly loaded from the
+// catalog, that keeps the marks and priorities of programs still in it.
+func (m selectorModel) reloaded(programs []catalog.Program, st *state.State) selectorModel {
+	names := make(map[string]bool, len(programs))
+	for _, p := range programs {
+		names[p.Name] = true
+	}
+	selected := map[string]bool{}
+	for _, p := range *m.result {
+		if p != nil && names[p.Name] {
+			selected[p.Name] = true
+		}
+	}
+	n := newSelectorModel(programs, st, m.info, selected)
+	n.priority = slices.DeleteFunc(slices.Clone(m.priority), func(name string) bool { return !names[name] })
+	n.status = styleDone.Render(fmt.Sprintf("  reloaded the catalog: %d programs", len(programs)))
+	return n
 }
 
 func (m selectorModel) selectedPrograms() []catalog.Program {
 	if m.result == nil {
 		return nil
 	}
+	// Manually prioritized programs outrank any catalog priority, in the
+	// order they were marked.
+	top := 0
+	for _, p := range *m.result {
+		if p != nil {
+			top = max(top, p.Priority)
+		}
+	}
 	out := make([]catalog.Program, 0, len(*m.result))
 	for _, p := range *m.result {
 		if p != nil {
-			out = append(out, *p)
+			prog := *p
+			if i := slices.Index(m.priority, prog.Name); i >= 0 {
+				prog.Priority = top + len(m.priority) - i
+			}
+			out = append(out, prog)
 		}
 	}
 	return out
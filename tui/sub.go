@@ -0,0 +1,75 @@
+package tui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+)
+
+// maxBatch bounds how many messages one poll of a progressSub delivers, so a
+// flood of events cannot delay key handling for long.
+const maxBatch = 64
+
+// coalesceWindow is how long a poll keeps gathering messages after the first
+// one arrives. A large run emits several states per program in quick
+// succession; gathering them for a frame or so turns hundreds of redraws into
+// a few, which is what keeps the screen from flickering over SSH.
+const coalesceWindow = 50 * time.Millisecond
+
+// progressBatch holds every ProgressMsg that was ready when the subscription
+// was polled. The root model applies them together, so a burst of events from
+// many programs costs one redraw instead of one per message.
+type progressBatch []installer.ProgressMsg
+
+// progressClosed is sent once the installer has closed its channel.
+type progressClosed struct{}
+
+// progressSub turns the installer's progress channel into a stream of
+// tea.Msgs. It is always driven by the root model — never polled from within
+// progressModel — and must only be polled again after the previous batch was
+// handled, since the installer blocks on bin pickers and confirmations.
+type progressSub struct {
+	ch <-chan installer.ProgressMsg
+}
+
+// next returns a tea.Cmd that blocks until at least one message is available,
+// then gathers whatever else arrives within coalesceWindow, up to maxBatch.
+// A message waiting on an answer (a bin picker or a confirmation) ends the
+// batch straight away so the prompt is not delayed.
+func (s progressSub) next() tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-s.ch
+		if !ok {
+			return progressClosed{}
+		}
+		batch := progressBatch{msg}
+		if awaitsAnswer(msg) {
+			return batch
+		}
+		timer := time.NewTimer(coalesceWindow)
+		defer timer.Stop()
+		for len(batch) < maxBatch {
+			select {
+			case msg, ok := <-s.ch:
+				if !ok {
+					// The next poll reports the close.
+					return batch
+				}
+				batch = append(batch, msg)
+				if awaitsAnswer(msg) {
+					return batch
+				}
+			case <-timer.C:
+				return batch
+			}
+		}
+		return batch
+	}
+}
+
+// awaitsAnswer reports whether the installer is blocked until msg is
+// answered.
+func awaitsAnswer(msg installer.ProgressMsg) bool {
+	return msg.BinCh != nil || msg.ConfirmCh != nil
+}
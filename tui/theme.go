@@ -1,6 +1,36 @@
 package tui
 
-import "github.com/charmbracelet/huh"
+import (
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+)
 
 // huhTheme is the shared huh form theme used across all form surfaces.
 var huhTheme = huh.ThemeCharm()
+
+// Glyphs mark each program state on the progress screen, so done, skipped
+// and failed programs stay apart without relying on their colour.
+const (
+	glyphDone    = "✓"
+	glyphSkipped = "↻"
+	glyphActive  = "…"
+	glyphError   = "✗"
+	glyphPaused  = "⏸"
+	glyphPending = "·"
+	glyphRemoved = "−"
+)
+
+// SetPalette switches the state colours to the named palette: "" or
+// "default" for the terminal's green, yellow and red, or "colorblind" for
+// blue, yellow and vermilion from the Okabe–Ito set, which stay distinct
+// under the common colour vision deficiencies. The colorblind palette also
+// draws errors bold and skips italic, for monochrome terminals. Unknown
+// names keep the default; config.Load rejects them.
+func SetPalette(name string) {
+	if name != "colorblind" {
+		return
+	}
+	styleDone = lipgloss.NewStyle().Foreground(lipgloss.Color("#56B4E9"))
+	styleSkipped = lipgloss.NewStyle().Foreground(lipgloss.Color("#F0E442")).Italic(true)
+	styleError = lipgloss.NewStyle().Foreground(lipgloss.Color("#D55E00")).Bold(true)
+}
@@ -0,0 +1,117 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/dsaleh/david-dotfiles/internal/catalog"
+	"github.com/dsaleh/david-dotfiles/internal/installer"
+)
+
+// triageEdit is the progress screen's form for fixing the catalog entry of
+// the program whose failure is being triaged, before it is retried.
+type triageEdit struct {
+	form    *huh.Form
+	program catalog.Program
+	repo    *string // heap-allocated; huh writes via pointer
+	pattern *string
+}
+
+func newTriageEdit(p catalog.Program) *triageEdit {
+	repo, pattern := p.Repo, p.AssetPattern
+	e := &triageEdit{program: p, repo: &repo, pattern: &pattern}
+	e.form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Repo").
+				Description("owner/name on GitHub").
+				Value(e.repo),
+			huh.NewInput().
+				Title("Asset pattern").
+				Description("The release asset to download; {version} is filled in.").
+				Value(e.pattern),
+		),
+	).WithTheme(huhTheme)
+	return e
+}
+
+// edited returns the program with the form's values.
+func (e *triageEdit) edited() catalog.Program {
+	p := e.program
+	p.Repo, p.AssetPattern = *e.repo, *e.pattern
+	return p
+}
+
+// triageKey answers the oldest triage prompt: r retries, s skips, a aborts
+// the run and e opens the catalog entry for editing. It reports whether k
+// was one of them, with the command to run.
+func (m *progressModel) triageKey(k string) (tea.Cmd, bool) {
+	if len(m.triageQueue) == 0 {
+		return nil, false
+	}
+	req := m.triageQueue[0]
+	switch k {
+	case "r":
+		m.answerTriage(installer.Triage{Action: installer.TriageRetry})
+	case "s":
+		m.answerTriage(installer.Triage{Action: installer.TriageSkip})
+	case "a":
+		m.answerTriage(installer.Triage{Action: installer.TriageAbort})
+	case "e":
+		m.triageEdit = newTriageEdit(m.specs[req.Program])
+		return m.triageEdit.form.Init(), true
+	default:
+		return nil, false
+	}
+	return nil, true
+}
+
+func (m *progressModel) answerTriage(t installer.Triage) {
+	m.triageQueue[0].TriageCh <- t
+	m.triageQueue = m.triageQueue[1:]
+}
+
+// updateTriageEdit passes msg to the open edit form. A completed form
+// retries the program with the edited entry, which is also written to its
+// catalog when it has one; an aborted form returns to the prompt.
+func (m *progressModel) updateTriageEdit(msg tea.Msg) tea.Cmd {
+	form, cmd := m.triageEdit.form.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.triageEdit.form = f
+	}
+	switch m.triageEdit.form.State {
+	case huh.StateCompleted:
+		p := m.triageEdit.edited()
+		m.triageEdit = nil
+		m.triageErr = m.saveEdit(p)
+		m.specs[p.Name] = p
+		m.answerTriage(installer.Triage{Action: installer.TriageRetry, Program: &p})
+		return nil
+	case huh.StateAborted:
+		m.triageEdit = nil
+		return nil
+	}
+	return cmd
+}
+
+// saveEdit writes the fields of p that differ from its catalog entry to its
+// catalog file, if it has one.
+func (m *progressModel) saveEdit(p catalog.Program) error {
+	path, old := m.catalogs[p.Name], m.specs[p.Name]
+	if path == "" {
+		return nil
+	}
+	for _, f := range []struct{ key, from, to string }{
+		{"repo", old.Repo, p.Repo},
+		{"asset_pattern", old.AssetPattern, p.AssetPattern},
+	} {
+		if f.from == f.to {
+			continue
+		}
+		if err := catalog.SetString(path, p.Name, f.key, f.to); err != nil {
+			return fmt.Errorf("update %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}